@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	hugeText     string
+	hugeTextOnce sync.Once
+)
+
+// largeText returns about 100MB of text made of lines of various lengths.
+func largeText() string {
+	hugeTextOnce.Do(func() {
+		const size = 100 << 20
+		words := strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua")
+		var sb strings.Builder
+		sb.Grow(size + 200)
+		for i := 0; sb.Len() < size; i++ {
+			for j := range i%16 + 1 {
+				sb.WriteString(words[(i+j)%len(words)])
+				sb.WriteByte(' ')
+			}
+			sb.WriteByte('\n')
+		}
+		hugeText = sb.String()
+	})
+	return hugeText
+}
+
+func openLargeText(b *testing.B) *Editor {
+	b.Helper()
+	e := &Editor{}
+	e.SetText(largeText(), false)
+	layoutEditor(e, image.Pt(800, 600))
+	return e
+}
+
+func BenchmarkOpen100MB(b *testing.B) {
+	txt := largeText()
+	b.SetBytes(int64(len(txt)))
+	b.ResetTimer()
+	for range b.N {
+		e := &Editor{}
+		e.SetText(txt, false)
+		layoutEditor(e, image.Pt(800, 600))
+	}
+}
+
+// BenchmarkType100MB measures typing a character and laying out the frame.
+func BenchmarkType100MB(b *testing.B) {
+	e := openLargeText(b)
+	mid := e.Len() / 2
+	e.SetCaret(mid, mid)
+	b.ResetTimer()
+	for range b.N {
+		e.Insert("x")
+		layoutEditor(e, image.Pt(800, 600))
+	}
+}
+
+// BenchmarkNewline100MB measures inserting a new paragraph in the middle of
+// the text.
+func BenchmarkNewline100MB(b *testing.B) {
+	e := openLargeText(b)
+	mid := e.Len() / 2
+	e.SetCaret(mid, mid)
+	b.ResetTimer()
+	for range b.N {
+		e.Insert("\n")
+		layoutEditor(e, image.Pt(800, 600))
+	}
+}
+
+// BenchmarkDelete100MB measures deleting a character and laying out the frame.
+func BenchmarkDelete100MB(b *testing.B) {
+	e := openLargeText(b)
+	mid := e.Len() / 2
+	e.SetCaret(mid, mid)
+	b.ResetTimer()
+	for range b.N {
+		e.Delete(-1)
+		layoutEditor(e, image.Pt(800, 600))
+	}
+}
+
+// BenchmarkScroll100MB measures scrolling by a page and laying out the frame.
+func BenchmarkScroll100MB(b *testing.B) {
+	e := openLargeText(b)
+	height := e.text.FullDimensions().Size.Y
+	b.ResetTimer()
+	for i := range b.N {
+		e.text.scrollAbs(0, (i*600)%height)
+		layoutEditor(e, image.Pt(800, 600))
+	}
+}
+
+// BenchmarkJumpToEnd100MB measures moving the caret between the start and the
+// end of the text.
+func BenchmarkJumpToEnd100MB(b *testing.B) {
+	e := openLargeText(b)
+	b.ResetTimer()
+	for i := range b.N {
+		if i%2 == 0 {
+			e.text.MoveTextEnd(selectionClear)
+		} else {
+			e.text.MoveTextStart(selectionClear)
+		}
+		e.text.ScrollToCaret()
+		layoutEditor(e, image.Pt(800, 600))
+	}
+}
@@ -3,148 +3,192 @@
 package editor
 
 import (
-	"bytes"
 	"io"
+	"slices"
+	"sort"
 	"unicode/utf8"
 
 	"golang.org/x/text/runes"
 )
 
-// editBuffer implements a gap buffer for text editing.
-type editBuffer struct {
-	// The gap start and end in bytes.
-	gapstart, gapend int
-	text             []byte
+// pieceTable implements a piece table for text editing. The text is a list of
+// pieces, each referring to a span of either the original text or the
+// append-only add buffer. Edits only split and insert pieces, so their cost
+// does not depend on the size of the text.
+type pieceTable struct {
+	// original is the text the table was created with. It is never modified.
+	original []byte
+	// add holds all the inserted text.
+	add    []byte
+	pieces []piece
+	// offsets holds the start offset of every piece in the text.
+	offsets []int64
+	size    int64
 
 	// changed tracks whether the buffer content
 	// has changed since the last call to Changed.
 	changed bool
 }
 
-var _ textSource = (*editBuffer)(nil)
+type piece struct {
+	// added tells whether the piece refers to the add buffer.
+	added bool
+	off   int
+	len   int
+}
 
-const minSpace = 5
+var _ textSource = (*pieceTable)(nil)
 
-func (e *editBuffer) Changed() bool {
-	c := e.changed
-	e.changed = false
-	return c
+// newPieceTable creates a piece table with the text. The table takes the
+// ownership of the slice.
+func newPieceTable(text []byte) *pieceTable {
+	if !utf8.Valid(text) {
+		text = runes.ReplaceIllFormed().Bytes(text)
+	}
+
+	pt := &pieceTable{original: text}
+	if len(text) > 0 {
+		pt.pieces = append(pt.pieces, piece{off: 0, len: len(text)})
+	}
+	pt.updateOffsets(0)
+	return pt
 }
 
-func (e *editBuffer) deleteRunes(caret, count int) (bytes int, runes int) {
-	e.moveGap(caret, 0)
-	for ; count < 0 && e.gapstart > 0; count++ {
-		_, s := utf8.DecodeLastRune(e.text[:e.gapstart])
-		e.gapstart -= s
-		bytes += s
-		runes++
-		e.changed = e.changed || s > 0
-	}
-	for ; count > 0 && e.gapend < len(e.text); count-- {
-		_, s := utf8.DecodeRune(e.text[e.gapend:])
-		e.gapend += s
-		e.changed = e.changed || s > 0
-	}
-	return
+func (pt *pieceTable) Changed() bool {
+	c := pt.changed
+	pt.changed = false
+	return c
 }
 
-// moveGap moves the gap to the caret position. After returning,
-// the gap is guaranteed to be at least space bytes long.
-func (e *editBuffer) moveGap(caret, space int) {
-	if e.gapLen() < space {
-		if space < minSpace {
-			space = minSpace
-		}
-		txt := make([]byte, int(e.Size())+space)
-		// Expand to capacity.
-		txt = txt[:cap(txt)]
-		gaplen := len(txt) - int(e.Size())
-		if caret > e.gapstart {
-			copy(txt, e.text[:e.gapstart])
-			copy(txt[caret+gaplen:], e.text[caret:])
-			copy(txt[e.gapstart:], e.text[e.gapend:caret+e.gapLen()])
-		} else {
-			copy(txt, e.text[:caret])
-			copy(txt[e.gapstart+gaplen:], e.text[e.gapend:])
-			copy(txt[caret+gaplen:], e.text[caret:e.gapstart])
-		}
-		e.text = txt
-		e.gapstart = caret
-		e.gapend = e.gapstart + gaplen
-	} else {
-		if caret > e.gapstart {
-			copy(e.text[e.gapstart:], e.text[e.gapend:caret+e.gapLen()])
-		} else {
-			copy(e.text[caret+e.gapLen():], e.text[caret:e.gapstart])
-		}
-		l := e.gapLen()
-		e.gapstart = caret
-		e.gapend = e.gapstart + l
-	}
+func (pt *pieceTable) Size() int64 {
+	return pt.size
 }
 
-func (e *editBuffer) Size() int64 {
-	return int64(len(e.text) - e.gapLen())
+func (pt *pieceTable) bytes(p piece) []byte {
+	if p.added {
+		return pt.add[p.off : p.off+p.len]
+	}
+	return pt.original[p.off : p.off+p.len]
 }
 
-func (e *editBuffer) gapLen() int {
-	return e.gapend - e.gapstart
+// pieceAt returns the index of the piece containing the byte at offset, or
+// the number of pieces if offset is at the end of the text.
+func (pt *pieceTable) pieceAt(offset int64) int {
+	return sort.Search(len(pt.pieces), func(i int) bool {
+		return pt.offsets[i]+int64(pt.pieces[i].len) > offset
+	})
 }
 
-func (e *editBuffer) ReadAt(p []byte, offset int64) (int, error) {
+func (pt *pieceTable) ReadAt(p []byte, offset int64) (int, error) {
 	if len(p) == 0 {
 		return 0, nil
 	}
-	if offset == e.Size() {
+	if offset >= pt.size {
 		return 0, io.EOF
 	}
+
 	var total int
-	if offset < int64(e.gapstart) {
-		n := copy(p, e.text[offset:e.gapstart])
+	for i := pt.pieceAt(offset); i < len(pt.pieces) && len(p) > 0; i++ {
+		n := copy(p, pt.bytes(pt.pieces[i])[offset-pt.offsets[i]:])
 		p = p[n:]
 		total += n
 		offset += int64(n)
 	}
-	if offset >= int64(e.gapstart) {
-		n := copy(p, e.text[offset+int64(e.gapLen()):])
-		total += n
-	}
 	return total, nil
 }
 
-func (e *editBuffer) ReplaceRunes(byteOffset, runeCount int64, s string) {
-	e.deleteRunes(int(byteOffset), int(runeCount))
-	e.prepend(int(byteOffset), s)
-}
-
-func (e *editBuffer) prepend(caret int, s string) {
+func (pt *pieceTable) ReplaceRunes(byteOffset, runeCount int64, s string) {
 	if !utf8.ValidString(s) {
 		s = runes.ReplaceIllFormed().String(s)
 	}
 
-	e.moveGap(caret, len(s))
-	copy(e.text[caret:], s)
-	e.gapstart += len(s)
-	e.changed = e.changed || len(s) > 0
+	if byteOffset > pt.size {
+		byteOffset = pt.size
+	}
+	pt.replace(byteOffset, pt.runeBytes(byteOffset, runeCount), s)
+}
+
+// runeBytes returns the length in bytes of count runes starting at offset.
+func (pt *pieceTable) runeBytes(offset, count int64) int64 {
+	var n int64
+	for i := pt.pieceAt(offset); i < len(pt.pieces) && count > 0; i++ {
+		b := pt.bytes(pt.pieces[i])[offset+n-pt.offsets[i]:]
+		for len(b) > 0 && count > 0 {
+			_, s := utf8.DecodeRune(b)
+			b = b[s:]
+			n += int64(s)
+			count--
+		}
+	}
+	return n
 }
 
-func (e *editBuffer) countLinesBeforeOffset(byteOffset int64) int {
-	cnt := 0
+// replace replaces n bytes at offset with s.
+func (pt *pieceTable) replace(offset, n int64, s string) {
+	if n == 0 && s == "" {
+		return
+	}
+
+	end := offset + n
+	first := pt.pieceAt(offset)
+	last := first
+	if n > 0 {
+		last = pt.pieceAt(end - 1)
+	}
+
+	var repl [3]piece
+	pieces := repl[:0]
+	if first < len(pt.pieces) && offset > pt.offsets[first] {
+		left := pt.pieces[first]
+		left.len = int(offset - pt.offsets[first])
+		pieces = append(pieces, left)
+	}
 
-	if byteOffset >= int64(len(e.text)) {
-		byteOffset = int64(len(e.text))
+	if s != "" {
+		// Extend the previous piece if it ends at the end of the add buffer,
+		// which is the case of typing continuously.
+		var prev *piece
+		if len(pieces) > 0 {
+			prev = &pieces[0]
+		} else if first > 0 {
+			prev = &pt.pieces[first-1]
+		}
+		if prev != nil && prev.added && prev.off+prev.len == len(pt.add) {
+			prev.len += len(s)
+		} else {
+			pieces = append(pieces, piece{added: true, off: len(pt.add), len: len(s)})
+		}
+		pt.add = append(pt.add, s...)
 	}
 
-	offset := 0
-	for {
-		index := bytes.IndexByte(e.text[offset:byteOffset], '\n')
-		if index == -1 {
-			break
+	if last < len(pt.pieces) {
+		p := pt.pieces[last]
+		if cut := int(end - pt.offsets[last]); cut < p.len && (n > 0 || cut > 0) {
+			pieces = append(pieces, piece{added: p.added, off: p.off + cut, len: p.len - cut})
 		}
-		offset += index
-		cnt++
-		offset++ // Move past the newline
 	}
 
-	return cnt
+	replaced := last + 1
+	if n == 0 && (first == len(pt.pieces) || offset == pt.offsets[first]) {
+		// Inserting between two pieces.
+		replaced = first
+	}
+	pt.pieces = slices.Replace(pt.pieces, first, min(replaced, len(pt.pieces)), pieces...)
+	pt.updateOffsets(max(first-1, 0))
+	pt.changed = true
+}
+
+// updateOffsets recalculates the offsets of the pieces starting from the
+// piece at from.
+func (pt *pieceTable) updateOffsets(from int) {
+	pt.offsets = slices.Grow(pt.offsets[:min(from, len(pt.offsets))], len(pt.pieces)-from)[:len(pt.pieces)]
+	var off int64
+	if from > 0 {
+		off = pt.offsets[from-1] + int64(pt.pieces[from-1].len)
+	}
+	for i := from; i < len(pt.pieces); i++ {
+		pt.offsets[i] = off
+		off += int64(pt.pieces[i].len)
+	}
+	pt.size = off
 }
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func readAll(t *testing.T, src textSource) string {
+	t.Helper()
+	buf := make([]byte, src.Size())
+	n, err := src.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestPieceTable(t *testing.T) {
+	pt := newPieceTable([]byte("hello world"))
+	want := "hello world"
+
+	replace := func(runeOff, runeCount int, s string) {
+		byteOff := len(string([]rune(want)[:runeOff]))
+		pt.ReplaceRunes(int64(byteOff), int64(runeCount), s)
+		r := []rune(want)
+		want = string(r[:runeOff]) + s + string(r[min(runeOff+runeCount, len(r)):])
+	}
+
+	replace(5, 0, ",")
+	replace(0, 0, ">> ")
+	replace(len([]rune(want)), 0, "!")
+	replace(3, 5, "J")
+	replace(4, 0, "ö")
+	replace(5, 0, "ü")
+	if got := readAll(t, pt); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"", "a", "bc", "\n", "日本", "é\n", "longer text"}
+	for range 2000 {
+		n := utf8.RuneCountInString(want)
+		off := rng.Intn(n + 1)
+		count := rng.Intn(min(n-off, 8) + 1)
+		replace(off, count, words[rng.Intn(len(words))])
+
+		if pt.Size() != int64(len(want)) {
+			t.Fatalf("size %d, want %d", pt.Size(), len(want))
+		}
+	}
+	if got := readAll(t, pt); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// read across pieces.
+	buf := make([]byte, 7)
+	for off := 0; off < len(want); off += 3 {
+		n, _ := pt.ReadAt(buf, int64(off))
+		if string(buf[:n]) != want[off:min(off+7, len(want))] {
+			t.Fatalf("read at %d: got %q", off, buf[:n])
+		}
+	}
+}
+
+func TestPieceTableInvalidUTF8(t *testing.T) {
+	pt := newPieceTable(nil)
+	pt.ReplaceRunes(0, 0, "a\xffb")
+	if got := readAll(t, pt); !utf8.ValidString(got) || !strings.HasPrefix(got, "a") {
+		t.Fatalf("unexpected text: %q", got)
+	}
+	if !pt.Changed() || pt.Changed() {
+		t.Fatal("Changed should be reset after it is called")
+	}
+}
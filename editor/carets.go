@@ -0,0 +1,349 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// Selection is a caret and its selection, in runes. Start is the caret
+// position, and the text between Start and End is selected. Start can be
+// > End.
+type Selection struct {
+	Start, End int
+}
+
+// caret is the state of a caret. It has the same layout as the caret of
+// textView, so they are assignable to each other.
+type caret struct {
+	xoff       fixed.Int26_6
+	start, end int
+}
+
+func (c caret) min() int { return min(c.start, c.end) }
+func (c caret) max() int { return max(c.start, c.end) }
+
+// Selections returns all the selections. The first one is the primary
+// selection, which is the one returned by Selection, and the others follow in
+// text order.
+func (e *Editor) Selections() []Selection {
+	e.initBuffer()
+	start, end := e.text.Selection()
+	sels := []Selection{{Start: start, End: end}}
+	for _, c := range e.carets {
+		sels = append(sels, Selection{Start: c.start, End: c.end})
+	}
+	return sels
+}
+
+// SetSelections replaces all the selections. The first one becomes the
+// primary selection. Overlapping selections are merged.
+func (e *Editor) SetSelections(sels []Selection) {
+	e.initBuffer()
+	if len(sels) == 0 {
+		return
+	}
+	e.SetCaret(sels[0].Start, sels[0].End)
+	for _, s := range sels[1:] {
+		e.carets = append(e.carets, e.clampCaret(s.Start, s.End))
+	}
+	e.normalizeCarets()
+}
+
+// AddCaret adds a caret with a selection from start to end, and makes it the
+// primary one.
+func (e *Editor) AddCaret(start, end int) {
+	e.initBuffer()
+	e.carets = append(e.carets, e.text.caret)
+	e.text.SetCaret(start, end)
+	e.text.caret.xoff = 0
+	e.normalizeCarets()
+	e.scrollCaret = true
+}
+
+// RemoveCarets removes all the carets but the primary one.
+func (e *Editor) RemoveCarets() {
+	e.carets = e.carets[:0]
+}
+
+// clampCaret returns a caret with the ends clamped to grapheme cluster
+// boundaries.
+func (e *Editor) clampCaret(start, end int) caret {
+	primary := e.text.caret
+	defer func() { e.text.caret = primary }()
+	e.text.SetCaret(start, end)
+	return e.text.caret
+}
+
+// normalizeCarets sorts the extra carets and merges the overlapping ones.
+// A caret overlapping the primary caret is merged into the primary one.
+func (e *Editor) normalizeCarets() {
+	if len(e.carets) == 0 {
+		return
+	}
+	slices.SortFunc(e.carets, func(a, b caret) int { return a.min() - b.min() })
+
+	primary := caret(e.text.caret)
+	overlaps := func(a, b caret) bool {
+		return a.min() < b.max() && b.min() < a.max() || a.min() == b.min() || a.max() == b.max()
+	}
+	merge := func(into *caret, c caret) {
+		lo, hi := min(into.min(), c.min()), max(into.max(), c.max())
+		if into.start >= into.end {
+			into.start, into.end = hi, lo
+		} else {
+			into.start, into.end = lo, hi
+		}
+		if into.start == into.end {
+			into.start, into.end = lo, lo
+		}
+	}
+
+	carets := e.carets[:0]
+	for _, c := range e.carets {
+		switch {
+		case overlaps(primary, c):
+			merge(&primary, c)
+		case len(carets) > 0 && overlaps(carets[len(carets)-1], c):
+			merge(&carets[len(carets)-1], c)
+		default:
+			carets = append(carets, c)
+		}
+	}
+	// The primary caret may have grown over the carets before it.
+	e.carets = slices.DeleteFunc(carets, func(c caret) bool {
+		if overlaps(primary, c) {
+			merge(&primary, c)
+			return true
+		}
+		return false
+	})
+	e.text.caret = primary
+}
+
+// forEachCaret runs f with every caret set as the caret of the text view, and
+// keeps the moved carets.
+func (e *Editor) forEachCaret(f func()) {
+	if len(e.carets) == 0 {
+		f()
+		return
+	}
+	primary := e.text.caret
+	for i := range e.carets {
+		e.text.caret = e.carets[i]
+		f()
+		e.carets[i] = e.text.caret
+	}
+	e.text.caret = primary
+	f()
+	e.normalizeCarets()
+}
+
+// editCarets runs the edit at every caret, from the end of the text to the
+// start, and records the edits as a single undo step. edit changes the text at
+// the caret of the text view, and returns the number of runes changed.
+func (e *Editor) editCarets(edit func() int) int {
+	if len(e.carets) == 0 {
+		return edit()
+	}
+
+	// Keep all the carets in e.carets while editing, so that replace adjusts
+	// them.
+	primary := len(e.carets)
+	e.carets = append(e.carets, e.text.caret)
+	order := make([]int, len(e.carets))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int { return e.carets[b].min() - e.carets[a].min() })
+
+	e.BeginBatch()
+	n := 0
+	for _, i := range order {
+		e.text.caret = e.carets[i]
+		n += edit()
+		e.carets[i] = e.text.caret
+	}
+	e.EndBatch()
+
+	e.text.caret = e.carets[primary]
+	e.carets = slices.Delete(e.carets, primary, primary+1)
+	e.normalizeCarets()
+	return n
+}
+
+// adjustCarets moves the extra carets after the runes in [start, end) are
+// replaced with n runes.
+func (e *Editor) adjustCarets(start, end, n int) {
+	newEnd := start + n
+	adjust := func(pos int) int {
+		switch {
+		case newEnd < pos && pos <= end:
+			pos = newEnd
+		case end < pos:
+			pos += newEnd - end
+		}
+		return pos
+	}
+	for i := range e.carets {
+		e.carets[i].start = adjust(e.carets[i].start)
+		e.carets[i].end = adjust(e.carets[i].end)
+	}
+}
+
+// selectedTexts returns the selected text of every caret in text order,
+// joined by line breaks.
+func (e *Editor) selectedTexts() string {
+	if len(e.carets) == 0 {
+		return e.SelectedText()
+	}
+	carets := append([]caret{e.text.caret}, e.carets...)
+	slices.SortFunc(carets, func(a, b caret) int { return a.min() - b.min() })
+
+	primary := e.text.caret
+	var texts []string
+	for _, c := range carets {
+		e.text.caret = c
+		e.scratch = e.text.SelectedText(e.scratch)
+		texts = append(texts, string(e.scratch))
+	}
+	e.text.caret = primary
+	return strings.Join(texts, "\n")
+}
+
+// hasSelection reports whether any of the carets selects some text.
+func (e *Editor) hasSelection() bool {
+	if start, end := e.text.Selection(); start != end {
+		return true
+	}
+	for _, c := range e.carets {
+		if c.start != c.end {
+			return true
+		}
+	}
+	return false
+}
+
+// paste inserts the text at every caret. If the text has as many lines as
+// there are carets, every caret gets one line of the text.
+func (e *Editor) paste(s string) int {
+	lines := strings.Split(s, "\n")
+	if len(e.carets) == 0 || len(lines) != len(e.carets)+1 {
+		return e.Insert(s)
+	}
+
+	// editCarets goes from the last caret to the first one.
+	i := len(lines)
+	return e.editCarets(func() int {
+		i--
+		return e.insert(lines[i])
+	})
+}
+
+// SelectNextOccurrence selects the next occurrence of the text selected by
+// the primary caret with a new caret. If nothing is selected, the word at the
+// caret is selected instead. It reports whether the selections are changed.
+func (e *Editor) SelectNextOccurrence() bool {
+	e.initBuffer()
+	start, end := e.text.Selection()
+	if start == end {
+		start, end = e.wordAt(start)
+		if start == end {
+			return false
+		}
+		e.text.SetCaret(end, start)
+		return true
+	}
+
+	needle := e.SelectedText()
+	text := e.Text()
+	// Search after the last selection, wrapping around the end of the text.
+	last := caret(e.text.caret).max()
+	for _, c := range e.carets {
+		last = max(last, c.max())
+	}
+	from := int(e.text.ByteOffset(last))
+	for range len(e.carets) + 2 {
+		i := strings.Index(text[from:], needle)
+		if i < 0 {
+			if from == 0 {
+				return false
+			}
+			from = 0
+			continue
+		}
+		off := from + i
+		occStart := e.text.runeAtByte(off)
+		occEnd := occStart + len([]rune(needle))
+		if !e.selected(occStart, occEnd) {
+			e.AddCaret(occEnd, occStart)
+			return true
+		}
+		from = off + len(needle)
+	}
+	return false
+}
+
+// selected reports whether a caret selects exactly the range.
+func (e *Editor) selected(start, end int) bool {
+	for _, c := range append([]caret{e.text.caret}, e.carets...) {
+		if c.min() == start && c.max() == end {
+			return true
+		}
+	}
+	return false
+}
+
+// wordAt returns the range of the word around the rune offset.
+func (e *Editor) wordAt(pos int) (start, end int) {
+	isWord := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	start, end = pos, pos
+	for start > 0 {
+		r, _, _ := e.text.ReadRuneBefore(e.text.ByteOffset(start))
+		if !isWord(r) {
+			break
+		}
+		start--
+	}
+	for end < e.text.Len() {
+		r, _, _ := e.text.ReadRuneAt(e.text.ByteOffset(end))
+		if !isWord(r) {
+			break
+		}
+		end++
+	}
+	return start, end
+}
+
+// selectColumn sets a caret on every screen line between the points, which
+// are relative to the editor, selecting the text between their x coordinates.
+// The caret on the line of to is the primary one.
+func (e *Editor) selectColumn(from, to image.Point) {
+	from = from.Add(e.text.scrollOff)
+	to = to.Add(e.text.scrollOff)
+	fromPos := e.text.closestToXY(fixed.I(from.X), from.Y)
+	toPos := e.text.closestToXY(fixed.I(to.X), to.Y)
+
+	lines := abs(toPos.lineCol.line - fromPos.lineCol.line)
+	e.carets = e.carets[:0]
+	for i := 0; i <= lines; i++ {
+		y := fromPos.y
+		if lines > 0 {
+			// Lines have the same height.
+			y += (toPos.y - fromPos.y) * i / lines
+		}
+		start := e.text.closestToXYGraphemes(fixed.I(to.X), y).runes
+		end := e.text.closestToXYGraphemes(fixed.I(from.X), y).runes
+		e.carets = append(e.carets, caret{start: start, end: end})
+	}
+	e.text.caret = e.carets[len(e.carets)-1]
+	e.carets = e.carets[:len(e.carets)-1]
+	e.normalizeCarets()
+}
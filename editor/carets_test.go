@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"slices"
+	"testing"
+)
+
+func TestMultipleCarets(t *testing.T) {
+	e := &Editor{}
+	e.SetText("one\ntwo\nthree", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	e.SetSelections([]Selection{{Start: 4, End: 4}, {Start: 0, End: 0}, {Start: 8, End: 8}, {Start: 8, End: 8}})
+	if got := e.Selections(); !slices.Equal(got, []Selection{{4, 4}, {0, 0}, {8, 8}}) {
+		t.Fatalf("unexpected selections: %v", got)
+	}
+
+	e.Insert("> ")
+	if got, want := e.Text(), "> one\n> two\n> three"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := e.Selections(); !slices.Equal(got, []Selection{{8, 8}, {2, 2}, {14, 14}}) {
+		t.Fatalf("unexpected selections: %v", got)
+	}
+
+	e.Delete(-1)
+	if got, want := e.Text(), ">one\n>two\n>three"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// The edits at all the carets are undone at once.
+	e.undo()
+	if got, want := e.Text(), "> one\n> two\n> three"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	e.undo()
+	if got, want := e.Text(), "one\ntwo\nthree"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	e.redo()
+	if got, want := e.Text(), "> one\n> two\n> three"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if n := len(e.Selections()); n != 1 {
+		t.Fatalf("undo should leave a single caret, got %d", n)
+	}
+}
+
+func TestMergeCarets(t *testing.T) {
+	e := &Editor{}
+	e.SetText("abcdefgh", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	e.SetSelections([]Selection{{Start: 2, End: 4}, {Start: 3, End: 6}, {Start: 7, End: 7}, {Start: 0, End: 1}})
+	if got := e.Selections(); !slices.Equal(got, []Selection{{2, 6}, {0, 1}, {7, 7}}) {
+		t.Fatalf("unexpected selections: %v", got)
+	}
+
+	// Moving every caret to the line end merges them.
+	e.forEachCaret(func() { e.text.MoveLineEnd(selectionClear) })
+	if got := e.Selections(); !slices.Equal(got, []Selection{{8, 8}}) {
+		t.Fatalf("unexpected selections: %v", got)
+	}
+}
+
+func TestSelectNextOccurrence(t *testing.T) {
+	e := &Editor{}
+	e.SetText("foo bar foo baz foo", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	e.SetCaret(9, 9)
+	e.SelectNextOccurrence()
+	if got := e.SelectedText(); got != "foo" {
+		t.Fatalf("word is not selected: %q", got)
+	}
+	e.SelectNextOccurrence()
+	e.SelectNextOccurrence()
+	if got := e.Selections(); !slices.Equal(got, []Selection{{3, 0}, {11, 8}, {19, 16}}) {
+		t.Fatalf("unexpected selections: %v", got)
+	}
+	if e.SelectNextOccurrence() {
+		t.Fatal("all occurrences are selected already")
+	}
+
+	e.Insert("x")
+	if got, want := e.Text(), "x bar x baz x"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := e.selectedTexts(); got != "\n\n" {
+		t.Fatalf("unexpected selected texts: %q", got)
+	}
+}
+
+func TestColumnSelection(t *testing.T) {
+	e := &Editor{}
+	e.SetText("abcdef\nab\nabcdef", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	from := e.text.closestToRune(1)
+	to := e.text.closestToRune(14)
+	e.selectColumn(image.Pt(from.x.Round(), from.y), image.Pt(to.x.Round(), to.y))
+	if got := e.Selections(); !slices.Equal(got, []Selection{{14, 11}, {4, 1}, {9, 8}}) {
+		t.Fatalf("unexpected selections: %v", got)
+	}
+	if got := e.selectedTexts(); got != "bcd\nb\nbcd" {
+		t.Fatalf("unexpected selected texts: %q", got)
+	}
+
+	e.paste("1\n2\n3")
+	if got, want := e.Text(), "a1ef\na2\na3ef"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
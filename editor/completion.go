@@ -0,0 +1,466 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gioui.org/font"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+
+	"github.com/oligo/gioview/menu"
+)
+
+// maxCompletions is the number of completion items shown in the popup.
+const maxCompletions = 10
+
+// CompletionItem is a candidate of a completion.
+type CompletionItem struct {
+	// Label is shown in the popup, and matched against the completed text.
+	Label string
+	// Detail is shown after the label, e.g., the type of a symbol.
+	Detail string
+	// Text replaces the completed text. It defaults to Label.
+	Text string
+	// Snippet reports whether Text is the body of a snippet, which is
+	// expanded with its tab stops.
+	Snippet bool
+}
+
+// CompletionContext is the context of a completion request.
+type CompletionContext struct {
+	// Line is the text of the caret line before the caret.
+	Line string
+	// Caret is the rune offset of the caret.
+	Caret int
+	// Explicit reports whether the completion is requested with Ctrl+Space
+	// rather than by typing.
+	Explicit bool
+}
+
+// CompletionProvider provides the candidates of a completion.
+type CompletionProvider interface {
+	// Complete returns the rune offset of the completed text, which ends at
+	// the caret, and the candidates to replace it with. The editor filters the
+	// candidates by the completed text, so they may be returned unfiltered.
+	Complete(ctx CompletionContext) (start int, items []CompletionItem)
+}
+
+// CompletionFunc adapts a function to a CompletionProvider.
+type CompletionFunc func(ctx CompletionContext) (start int, items []CompletionItem)
+
+func (f CompletionFunc) Complete(ctx CompletionContext) (int, []CompletionItem) {
+	return f(ctx)
+}
+
+// completionCandidate is a candidate and the start of the text it completes.
+type completionCandidate struct {
+	CompletionItem
+	start int
+}
+
+// completionState is the state of the completion popup.
+type completionState struct {
+	providers []CompletionProvider
+	active    bool
+	// external reports whether the candidates are set by ShowCompletions.
+	// They are filtered as the text changes, while the candidates of the
+	// providers are requested again.
+	external   bool
+	explicit   bool
+	candidates []completionCandidate
+	// items are the candidates matching the completed text.
+	items    []completionCandidate
+	selected int
+	// first is the first item shown in the popup.
+	first int
+	// revision and caret are the state of the text the items are filtered
+	// for.
+	revision, caret int
+	// typed is set when text is typed, which requests the completions.
+	typed bool
+	// rows are the pointer tags of the rows.
+	rows [maxCompletions]int
+}
+
+// completionStyle is the style of the completion popup.
+type completionStyle struct {
+	menu.Style
+	shaper   *text.Shaper
+	typeFace font.Typeface
+	textSize unit.Sp
+	fg       color.NRGBA
+}
+
+// SetCompletionProviders sets the providers of the completion popup, which is
+// shown as the user types, or with Ctrl+Space. The candidates of all the
+// providers are shown in order.
+func (e *Editor) SetCompletionProviders(providers ...CompletionProvider) {
+	e.completion.providers = providers
+	e.HideCompletions()
+}
+
+// TriggerCompletion requests the completions at the caret from the providers,
+// and shows the popup if there are any.
+func (e *Editor) TriggerCompletion() {
+	e.requestCompletions(true)
+}
+
+// ShowCompletions shows the completion popup at the caret. The text from start
+// to the caret is replaced by the accepted item. The items are filtered as the
+// user types.
+func (e *Editor) ShowCompletions(start int, items []CompletionItem) {
+	candidates := make([]completionCandidate, len(items))
+	for i, item := range items {
+		candidates[i] = completionCandidate{CompletionItem: item, start: start}
+	}
+	e.showCandidates(candidates, true, true)
+}
+
+// HideCompletions hides the completion popup.
+func (e *Editor) HideCompletions() {
+	c := &e.completion
+	c.active, c.external, c.explicit = false, false, false
+	c.candidates, c.items = nil, nil
+}
+
+// Completing reports whether the completion popup is shown.
+func (e *Editor) Completing() bool {
+	return e.completion.active
+}
+
+// Completions returns the items shown in the completion popup, and the index
+// of the selected item.
+func (e *Editor) Completions() ([]CompletionItem, int) {
+	c := &e.completion
+	if !c.active {
+		return nil, -1
+	}
+	items := make([]CompletionItem, len(c.items))
+	for i, item := range c.items {
+		items[i] = item.CompletionItem
+	}
+	return items, c.selected
+}
+
+// AcceptCompletion replaces the completed text with the item i of the popup,
+// and hides the popup.
+func (e *Editor) AcceptCompletion(i int) {
+	c := &e.completion
+	if !c.active || i < 0 || i >= len(c.items) {
+		return
+	}
+	item := c.items[i]
+	e.HideCompletions()
+	text := item.Text
+	if text == "" {
+		text = item.Label
+	}
+	caret, _ := e.Selection()
+	e.SetCaret(item.start, caret)
+	if item.Snippet {
+		e.InsertSnippet(text)
+	} else {
+		e.Insert(text)
+	}
+	// Accepting is not typing.
+	c.typed = false
+	c.revision = e.revision
+}
+
+// requestCompletions requests the completions of the providers.
+func (e *Editor) requestCompletions(explicit bool) {
+	c := &e.completion
+	caret, end := e.Selection()
+	if len(c.providers) == 0 || caret != end || len(e.carets) > 0 {
+		return
+	}
+	_, col := e.text.CaretPos()
+	ctx := CompletionContext{
+		Line:     e.textRange(caret-col, caret),
+		Caret:    caret,
+		Explicit: explicit,
+	}
+	var candidates []completionCandidate
+	for _, p := range c.providers {
+		start, items := p.Complete(ctx)
+		for _, item := range items {
+			candidates = append(candidates, completionCandidate{CompletionItem: item, start: start})
+		}
+	}
+	e.showCandidates(candidates, explicit, false)
+}
+
+func (e *Editor) showCandidates(candidates []completionCandidate, explicit, external bool) {
+	c := &e.completion
+	c.active, c.external, c.explicit = true, external, explicit
+	c.candidates, c.items = candidates, nil
+	c.selected, c.first = 0, 0
+	e.filterCompletions()
+}
+
+// filterCompletions filters the candidates by the completed text, and hides
+// the popup when none is left.
+func (e *Editor) filterCompletions() {
+	c := &e.completion
+	caret, end := e.Selection()
+	c.revision, c.caret = e.revision, caret
+	if caret != end {
+		e.HideCompletions()
+		return
+	}
+	var selected *completionCandidate
+	if c.selected < len(c.items) {
+		selected = &c.items[c.selected]
+	}
+	type match struct {
+		completionCandidate
+		rank int
+	}
+	var matches []match
+	for _, cand := range c.candidates {
+		// The completed text can't be longer than the candidate.
+		if cand.start > caret || caret-cand.start > len(cand.Label) {
+			continue
+		}
+		typed := e.textRange(cand.start, caret)
+		if strings.IndexFunc(typed, unicode.IsSpace) >= 0 {
+			continue
+		}
+		if rank, ok := matchCompletion(cand.Label, typed); ok {
+			matches = append(matches, match{cand, rank})
+		}
+	}
+	if len(matches) == 0 {
+		e.HideCompletions()
+		return
+	}
+	slices.SortStableFunc(matches, func(a, b match) int { return a.rank - b.rank })
+	items := make([]completionCandidate, len(matches))
+	// Keep the selected item selected.
+	c.selected = 0
+	for i, m := range matches {
+		items[i] = m.completionCandidate
+		if selected != nil && items[i] == *selected {
+			c.selected = i
+		}
+	}
+	c.items = items
+	e.selectCompletion(c.selected)
+}
+
+// matchCompletion reports whether the label matches the typed text, and ranks
+// the match: a prefix is better than a case-insensitive prefix, which is
+// better than a subsequence.
+func matchCompletion(label, typed string) (rank int, ok bool) {
+	switch {
+	case strings.HasPrefix(label, typed):
+		return 0, true
+	case len(label) >= len(typed) && strings.EqualFold(label[:len(typed)], typed):
+		return 1, true
+	}
+	rest := strings.ToLower(label)
+	for _, r := range strings.ToLower(typed) {
+		i := strings.IndexRune(rest, r)
+		if i < 0 {
+			return 0, false
+		}
+		rest = rest[i+len(string(r)):]
+	}
+	return 2, true
+}
+
+// selectCompletion selects the item i, and scrolls it into the popup.
+func (e *Editor) selectCompletion(i int) {
+	c := &e.completion
+	n := len(c.items)
+	if n == 0 {
+		return
+	}
+	c.selected = (i%n + n) % n
+	if c.selected < c.first {
+		c.first = c.selected
+	} else if c.selected >= c.first+maxCompletions {
+		c.first = c.selected - maxCompletions + 1
+	}
+	c.first = max(min(c.first, n-maxCompletions), 0)
+}
+
+// completionCommand handles the keys of the completion popup, and reports
+// whether the key is consumed.
+func (e *Editor) completionCommand(k key.Event) bool {
+	c := &e.completion
+	if !c.active || k.Modifiers.Contain(key.ModShift) || k.Modifiers.Contain(key.ModShortcut) {
+		return false
+	}
+	switch k.Name {
+	case key.NameUpArrow:
+		e.selectCompletion(c.selected - 1)
+	case key.NameDownArrow:
+		e.selectCompletion(c.selected + 1)
+	case key.NamePageUp:
+		e.selectCompletion(max(c.selected-maxCompletions, 0))
+	case key.NamePageDown:
+		e.selectCompletion(min(c.selected+maxCompletions, len(c.items)-1))
+	case key.NameReturn, key.NameEnter, key.NameTab:
+		e.AcceptCompletion(c.selected)
+	case key.NameEscape:
+		e.HideCompletions()
+	default:
+		return false
+	}
+	return true
+}
+
+// updateCompletions requests the completions after typing, refilters them as
+// the text or the caret changes, and accepts the clicked item.
+func (e *Editor) updateCompletions(gtx layout.Context) {
+	c := &e.completion
+	if c.typed {
+		c.typed = false
+		if !c.active || !c.external {
+			e.requestCompletions(c.active && c.explicit)
+		}
+	}
+	if !c.active {
+		return
+	}
+	if !gtx.Focused(e) || len(e.carets) > 0 {
+		e.HideCompletions()
+		return
+	}
+	if caret, _ := e.Selection(); c.revision != e.revision || c.caret != caret {
+		e.filterCompletions()
+	}
+	// The popup may be shown after the keys of the editor are handled, so
+	// its keys are handled here in the frame it is shown.
+	for c.active {
+		ev, ok := gtx.Event(
+			key.Filter{Focus: e, Name: key.NameEscape},
+			key.Filter{Focus: e, Name: key.NameUpArrow},
+			key.Filter{Focus: e, Name: key.NameDownArrow},
+		)
+		if !ok {
+			break
+		}
+		if ke, ok := ev.(key.Event); ok && ke.State == key.Press {
+			e.completionCommand(ke)
+		}
+	}
+	if !c.active {
+		return
+	}
+	for i := range c.rows {
+		for {
+			ev, ok := gtx.Event(pointer.Filter{Target: &c.rows[i], Kinds: pointer.Press})
+			if !ok {
+				break
+			}
+			if pe, ok := ev.(pointer.Event); ok && pe.Buttons == pointer.ButtonPrimary {
+				e.AcceptCompletion(c.first + i)
+				gtx.Execute(op.InvalidateCmd{})
+			}
+		}
+	}
+}
+
+// layoutCompletions draws the completion popup below the caret, or above it
+// if there is no room below.
+func (e *Editor) layoutCompletions(gtx layout.Context, cs completionStyle) {
+	e.updateCompletions(gtx)
+	c := &e.completion
+	if !c.active {
+		return
+	}
+	items := c.items[c.first:min(len(c.items), c.first+maxCompletions)]
+
+	// Measure the rows to find the width of the popup.
+	rowGtx := gtx
+	rowGtx.Constraints = layout.Constraints{Max: image.Pt(gtx.Dp(480), gtx.Constraints.Max.Y)}
+	rowGtx.Ops = new(op.Ops)
+	width := 0
+	for _, item := range items {
+		dims := cs.LayoutOption(rowGtx, false, func(gtx layout.Context) layout.Dimensions {
+			return cs.layoutItem(gtx, item.CompletionItem)
+		})
+		width = max(width, dims.Size.X)
+	}
+
+	macro := op.Record(gtx.Ops)
+	popupGtx := gtx
+	popupGtx.Constraints = layout.Constraints{Max: gtx.Constraints.Max}
+	dims := cs.LayoutSurface(popupGtx, func(gtx layout.Context) layout.Dimensions {
+		y := 0
+		for i, item := range items {
+			stack := op.Offset(image.Pt(0, y)).Push(gtx.Ops)
+			gtx.Constraints = layout.Exact(image.Pt(width, 0))
+			gtx.Constraints.Max.Y = gtx.Dp(480)
+			dims := cs.LayoutOption(gtx, c.first+i == c.selected, func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min.Y = 0
+				return cs.layoutItem(gtx, item.CompletionItem)
+			})
+			area := clip.Rect{Max: dims.Size}.Push(gtx.Ops)
+			event.Op(gtx.Ops, &c.rows[i])
+			pointer.CursorPointer.Add(gtx.Ops)
+			area.Pop()
+			stack.Pop()
+			y += dims.Size.Y
+		}
+		return layout.Dimensions{Size: image.Pt(width, y)}
+	})
+	call := macro.Stop()
+
+	caret := e.CaretCoords().Round()
+	_, asc, desc := e.text.CaretInfo()
+	gap := gtx.Dp(unit.Dp(4))
+	pos := image.Pt(caret.X, caret.Y+desc+gap)
+	if top := caret.Y - asc - gap - dims.Size.Y; pos.Y+dims.Size.Y > gtx.Constraints.Max.Y && top >= 0 {
+		pos.Y = top
+	}
+	pos.X = max(min(pos.X, gtx.Constraints.Max.X-dims.Size.X), 0)
+
+	macro = op.Record(gtx.Ops)
+	op.Offset(pos).Add(gtx.Ops)
+	call.Add(gtx.Ops)
+	op.Defer(gtx.Ops, macro.Stop())
+}
+
+// layoutItem lays out the label of an item, and its detail at the end of the
+// row.
+func (cs completionStyle) layoutItem(gtx layout.Context, item CompletionItem) layout.Dimensions {
+	minWidth := gtx.Constraints.Min.X
+	gtx.Constraints.Min = image.Point{}
+	label := func(txt string, fg color.NRGBA) layout.Dimensions {
+		colorMacro := op.Record(gtx.Ops)
+		paint.ColorOp{Color: fg}.Add(gtx.Ops)
+		return widget.Label{MaxLines: 1}.Layout(gtx, cs.shaper, font.Font{Typeface: cs.typeFace}, cs.textSize, txt, colorMacro.Stop())
+	}
+	dims := label(item.Label, cs.fg)
+	if item.Detail == "" {
+		dims.Size.X = max(dims.Size.X, minWidth)
+		return dims
+	}
+	macro := op.Record(gtx.Ops)
+	detail := label(item.Detail, MulAlpha(cs.fg, 0x90))
+	call := macro.Stop()
+	x := max(dims.Size.X+gtx.Dp(unit.Dp(16)), minWidth-detail.Size.X)
+	stack := op.Offset(image.Pt(x, dims.Baseline-detail.Baseline)).Push(gtx.Ops)
+	call.Add(gtx.Ops)
+	stack.Pop()
+	dims.Size.X = x + detail.Size.X
+	dims.Size.Y = max(dims.Size.Y, detail.Size.Y)
+	return dims
+}
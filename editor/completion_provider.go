@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordPrefix returns the word at the end of the line.
+func wordPrefix(line string) string {
+	i := strings.LastIndexFunc(line, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if i < 0 {
+		return line
+	}
+	_, size := utf8.DecodeRuneInString(line[i:])
+	return line[i+size:]
+}
+
+// WordCompletion completes the word at the caret with the words, e.g., the
+// keywords of a language. Typing completes words of at least one letter, and
+// Ctrl+Space completes empty words too.
+func WordCompletion(words ...string) CompletionProvider {
+	return CompletionFunc(func(ctx CompletionContext) (int, []CompletionItem) {
+		prefix := wordPrefix(ctx.Line)
+		if prefix == "" && !ctx.Explicit {
+			return ctx.Caret, nil
+		}
+		items := make([]CompletionItem, 0, len(words))
+		for _, w := range words {
+			if w != prefix {
+				items = append(items, CompletionItem{Label: w})
+			}
+		}
+		return ctx.Caret - utf8.RuneCountInString(prefix), items
+	})
+}
+
+// SnippetCompletion completes the word at the caret with the prefixes of the
+// snippets. The completed word is replaced by the expanded snippet.
+func SnippetCompletion(snippets []Snippet) CompletionProvider {
+	return CompletionFunc(func(ctx CompletionContext) (int, []CompletionItem) {
+		prefix := wordPrefix(ctx.Line)
+		if prefix == "" && !ctx.Explicit {
+			return ctx.Caret, nil
+		}
+		items := make([]CompletionItem, 0, len(snippets))
+		for _, s := range snippets {
+			detail := s.Description
+			if detail == "" {
+				detail = "snippet"
+			}
+			items = append(items, CompletionItem{Label: s.Prefix, Detail: detail, Text: s.Body, Snippet: true})
+		}
+		return ctx.Caret - utf8.RuneCountInString(prefix), items
+	})
+}
+
+// FileCompletion completes the file path at the caret with the names of the
+// files of its directory. Relative paths are relative to dir. Typing completes
+// paths containing a slash, and Ctrl+Space completes any path.
+func FileCompletion(dir string) CompletionProvider {
+	return CompletionFunc(func(ctx CompletionContext) (int, []CompletionItem) {
+		i := strings.LastIndexFunc(ctx.Line, func(r rune) bool {
+			return unicode.IsSpace(r) || strings.ContainsRune(`"'()<>[]{}`+"`", r)
+		})
+		path := ctx.Line[i+1:]
+		if !strings.Contains(path, "/") && !ctx.Explicit {
+			return ctx.Caret, nil
+		}
+		slash := strings.LastIndex(path, "/")
+		name := path[slash+1:]
+		start := ctx.Caret - utf8.RuneCountInString(name)
+
+		dirPath := path[:slash+1]
+		if strings.HasPrefix(dirPath, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				dirPath = filepath.Join(home, dirPath[2:])
+			}
+		} else if !filepath.IsAbs(dirPath) {
+			dirPath = filepath.Join(dir, dirPath)
+		}
+		entries, err := os.ReadDir(dirPath)
+		if err != nil {
+			return start, nil
+		}
+		items := make([]CompletionItem, 0, len(entries))
+		for _, entry := range entries {
+			// Hidden files are completed when the name starts with a dot.
+			if strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(name, ".") {
+				continue
+			}
+			item := CompletionItem{Label: entry.Name(), Detail: "file"}
+			if entry.IsDir() {
+				item.Label += "/"
+				item.Detail = "dir"
+			}
+			items = append(items, item)
+		}
+		return start, items
+	})
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gioui.org/io/key"
+)
+
+func completionLabels(e *Editor) []string {
+	items, _ := e.Completions()
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	return labels
+}
+
+func TestMatchCompletion(t *testing.T) {
+	tests := []struct {
+		label, typed string
+		rank         int
+		ok           bool
+	}{
+		{"Println", "Pr", 0, true},
+		{"Println", "pr", 1, true},
+		{"Println", "pln", 2, true},
+		{"Println", "", 0, true},
+		{"Println", "px", 0, false},
+		{"Pr", "Println", 0, false},
+	}
+	for _, tc := range tests {
+		rank, ok := matchCompletion(tc.label, tc.typed)
+		if rank != tc.rank || ok != tc.ok {
+			t.Errorf("matchCompletion(%q, %q) = %d, %v, want %d, %v", tc.label, tc.typed, rank, ok, tc.rank, tc.ok)
+		}
+	}
+}
+
+func TestCompletionFilter(t *testing.T) {
+	e := &Editor{}
+	e.SetText("fmt.", false)
+	layoutEditor(e, image.Pt(400, 400))
+	e.SetCaret(4, 4)
+	e.ShowCompletions(4, []CompletionItem{{Label: "Sprint"}, {Label: "Println"}, {Label: "printf"}})
+	if got, want := completionLabels(e), []string{"Sprint", "Println", "printf"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// Typing filters and ranks the items, and keeps the selected one.
+	e.selectCompletion(2)
+	e.Insert("pr")
+	e.filterCompletions()
+	if got, want := completionLabels(e), []string{"printf", "Println", "Sprint"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, selected := e.Completions(); selected != 0 {
+		t.Fatalf("selected %d, want 0", selected)
+	}
+	e.Insert("l")
+	e.filterCompletions()
+	if got, want := completionLabels(e), []string{"Println"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	e.AcceptCompletion(0)
+	if got := e.Text(); got != "fmt.Println" {
+		t.Fatalf("got %q", got)
+	}
+	if e.Completing() {
+		t.Fatal("the popup is not hidden")
+	}
+
+	// Moving the caret before the completed text hides the popup.
+	e.ShowCompletions(4, []CompletionItem{{Label: "Println"}})
+	e.SetCaret(2, 2)
+	e.filterCompletions()
+	if e.Completing() {
+		t.Fatal("the popup is not hidden")
+	}
+}
+
+func TestCompletionProviders(t *testing.T) {
+	words := WordCompletion("func", "for", "fallthrough")
+	start, items := words.Complete(CompletionContext{Line: "\tfo", Caret: 10})
+	if start != 8 || len(items) != 3 {
+		t.Fatalf("got %d, %v", start, items)
+	}
+	if _, items := words.Complete(CompletionContext{Line: "a ", Caret: 2}); len(items) != 0 {
+		t.Fatalf("got %v for an empty word", items)
+	}
+	if _, items := words.Complete(CompletionContext{Line: "a ", Caret: 2, Explicit: true}); len(items) != 3 {
+		t.Fatalf("got %v for an explicit completion", items)
+	}
+
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "docs"), 0o755)
+	os.WriteFile(filepath.Join(dir, "docs", "readme.md"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "docs", ".hidden"), nil, 0o644)
+	files := FileCompletion(dir)
+	start, items = files.Complete(CompletionContext{Line: `open("docs/re`, Caret: 13})
+	if start != 11 || len(items) != 1 || items[0].Label != "readme.md" {
+		t.Fatalf("got %d, %v", start, items)
+	}
+	start, items = files.Complete(CompletionContext{Line: "see d", Caret: 5, Explicit: true})
+	if start != 4 || len(items) != 1 || items[0].Label != "docs/" {
+		t.Fatalf("got %d, %v", start, items)
+	}
+}
+
+func TestCompletionPopup(t *testing.T) {
+	e := &Editor{}
+	e.SetCompletionProviders(WordCompletion("print", "println", "printf", "return"))
+	h := newHarness(e, image.Pt(400, 200), EditorConf{})
+	h.Click(image.Pt(10, 10))
+
+	// Typing shows the matching words, and the arrows select one.
+	h.Type("p")
+	h.Type("r")
+	if items, _ := e.Completions(); len(items) != 3 {
+		t.Fatalf("unexpected completions: %v", items)
+	}
+	h.Key(key.NameDownArrow, 0)
+	h.Key(key.NameReturn, 0)
+	if e.Text() != "println" || e.Completing() {
+		t.Fatalf("unexpected text: %q", e.Text())
+	}
+
+	// Escape hides the popup, and Ctrl+Space shows it again.
+	h.Type(" ")
+	h.Type("r")
+	if !e.Completing() {
+		t.Fatal("no completions")
+	}
+	h.Key(key.NameEscape, 0)
+	if e.Completing() {
+		t.Fatal("escape should hide the popup")
+	}
+	h.Key(key.NameSpace, key.ModCtrl)
+	if items, _ := e.Completions(); len(items) != 4 || items[0].Label != "return" {
+		t.Fatalf("unexpected completions: %v", items)
+	}
+
+	// Clicking an item accepts it.
+	caret := e.CaretCoords().Round()
+	h.Click(caret.Add(image.Pt(10, 24)))
+	if e.Text() != "println return" {
+		t.Fatalf("unexpected text: %q", e.Text())
+	}
+}
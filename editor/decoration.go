@@ -0,0 +1,282 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"sort"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// UnderlineStyle is the style of the underline of a Decoration.
+type UnderlineStyle uint8
+
+const (
+	UnderlineNone UnderlineStyle = iota
+	// UnderlineStraight is a straight line, e.g., for links.
+	UnderlineStraight
+	// UnderlineWavy is a squiggly line, e.g., for spelling errors and
+	// diagnostics.
+	UnderlineWavy
+)
+
+// Decoration annotates a rune range of the text, e.g., with the diagnostics of
+// a linter or a spell checker. The range follows the edits of the text, and
+// the decoration is dropped when all of its text is deleted.
+type Decoration struct {
+	// Source groups the decorations set together, e.g., "lint" or "spell".
+	Source string
+	// Start and End are the rune offsets of the decorated range. A hint may be
+	// put after an empty range.
+	Start, End    int
+	Underline     UnderlineStyle
+	Strikethrough bool
+	// Color is the color of the lines and of the hint.
+	Color color.NRGBA
+	// Hint is shown after the end of the logical line of End.
+	Hint string
+	// Hover is shown in a popup when the pointer rests on the range.
+	Hover string
+}
+
+// SetDecorations replaces the decorations of the source. Setting no
+// decorations clears them.
+func (e *Editor) SetDecorations(source string, decorations []Decoration) {
+	e.decorations = slices.DeleteFunc(e.decorations, func(d Decoration) bool {
+		return d.Source == source
+	})
+	for _, d := range decorations {
+		if d.Start > d.End {
+			d.Start, d.End = d.End, d.Start
+		}
+		d.Source = source
+		e.decorations = append(e.decorations, d)
+	}
+	slices.SortStableFunc(e.decorations, func(a, b Decoration) int { return a.Start - b.Start })
+}
+
+// Decorations returns the decorations of all the sources, sorted by their start
+// offsets.
+func (e *Editor) Decorations() []Decoration {
+	return e.decorations
+}
+
+// DecorationsAt returns the decorations covering the rune offset.
+func (e *Editor) DecorationsAt(r int) []Decoration {
+	var out []Decoration
+	for _, d := range e.decorations {
+		if d.Start > r {
+			break
+		}
+		if r < d.End {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// updateDecorations shifts the decorations after the replacement of the runes
+// [start, end) with n runes. Text inserted inside a range extends it, and
+// deleted text shrinks it.
+func (e *Editor) updateDecorations(start, end, n int) {
+	if len(e.decorations) == 0 {
+		return
+	}
+	newEnd := start + n
+	delta := newEnd - end
+	e.decorations = slices.DeleteFunc(e.decorations, func(d Decoration) bool {
+		return d.End > start && d.End <= end && d.Start >= start && d.Start < end
+	})
+	for i := range e.decorations {
+		d := &e.decorations[i]
+		empty := d.Start == d.End
+		switch {
+		case d.Start >= end:
+			d.Start += delta
+		case d.Start >= start:
+			d.Start = newEnd
+		}
+		switch {
+		case d.End <= start:
+		case d.End >= end:
+			d.End += delta
+		default:
+			d.End = start
+		}
+		if empty {
+			d.End = d.Start
+		}
+		d.End = max(d.End, d.Start)
+	}
+}
+
+// paintDecorations paints the lines and the hints of the visible decorations.
+func (e *Editor) paintDecorations(gtx layout.Context) {
+	if len(e.decorations) == 0 {
+		return
+	}
+	decorations := e.decorations
+	if start, end, ok := e.text.visibleRunes(); ok {
+		j := sort.Search(len(decorations), func(i int) bool { return decorations[i].Start > end })
+		decorations = decorations[:j]
+		for len(decorations) > 0 && decorations[0].End < start {
+			decorations = decorations[1:]
+		}
+	}
+
+	hints := make(map[int][]Decoration)
+	var hintLines []int
+	for _, d := range decorations {
+		if d.Underline != UnderlineNone || d.Strikethrough {
+			e.text.paintLines(gtx, d)
+		}
+		if d.Hint != "" {
+			end := e.text.lineEnd(d.End)
+			if _, ok := hints[end]; !ok {
+				hintLines = append(hintLines, end)
+			}
+			hints[end] = append(hints[end], d)
+		}
+	}
+	for _, end := range hintLines {
+		e.text.paintHints(gtx, end, hints[end])
+	}
+}
+
+// paintLines paints the underline and the strike-through line of the
+// decoration.
+func (e *textView) paintLines(gtx layout.Context, d Decoration) {
+	if d.Start >= d.End {
+		return
+	}
+	docViewport := image.Rectangle{Max: e.viewSize}.Add(e.scrollOff)
+	defer clip.Rect(image.Rectangle{Max: e.viewSize}).Push(gtx.Ops).Pop()
+	e.regions = e.index.locate(docViewport, d.Start, d.End, e.regions)
+	thickness := float32(max(gtx.Dp(1), 1))
+	for _, region := range e.regions {
+		bounds := region.Bounds
+		baseline := bounds.Max.Y - region.Baseline
+		x0, x1 := float32(bounds.Min.X), float32(bounds.Max.X)
+		if x1 <= x0 {
+			continue
+		}
+		if d.Strikethrough {
+			y := float32(baseline) - float32(baseline-bounds.Min.Y)*0.3
+			rect := clip.Rect{Min: image.Pt(bounds.Min.X, int(y)), Max: image.Pt(bounds.Max.X, int(y+thickness))}
+			paint.FillShape(gtx.Ops, d.Color, rect.Op())
+		}
+		y := float32(baseline) + thickness*1.5
+		switch d.Underline {
+		case UnderlineStraight:
+			rect := clip.Rect{Min: image.Pt(bounds.Min.X, int(y)), Max: image.Pt(bounds.Max.X, int(y+thickness))}
+			paint.FillShape(gtx.Ops, d.Color, rect.Op())
+		case UnderlineWavy:
+			paint.FillShape(gtx.Ops, d.Color, clip.Stroke{Path: wavyLine(gtx.Ops, x0, x1, y+thickness, thickness), Width: thickness}.Op())
+		}
+	}
+}
+
+// wavyLine returns a wave from x0 to x1 around y, whose amplitude is
+// proportional to the line thickness.
+func wavyLine(ops *op.Ops, x0, x1, y, thickness float32) clip.PathSpec {
+	amplitude, halfPeriod := thickness*1.5, thickness*3
+	var path clip.Path
+	path.Begin(ops)
+	path.MoveTo(f32.Pt(x0, y))
+	up := true
+	for x := x0; x < x1; x += halfPeriod {
+		step := halfPeriod
+		if x+step > x1 {
+			step = x1 - x
+		}
+		dy := amplitude * 2 * step / halfPeriod
+		if up {
+			dy = -dy
+		}
+		path.QuadTo(f32.Pt(x+step/2, y+dy), f32.Pt(x+step, y))
+		up = !up
+	}
+	return path.End()
+}
+
+// lineEnd returns the rune offset of the end of the logical line containing
+// the rune, before the line break.
+func (e *textView) lineEnd(r int) int {
+	paras := &e.paras
+	if paras.monolithic || len(paras.paragraphs) == 0 {
+		return paras.runes
+	}
+	idx := paras.paragraphAtRune(r)
+	_, n := paras.size(idx)
+	end := paras.paragraphs[idx].runeOff + n
+	if idx < len(paras.paragraphs)-1 {
+		end--
+	}
+	return end
+}
+
+// paintHints paints the hints of the decorations after the line end, which
+// is a rune offset.
+func (e *textView) paintHints(gtx layout.Context, end int, decorations []Decoration) {
+	if first, last, ok := e.visibleRunes(); ok && (end < first || end > last) {
+		return
+	}
+	if paras := &e.paras; !paras.monolithic && paras.paragraphs[paras.paragraphAtRune(end)].hidden {
+		return
+	}
+	// The hints are one em apart from the text and from each other.
+	gap := e.params.PxPerEm.Round()
+	pos := e.closestToRune(end)
+	x := pos.x.Round() - e.scrollOff.X + gap
+	y := pos.y - e.scrollOff.Y
+
+	// The hints may be wider than the longest line, which bounds the view size.
+	defer clip.Rect(image.Rectangle{Max: gtx.Constraints.Max}).Push(gtx.Ops).Pop()
+	params := e.params
+	params.MinWidth, params.MaxWidth, params.MaxLines = 0, 1<<24, 1
+	params.Alignment = text.Start
+	for _, d := range decorations {
+		x = e.paintHint(gtx, params, d.Hint, image.Pt(x, y), MulAlpha(d.Color, 0xb0))
+		x += gap
+	}
+}
+
+// paintHint paints a line of text with the baseline at pos, and returns the
+// x coordinate of its end.
+func (e *textView) paintHint(gtx layout.Context, params text.Parameters, s string, pos image.Point, c color.NRGBA) int {
+	e.shaper.LayoutString(params, strings.TrimSpace(s))
+	var glyphs []text.Glyph
+	var advance fixed.Int26_6
+	for {
+		g, ok := e.shaper.NextGlyph()
+		if !ok {
+			break
+		}
+		glyphs = append(glyphs, g)
+		if end := g.X + g.Advance; end > advance {
+			advance = end
+		}
+	}
+	if len(glyphs) == 0 {
+		return pos.X
+	}
+	// The outlines are relative to the dot of the first glyph.
+	offset := op.Offset(image.Pt(pos.X+glyphs[0].X.Round(), pos.Y)).Push(gtx.Ops)
+	outline := clip.Outline{Path: e.shaper.Shape(glyphs)}.Op().Push(gtx.Ops)
+	paint.ColorOp{Color: c}.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	outline.Pop()
+	offset.Pop()
+	return pos.X + advance.Ceil()
+}
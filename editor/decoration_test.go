@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"slices"
+	"testing"
+)
+
+type decorationRange struct{ start, end int }
+
+func decorationRanges(e *Editor) []decorationRange {
+	var out []decorationRange
+	for _, d := range e.Decorations() {
+		out = append(out, decorationRange{d.Start, d.End})
+	}
+	return out
+}
+
+func TestDecorations(t *testing.T) {
+	e := &Editor{}
+	e.SetText("teh quick borwn fox\njumps", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	e.SetDecorations("spell", []Decoration{
+		{Start: 10, End: 15, Underline: UnderlineWavy},
+		{Start: 0, End: 3, Underline: UnderlineWavy},
+	})
+	e.SetDecorations("lint", []Decoration{{Start: 19, End: 19, Hint: "missing period"}})
+	if got, want := decorationRanges(e), []decorationRange{{0, 3}, {10, 15}, {19, 19}}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := e.DecorationsAt(12); len(got) != 1 || got[0].Source != "spell" {
+		t.Fatalf("unexpected decorations at 12: %v", got)
+	}
+
+	// Typing before a range shifts it, and typing inside extends it.
+	e.SetCaret(4, 4)
+	e.Insert("very ")
+	e.SetCaret(17, 17)
+	e.Insert("x")
+	if got, want := decorationRanges(e), []decorationRange{{0, 3}, {15, 21}, {25, 25}}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// Replacing all the text of a range drops it, and deleting a part of it
+	// shrinks it.
+	e.SetCaret(0, 3)
+	e.Insert("the")
+	e.SetCaret(13, 17)
+	e.Delete(1)
+	if got, want := decorationRanges(e), []decorationRange{{13, 17}, {21, 21}}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	e.SetDecorations("spell", nil)
+	if got, want := decorationRanges(e), []decorationRange{{21, 21}}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestDecorationHover(t *testing.T) {
+	e := &Editor{}
+	e.SetText("one two\nthree", false)
+	layoutEditor(e, image.Pt(400, 400))
+	e.SetDecorations("lint", []Decoration{
+		{Start: 4, End: 7, Underline: UnderlineWavy, Hover: "unused"},
+		{Start: 4, End: 13, Hover: "deprecated"},
+	})
+	layoutEditor(e, image.Pt(400, 400))
+
+	regions := e.Regions(4, 7, nil)
+	if len(regions) != 1 {
+		t.Fatalf("got %d regions", len(regions))
+	}
+	center := regions[0].Bounds.Min.Add(regions[0].Bounds.Size().Div(2))
+	if got := e.hoverText(e.runeAt(center)); got != "unused\ndeprecated" {
+		t.Fatalf("unexpected hover text: %q", got)
+	}
+	// The space after the line end is not hovered.
+	if r := e.runeAt(image.Pt(380, center.Y)); r != -1 {
+		t.Fatalf("unexpected rune after the line end: %d", r)
+	}
+	if got := e.hoverText(e.runeAt(image.Pt(2, center.Y))); got != "" {
+		t.Fatalf("unexpected hover text: %q", got)
+	}
+
+	e.SetHoverProvider(hoverFunc(func(r int) string { return "func two()" }))
+	if got := e.hoverText(e.runeAt(center)); got != "unused\ndeprecated\nfunc two()" {
+		t.Fatalf("unexpected hover text: %q", got)
+	}
+}
+
+type hoverFunc func(r int) string
+
+func (f hoverFunc) HoverText(r int) string { return f(r) }
@@ -15,18 +15,18 @@ import (
 	"gioui.org/f32"
 	"gioui.org/font"
 	"gioui.org/gesture"
-	"gioui.org/io/clipboard"
 	"gioui.org/io/event"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
 	"gioui.org/io/semantic"
-	"gioui.org/io/system"
 	"gioui.org/io/transfer"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/text"
 	"gioui.org/unit"
+
+	"github.com/oligo/gioview/keybinding"
 )
 
 // Editor implements an editable and scrollable text area.
@@ -72,12 +72,33 @@ type Editor struct {
 	// TabCharacter is the character used to represent a tab. If empty, \t is used.
 	TabCharacter string
 
-	buffer     *editBuffer
+	// UndoTree keeps the steps undone when other edits are made after undo,
+	// as branches which redo may go to. See SetRedoBranch.
+	UndoTree bool
+
+	// Commands has the key chords of the edit commands of the keymaps, e.g.,
+	// the registry of a view manager with the user bindings. If it is nil,
+	// the commands have their default key chords.
+	Commands *keybinding.Registry
+
+	buffer     *pieceTable
 	textStyles []*TextStyle
+	highlight  highlighting
+	// carets are the carets other than the primary one, in text order.
+	carets []caret
 	// Match ranges in rune offset, for text search.
 	matches []MatchRange
 	// Index of the current [MatchRange].
 	currentMatch int
+	search       searchState
+	folds        folding
+	gutter       gutterState
+	decorations  []Decoration
+	hover        hoverState
+	completion   completionState
+	snippets     snippetState
+	keys         Keymap
+	revision     int
 	// scratch is a byte buffer that is reused to efficiently read portions of text
 	// from the textView.
 	scratch    []byte
@@ -87,22 +108,24 @@ type Editor struct {
 	ime struct {
 		imeState
 		scratch []byte
+		// skipSelection drops the selection event following text consumed
+		// by the keymap.
+		skipSelection bool
 	}
 
-	dragging    bool
-	dragger     gesture.Drag
-	scroller    gesture.Scroll
-	scrollCaret bool
-	showCaret   bool
+	dragging bool
+	// columnAnchor is where a column selection started, if columnSelecting.
+	columnAnchor    image.Point
+	columnSelecting bool
+	dragger         gesture.Drag
+	scroller        gesture.Scroll
+	scrollCaret     bool
+	showCaret       bool
 
 	clicker gesture.Click
 
 	// history contains undo history.
-	history []modification
-	// nextHistoryIdx is the index within the history of the next modification. This
-	// is only not len(history) immediately after undo operations occur. It is framed as the "next" value
-	// to make the zero value consistent.
-	nextHistoryIdx int
+	history undoHistory
 
 	pending []EditorEvent
 }
@@ -239,6 +262,16 @@ func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
 		soff = e.text.ScrollOff().Y
 	}
 
+	for {
+		evt, ok := gtx.Event(pointer.Filter{
+			Target: e,
+			Kinds:  pointer.Move | pointer.Enter | pointer.Leave | pointer.Cancel,
+		})
+		if !ok {
+			break
+		}
+		e.updateHover(gtx, evt)
+	}
 	for {
 		evt, ok := e.clicker.Update(gtx.Source)
 		if !ok {
@@ -274,10 +307,21 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 			evt.Kind == gesture.KindClick && evt.Source != pointer.Mouse:
 			prevCaretPos, _ := e.text.Selection()
 			e.blinkStart = gtx.Now
-			e.text.MoveCoord(image.Point{
+			pos := image.Point{
 				X: int(math.Round(float64(evt.Position.X))),
 				Y: int(math.Round(float64(evt.Position.Y))),
-			})
+			}
+			addCaret := evt.Modifiers.Contain(key.ModAlt) && evt.NumClicks == 1
+			if addCaret {
+				// Alt-click adds a caret, and Alt-drag selects a column.
+				e.carets = append(e.carets, e.text.caret)
+				e.columnAnchor = pos
+				e.columnSelecting = true
+			} else {
+				e.RemoveCarets()
+				e.columnSelecting = false
+			}
+			e.text.MoveCoord(pos)
 			gtx.Execute(key.FocusCmd{Tag: e})
 			if !e.ReadOnly {
 				gtx.Execute(key.SoftKeyboardCmd{Show: true})
@@ -286,7 +330,10 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 				e.scrollCaret = true
 			}
 
-			if evt.Modifiers == key.ModShift {
+			if addCaret {
+				e.text.ClearSelection()
+				e.normalizeCarets()
+			} else if evt.Modifiers == key.ModShift {
 				start, end := e.text.Selection()
 				// If they clicked closer to the end, then change the end to
 				// where the caret used to be (effectively swapping start & end).
@@ -319,14 +366,22 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 		case evt.Kind == pointer.Drag && evt.Source == pointer.Mouse:
 			if e.dragging {
 				e.blinkStart = gtx.Now
-				e.text.MoveCoord(image.Point{
+				pos := image.Point{
 					X: int(math.Round(float64(evt.Position.X))),
 					Y: int(math.Round(float64(evt.Position.Y))),
-				})
+				}
+				if e.columnSelecting {
+					if pos != e.columnAnchor {
+						e.selectColumn(e.columnAnchor, pos)
+					}
+				} else {
+					e.text.MoveCoord(pos)
+				}
 				e.scrollCaret = true
 
 				if release {
 					e.dragging = false
+					e.columnSelecting = false
 				}
 			}
 		}
@@ -346,37 +401,16 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 	if e.text.Changed() {
 		return ChangeEvent{}, true
 	}
-	caret, _ := e.text.Selection()
-	atBeginning := caret == 0
-	atEnd := caret == e.text.Len()
-	if gtx.Locale.Direction.Progression() != system.FromOrigin {
-		atEnd, atBeginning = atBeginning, atEnd
-	}
+	completing := e.completion.active
+	keymap := e.Keymap()
 	filters := []event.Filter{
 		key.FocusFilter{Target: e},
 		transfer.TargetFilter{Target: e, Type: "application/text"},
-		key.Filter{Focus: e, Name: key.NameEnter, Optional: key.ModShift},
-		key.Filter{Focus: e, Name: key.NameReturn, Optional: key.ModShift},
-
-		key.Filter{Focus: e, Name: "Z", Required: key.ModShortcut, Optional: key.ModShift},
-		key.Filter{Focus: e, Name: "C", Required: key.ModShortcut},
-		key.Filter{Focus: e, Name: "V", Required: key.ModShortcut},
-		key.Filter{Focus: e, Name: "X", Required: key.ModShortcut},
-		key.Filter{Focus: e, Name: "A", Required: key.ModShortcut},
-
-		key.Filter{Focus: e, Name: key.NameDeleteBackward, Optional: key.ModShortcutAlt | key.ModShift},
-		key.Filter{Focus: e, Name: key.NameDeleteForward, Optional: key.ModShortcutAlt | key.ModShift},
-
-		key.Filter{Focus: e, Name: key.NameHome, Optional: key.ModShortcut | key.ModShift},
-		key.Filter{Focus: e, Name: key.NameEnd, Optional: key.ModShortcut | key.ModShift},
-		key.Filter{Focus: e, Name: key.NamePageDown, Optional: key.ModShift},
-		key.Filter{Focus: e, Name: key.NamePageUp, Optional: key.ModShift},
-		key.Filter{Focus: e, Name: key.NameTab},
-		condFilter(!atBeginning, key.Filter{Focus: e, Name: key.NameLeftArrow, Optional: key.ModShortcutAlt | key.ModShift}),
-		condFilter(!atBeginning, key.Filter{Focus: e, Name: key.NameUpArrow, Optional: key.ModShortcutAlt | key.ModShift}),
-		condFilter(!atEnd, key.Filter{Focus: e, Name: key.NameRightArrow, Optional: key.ModShortcutAlt | key.ModShift}),
-		condFilter(!atEnd, key.Filter{Focus: e, Name: key.NameDownArrow, Optional: key.ModShortcutAlt | key.ModShift}),
+		condFilter(completing, key.Filter{Focus: e, Name: key.NameEscape}),
+		condFilter(completing, key.Filter{Focus: e, Name: key.NameUpArrow}),
+		condFilter(completing, key.Filter{Focus: e, Name: key.NameDownArrow}),
 	}
+	filters = append(filters, keymap.Filters(gtx, e)...)
 	// adjust keeps track of runes dropped because of MaxLen.
 	var adjust int
 	for {
@@ -413,6 +447,17 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 		case key.SnippetEvent:
 			e.updateSnippet(gtx, ke.Start, ke.End)
 		case key.EditEvent:
+			e.ime.skipSelection = false
+			if keymap.Text(gtx, e, ke.Text) {
+				// The input method assumes the text is inserted. Drop its
+				// selection update, and send it the state again.
+				e.ime.imeState = imeState{}
+				e.ime.skipSelection = true
+				if e.text.Changed() {
+					return ChangeEvent{}, true
+				}
+				break
+			}
 			if e.ReadOnly {
 				break
 			}
@@ -431,8 +476,20 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 			case e.SingleLine:
 				s = strings.ReplaceAll(s, "\n", " ")
 			}
-			moves += e.replace(ke.Range.Start, ke.Range.End, s, true, 0)
+			rng := key.Range{Start: min(ke.Range.Start, ke.Range.End), End: max(ke.Range.Start, ke.Range.End)}
+			if start, end := e.text.Selection(); len(e.carets) > 0 && rng == (key.Range{Start: min(start, end), End: max(start, end)}) {
+				// Typing at every caret. Only the runes of the primary caret
+				// count for the adjustment of the selection events.
+				if e.Insert(s) > 0 {
+					moves += utf8.RuneCountInString(s)
+				}
+			} else {
+				moves += e.recordAs(editTyping, func() int {
+					return e.replace(ke.Range.Start, ke.Range.End, s, true)
+				})
+			}
 			adjust += utf8.RuneCountInString(ke.Text) - moves
+			e.completion.typed = true
 			// Reset caret xoff.
 			e.text.MoveCaret(0, 0)
 			if submit {
@@ -452,11 +509,15 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 			e.scroller.Stop()
 			content, err := io.ReadAll(ke.Open())
 			if err == nil {
-				if e.Insert(string(content)) != 0 {
+				if e.paste(string(content)) != 0 {
 					return ChangeEvent{}, true
 				}
 			}
 		case key.SelectionEvent:
+			if e.ime.skipSelection {
+				e.ime.skipSelection = false
+				break
+			}
 			e.scrollCaret = true
 			e.scroller.Stop()
 			ke.Start -= adjust
@@ -471,124 +532,14 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 	return nil, false
 }
 
+// command runs the command of the key press, which is either a command of the
+// completion popup or of the keymap.
 func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
-	direction := 1
-	if gtx.Locale.Direction.Progression() == system.TowardOrigin {
-		direction = -1
-	}
-	moveByWord := k.Modifiers.Contain(key.ModShortcutAlt)
-	selAct := selectionClear
-	if k.Modifiers.Contain(key.ModShift) {
-		selAct = selectionExtend
-	}
-	if k.Modifiers.Contain(key.ModShortcut) {
-		switch k.Name {
-		// Initiate a paste operation, by requesting the clipboard contents; other
-		// half is in Editor.processKey() under clipboard.Event.
-		case "V":
-			if !e.ReadOnly {
-				gtx.Execute(clipboard.ReadCmd{Tag: e})
-			}
-		// Copy or Cut selection -- ignored if nothing selected.
-		case "C", "X":
-			e.scratch = e.text.SelectedText(e.scratch)
-			if text := string(e.scratch); text != "" {
-				gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
-				if k.Name == "X" && !e.ReadOnly {
-					if e.Delete(1) != 0 {
-						return ChangeEvent{}, true
-					}
-				}
-			}
-		// Select all
-		case "A":
-			e.text.SetCaret(0, e.text.Len())
-		case "Z":
-			if !e.ReadOnly {
-				if k.Modifiers.Contain(key.ModShift) {
-					if ev, ok := e.redo(); ok {
-						return ev, ok
-					}
-				} else {
-					if ev, ok := e.undo(); ok {
-						return ev, ok
-					}
-				}
-			}
-		case key.NameHome:
-			e.text.MoveTextStart(selAct)
-		case key.NameEnd:
-			e.text.MoveTextEnd(selAct)
-		}
-		return nil, false
+	if !e.completionCommand(k) {
+		e.Keymap().Key(gtx, e, k)
 	}
-	switch k.Name {
-	case key.NameReturn, key.NameEnter:
-		if !e.ReadOnly {
-			if e.Insert("\n") != 0 {
-				return ChangeEvent{}, true
-			}
-		}
-	case key.NameTab:
-		if !e.ReadOnly {
-			if e.Insert(e.TabCharacter) != 0 {
-				return ChangeEvent{}, true
-			}
-		}
-	case key.NameDeleteBackward:
-		if !e.ReadOnly {
-			if moveByWord {
-				if e.deleteWord(-1) != 0 {
-					return ChangeEvent{}, true
-				}
-			} else {
-				if e.Delete(-1) != 0 {
-					return ChangeEvent{}, true
-				}
-			}
-		}
-	case key.NameDeleteForward:
-		if !e.ReadOnly {
-			if moveByWord {
-				if e.deleteWord(1) != 0 {
-					return ChangeEvent{}, true
-				}
-			} else {
-				if e.Delete(1) != 0 {
-					return ChangeEvent{}, true
-				}
-			}
-		}
-	case key.NameUpArrow:
-		e.text.MoveLines(-1, selAct)
-	case key.NameDownArrow:
-		e.text.MoveLines(+1, selAct)
-	case key.NameLeftArrow:
-		if moveByWord {
-			e.text.MoveWord(-1*direction, selAct)
-		} else {
-			if selAct == selectionClear {
-				e.text.ClearSelection()
-			}
-			e.text.MoveCaret(-1*direction, -1*direction*int(selAct))
-		}
-	case key.NameRightArrow:
-		if moveByWord {
-			e.text.MoveWord(1*direction, selAct)
-		} else {
-			if selAct == selectionClear {
-				e.text.ClearSelection()
-			}
-			e.text.MoveCaret(1*direction, int(selAct)*direction)
-		}
-	case key.NamePageUp:
-		e.text.MovePages(-1, selAct)
-	case key.NamePageDown:
-		e.text.MovePages(+1, selAct)
-	case key.NameHome:
-		e.text.MoveLineStart(selAct)
-	case key.NameEnd:
-		e.text.MoveLineEnd(selAct)
+	if e.text.Changed() {
+		return ChangeEvent{}, true
 	}
 	return nil, false
 }
@@ -598,7 +549,7 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 // and has its fields synced with the editor.
 func (e *Editor) initBuffer() {
 	if e.buffer == nil {
-		e.buffer = new(editBuffer)
+		e.buffer = newPieceTable(nil)
 		e.text.SetSource(e.buffer)
 	}
 	e.text.Alignment = e.Alignment
@@ -732,6 +683,7 @@ func (e *Editor) layout(gtx layout.Context, textMaterial, selectMaterial op.Call
 		e.paintMatches(gtx, matchMaterial)
 		e.paintLineHighlight(gtx, lineMaterial)
 		e.paintText(gtx, textMaterial)
+		e.paintDecorations(gtx)
 	}
 	if gtx.Enabled() {
 		e.paintCaret(gtx, textMaterial)
@@ -748,13 +700,16 @@ func (e *Editor) paintSelection(gtx layout.Context, material op.CallOp) {
 		return
 	}
 	e.text.PaintSelection(gtx, material)
+	for _, c := range e.carets {
+		e.text.paintRange(gtx, c.start, c.end, material)
+	}
 }
 
 // paintText paints the text glyphs using the provided material to set the fill of the
 // glyphs.
 func (e *Editor) paintText(gtx layout.Context, material op.CallOp) {
 	e.initBuffer()
-	e.text.PaintText(gtx, material, e.textStyles)
+	e.text.PaintText(gtx, material, e.textStyles, e.syntaxStyles())
 }
 
 // paintCaret paints the text glyphs using the provided material to set the fill material
@@ -765,6 +720,9 @@ func (e *Editor) paintCaret(gtx layout.Context, material op.CallOp) {
 		return
 	}
 	e.text.PaintCaret(gtx, material)
+	for _, c := range e.carets {
+		e.text.paintCaretAt(gtx, c.start, material)
+	}
 }
 
 func (e *Editor) paintLineHighlight(gtx layout.Context, material op.CallOp) {
@@ -772,8 +730,10 @@ func (e *Editor) paintLineHighlight(gtx layout.Context, material op.CallOp) {
 	e.text.paintLineHighlight(gtx, material)
 }
 
-// SetMatches sets the matched text ranges after a find operation.
+// SetMatches sets the matched text ranges, in text order, after a find
+// operation. It stops the search started by Find.
 func (e *Editor) SetMatches(matches []MatchRange) {
+	e.search = searchState{}
 	e.matches = matches
 	e.ClearSelection()
 	if len(matches) > 0 {
@@ -811,13 +771,19 @@ func (e *Editor) Text() string {
 	return string(e.scratch)
 }
 
+// Revision returns the number of edits of the text, which tells whether the
+// text is changed since it was last read.
+func (e *Editor) Revision() int {
+	return e.revision
+}
+
 func (e *Editor) SetText(s string, addHistory bool) {
 	e.initBuffer()
 	if e.SingleLine {
 		s = strings.ReplaceAll(s, "\n", " ")
 	}
 	// disable history when loading doc. In other case history might be required
-	e.replace(0, e.text.Len(), s, addHistory, 0)
+	e.replace(0, e.text.Len(), s, addHistory)
 	// Reset xoff and move the caret to the beginning.
 	e.SetCaret(0, 0)
 }
@@ -839,142 +805,69 @@ func (e *Editor) CaretCoords() f32.Point {
 // direction to delete: positive is forward, negative is backward.
 //
 // If there is a selection, it is deleted and counts as a single grapheme
-// cluster.
+// cluster. With multiple carets, runes are deleted at every caret as a single
+// undo step.
 func (e *Editor) Delete(graphemeClusters int) (deletedRunes int) {
 	e.initBuffer()
 	if graphemeClusters == 0 {
 		return 0
 	}
+	return e.editCarets(func() int { return e.delete(graphemeClusters) })
+}
 
+// delete deletes runes from the primary caret.
+func (e *Editor) delete(graphemeClusters int) int {
 	start, end := e.text.Selection()
 	if start != end {
 		graphemeClusters -= sign(graphemeClusters)
+	} else {
+		// Deleting the line break of a folded line unfolds it, rather than
+		// deleting the folded lines.
+		e.revealFolded(start + sign(graphemeClusters))
 	}
 
 	// Move caret by the target quantity of clusters.
 	e.text.MoveCaret(0, graphemeClusters)
 	// Get the new rune offsets of the selection.
 	start, end = e.text.Selection()
-	e.replace(start, end, "", true, 0)
+	e.replace(start, end, "", true)
 	// Reset xoff.
 	e.text.MoveCaret(0, 0)
-	e.ClearSelection()
+	e.text.ClearSelection()
 	return end - start
 }
 
+// Insert inserts the text at the caret, replacing the selection. With
+// multiple carets, the text is inserted at every caret as a single undo step.
 func (e *Editor) Insert(s string) (insertedRunes int) {
 	e.initBuffer()
 	if e.SingleLine {
 		s = strings.ReplaceAll(s, "\n", " ")
 	}
+	moves := e.editCarets(func() int { return e.insert(s) })
+	e.scrollCaret = true
+	return moves
+}
+
+// insert inserts the text at the primary caret.
+func (e *Editor) insert(s string) int {
 	start, end := e.text.Selection()
-	moves := e.replace(start, end, s, true, 0)
+	moves := e.replace(start, end, s, true)
 	if end < start {
 		start = end
 	}
 	// Reset xoff.
 	e.text.MoveCaret(0, 0)
-	e.SetCaret(start+moves, start+moves)
-	e.scrollCaret = true
+	e.text.SetCaret(start+moves, start+moves)
 	return moves
 }
 
-// modification represents a change to the contents of the editor buffer.
-// It contains the necessary information to both apply the change and
-// reverse it, and is useful for implementing undo/redo.
-// BatchIdx is added to support undo/redo of a replaceAll operation.
-type modification struct {
-	// BatchIdx is the index of a group of modifications cased by a atomic operation.
-	// undo/redo should check BatchIdx to find all modifications until it reaches 0.
-	BatchIdx int
-	// StartRune is the inclusive index of the first rune
-	// modified.
-	StartRune int
-	// ApplyContent is the data inserted at StartRune to
-	// apply this operation. It overwrites len([]rune(ReverseContent)) runes.
-	ApplyContent string
-	// ReverseContent is the data inserted at StartRune to
-	// apply this operation. It overwrites len([]rune(ApplyContent)) runes.
-	ReverseContent string
-}
-
-// undo applies the modification at e.history[e.historyIdx] and decrements
-// e.historyIdx.
-func (e *Editor) undo() (EditorEvent, bool) {
-	e.initBuffer()
-	if len(e.history) < 1 || e.nextHistoryIdx == 0 {
-		return nil, false
-	}
-
-	undoOnce := func(mod modification) {
-		replaceEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
-		// batchIdx is omitted when addHistory is false.
-		e.replace(mod.StartRune, replaceEnd, mod.ReverseContent, false, 0)
-		caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
-		e.SetCaret(caretEnd, mod.StartRune)
-		e.nextHistoryIdx--
-	}
-
-	mod := e.history[e.nextHistoryIdx-1]
-	undoOnce(mod)
-
-	// ReplaceAll is in reverse order, so the first batchIdx will always be zero.
-	// Try next modification to check if they belong to a replaceAll group.
-	for e.nextHistoryIdx >= 1 {
-		mod = e.history[e.nextHistoryIdx-1]
-		if mod.BatchIdx == 0 {
-			break
-		}
-
-		undoOnce(mod)
-	}
-
-	return ChangeEvent{}, true
-}
-
-// redo applies the modification at e.history[e.historyIdx] and increments
-// e.historyIdx.
-func (e *Editor) redo() (EditorEvent, bool) {
-	e.initBuffer()
-	if len(e.history) < 1 || e.nextHistoryIdx == len(e.history) {
-		return nil, false
-	}
-
-	redoOnce := func(mod modification) {
-		end := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
-		e.replace(mod.StartRune, end, mod.ApplyContent, false, 0)
-		caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
-		e.SetCaret(caretEnd, mod.StartRune)
-		e.nextHistoryIdx++
-	}
-
-	mod := e.history[e.nextHistoryIdx]
-	redoOnce(mod)
-
-	// ReplaceAll is in reverse order, so the first batchIdx will always >= zero.
-	// Try next modification to check if they belong to a replaceAll group.
-	if mod.BatchIdx != 0 {
-		for e.nextHistoryIdx < len(e.history) {
-			mod = e.history[e.nextHistoryIdx]
-			if mod.BatchIdx >= 0 {
-				redoOnce(mod)
-			}
-
-			if mod.BatchIdx == 0 {
-				break
-			}
-		}
-	}
-
-	return ChangeEvent{}, true
-}
-
 // replace the text between start and end with s. Indices are in runes.
 // It returns the number of runes inserted.
 // addHistory controls whether this modification is recorded in the undo
 // history. replace can modify text in positions unrelated to the cursor
 // position.
-func (e *Editor) replace(start, end int, s string, addHistory bool, batchIdx int) int {
+func (e *Editor) replace(start, end int, s string, addHistory bool) int {
 	length := e.text.Len()
 	if start > end {
 		start, end = end, start
@@ -1007,18 +900,15 @@ func (e *Editor) replace(start, end int, s string, addHistory bool, batchIdx int
 			readPos += int64(s)
 			deleted = append(deleted, ru)
 		}
-		if e.nextHistoryIdx < len(e.history) {
-			e.history = e.history[:e.nextHistoryIdx]
-		}
-		e.history = append(e.history, modification{
-			BatchIdx:       batchIdx,
+		e.history.record(modification{
 			StartRune:      start,
 			ApplyContent:   s,
 			ReverseContent: string(deleted),
-		})
-		e.nextHistoryIdx++
+		}, e.UndoTree)
 	}
 
+	e.highlightEdit(start, end, s)
+	e.foldEdit(start, end, s)
 	sc = e.text.Replace(start, end, s)
 	newEnd := start + sc
 	adjust := func(pos int) int {
@@ -1033,30 +923,50 @@ func (e *Editor) replace(start, end int, s string, addHistory bool, batchIdx int
 	}
 	e.ime.start = adjust(e.ime.start)
 	e.ime.end = adjust(e.ime.end)
+	e.adjustCarets(start, end, sc)
+	e.updateMatches(start, end, sc)
+	e.updateDecorations(start, end, sc)
+	e.updateSnippetStops(start, end, sc)
+	e.revision++
 	return sc
 }
 
 // ReplaceAll assumes a context of "Find & Replace". newStr applies
 // to a list of text [MatchRange], and the matched text is replaced
-// with newStr one by one. The number of replacement is saved to be
-// used during undo/redo.
+// with newStr one by one, as a single undo step.
+// For regular expression queries of Find, the submatches are expanded
+// in newStr as in ReplaceMatch, and the matches are searched again after
+// the replacement.
 // It returns the number of occurrences replaced.
 func (e *Editor) ReplaceAll(newStr string) int {
 	if len(e.matches) <= 0 {
 		return 0
 	}
 
+	e.RemoveCarets()
+	matches := e.matches
+	count := len(matches)
+	e.search.suspended = true
 	// Traverse in reverse order to prevent match offset changes after
 	// each replace.
+	// The replacements are expanded before the text is changed.
+	repls := e.expandReplacements(matches, newStr)
 	finalPos := 0
-	for idx := len(e.matches) - 1; idx >= 0; idx-- {
-		start, end := e.matches[idx].Start, e.matches[idx].End
-		e.replace(start, end, newStr, true, idx)
+	e.BeginBatch()
+	for idx := count - 1; idx >= 0; idx-- {
+		start, end := matches[idx].Start, matches[idx].End
+		e.replace(start, end, repls[idx], true)
 		finalPos = start
 	}
+	e.EndBatch()
+	e.search.suspended = false
 
 	e.SetCaret(finalPos, finalPos)
-	return len(e.matches)
+	if e.search.active {
+		e.findAll()
+		e.currentMatch = 0
+	}
+	return count
 }
 
 // MoveCaret moves the caret (aka selection start) and the selection end
@@ -1081,7 +991,7 @@ func (e *Editor) deleteWord(distance int) (deletedRunes int) {
 
 	start, end := e.text.Selection()
 	if start != end {
-		deletedRunes = e.Delete(1)
+		deletedRunes = e.delete(1)
 		distance -= sign(distance)
 	}
 	if distance == 0 {
@@ -1125,7 +1035,7 @@ func (e *Editor) deleteWord(distance int) (deletedRunes int) {
 			runes += 1
 		}
 	}
-	deletedRunes += e.Delete(runes * direction)
+	deletedRunes += e.delete(runes * direction)
 	return deletedRunes
 }
 
@@ -1144,9 +1054,14 @@ func (e *Editor) Selection() (start, end int) {
 }
 
 // SetCaret moves the caret to start, and sets the selection end to end. start
-// and end are in runes, and represent offsets into the editor text.
+// and end are in runes, and represent offsets into the editor text. Other
+// carets are removed.
 func (e *Editor) SetCaret(start, end int) {
 	e.initBuffer()
+	e.RemoveCarets()
+	// Show the folded lines of the caret.
+	e.revealFolded(start)
+	e.revealFolded(end)
 	e.text.SetCaret(start, end)
 	e.scrollCaret = true
 	e.scroller.Stop()
@@ -1159,11 +1074,11 @@ func (e *Editor) SelectedText() string {
 	return string(e.scratch)
 }
 
-// ClearSelection clears the selection, by setting the selection end equal to
-// the selection start.
+// ClearSelection clears the selections, by setting the selection end equal to
+// the selection start of every caret.
 func (e *Editor) ClearSelection() {
 	e.initBuffer()
-	e.text.ClearSelection()
+	e.forEachCaret(e.text.ClearSelection)
 }
 
 // WriteTo implements io.WriterTo.
@@ -1206,6 +1121,8 @@ func (e *Editor) ScrollByRatio(gtx layout.Context, ratio float32) {
 	e.text.ScrollRel(0, sdist)
 }
 
+// UpdateTextStyles sets the styles of the text. They take precedence over the
+// styles of syntax highlighting.
 func (e *Editor) UpdateTextStyles(styles []*TextStyle) {
 	e.textStyles = styles
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"slices"
 
 	"gioui.org/font"
 	"gioui.org/layout"
@@ -16,7 +17,10 @@ import (
 	"gioui.org/unit"
 	"gioui.org/widget"
 
+	"github.com/oligo/gioview/editor/syntax"
+	"github.com/oligo/gioview/menu"
 	"github.com/oligo/gioview/misc"
+	"github.com/oligo/gioview/theme"
 )
 
 type EditorStyle struct {
@@ -44,11 +48,18 @@ type EditorStyle struct {
 
 	Editor      *Editor
 	ShowLineNum bool
-
-	shaper  *text.Shaper
-	lineBar *lineNumberBar
+	// Gutter are the custom columns of the gutter, laid out before the line
+	// numbers and the fold markers.
+	Gutter []GutterColumn
+
+	shaper     *text.Shaper
+	lineBar    *lineNumberBar
+	gutter     *gutter
+	tooltip    tooltipStyle
+	completion completionStyle
 }
 
+// lineNumberBar is the gutter column of the line numbers.
 type lineNumberBar struct {
 	shaper          *text.Shaper
 	lineHeight      unit.Sp
@@ -57,8 +68,6 @@ type lineNumberBar struct {
 	color    color.NRGBA
 	typeFace font.Typeface
 	textSize unit.Sp
-	// padding between line number and the editor content.
-	padding unit.Dp
 }
 
 type EditorConf struct {
@@ -75,11 +84,25 @@ type EditorConf struct {
 	Weight          font.Weight
 	LineHeight      unit.Sp
 	LineHeightScale float32
-	//May be helpful for code syntax highlighting.
+	// ColorScheme is the name of the color scheme for syntax highlighting,
+	// e.g., "light", "dark", "monokai" or "solarized-light". See package
+	// syntax for the bundled schemes.
 	ColorScheme string
+	// Language enables syntax highlighting of the named language, e.g., "go",
+	// "markdown", "json" or "shell".
+	Language string
+	// Folding enables code folding with a built-in fold provider, "indent" or
+	// "heading". Fold markers are shown in the gutter.
+	Folding     string
 	ShowLineNum bool
-	// padding between line number and the editor content.
+	// Gutter are the custom columns of the gutter, e.g., breakpoints or diff
+	// markers, which are laid out before the line numbers.
+	Gutter []GutterColumn
+	// padding between the gutter and the editor content.
 	LineNumPadding unit.Dp
+	// Popup is the style of the completion popup, e.g., menu.NewStyle of the
+	// app theme. It defaults to a menu style of the editor colors.
+	Popup menu.Style
 
 	// TabCharacter is the character used to represent a tab.
 	TabCharacter string
@@ -91,6 +114,14 @@ func NewEditor(editor *Editor, conf *EditorConf, hint string) EditorStyle {
 		editor.TabCharacter = conf.TabCharacter
 	}
 
+	editor.SetColorScheme(syntax.LookupScheme(conf.ColorScheme))
+	if conf.Language != "" {
+		editor.SetLanguage(conf.Language)
+	}
+	if conf.Folding != "" {
+		editor.SetFolding(conf.Folding)
+	}
+
 	es := EditorStyle{
 		Editor: editor,
 		Font: font.Font{
@@ -107,6 +138,7 @@ func NewEditor(editor *Editor, conf *EditorConf, hint string) EditorStyle {
 		LineHighlightColor: MulAlpha(conf.LineHighlightColor, 0x25),
 		TextMatchColor:     conf.TextMatchColor,
 		ShowLineNum:        conf.ShowLineNum,
+		Gutter:             conf.Gutter,
 		lineBar: &lineNumberBar{
 			shaper:          conf.Shaper,
 			lineHeight:      conf.LineHeight,
@@ -114,18 +146,44 @@ func NewEditor(editor *Editor, conf *EditorConf, hint string) EditorStyle {
 			color:           conf.LineNumberColor,
 			typeFace:        conf.TypeFace,
 			textSize:        conf.TextSize,
-			padding:         conf.LineNumPadding,
+		},
+		gutter: &gutter{padding: conf.LineNumPadding},
+		tooltip: tooltipStyle{
+			shaper:   conf.Shaper,
+			typeFace: conf.TypeFace,
+			textSize: conf.TextSize,
+			fg:       conf.Bg,
+			bg:       conf.TextColor,
+		},
+		completion: completionStyle{
+			Style:    conf.Popup,
+			shaper:   conf.Shaper,
+			typeFace: conf.TypeFace,
+			textSize: conf.TextSize,
+			fg:       conf.TextColor,
 		},
 	}
 
 	if conf.LineNumPadding <= 0 {
-		es.lineBar.padding = unit.Dp(32)
+		es.gutter.padding = unit.Dp(32)
 	}
 
 	if conf.LineNumberColor == (color.NRGBA{}) {
 		es.lineBar.color = misc.WithAlpha(conf.TextColor, 0xb6)
 	}
 
+	if conf.Popup == (menu.Style{}) {
+		es.completion.Style = menu.Style{
+			Background:  conf.Bg,
+			Border:      misc.WithAlpha(conf.TextColor, 0xb6),
+			Highlight:   misc.WithAlpha(conf.TextColor, uint8(theme.DefaultHover)),
+			OptionInset: layout.Inset{Top: unit.Dp(2), Bottom: unit.Dp(2), Left: unit.Dp(8), Right: unit.Dp(8)},
+		}
+		if conf.Bg.A == 0 {
+			es.completion.Background = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+		}
+	}
+
 	return es
 }
 
@@ -167,27 +225,32 @@ func (e EditorStyle) Layout(gtx layout.Context) layout.Dimensions {
 	e.Editor.LineHeight = e.LineHeight
 	e.Editor.LineHeightScale = e.LineHeightScale
 
-	if !e.ShowLineNum {
+	columns := e.gutterColumns()
+	if len(columns) == 0 {
 		d := e.Editor.Layout(gtx, e.shaper, e.Font, e.TextSize, textColor, selectionColor, lineColor, matchColor)
+		e.Editor.layoutHoverPopup(gtx, e.tooltip)
+		e.Editor.layoutCompletions(gtx, e.completion)
 		if e.Editor.Len() == 0 {
 			call.Add(gtx.Ops)
 		}
 		return d
 	}
 
-	// clip line number bar.
+	// clip the gutter.
 	defer clip.Rect(image.Rectangle{Max: gtx.Constraints.Max}).Push(gtx.Ops).Pop()
 	dims = layout.Flex{
 		Axis: layout.Horizontal,
 	}.Layout(gtx,
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-			return e.lineBar.Layout(gtx, e.Editor)
+			e.gutter.columns = columns
+			e.gutter.tooltip = e.tooltip
+			return e.gutter.Layout(gtx, e.Editor)
 		}),
 
-		layout.Rigid(layout.Spacer{Width: e.lineBar.padding}.Layout),
-
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			d := e.Editor.Layout(gtx, e.shaper, e.Font, e.TextSize, textColor, selectionColor, lineColor, matchColor)
+			e.Editor.layoutHoverPopup(gtx, e.tooltip)
+			e.Editor.layoutCompletions(gtx, e.completion)
 			if e.Editor.Len() == 0 {
 				call.Add(gtx.Ops)
 			}
@@ -198,8 +261,23 @@ func (e EditorStyle) Layout(gtx layout.Context) layout.Dimensions {
 	return dims
 }
 
-func (bar lineNumberBar) layoutLine(gtx layout.Context, pos *LineInfo, textColor op.CallOp) layout.Dimensions {
-	stack := op.Offset(image.Point{Y: pos.YOffset}).Push(gtx.Ops)
+// gutterColumns returns the columns of the gutter, which is not shown if there
+// are none.
+func (e EditorStyle) gutterColumns() []GutterColumn {
+	columns := slices.Clip(e.Gutter)
+	if e.ShowLineNum {
+		columns = append(columns, e.lineBar)
+	}
+	if e.Editor.folds.provider != nil {
+		columns = append(columns, foldColumn{color: e.lineBar.color, textSize: e.TextSize})
+	}
+	return columns
+}
+
+func (bar lineNumberBar) layoutLine(gtx layout.Context, pos *LineInfo) layout.Dimensions {
+	textColorMacro := op.Record(gtx.Ops)
+	paint.ColorOp{Color: bar.color}.Add(gtx.Ops)
+	textColor := textColorMacro.Stop()
 
 	tl := widget.Label{
 		Alignment:       text.End,
@@ -208,43 +286,29 @@ func (bar lineNumberBar) layoutLine(gtx layout.Context, pos *LineInfo, textColor
 		LineHeightScale: bar.lineHeightScale,
 	}
 
-	d := tl.Layout(gtx, bar.shaper,
+	return tl.Layout(gtx, bar.shaper,
 		font.Font{Typeface: bar.typeFace, Weight: font.Normal},
 		bar.textSize,
 		fmt.Sprintf("%d", pos.LineNum),
 		textColor)
-	stack.Pop()
-	return d
 }
 
-func (bar lineNumberBar) Layout(gtx layout.Context, e *Editor) layout.Dimensions {
-	dims := layout.Dimensions{Size: image.Point{X: gtx.Constraints.Min.X}}
-
-	textColorMacro := op.Record(gtx.Ops)
-	paint.ColorOp{Color: bar.color}.Add(gtx.Ops)
-	textColor := textColorMacro.Stop()
-
+func (bar lineNumberBar) Width(gtx layout.Context, e *Editor, lines []*LineInfo) int {
 	fake := gtx
 	fake.Ops = &op.Ops{}
+	fake.Constraints = layout.Constraints{Max: gtx.Constraints.Max}
 
-	positions, _ := e.VisibleLines()
 	maxWidth := 0
-	{
-		for _, pos := range positions {
-			d := bar.layoutLine(fake, pos, textColor)
-			maxWidth = max(maxWidth, d.Size.X)
-		}
-
+	for _, pos := range lines {
+		d := bar.layoutLine(fake, pos)
+		maxWidth = max(maxWidth, d.Size.X)
 	}
+	return maxWidth
+}
 
-	gtx.Constraints.Max.X = maxWidth
+func (bar lineNumberBar) LayoutLine(gtx layout.Context, e *Editor, line *LineInfo) layout.Dimensions {
 	gtx.Constraints.Min.X = gtx.Constraints.Max.X
-	for _, pos := range positions {
-		d := bar.layoutLine(gtx, pos, textColor)
-		dims.Size = image.Point{X: maxWidth, Y: dims.Size.Y + d.Size.Y}
-	}
-
-	return dims
+	return bar.layoutLine(gtx, line)
 }
 
 func blendDisabledColor(disabled bool, c color.NRGBA) color.NRGBA {
@@ -0,0 +1,271 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"golang.org/x/exp/shiny/materialdesign/icons"
+
+	"github.com/oligo/gioview/misc"
+	"github.com/oligo/gioview/theme"
+	gvwidget "github.com/oligo/gioview/widget"
+)
+
+var (
+	prevMatchIcon, _   = widget.NewIcon(icons.NavigationExpandLess)
+	nextMatchIcon, _   = widget.NewIcon(icons.NavigationExpandMore)
+	closeFindIcon, _   = widget.NewIcon(icons.NavigationClose)
+	showReplaceIcon, _ = widget.NewIcon(icons.ActionFindReplace)
+)
+
+// FindBar is a find and replace bar searching the text of an Editor. The
+// matches are updated as the query is typed. Enter in the query field selects
+// the next match, and Enter in the replacement field replaces it.
+type FindBar struct {
+	// ShowReplace shows the replacement field and buttons.
+	ShowReplace bool
+
+	query       gvwidget.TextField
+	replacement gvwidget.TextField
+	options     SearchQuery
+	opened      bool
+
+	caseBtn       widget.Clickable
+	wordBtn       widget.Clickable
+	regexpBtn     widget.Clickable
+	prevBtn       widget.Clickable
+	nextBtn       widget.Clickable
+	closeBtn      widget.Clickable
+	toggleBtn     widget.Clickable
+	replaceBtn    widget.Clickable
+	replaceAllBtn widget.Clickable
+}
+
+// Open shows the bar and focuses the query field. The single line text
+// selected in the editor, if any, becomes the query.
+func (fb *FindBar) Open(gtx layout.Context, e *Editor) {
+	fb.opened = true
+	if selected := e.SelectedText(); selected != "" && !strings.Contains(selected, "\n") {
+		fb.query.SetText(selected)
+	}
+	fb.query.SetFocus(gtx)
+	fb.find(e)
+}
+
+// Close hides the bar and clears the search of the editor.
+func (fb *FindBar) Close(e *Editor) {
+	fb.opened = false
+	e.ClearSearch()
+}
+
+// Opened reports whether the bar is shown.
+func (fb *FindBar) Opened() bool {
+	return fb.opened
+}
+
+// Query returns the current search query.
+func (fb *FindBar) Query() SearchQuery {
+	q := fb.options
+	q.Text = fb.query.Text()
+	return q
+}
+
+func (fb *FindBar) find(e *Editor) {
+	if _, err := e.Find(fb.Query()); err != nil {
+		e.ClearSearch()
+		fb.query.SetError(err.Error())
+		return
+	}
+	fb.query.ClearError()
+}
+
+func (fb *FindBar) update(gtx layout.Context, e *Editor) {
+	changed := false
+	for _, toggle := range []struct {
+		btn *widget.Clickable
+		opt *bool
+	}{
+		{&fb.caseBtn, &fb.options.IgnoreCase},
+		{&fb.wordBtn, &fb.options.WholeWord},
+		{&fb.regexpBtn, &fb.options.Regexp},
+	} {
+		if toggle.btn.Clicked(gtx) {
+			*toggle.opt = !*toggle.opt
+			changed = true
+		}
+	}
+	if changed {
+		fb.find(e)
+	}
+
+	if fb.nextBtn.Clicked(gtx) {
+		e.FindNext()
+	}
+	if fb.prevBtn.Clicked(gtx) {
+		e.FindPrevious()
+	}
+	if fb.toggleBtn.Clicked(gtx) {
+		fb.ShowReplace = !fb.ShowReplace
+	}
+	if fb.replaceBtn.Clicked(gtx) {
+		e.ReplaceMatch(fb.replacement.Text())
+	}
+	if fb.replaceAllBtn.Clicked(gtx) {
+		e.ReplaceAll(fb.replacement.Text())
+	}
+	if fb.closeBtn.Clicked(gtx) {
+		fb.Close(e)
+	}
+}
+
+// updateFields handles the input of the text fields, which is only known
+// after they are laid out.
+func (fb *FindBar) updateFields(gtx layout.Context, e *Editor) {
+	handled := false
+	if fb.query.Changed() {
+		fb.find(e)
+		handled = true
+	}
+	if fb.query.Submitted() {
+		e.FindNext()
+		handled = true
+	}
+	if fb.replacement.Submitted() {
+		e.ReplaceMatch(fb.replacement.Text())
+		handled = true
+	}
+	if handled {
+		gtx.Execute(op.InvalidateCmd{})
+	}
+}
+
+// Layout lays out the bar for the editor. Nothing is laid out if the bar is
+// closed.
+func (fb *FindBar) Layout(gtx layout.Context, th *theme.Theme, e *Editor) layout.Dimensions {
+	if !fb.opened {
+		return layout.Dimensions{}
+	}
+	fb.query.SingleLine = true
+	fb.query.Padding = unit.Dp(6)
+	fb.replacement.SingleLine = true
+	fb.replacement.Padding = unit.Dp(6)
+	fb.update(gtx, e)
+	if !fb.opened {
+		return layout.Dimensions{}
+	}
+
+	defer fb.updateFields(gtx, e)
+	return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return fb.layoutFindRow(gtx, th, e)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !fb.ShowReplace {
+					return layout.Dimensions{}
+				}
+				return layout.Inset{Top: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return fb.layoutReplaceRow(gtx, th)
+				})
+			}),
+		)
+	})
+}
+
+func (fb *FindBar) layoutFindRow(gtx layout.Context, th *theme.Theme, e *Editor) layout.Dimensions {
+	return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return misc.IconButton(th, showReplaceIcon, &fb.toggleBtn, "toggle replace").Layout(gtx)
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return fb.query.Layout(gtx, th, "Find")
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layoutToggle(gtx, th, &fb.caseBtn, "Aa", fb.options.IgnoreCase)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layoutToggle(gtx, th, &fb.wordBtn, "W", fb.options.WholeWord)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layoutToggle(gtx, th, &fb.regexpBtn, ".*", fb.options.Regexp)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Left: unit.Dp(6), Right: unit.Dp(6)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return material.Label(th.Theme, th.TextSize*0.9, fb.matchStatus(e)).Layout(gtx)
+			})
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return misc.IconButton(th, prevMatchIcon, &fb.prevBtn, "previous match").Layout(gtx)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return misc.IconButton(th, nextMatchIcon, &fb.nextBtn, "next match").Layout(gtx)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return misc.IconButton(th, closeFindIcon, &fb.closeBtn, "close").Layout(gtx)
+		}),
+	)
+}
+
+func (fb *FindBar) layoutReplaceRow(gtx layout.Context, th *theme.Theme) layout.Dimensions {
+	// Align the replacement field with the query field.
+	return layout.Inset{Left: unit.Dp(26)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				return fb.replacement.Layout(gtx, th, "Replace")
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layoutToggle(gtx, th, &fb.replaceBtn, "Replace", false)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layoutToggle(gtx, th, &fb.replaceAllBtn, "All", false)
+			}),
+		)
+	})
+}
+
+// matchStatus describes the matches and the current one.
+func (fb *FindBar) matchStatus(e *Editor) string {
+	if fb.query.Text() == "" {
+		return ""
+	}
+	matches, current := e.Matches()
+	if current < 0 {
+		return "No results"
+	}
+	return fmt.Sprintf("%d of %d", current+1, len(matches))
+}
+
+// layoutToggle lays out a small text button, highlighted if it is on.
+func layoutToggle(gtx layout.Context, th *theme.Theme, btn *widget.Clickable, txt string, on bool) layout.Dimensions {
+	return layout.Inset{Left: unit.Dp(2)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return material.Clickable(gtx, btn, func(gtx layout.Context) layout.Dimensions {
+			return layout.Background{}.Layout(gtx,
+				func(gtx layout.Context) layout.Dimensions {
+					if on {
+						rect := clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, gtx.Dp(unit.Dp(4)))
+						paint.FillShape(gtx.Ops, misc.WithAlpha(th.ContrastBg, th.SelectedAlpha), rect.Op(gtx.Ops))
+					}
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				},
+				func(gtx layout.Context) layout.Dimensions {
+					return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						label := material.Label(th.Theme, th.TextSize*0.9, txt)
+						label.Font.Weight = font.Medium
+						return label.Layout(gtx)
+					})
+				},
+			)
+		})
+	})
+}
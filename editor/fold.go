@@ -0,0 +1,457 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"slices"
+	"strings"
+)
+
+// FoldRange is a foldable range of logical lines, which are counted from 0.
+// Folding it hides the lines after Start up to and including End.
+type FoldRange struct {
+	Start, End int
+}
+
+// FoldProvider finds the fold levels of the lines. A foldable range starts at
+// a line followed by lines of higher levels, and ends at the last of them.
+// The levels are cached per line, and an edit only outdates the edited lines:
+// the lines after them are scanned again only if the state they start with
+// has changed.
+type FoldProvider interface {
+	// FoldLevel returns the fold level of a line, which does not include the
+	// line break. Lines of negative levels, e.g., blank lines, neither start
+	// nor end a range. state is the state left by the previous line, which is
+	// zero for the first line, and the returned state is passed to the next
+	// line.
+	FoldLevel(line string, state int) (level, next int)
+}
+
+// FoldProviderFunc adapts a function to a FoldProvider.
+type FoldProviderFunc func(line string, state int) (level, next int)
+
+func (f FoldProviderFunc) FoldLevel(line string, state int) (int, int) {
+	return f(line, state)
+}
+
+var (
+	// IndentFolds folds the lines indented more than the line before them.
+	// Blank lines do not end a range.
+	IndentFolds FoldProvider = FoldProviderFunc(indentFold)
+	// HeadingFolds folds the sections of Markdown text. A section starts at a
+	// heading, and ends before the next heading of the same or a higher level.
+	HeadingFolds FoldProvider = FoldProviderFunc(headingFold)
+)
+
+// folding holds the code folding state of the editor. The folded lines are
+// tracked by the paragraphs of the text view.
+type folding struct {
+	name     string
+	provider FoldProvider
+	lines    []foldLine
+	// valid is the number of lines known to be up to date.
+	valid int
+}
+
+type foldLine struct {
+	// start and end are the states at the start and at the end of the line.
+	start, end int
+	level      int
+	dirty      bool
+}
+
+// SetFolding enables code folding with a built-in provider, which is "indent"
+// or "heading". An empty or unknown name disables it.
+func (e *Editor) SetFolding(name string) {
+	if name == e.folds.name && (name == "") == (e.folds.provider == nil) {
+		return
+	}
+	var provider FoldProvider
+	switch name {
+	case "indent":
+		provider = IndentFolds
+	case "heading":
+		provider = HeadingFolds
+	}
+	e.SetFoldProvider(provider)
+	e.folds.name = name
+}
+
+// SetFoldProvider enables code folding with the provider, or disables it if
+// provider is nil. The folded lines are unfolded.
+func (e *Editor) SetFoldProvider(provider FoldProvider) {
+	e.initBuffer()
+	e.UnfoldAll()
+	e.folds = folding{provider: provider}
+}
+
+// foldEdit tells the fold level cache the runes in [start, end) are going to
+// be replaced with s.
+func (e *Editor) foldEdit(start, end int, s string) {
+	f := &e.folds
+	if f.provider == nil {
+		return
+	}
+	if e.text.monolithic() {
+		f.lines, f.valid = f.lines[:0], 0
+		return
+	}
+
+	line, removed, inserted := e.editedLines(start, end, s)
+	f.valid = min(f.valid, line)
+	if line >= len(f.lines) {
+		return
+	}
+	if line+removed+1 >= len(f.lines) {
+		// The cache ends within the edited lines.
+		f.lines = f.lines[:line]
+		return
+	}
+
+	if removed == inserted {
+		for i := line; i <= line+removed; i++ {
+			f.lines[i].dirty = true
+		}
+		return
+	}
+	edited := make([]foldLine, inserted+1)
+	for i := range edited {
+		edited[i].dirty = true
+	}
+	rest := f.lines[line+removed+1:]
+	f.lines = append(f.lines[:line], append(edited, rest...)...)
+}
+
+// foldLines returns the number of lines, or false if folding is disabled.
+func (e *Editor) foldLines() (int, bool) {
+	e.initBuffer()
+	if e.folds.provider == nil || e.text.monolithic() {
+		return 0, false
+	}
+	e.text.makeValid()
+	return len(e.text.paras.paragraphs), true
+}
+
+// foldLevel returns the fold level of the line. The levels of the lines before
+// it are scanned first if they are not up to date.
+func (e *Editor) foldLevel(line int) int {
+	f := &e.folds
+	for len(f.lines) <= line {
+		f.lines = append(f.lines, foldLine{dirty: true})
+	}
+
+	for i := f.valid; i <= line; i++ {
+		var state int
+		if i > 0 {
+			state = f.lines[i-1].end
+		}
+		l := &f.lines[i]
+		if l.dirty || l.start != state {
+			l.level, l.end = f.provider.FoldLevel(e.text.lineText(i), state)
+			l.start = state
+			l.dirty = false
+		}
+	}
+	f.valid = max(f.valid, line+1)
+	return f.lines[line].level
+}
+
+// FoldRanges returns the foldable ranges of the text, sorted by their start
+// lines. It returns nil if folding is disabled, or for single line editors.
+func (e *Editor) FoldRanges() []FoldRange {
+	lines, ok := e.foldLines()
+	if !ok {
+		return nil
+	}
+
+	type open struct{ line, level int }
+	var ranges []FoldRange
+	var stack []open
+	last := -1
+	closeRanges := func(level int) {
+		for len(stack) > 0 && stack[len(stack)-1].level >= level {
+			o := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if last > o.line {
+				ranges = append(ranges, FoldRange{Start: o.line, End: last})
+			}
+		}
+	}
+
+	for i := range lines {
+		level := e.foldLevel(i)
+		if level < 0 {
+			continue
+		}
+		closeRanges(level)
+		stack = append(stack, open{line: i, level: level})
+		last = i
+	}
+	closeRanges(0)
+
+	slices.SortFunc(ranges, func(a, b FoldRange) int { return a.Start - b.Start })
+	return ranges
+}
+
+// isFoldStart reports whether a foldable range starts at the line. Unlike
+// foldRange, it does not scan the whole range.
+func (e *Editor) isFoldStart(line int) bool {
+	lines, ok := e.foldLines()
+	if !ok || line < 0 || line >= lines {
+		return false
+	}
+	level := e.foldLevel(line)
+	if level < 0 {
+		return false
+	}
+	for i := line + 1; i < lines; i++ {
+		if next := e.foldLevel(i); next >= 0 {
+			return next > level
+		}
+	}
+	return false
+}
+
+// foldRange returns the foldable range starting at the line.
+func (e *Editor) foldRange(line int) (FoldRange, bool) {
+	lines, ok := e.foldLines()
+	if !ok || line < 0 || line >= lines {
+		return FoldRange{}, false
+	}
+	level := e.foldLevel(line)
+	if level < 0 {
+		return FoldRange{}, false
+	}
+
+	end := line
+	for i := line + 1; i < lines; i++ {
+		next := e.foldLevel(i)
+		if next < 0 {
+			continue
+		}
+		if next <= level {
+			break
+		}
+		end = i
+	}
+	return FoldRange{Start: line, End: end}, end > line
+}
+
+// Fold folds the range starting at the logical line. Carets in the folded
+// lines are moved to the end of the line. It reports whether there is such a
+// range.
+func (e *Editor) Fold(line int) bool {
+	r, ok := e.foldRange(line)
+	if !ok {
+		return false
+	}
+	e.text.paras.fold(r.Start, r.End-r.Start)
+	e.text.valid = false
+	e.forEachCaret(func() {
+		e.text.caret.start = e.text.skipFolded(e.text.caret.start, false)
+		e.text.caret.end = e.text.skipFolded(e.text.caret.end, false)
+	})
+	return true
+}
+
+// Unfold unfolds the range starting at the logical line.
+func (e *Editor) Unfold(line int) {
+	e.initBuffer()
+	if e.IsFolded(line) {
+		e.text.paras.unfold(line)
+		e.text.valid = false
+	}
+}
+
+// ToggleFold folds or unfolds the range starting at the logical line.
+func (e *Editor) ToggleFold(line int) {
+	if e.IsFolded(line) {
+		e.Unfold(line)
+	} else {
+		e.Fold(line)
+	}
+}
+
+// IsFolded reports whether the range starting at the logical line is folded.
+func (e *Editor) IsFolded(line int) bool {
+	e.initBuffer()
+	paras := &e.text.paras
+	return line >= 0 && line < len(paras.paragraphs) && paras.paragraphs[line].folded > 0
+}
+
+// UnfoldAll unfolds all the folded ranges.
+func (e *Editor) UnfoldAll() {
+	e.initBuffer()
+	for i := range e.text.paras.paragraphs {
+		if e.text.paras.paragraphs[i].folded > 0 {
+			e.text.paras.unfold(i)
+			e.text.valid = false
+		}
+	}
+}
+
+// revealFolded unfolds the ranges hiding the rune.
+func (e *Editor) revealFolded(r int) {
+	paras := &e.text.paras
+	if paras.monolithic || r < 0 || r > paras.runes {
+		return
+	}
+	idx := paras.paragraphAtRune(r)
+	for paras.paragraphs[idx].hidden {
+		paras.unfold(paras.foldHeader(idx))
+		e.text.valid = false
+	}
+}
+
+// fold hides the n paragraphs after the paragraph idx.
+func (pi *paragraphIndex) fold(idx, n int) {
+	n = min(n, len(pi.paragraphs)-1-idx)
+	if pi.monolithic || n <= 0 {
+		return
+	}
+	pi.paragraphs[idx].folded = n
+	pi.updateHidden(idx)
+}
+
+// unfold shows the paragraphs hidden by folding the paragraph idx, except
+// for those in other folded paragraphs.
+func (pi *paragraphIndex) unfold(idx int) {
+	pi.paragraphs[idx].folded = 0
+	pi.updateHidden(idx)
+}
+
+// foldHeader returns the innermost folded paragraph hiding the paragraph idx.
+func (pi *paragraphIndex) foldHeader(idx int) int {
+	i := idx - 1
+	for i > 0 && (pi.paragraphs[i].folded == 0 || i+pi.paragraphs[i].folded < idx) {
+		i--
+	}
+	return i
+}
+
+// updateHidden updates the hidden paragraphs of the folds around the
+// paragraph idx.
+func (pi *paragraphIndex) updateHidden(idx int) {
+	// Start from the outermost fold, which is not hidden.
+	start := idx
+	for start > 0 && pi.paragraphs[start].hidden {
+		start--
+	}
+	// Clear the hidden paragraphs after it, as it may have been unfolded.
+	end := start
+	for end+1 < len(pi.paragraphs) && pi.paragraphs[end+1].hidden {
+		end++
+	}
+
+	hideUntil := -1
+	for i := start; i < len(pi.paragraphs) && (i <= end || i <= hideUntil); i++ {
+		p := &pi.paragraphs[i]
+		p.hidden = i <= hideUntil
+		if p.folded > 0 {
+			hideUntil = max(hideUntil, i+p.folded)
+		}
+	}
+	pi.dirty = min(pi.dirty, start+1)
+}
+
+// unfoldEdited unfolds the folds of the paragraphs in [first, last] before
+// they are edited.
+func (pi *paragraphIndex) unfoldEdited(first, last int) {
+	for i := first; i <= last; i++ {
+		for pi.paragraphs[i].hidden {
+			pi.unfold(pi.foldHeader(i))
+		}
+		if pi.paragraphs[i].folded > 0 {
+			pi.unfold(i)
+		}
+	}
+}
+
+// lastVisible returns the last paragraph not hidden by folding.
+func (pi *paragraphIndex) lastVisible() int {
+	i := len(pi.paragraphs) - 1
+	for i > 0 && pi.paragraphs[i].hidden {
+		i--
+	}
+	return i
+}
+
+// skipFolded moves the rune out of the folded lines. The rune is moved
+// forward to the line after the fold, or backward to the end of the first line
+// of the fold.
+func (e *textView) skipFolded(r int, forward bool) int {
+	paras := &e.paras
+	if paras.monolithic {
+		return r
+	}
+	idx := paras.paragraphAtRune(r)
+	if !paras.paragraphs[idx].hidden {
+		return r
+	}
+	first, last := idx, idx
+	for first > 0 && paras.paragraphs[first-1].hidden {
+		first--
+	}
+	for last+1 < len(paras.paragraphs) && paras.paragraphs[last+1].hidden {
+		last++
+	}
+	if forward && last+1 < len(paras.paragraphs) {
+		return paras.paragraphs[last+1].runeOff
+	}
+	return paras.paragraphs[first].runeOff - 1
+}
+
+// indentFold returns the indentation of the line as its level.
+func indentFold(line string, state int) (int, int) {
+	if strings.TrimSpace(line) == "" {
+		return -1, state
+	}
+	return indentation(line), state
+}
+
+// indentation returns the width of the leading white space, with tab stops
+// every 4 columns.
+func indentation(s string) int {
+	width := 0
+	for _, r := range s {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// headingFold returns the level of Markdown headings, and a level higher
+// than all the headings for the other lines. The state tells whether the line
+// is in a fenced code block.
+func headingFold(line string, state int) (int, int) {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+		state ^= 1
+	}
+	if trimmed == "" {
+		return -1, state
+	}
+	if level := headingLevel(line); state == 0 && level > 0 {
+		return level, state
+	}
+	return 7, state
+}
+
+// headingLevel returns the level of a Markdown ATX heading, or 0 if the line
+// is not a heading.
+func headingLevel(s string) int {
+	level := 0
+	for level < len(s) && s[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(s) && s[level] != ' ' && s[level] != '\t') {
+		return 0
+	}
+	return level
+}
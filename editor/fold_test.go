@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFoldProviders(t *testing.T) {
+	ranges := func(provider FoldProvider, text string) []FoldRange {
+		e := &Editor{}
+		e.SetText(text, false)
+		e.SetFoldProvider(provider)
+		layoutEditor(e, image.Pt(400, 400))
+		return e.FoldRanges()
+	}
+
+	code := "func a() {\n\tif x {\n\t\ty()\n\n\t}\n}\nfunc b() {}"
+	if got, want := ranges(IndentFolds, code), []FoldRange{{0, 4}, {1, 2}}; !slices.Equal(got, want) {
+		t.Errorf("indent folds: got %v, want %v", got, want)
+	}
+
+	md := "# A\ntext\n## B\n```\n# not a heading\n```\n\n# C\nmore\n#no heading"
+	if got, want := ranges(HeadingFolds, md), []FoldRange{{0, 5}, {2, 5}, {7, 9}}; !slices.Equal(got, want) {
+		t.Errorf("heading folds: got %v, want %v", got, want)
+	}
+}
+
+func TestFoldLevelCache(t *testing.T) {
+	scanned := 0
+	counting := FoldProviderFunc(func(line string, state int) (int, int) {
+		scanned++
+		return HeadingFolds.FoldLevel(line, state)
+	})
+
+	e := &Editor{}
+	e.SetText(strings.Repeat("# A\ntext\n", 50), false)
+	e.SetFoldProvider(counting)
+	layoutEditor(e, image.Pt(400, 400))
+	if got := len(e.FoldRanges()); got != 50 {
+		t.Fatalf("got %d ranges", got)
+	}
+
+	// Editing a line only scans the line again.
+	scanned = 0
+	e.SetCaret(5, 5)
+	e.Insert("more ")
+	if got := len(e.FoldRanges()); got != 50 || scanned != 1 {
+		t.Fatalf("got %d ranges, scanned %d lines", got, scanned)
+	}
+
+	// Opening a fence changes the state of the lines after it.
+	e.SetCaret(0, 0)
+	e.Insert("```\n")
+	if got, want := e.FoldRanges(), []FoldRange(nil); !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	e.undo()
+	if got := len(e.FoldRanges()); got != 50 {
+		t.Fatalf("got %d ranges after undo", got)
+	}
+}
+
+func visibleLineNums(e *Editor) []int {
+	lines, _ := e.VisibleLines()
+	var nums []int
+	for _, l := range lines {
+		nums = append(nums, l.LineNum)
+	}
+	return nums
+}
+
+func TestFolding(t *testing.T) {
+	e := &Editor{}
+	e.SetText("a {\n  b\n  c {\n    d\n  }\n}\ne", false)
+	e.SetFolding("indent")
+	layoutEditor(e, image.Pt(400, 400))
+
+	e.SetCaret(12, 12)
+	if !e.Fold(2) || !e.Fold(0) {
+		t.Fatal("lines are not folded")
+	}
+	layoutEditor(e, image.Pt(400, 400))
+	if got := visibleLineNums(e); !slices.Equal(got, []int{1, 6, 7}) {
+		t.Fatalf("unexpected visible lines: %v", got)
+	}
+	// The caret is moved out of the folded lines.
+	if start, _ := e.Selection(); start != 3 {
+		t.Fatalf("unexpected caret: %d", start)
+	}
+
+	// Moving the caret skips the folded lines.
+	e.MoveCaret(1, 1)
+	if start, _ := e.Selection(); start != 24 {
+		t.Fatalf("unexpected caret after moving right: %d", start)
+	}
+	e.MoveCaret(-1, -1)
+	if start, _ := e.Selection(); start != 3 {
+		t.Fatalf("unexpected caret after moving left: %d", start)
+	}
+	e.text.MoveLines(1, selectionClear)
+	if line, _ := e.CaretPos(); line != 1 {
+		t.Fatalf("unexpected screen line after moving down: %d", line)
+	}
+
+	// Editing the first line keeps the fold, and the folded lines move with
+	// the edits before them.
+	e.SetCaret(0, 0)
+	e.Insert("x\n")
+	if !e.IsFolded(1) || e.IsFolded(0) {
+		t.Fatal("the fold does not follow the edit")
+	}
+	e.SetCaret(5, 5)
+	e.Insert("y")
+	layoutEditor(e, image.Pt(400, 400))
+	if got := visibleLineNums(e); !slices.Equal(got, []int{1, 2, 7, 8}) {
+		t.Fatalf("unexpected visible lines: %v", got)
+	}
+
+	// Deleting the line break before the fold unfolds it.
+	e.SetCaret(27, 27)
+	e.Delete(-1)
+	if e.IsFolded(1) || !e.IsFolded(3) {
+		t.Fatal("the fold is not unfolded")
+	}
+	e.undo()
+
+	// Selecting a folded line unfolds it, but not the nested fold.
+	e.Fold(1)
+	e.SetCaret(7, 7)
+	layoutEditor(e, image.Pt(400, 400))
+	if got := visibleLineNums(e); !slices.Equal(got, []int{1, 2, 3, 4, 6, 7, 8}) {
+		t.Fatalf("unexpected visible lines: %v", got)
+	}
+	if e.IsFolded(1) || !e.IsFolded(3) {
+		t.Fatal("unexpected folds")
+	}
+}
@@ -0,0 +1,353 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/font"
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+// tooltipDelay is how long the pointer stays still on a line of the gutter, or
+// on a decoration, before its tooltip is shown.
+const tooltipDelay = 500 * time.Millisecond
+
+// GutterColumn is a column of the gutter, which is laid out on the left of the
+// text next to the visible lines, e.g., line numbers, breakpoints or diff
+// markers. A column may also implement GutterClicker and GutterTooltipper.
+type GutterColumn interface {
+	// Width returns the width of the column for the visible lines, in pixels.
+	Width(gtx layout.Context, e *Editor, lines []*LineInfo) int
+	// LayoutLine lays out the column for a line. The origin is at the top of
+	// the line, and the max constraints are the width of the column and the
+	// height of a screen line.
+	LayoutLine(gtx layout.Context, e *Editor, line *LineInfo) layout.Dimensions
+}
+
+// GutterClicker is implemented by the gutter columns handling clicks.
+type GutterClicker interface {
+	// ClickLine is called when the column of the line is clicked.
+	ClickLine(e *Editor, line *LineInfo, buttons pointer.Buttons)
+}
+
+// GutterTooltipper is implemented by the gutter columns having tooltips.
+type GutterTooltipper interface {
+	// Tooltip returns the tooltip of the line, or an empty string for none.
+	Tooltip(e *Editor, line *LineInfo) string
+}
+
+// gutterState is the pointer state of the gutter, which outlives the
+// EditorStyle the gutter belongs to.
+type gutterState struct {
+	hovering   bool
+	hoverPos   image.Point
+	hoverSince time.Time
+}
+
+// gutter lays out the columns of the gutter.
+type gutter struct {
+	columns []GutterColumn
+	// padding between the gutter and the text.
+	padding unit.Dp
+	tooltip tooltipStyle
+}
+
+// tooltipStyle draws the tooltips of the gutter and the hover popups of the
+// decorations.
+type tooltipStyle struct {
+	shaper   *text.Shaper
+	typeFace font.Typeface
+	textSize unit.Sp
+	fg, bg   color.NRGBA
+}
+
+// hit returns the column and the line at the position of the gutter.
+func (g *gutter) hit(pos image.Point, widths []int, lines []*LineInfo, lineHeight int) (GutterColumn, *LineInfo) {
+	var col GutterColumn
+	x := 0
+	for i, w := range widths {
+		if pos.X >= x && pos.X < x+w {
+			col = g.columns[i]
+			break
+		}
+		x += w
+	}
+	if col == nil {
+		return nil, nil
+	}
+	for i, line := range lines {
+		bottom := line.YOffset + lineHeight
+		if i+1 < len(lines) {
+			bottom = lines[i+1].YOffset
+		}
+		if pos.Y >= line.YOffset && pos.Y < bottom {
+			return col, line
+		}
+	}
+	return nil, nil
+}
+
+func (g *gutter) update(gtx layout.Context, e *Editor, widths []int, lines []*LineInfo, lineHeight int) bool {
+	state := &e.gutter
+	clicked := false
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target: state,
+			Kinds:  pointer.Press | pointer.Move | pointer.Enter | pointer.Leave | pointer.Cancel,
+		})
+		if !ok {
+			break
+		}
+		pe, ok := ev.(pointer.Event)
+		if !ok {
+			continue
+		}
+		switch pe.Kind {
+		case pointer.Press:
+			state.hovering = false
+			col, line := g.hit(pe.Position.Round(), widths, lines, lineHeight)
+			if clicker, ok := col.(GutterClicker); ok && line != nil {
+				clicker.ClickLine(e, line, pe.Buttons)
+				clicked = true
+			}
+		case pointer.Move, pointer.Enter:
+			pos := pe.Position.Round()
+			_, oldLine := g.hit(state.hoverPos, widths, lines, lineHeight)
+			_, newLine := g.hit(pos, widths, lines, lineHeight)
+			if !state.hovering || oldLine != newLine {
+				state.hoverSince = gtx.Now
+			}
+			state.hovering = true
+			state.hoverPos = pos
+		case pointer.Leave, pointer.Cancel:
+			state.hovering = false
+		}
+	}
+	if clicked {
+		gtx.Execute(op.InvalidateCmd{})
+	}
+	return clicked
+}
+
+// Layout lays out the gutter for the visible lines of the editor, including the
+// padding after it.
+func (g *gutter) Layout(gtx layout.Context, e *Editor) layout.Dimensions {
+	lines, _ := e.VisibleLines()
+	lineHeight := e.text.paras.metrics.height
+	widths := g.widths(gtx, e, lines)
+	if g.update(gtx, e, widths, lines, lineHeight) {
+		lines, _ = e.VisibleLines()
+		widths = g.widths(gtx, e, lines)
+	}
+
+	width := 0
+	for _, w := range widths {
+		width += w
+	}
+	height := gtx.Constraints.Max.Y
+	// The areas of the clickable columns are nested in the area of the gutter
+	// to show the pointer cursor over them.
+	area := clip.Rect{Max: image.Pt(width, height)}.Push(gtx.Ops)
+	event.Op(gtx.Ops, &e.gutter)
+	x := 0
+	for i, col := range g.columns {
+		stack := op.Offset(image.Pt(x, 0)).Push(gtx.Ops)
+		if _, ok := col.(GutterClicker); ok {
+			colArea := clip.Rect{Max: image.Pt(widths[i], height)}.Push(gtx.Ops)
+			pointer.CursorPointer.Add(gtx.Ops)
+			colArea.Pop()
+		}
+		for _, line := range lines {
+			lineGtx := gtx
+			lineGtx.Constraints = layout.Constraints{Max: image.Pt(widths[i], lineHeight)}
+			offset := op.Offset(image.Pt(0, line.YOffset)).Push(gtx.Ops)
+			col.LayoutLine(lineGtx, e, line)
+			offset.Pop()
+		}
+		stack.Pop()
+		x += widths[i]
+	}
+	area.Pop()
+
+	g.layoutTooltip(gtx, e, widths, lines, lineHeight)
+	return layout.Dimensions{Size: image.Pt(width+gtx.Dp(g.padding), height)}
+}
+
+func (g *gutter) widths(gtx layout.Context, e *Editor, lines []*LineInfo) []int {
+	widths := make([]int, len(g.columns))
+	for i, col := range g.columns {
+		widths[i] = col.Width(gtx, e, lines)
+	}
+	return widths
+}
+
+// layoutTooltip shows the tooltip of the hovered line after a delay.
+func (g *gutter) layoutTooltip(gtx layout.Context, e *Editor, widths []int, lines []*LineInfo, lineHeight int) {
+	state := &e.gutter
+	if !state.hovering {
+		return
+	}
+	col, line := g.hit(state.hoverPos, widths, lines, lineHeight)
+	tooltipper, ok := col.(GutterTooltipper)
+	if !ok || line == nil {
+		return
+	}
+	tip := tooltipper.Tooltip(e, line)
+	if tip == "" {
+		return
+	}
+	if wait := tooltipDelay - gtx.Now.Sub(state.hoverSince); wait > 0 {
+		gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(wait)})
+		return
+	}
+
+	g.tooltip.Layout(gtx, state.hoverPos, tip)
+}
+
+// Layout draws the tooltip near the pointer position, above everything else.
+func (ts tooltipStyle) Layout(gtx layout.Context, pos image.Point, tip string) {
+	macro := op.Record(gtx.Ops)
+	op.Offset(pos.Add(image.Pt(gtx.Dp(12), gtx.Dp(16)))).Add(gtx.Ops)
+	gtx.Constraints = layout.Constraints{Max: image.Pt(gtx.Dp(360), gtx.Dp(200))}
+	layout.Background{}.Layout(gtx,
+		func(gtx layout.Context) layout.Dimensions {
+			rect := clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, gtx.Dp(4))
+			paint.FillShape(gtx.Ops, ts.bg, rect.Op(gtx.Ops))
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		},
+		func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(unit.Dp(6)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				colorMacro := op.Record(gtx.Ops)
+				paint.ColorOp{Color: ts.fg}.Add(gtx.Ops)
+				return widget.Label{}.Layout(gtx, ts.shaper, font.Font{Typeface: ts.typeFace}, ts.textSize*0.9, tip, colorMacro.Stop())
+			})
+		},
+	)
+	op.Defer(gtx.Ops, macro.Stop())
+}
+
+// MarkerShape is the shape of the markers of a MarkerColumn.
+type MarkerShape uint8
+
+const (
+	// MarkerDot is a filled circle, e.g., for breakpoints.
+	MarkerDot MarkerShape = iota
+	// MarkerBar is a vertical bar, e.g., for diff markers.
+	MarkerBar
+	// MarkerTriangle is a triangle pointing right, e.g., for bookmarks.
+	MarkerTriangle
+)
+
+// Marker is a marker of a line in a MarkerColumn.
+type Marker struct {
+	Color   color.NRGBA
+	Shape   MarkerShape
+	Tooltip string
+}
+
+// MarkerColumn is a gutter column showing markers on some lines, e.g.,
+// breakpoints, bookmarks, lint errors or diff markers.
+type MarkerColumn struct {
+	// Size is the width of the column. It defaults to 12dp.
+	Size unit.Dp
+	// Markers are the markers by line number, which starts from 1.
+	Markers map[int]Marker
+	// OnClick is called with the number of the clicked line.
+	OnClick func(line int, buttons pointer.Buttons)
+}
+
+func (mc *MarkerColumn) Width(gtx layout.Context, e *Editor, lines []*LineInfo) int {
+	if mc.Size <= 0 {
+		return gtx.Dp(12)
+	}
+	return gtx.Dp(mc.Size)
+}
+
+func (mc *MarkerColumn) LayoutLine(gtx layout.Context, e *Editor, line *LineInfo) layout.Dimensions {
+	m, ok := mc.Markers[line.LineNum]
+	if !ok {
+		return layout.Dimensions{}
+	}
+	size := gtx.Constraints.Max
+	w, h := float32(size.X), float32(size.Y)
+	switch m.Shape {
+	case MarkerDot:
+		d := min(size.X, size.Y) * 3 / 5
+		rect := image.Rectangle{Max: image.Pt(d, d)}.Add(image.Pt((size.X-d)/2, (size.Y-d)/2))
+		paint.FillShape(gtx.Ops, m.Color, clip.Ellipse(rect).Op(gtx.Ops))
+	case MarkerBar:
+		bar := image.Rect(size.X/3, 0, size.X*2/3, size.Y)
+		paint.FillShape(gtx.Ops, m.Color, clip.Rect(bar).Op())
+	case MarkerTriangle:
+		var path clip.Path
+		path.Begin(gtx.Ops)
+		path.MoveTo(f32.Pt(w*0.2, h*0.2))
+		path.LineTo(f32.Pt(w*0.8, h*0.5))
+		path.LineTo(f32.Pt(w*0.2, h*0.8))
+		path.Close()
+		paint.FillShape(gtx.Ops, m.Color, clip.Outline{Path: path.End()}.Op())
+	}
+	return layout.Dimensions{Size: size}
+}
+
+func (mc *MarkerColumn) ClickLine(e *Editor, line *LineInfo, buttons pointer.Buttons) {
+	if mc.OnClick != nil {
+		mc.OnClick(line.LineNum, buttons)
+	}
+}
+
+func (mc *MarkerColumn) Tooltip(e *Editor, line *LineInfo) string {
+	return mc.Markers[line.LineNum].Tooltip
+}
+
+// foldColumn shows the fold markers of the foldable lines.
+type foldColumn struct {
+	color    color.NRGBA
+	textSize unit.Sp
+}
+
+func (fc foldColumn) Width(gtx layout.Context, e *Editor, lines []*LineInfo) int {
+	return gtx.Sp(fc.textSize)
+}
+
+// LayoutLine draws a marker pointing right if the line is folded, and down
+// otherwise.
+func (fc foldColumn) LayoutLine(gtx layout.Context, e *Editor, line *LineInfo) layout.Dimensions {
+	if !e.isFoldStart(line.LineNum - 1) {
+		return layout.Dimensions{}
+	}
+	size := gtx.Constraints.Max
+	w, y := float32(size.X), float32(size.Y-size.X)/2
+	var path clip.Path
+	path.Begin(gtx.Ops)
+	if e.IsFolded(line.LineNum - 1) {
+		path.MoveTo(f32.Pt(w*0.35, y+w*0.2))
+		path.LineTo(f32.Pt(w*0.75, y+w*0.5))
+		path.LineTo(f32.Pt(w*0.35, y+w*0.8))
+	} else {
+		path.MoveTo(f32.Pt(w*0.2, y+w*0.35))
+		path.LineTo(f32.Pt(w*0.8, y+w*0.35))
+		path.LineTo(f32.Pt(w*0.5, y+w*0.75))
+	}
+	path.Close()
+	paint.FillShape(gtx.Ops, fc.color, clip.Outline{Path: path.End()}.Op())
+	return layout.Dimensions{Size: size}
+}
+
+func (fc foldColumn) ClickLine(e *Editor, line *LineInfo, buttons pointer.Buttons) {
+	if buttons == pointer.ButtonPrimary {
+		e.ToggleFold(line.LineNum - 1)
+	}
+}
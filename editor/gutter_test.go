@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+	"time"
+
+	"gioui.org/io/pointer"
+	"gioui.org/unit"
+)
+
+func TestGutter(t *testing.T) {
+	e := &Editor{}
+	e.SetText("one\ntwo\nthree", false)
+	var clicked []int
+	markers := &MarkerColumn{
+		Size:    unit.Dp(12),
+		Markers: map[int]Marker{2: {Color: color.NRGBA{R: 0xff, A: 0xff}, Tooltip: "breakpoint"}},
+		OnClick: func(line int, buttons pointer.Buttons) { clicked = append(clicked, line) },
+	}
+	h := newHarness(e, image.Pt(400, 200), EditorConf{ShowLineNum: true, Gutter: []GutterColumn{markers}})
+
+	lines, _ := e.VisibleLines()
+	if len(lines) != 3 {
+		t.Fatalf("got %d visible lines", len(lines))
+	}
+	pos := image.Pt(6, lines[1].YOffset+2)
+	h.Click(pos)
+	h.Click(image.Pt(6, lines[2].YOffset+2))
+	if !slices.Equal(clicked, []int{2, 3}) {
+		t.Fatalf("unexpected clicked lines: %v", clicked)
+	}
+
+	h.Move(pos)
+	if h.Cursor() != pointer.CursorPointer {
+		t.Fatalf("unexpected cursor: %v", h.Cursor())
+	}
+	// The tooltip is shown after a delay.
+	if !h.Invalidated() {
+		t.Fatal("the gutter is not invalidated for the tooltip")
+	}
+	h.Advance(time.Second)
+	h.Frame()
+
+	// Clicking the line numbers does nothing.
+	h.Click(image.Pt(16, lines[0].YOffset+2))
+	if len(clicked) != 2 {
+		t.Fatalf("unexpected clicked lines: %v", clicked)
+	}
+}
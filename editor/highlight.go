@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/op"
+	"gioui.org/op/paint"
+
+	"github.com/oligo/gioview/editor/syntax"
+)
+
+// highlighting holds the syntax highlighting state of the editor.
+type highlighting struct {
+	language    string
+	highlighter *syntax.Highlighter
+	scheme      *syntax.Scheme
+	// ops and materials cache the colors of scheme.
+	ops       *op.Ops
+	materials map[syntax.Kind][2]op.CallOp
+	// styles are the styles of the visible lines.
+	styles    []TextStyle
+	stylePtrs []*TextStyle
+}
+
+// SetLanguage enables syntax highlighting with the tokenizer of a language
+// registered in package syntax, e.g., "go", "markdown", "json" or "shell". An
+// empty or unknown name disables it.
+func (e *Editor) SetLanguage(name string) {
+	if name == e.highlight.language && (name == "") == (e.highlight.highlighter == nil) {
+		return
+	}
+	e.SetTokenizer(syntax.Lookup(name))
+	e.highlight.language = name
+}
+
+// SetTokenizer enables syntax highlighting with the tokenizer, or disables it
+// if tokenizer is nil.
+func (e *Editor) SetTokenizer(tokenizer syntax.Tokenizer) {
+	e.highlight.language = ""
+	e.highlight.highlighter = nil
+	if tokenizer != nil {
+		e.highlight.highlighter = syntax.NewHighlighter(tokenizer)
+	}
+}
+
+// SetColorScheme sets the color scheme of syntax highlighting. A nil scheme
+// resets it to syntax.Light.
+func (e *Editor) SetColorScheme(scheme *syntax.Scheme) {
+	if scheme == nil {
+		scheme = syntax.Light
+	}
+	if scheme == e.highlight.scheme {
+		return
+	}
+	e.highlight.scheme = scheme
+	e.highlight.ops = nil
+}
+
+// highlightEdit tells the highlighter the runes in [start, end) are going to
+// be replaced with s.
+func (e *Editor) highlightEdit(start, end int, s string) {
+	h := e.highlight.highlighter
+	if h == nil {
+		return
+	}
+	if e.text.monolithic() {
+		// Single line text is not highlighted.
+		h.Reset()
+		return
+	}
+
+	h.Edit(e.editedLines(start, end, s))
+}
+
+// editedLines returns the first line of the runes in [start, end), and the
+// number of line breaks removed and inserted by replacing them with s.
+func (e *Editor) editedLines(start, end int, s string) (line, removed, inserted int) {
+	line = e.text.lineAt(int(e.text.ByteOffset(start)))
+	removed = e.text.lineAt(int(e.text.ByteOffset(end))) - line
+	return line, removed, strings.Count(s, "\n")
+}
+
+// syntaxStyles returns the styles of the tokens in the visible lines.
+func (e *Editor) syntaxStyles() []*TextStyle {
+	hl := &e.highlight
+	if hl.highlighter == nil || e.text.monolithic() {
+		return nil
+	}
+	first, last, ok := e.text.visibleParagraphs()
+	if !ok {
+		return nil
+	}
+	if hl.scheme == nil {
+		hl.scheme = syntax.Light
+	}
+	if hl.ops == nil {
+		hl.ops = new(op.Ops)
+		hl.materials = make(map[syntax.Kind][2]op.CallOp)
+		for kind, style := range hl.scheme.Styles {
+			hl.materials[kind] = [2]op.CallOp{colorMaterial(hl.ops, style.Color), colorMaterial(hl.ops, style.Background)}
+		}
+	}
+
+	hl.styles = hl.styles[:0]
+	for i := first; i <= last && i < len(e.text.paras.paragraphs); i++ {
+		line := e.text.lineText(i)
+		tokens := hl.highlighter.Tokens(i, e.text.lineText)
+		runeOff, byteOff := e.text.paras.paragraphs[i].runeOff, 0
+		for _, tok := range tokens {
+			if tok.End > len(line) {
+				break
+			}
+			m, ok := hl.materials[tok.Kind]
+			if !ok {
+				continue
+			}
+			runeOff += utf8.RuneCountInString(line[byteOff:tok.Start])
+			start := runeOff
+			runeOff += utf8.RuneCountInString(line[tok.Start:tok.End])
+			byteOff = tok.End
+			hl.styles = append(hl.styles, TextStyle{Line: i, Start: start, End: runeOff, Color: m[0], Background: m[1]})
+		}
+	}
+
+	hl.stylePtrs = hl.stylePtrs[:0]
+	for i := range hl.styles {
+		hl.stylePtrs = append(hl.stylePtrs, &hl.styles[i])
+	}
+	return hl.stylePtrs
+}
+
+func colorMaterial(ops *op.Ops, c color.NRGBA) op.CallOp {
+	if c == (color.NRGBA{}) {
+		return op.CallOp{}
+	}
+	m := op.Record(ops)
+	paint.ColorOp{Color: c}.Add(ops)
+	return m.Stop()
+}
+
+// lineText returns the content of the logical line without the line break.
+func (e *textView) lineText(line int) string {
+	if line >= len(e.paras.paragraphs) {
+		return ""
+	}
+	n, _ := e.paras.size(line)
+	buf := make([]byte, n)
+	n, _ = e.rr.ReadAt(buf, int64(e.paras.paragraphs[line].byteOff))
+	return string(bytes.TrimSuffix(buf[:n], []byte{'\n'}))
+}
+
+// lineAt returns the logical line containing the byte offset. Unlike
+// logicalLine, the empty line after a trailing line break is counted.
+func (e *textView) lineAt(byteOff int) int {
+	line := e.logicalLine(byteOff)
+	if byteOff >= e.paras.bytes && e.paras.bytes > 0 {
+		var last [1]byte
+		if n, _ := e.rr.ReadAt(last[:], int64(e.paras.bytes-1)); n == 1 && last[0] == '\n' {
+			line++
+		}
+	}
+	return line
+}
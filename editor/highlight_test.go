@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"testing"
+
+	"github.com/oligo/gioview/editor/syntax"
+)
+
+func styledText(e *Editor, kind syntax.Kind) []string {
+	var out []string
+	text := []rune(e.Text())
+	want := e.highlight.materials[kind][0]
+	for _, s := range e.syntaxStyles() {
+		if s.Color == want {
+			out = append(out, string(text[s.Start:s.End]))
+		}
+	}
+	return out
+}
+
+func TestSyntaxHighlighting(t *testing.T) {
+	e := &Editor{}
+	e.SetLanguage("go")
+	e.SetText("x := \"日本\"\n/* a\nb */ y\n", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	if got := styledText(e, syntax.String); len(got) != 1 || got[0] != `"日本"` {
+		t.Fatalf("unexpected strings: %q", got)
+	}
+	if got := styledText(e, syntax.Comment); len(got) != 2 || got[1] != "b */" {
+		t.Fatalf("unexpected comments: %q", got)
+	}
+
+	// Closing the comment on the second line updates the third one.
+	e.SetCaret(14, 14)
+	e.Insert(" */")
+	layoutEditor(e, image.Pt(400, 400))
+	if got := styledText(e, syntax.Comment); len(got) != 1 || got[0] != "/* a */" {
+		t.Fatalf("unexpected comments: %q", got)
+	}
+	if got := styledText(e, syntax.Operator); len(got) != 2 || got[1] != "*/" {
+		t.Fatalf("unexpected operators: %q", got)
+	}
+
+	e.SetLanguage("")
+	if styles := e.syntaxStyles(); len(styles) != 0 {
+		t.Fatalf("highlighting is not disabled: %d styles", len(styles))
+	}
+}
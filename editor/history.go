@@ -0,0 +1,357 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// coalesceInterval is the longest pause between the typed edits of an undo
+// step.
+const coalesceInterval = time.Second
+
+// ErrHistoryMismatch is returned by SetHistory for a history of another text.
+var ErrHistoryMismatch = errors.New("editor: the history is not of the text")
+
+// modification represents a change to the contents of the editor buffer.
+// It contains the necessary information to both apply the change and
+// reverse it, and is useful for implementing undo/redo.
+type modification struct {
+	// StartRune is the inclusive index of the first rune
+	// modified.
+	StartRune int
+	// ApplyContent is the data inserted at StartRune to
+	// apply this operation. It overwrites len([]rune(ReverseContent)) runes.
+	ApplyContent string
+	// ReverseContent is the data inserted at StartRune to
+	// apply this operation. It overwrites len([]rune(ApplyContent)) runes.
+	ReverseContent string
+}
+
+// editKind is the kind of the edits of an undo step. Consecutive typed or
+// deleted runes are coalesced into a step.
+type editKind uint8
+
+const (
+	editOther editKind = iota
+	editTyping
+	editDeleting
+)
+
+// undoStep is a group of modifications undone and redone together. The steps
+// form a tree rooted at the initial text, whose branches are the steps undone
+// before other edits were made.
+type undoStep struct {
+	mods     []modification
+	parent   *undoStep
+	children []*undoStep
+	// redo is the index of the child redo goes to.
+	redo int
+	kind editKind
+	// time is when the step was last extended.
+	time time.Time
+}
+
+// undoHistory is the undo history of an editor.
+type undoHistory struct {
+	root, current *undoStep
+	// last is the step recorded last, which typing may extend. It is reset
+	// by undo and redo.
+	last *undoStep
+	// kind is the kind of the edits being made.
+	kind editKind
+	// depth is the nesting depth of the batches, and batch is the step
+	// recording the edits of the outermost one.
+	depth int
+	batch *undoStep
+	// now returns the current time, and defaults to time.Now.
+	now func() time.Time
+}
+
+func (h *undoHistory) top() *undoStep {
+	if h.current == nil {
+		h.root = &undoStep{}
+		h.current = h.root
+	}
+	return h.current
+}
+
+func (h *undoHistory) reset() {
+	*h = undoHistory{now: h.now}
+}
+
+func (h *undoHistory) clock() time.Time {
+	if h.now != nil {
+		return h.now()
+	}
+	return time.Now()
+}
+
+// record adds the modification to the history. Without tree, the steps undone
+// are discarded.
+func (h *undoHistory) record(mod modification, tree bool) {
+	now := h.clock()
+	step := h.batch
+	if step == nil {
+		step = h.top()
+		if h.depth > 0 || !h.coalesces(step, mod, now) {
+			step = &undoStep{parent: h.current}
+			if h.depth == 0 {
+				step.kind = h.kind
+			}
+			if !tree {
+				h.current.children = nil
+			}
+			h.current.children = append(h.current.children, step)
+			h.current.redo = len(h.current.children) - 1
+			h.current = step
+		}
+		if h.depth > 0 {
+			h.batch = step
+		}
+	}
+	step.mods = append(step.mods, mod)
+	step.time = now
+	h.last = step
+}
+
+// coalesces reports whether the modification continues the typing or deleting
+// of the step. A step ends at the start of a word or a line.
+func (h *undoHistory) coalesces(step *undoStep, mod modification, now time.Time) bool {
+	if h.kind == editOther || step != h.last || step.kind != h.kind || now.Sub(step.time) > coalesceInterval {
+		return false
+	}
+	prev := step.mods[len(step.mods)-1]
+	switch h.kind {
+	case editTyping:
+		if mod.ReverseContent != "" || mod.StartRune != prev.StartRune+utf8.RuneCountInString(prev.ApplyContent) {
+			return false
+		}
+		return !wordStart(lastRune(prev.ApplyContent), firstRune(mod.ApplyContent))
+	case editDeleting:
+		if mod.ApplyContent != "" {
+			return false
+		}
+		switch mod.StartRune {
+		case prev.StartRune - utf8.RuneCountInString(mod.ReverseContent):
+			// Deleting backward.
+			return !wordStart(lastRune(mod.ReverseContent), firstRune(prev.ReverseContent))
+		case prev.StartRune:
+			return !wordStart(lastRune(prev.ReverseContent), firstRune(mod.ReverseContent))
+		}
+	}
+	return false
+}
+
+// wordStart reports whether a word or a line starts between the runes.
+func wordStart(prev, next rune) bool {
+	isWord := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	return next == '\n' || prev == '\n' || isWord(next) && !isWord(prev)
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// recordAs runs the edit with its modifications recorded as of the kind.
+func (e *Editor) recordAs(kind editKind, edit func() int) int {
+	e.history.kind = kind
+	defer func() { e.history.kind = editOther }()
+	return edit()
+}
+
+// BeginBatch starts a batch of edits, which are undone and redone as a single
+// step when the batch is ended by EndBatch. Batches may be nested, in which
+// case the outermost one makes the step.
+func (e *Editor) BeginBatch() {
+	e.history.depth++
+}
+
+// EndBatch ends the batch started by BeginBatch.
+func (e *Editor) EndBatch() {
+	h := &e.history
+	if h.depth == 0 {
+		return
+	}
+	h.depth--
+	if h.depth == 0 {
+		h.batch = nil
+	}
+}
+
+// Undo undoes the last step of the edits, and reports whether there is one.
+func (e *Editor) Undo() bool {
+	_, ok := e.undo()
+	return ok
+}
+
+// Redo redoes the step last undone, and reports whether there is one.
+func (e *Editor) Redo() bool {
+	_, ok := e.redo()
+	return ok
+}
+
+// undo reverses the modifications of the current step.
+func (e *Editor) undo() (EditorEvent, bool) {
+	e.initBuffer()
+	h := &e.history
+	step := h.top()
+	if step.parent == nil {
+		return nil, false
+	}
+	for i := len(step.mods) - 1; i >= 0; i-- {
+		mod := step.mods[i]
+		replaceEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
+		e.replace(mod.StartRune, replaceEnd, mod.ReverseContent, false)
+		caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
+		e.SetCaret(caretEnd, mod.StartRune)
+	}
+	h.current = step.parent
+	h.last, h.batch = nil, nil
+	return ChangeEvent{}, true
+}
+
+// redo applies the modifications of the step redo goes to from the current
+// one.
+func (e *Editor) redo() (EditorEvent, bool) {
+	e.initBuffer()
+	h := &e.history
+	current := h.top()
+	if len(current.children) == 0 {
+		return nil, false
+	}
+	step := current.children[current.redo]
+	for _, mod := range step.mods {
+		end := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
+		e.replace(mod.StartRune, end, mod.ApplyContent, false)
+		caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
+		e.SetCaret(caretEnd, mod.StartRune)
+	}
+	h.current = step
+	h.last, h.batch = nil, nil
+	return ChangeEvent{}, true
+}
+
+// RedoBranches returns the number of steps redo may go to. There is more than
+// one after edits made after undo, if UndoTree is set.
+func (e *Editor) RedoBranches() int {
+	return len(e.history.top().children)
+}
+
+// SetRedoBranch sets the step redo goes to, which defaults to the latest one.
+func (e *Editor) SetRedoBranch(i int) {
+	if current := e.history.top(); i >= 0 && i < len(current.children) {
+		current.redo = i
+	}
+}
+
+// ClearHistory discards the undo history.
+func (e *Editor) ClearHistory() {
+	e.history.reset()
+}
+
+// History is the undo history of an editor in a serializable form, e.g., to
+// JSON, which lets undo survive the reopening of a file.
+type History struct {
+	// Checksum identifies the text of the editor.
+	Checksum string `json:"checksum"`
+	// Steps are the undo steps, after the steps they follow.
+	Steps []HistoryStep `json:"steps"`
+	// Current is the index of the step of the text, or -1 for the initial
+	// text.
+	Current int `json:"current"`
+}
+
+// HistoryStep is a group of edits undone and redone together.
+type HistoryStep struct {
+	// Parent is the index of the step it follows, or -1 for the initial
+	// text.
+	Parent int `json:"parent"`
+	// Edits are the edits of the step, in the order they are applied.
+	Edits []HistoryEdit `json:"edits"`
+}
+
+// HistoryEdit is the replacement of the text Delete at the rune offset Start
+// by Insert.
+type HistoryEdit struct {
+	Start  int    `json:"start"`
+	Insert string `json:"insert,omitempty"`
+	Delete string `json:"delete,omitempty"`
+}
+
+func textChecksum(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// History returns the undo history of the text.
+func (e *Editor) History() History {
+	h := History{Checksum: textChecksum(e.Text()), Current: -1}
+	var walk func(step *undoStep, parent int)
+	walk = func(step *undoStep, parent int) {
+		for _, child := range step.children {
+			s := HistoryStep{Parent: parent, Edits: make([]HistoryEdit, len(child.mods))}
+			for i, mod := range child.mods {
+				s.Edits[i] = HistoryEdit{Start: mod.StartRune, Insert: mod.ApplyContent, Delete: mod.ReverseContent}
+			}
+			h.Steps = append(h.Steps, s)
+			if child == e.history.current {
+				h.Current = len(h.Steps) - 1
+			}
+			walk(child, len(h.Steps)-1)
+		}
+	}
+	e.history.top()
+	walk(e.history.root, -1)
+	return h
+}
+
+// SetHistory replaces the undo history by a history returned by History. It
+// returns ErrHistoryMismatch if the history is not of the text of the editor.
+// Redo goes to the latest steps.
+func (e *Editor) SetHistory(h History) error {
+	if h.Checksum != textChecksum(e.Text()) {
+		return ErrHistoryMismatch
+	}
+	if h.Current < -1 || h.Current >= len(h.Steps) {
+		return fmt.Errorf("editor: invalid current history step %d", h.Current)
+	}
+	root := &undoStep{}
+	steps := make([]*undoStep, len(h.Steps))
+	for i, s := range h.Steps {
+		if s.Parent < -1 || s.Parent >= i {
+			return fmt.Errorf("editor: invalid parent of history step %d", i)
+		}
+		parent := root
+		if s.Parent >= 0 {
+			parent = steps[s.Parent]
+		}
+		step := &undoStep{parent: parent, mods: make([]modification, len(s.Edits))}
+		for j, edit := range s.Edits {
+			step.mods[j] = modification{StartRune: edit.Start, ApplyContent: edit.Insert, ReverseContent: edit.Delete}
+		}
+		parent.children = append(parent.children, step)
+		parent.redo = len(parent.children) - 1
+		steps[i] = step
+	}
+	e.history.reset()
+	e.history.root, e.history.current = root, root
+	if h.Current >= 0 {
+		e.history.current = steps[h.Current]
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"testing"
+	"time"
+)
+
+func TestHistoryCoalescing(t *testing.T) {
+	e := &Editor{}
+	e.SetText("", false)
+	layoutEditor(e, image.Pt(400, 400))
+	now := time.Now()
+	e.history.now = func() time.Time { return now }
+	typeText := func(s string) {
+		for _, r := range s {
+			e.recordAs(editTyping, func() int { return e.Insert(string(r)) })
+			now = now.Add(100 * time.Millisecond)
+		}
+	}
+
+	// Typing is undone a word at a time.
+	typeText("hello world")
+	e.undo()
+	if got := e.Text(); got != "hello " {
+		t.Fatalf("got %q", got)
+	}
+	// A pause ends the step.
+	typeText("big")
+	now = now.Add(2 * coalesceInterval)
+	typeText("ger")
+	e.undo()
+	if got := e.Text(); got != "hello big" {
+		t.Fatalf("got %q", got)
+	}
+	e.undo()
+	if got := e.Text(); got != "hello " {
+		t.Fatalf("got %q", got)
+	}
+
+	// Deleting backward is coalesced too.
+	for range 3 {
+		e.recordAs(editDeleting, func() int { return e.Delete(-1) })
+	}
+	if got := e.Text(); got != "hel" {
+		t.Fatalf("got %q", got)
+	}
+	e.undo()
+	if got := e.Text(); got != "hello " {
+		t.Fatalf("got %q", got)
+	}
+	e.undo()
+	if got := e.Text(); got != "" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestHistoryBatch(t *testing.T) {
+	e := &Editor{}
+	e.SetText("abc", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	e.BeginBatch()
+	e.SetCaret(3, 3)
+	e.Insert("d")
+	e.BeginBatch()
+	e.SetCaret(0, 0)
+	e.Insert(">")
+	e.EndBatch()
+	e.Delete(1)
+	e.EndBatch()
+	e.Insert("!")
+	if got := e.Text(); got != ">!bcd" {
+		t.Fatalf("got %q", got)
+	}
+	e.undo()
+	e.undo()
+	if got := e.Text(); got != "abc" {
+		t.Fatalf("got %q", got)
+	}
+	e.redo()
+	if got := e.Text(); got != ">bcd" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestUndoTree(t *testing.T) {
+	for _, tree := range []bool{false, true} {
+		e := &Editor{UndoTree: tree}
+		e.SetText("", false)
+		layoutEditor(e, image.Pt(400, 400))
+		e.Insert("a")
+		e.Insert("b")
+		e.undo()
+		e.Insert("c")
+		e.undo()
+		if got, want := e.RedoBranches(), map[bool]int{false: 1, true: 2}[tree]; got != want {
+			t.Fatalf("tree %v: got %d branches, want %d", tree, got, want)
+		}
+		e.redo()
+		if got := e.Text(); got != "ac" {
+			t.Fatalf("tree %v: got %q", tree, got)
+		}
+		if !tree {
+			continue
+		}
+		e.undo()
+		e.SetRedoBranch(0)
+		e.redo()
+		if got := e.Text(); got != "ab" {
+			t.Fatalf("got %q", got)
+		}
+	}
+}
+
+func TestHistorySerialization(t *testing.T) {
+	e := &Editor{UndoTree: true}
+	e.SetText("x", false)
+	layoutEditor(e, image.Pt(400, 400))
+	e.SetCaret(1, 1)
+	e.Insert("1")
+	e.undo()
+	e.Insert("2")
+	e.Insert("3")
+	e.undo()
+
+	data, err := json.Marshal(e.History())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h History
+	if err := json.Unmarshal(data, &h); err != nil {
+		t.Fatal(err)
+	}
+
+	// The history is restored when the text is reopened.
+	reopened := &Editor{}
+	reopened.SetText(e.Text(), false)
+	layoutEditor(reopened, image.Pt(400, 400))
+	if err := reopened.SetHistory(h); err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.Redo() || reopened.Text() != "x23" {
+		t.Fatalf("got %q", reopened.Text())
+	}
+	reopened.Undo()
+	reopened.Undo()
+	if got := reopened.RedoBranches(); got != 2 {
+		t.Fatalf("got %d branches", got)
+	}
+	if reopened.Undo() || reopened.Text() != "x" {
+		t.Fatalf("got %q", reopened.Text())
+	}
+
+	other := &Editor{}
+	other.SetText("y", false)
+	if err := other.SetHistory(h); !errors.Is(err, ErrHistoryMismatch) {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"strings"
+	"time"
+
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// HoverProvider provides the text of the hover popups, e.g., the documentation
+// of the symbol under the pointer.
+type HoverProvider interface {
+	// HoverText returns the text to show for the rune at the offset, or an
+	// empty string for none. It is called on every frame once the pointer
+	// rests on the rune, so a slow provider should return an empty string and
+	// invalidate the window when the text is ready.
+	HoverText(r int) string
+}
+
+// hoverState is the pointer state of the hover popups.
+type hoverState struct {
+	active bool
+	pos    image.Point
+	// r is the rune under the pointer, or -1 if it is not over the text.
+	r        int
+	since    time.Time
+	provider HoverProvider
+}
+
+// SetHoverProvider sets the provider of the hover popups, which are shown
+// along with the hover texts of the decorations.
+func (e *Editor) SetHoverProvider(p HoverProvider) {
+	e.hover.provider = p
+}
+
+// updateHover tracks the pointer for the hover popups.
+func (e *Editor) updateHover(gtx layout.Context, ev event.Event) {
+	pe, ok := ev.(pointer.Event)
+	if !ok {
+		return
+	}
+	switch pe.Kind {
+	case pointer.Move, pointer.Enter:
+		pos := pe.Position.Round()
+		r := e.runeAt(pos)
+		if !e.hover.active || r != e.hover.r {
+			e.hover.since = gtx.Now
+		}
+		e.hover.active, e.hover.pos, e.hover.r = true, pos, r
+	default:
+		e.hover.active = false
+	}
+}
+
+// runeAt returns the rune under the position, or -1 if the position is not
+// over any glyph.
+func (e *Editor) runeAt(pos image.Point) int {
+	if e.Len() == 0 {
+		return -1
+	}
+	docPos := pos.Add(e.text.scrollOff)
+	r := e.text.index.closestToXY(fixed.I(docPos.X), docPos.Y).runes
+	docViewport := image.Rectangle{Max: e.text.viewSize}.Add(e.text.scrollOff)
+	// The closest position is between two runes.
+	for _, start := range []int{r, r - 1} {
+		if start < 0 {
+			continue
+		}
+		e.text.regions = e.text.index.locate(docViewport, start, start+1, e.text.regions)
+		for _, region := range e.text.regions {
+			if pos.In(region.Bounds) {
+				return start
+			}
+		}
+	}
+	return -1
+}
+
+// hoverText returns the hover texts of the decorations and of the provider
+// for the rune.
+func (e *Editor) hoverText(r int) string {
+	if r < 0 {
+		return ""
+	}
+	var texts []string
+	for _, d := range e.DecorationsAt(r) {
+		if d.Hover != "" {
+			texts = append(texts, d.Hover)
+		}
+	}
+	if e.hover.provider != nil {
+		if text := e.hover.provider.HoverText(r); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// layoutHoverPopup shows the hover texts of the rune under the pointer after a
+// delay.
+func (e *Editor) layoutHoverPopup(gtx layout.Context, ts tooltipStyle) {
+	if !e.hover.active || e.hover.r < 0 {
+		return
+	}
+	if len(e.decorations) == 0 && e.hover.provider == nil {
+		return
+	}
+	if wait := tooltipDelay - gtx.Now.Sub(e.hover.since); wait > 0 {
+		gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(wait)})
+		return
+	}
+	if text := e.hoverText(e.hover.r); text != "" {
+		ts.Layout(gtx, e.hover.pos, text)
+	}
+}
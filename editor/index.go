@@ -15,6 +15,8 @@ import (
 )
 
 type lineInfo struct {
+	// line is the index of the screen line.
+	line            int
 	xOff            fixed.Int26_6
 	yOff            int
 	width           fixed.Int26_6
@@ -28,7 +30,8 @@ type glyphIndex struct {
 	// positions contain all possible caret positions, sorted by rune index.
 	positions []combinedPos
 	// lines contains metadata about the size and position of each line of
-	// text.
+	// text, sorted by the line index. Only the lines of the indexed paragraphs
+	// are present.
 	lines []lineInfo
 
 	// currentLineMin and currentLineMax track the dimensions of the line
@@ -66,6 +69,25 @@ func (g *glyphIndex) reset() {
 	g.midCluster = false
 }
 
+// startParagraph prepares the index for the glyphs of a paragraph starting at
+// the rune and screen line. The paragraphs must be indexed in order, but
+// they need not be adjacent.
+func (g *glyphIndex) startParagraph(runes, line int) {
+	g.pos.runes = runes
+	g.pos.lineCol = screenPos{line: line}
+	g.pos.runIndex = 0
+	g.clusterAdvance = 0
+	g.midCluster = false
+}
+
+// lineAt returns the index in g.lines of the first line not before the
+// screen line.
+func (g *glyphIndex) lineAt(line int) int {
+	return sort.Search(len(g.lines), func(i int) bool {
+		return g.lines[i].line >= line
+	})
+}
+
 // screenPos represents a character position in text line and column numbers,
 // not pixels.
 type screenPos struct {
@@ -214,6 +236,7 @@ func (g *glyphIndex) Glyph(gl text.Glyph) {
 	}
 	if needsNewLine {
 		g.lines = append(g.lines, lineInfo{
+			line:    g.pos.lineCol.line,
 			xOff:    g.currentLineMin,
 			yOff:    int(gl.Y),
 			width:   g.currentLineMax - g.currentLineMin,
@@ -360,7 +383,9 @@ func (g *glyphIndex) locate(viewport image.Rectangle, startRune, endRune int, re
 	caretStart, _ := g.closestToRune(startRune)
 	caretEnd, _ := g.closestToRune(endRune)
 
-	for lineIdx := caretStart.lineCol.line; lineIdx < len(g.lines); lineIdx++ {
+	for i := g.lineAt(caretStart.lineCol.line); i < len(g.lines); i++ {
+		line := g.lines[i]
+		lineIdx := line.line
 		if lineIdx > caretEnd.lineCol.line {
 			break
 		}
@@ -371,7 +396,6 @@ func (g *glyphIndex) locate(viewport image.Rectangle, startRune, endRune int, re
 		if int(pos.y)-pos.ascent.Ceil() > viewport.Max.Y {
 			break
 		}
-		line := g.lines[lineIdx]
 		if lineIdx > caretStart.lineCol.line && lineIdx < caretEnd.lineCol.line {
 			startX := line.xOff
 			endX := startX + line.width
@@ -0,0 +1,302 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"io"
+	"strings"
+
+	"gioui.org/io/clipboard"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+
+	"github.com/oligo/gioview/keybinding"
+)
+
+// Keymap binds the keys pressed in an editor to its commands. Keymaps may keep
+// state, e.g., the mode of a modal keymap, in which case a keymap is not to be
+// shared by editors.
+type Keymap interface {
+	// Filters returns the filters of the key events the keymap handles. It
+	// is called at every frame.
+	Filters(gtx layout.Context, e *Editor) []event.Filter
+	// Key runs the command bound to the key press, if any.
+	Key(gtx layout.Context, e *Editor, k key.Event)
+	// Text is called with the text typed in the editor, and reports whether
+	// the keymap consumes it rather than letting it be inserted, e.g., as
+	// commands of a modal keymap.
+	Text(gtx layout.Context, e *Editor, s string) bool
+}
+
+// SetKeymap sets the keymap of the editor, which may be changed at any time.
+// A nil keymap restores the default one.
+func (e *Editor) SetKeymap(k Keymap) {
+	e.keys = k
+}
+
+// Keymap returns the keymap of the editor.
+func (e *Editor) Keymap() Keymap {
+	if e.keys == nil {
+		return DefaultKeymap()
+	}
+	return e.keys
+}
+
+// DefaultKeymap returns the keymap of the usual shortcuts of text fields: the
+// arrows, Home, End and Page keys move the caret and extend the selection with
+// Shift, Ctrl (Alt on macOS) moves by words, and Shortcut with Z, C, X, V, A
+// and D undoes, copies, cuts, pastes, selects all and selects the next
+// occurrence. Ctrl+Space shows the completions. The key chords of these edit
+// commands are the ones of Editor.Commands.
+func DefaultKeymap() Keymap {
+	return defaultKeymap{}
+}
+
+type defaultKeymap struct{}
+
+// editCommands are the commands of the keymaps run by runCommand, except for
+// the completion command which is only bound with completion providers.
+var editCommands = []keybinding.CommandID{
+	keybinding.CopyCommand,
+	keybinding.CutCommand,
+	keybinding.PasteCommand,
+	keybinding.SelectAllCommand,
+	keybinding.SelectNextOccurrenceCommand,
+	keybinding.UndoCommand,
+	keybinding.RedoCommand,
+}
+
+// editCommand returns the edit command whose key chord is pressed, if any.
+func (e *Editor) editCommand(k key.Event) (keybinding.CommandID, bool) {
+	if id, ok := e.Commands.Match(k, editCommands...); ok {
+		return id, true
+	}
+	return e.Commands.Match(k, keybinding.CompleteCommand)
+}
+
+// hasEditCommand reports whether the key event runs an edit command.
+func (e *Editor) hasEditCommand(k key.Event) bool {
+	_, ok := e.editCommand(k)
+	return ok
+}
+
+// runCommand runs the edit command of the ID.
+func (e *Editor) runCommand(gtx layout.Context, id keybinding.CommandID) {
+	switch id {
+	// Initiate a paste operation, by requesting the clipboard contents; other
+	// half is in Editor.processKey() under clipboard.Event.
+	case keybinding.PasteCommand:
+		if !e.ReadOnly {
+			gtx.Execute(clipboard.ReadCmd{Tag: e})
+		}
+	// Copy or Cut selection -- ignored if nothing selected.
+	case keybinding.CopyCommand, keybinding.CutCommand:
+		if e.hasSelection() {
+			writeClipboard(gtx, e.selectedTexts())
+			if id == keybinding.CutCommand && !e.ReadOnly {
+				e.Delete(1)
+			}
+		}
+	case keybinding.SelectAllCommand:
+		e.RemoveCarets()
+		e.text.SetCaret(0, e.text.Len())
+	case keybinding.SelectNextOccurrenceCommand:
+		e.SelectNextOccurrence()
+	case keybinding.UndoCommand:
+		if !e.ReadOnly {
+			e.undo()
+		}
+	case keybinding.RedoCommand:
+		if !e.ReadOnly {
+			e.redo()
+		}
+	case keybinding.CompleteCommand:
+		e.TriggerCompletion()
+	}
+}
+
+func (defaultKeymap) Filters(gtx layout.Context, e *Editor) []event.Filter {
+	caret, _ := e.text.Selection()
+	atBeginning := caret == 0 && len(e.carets) == 0
+	atEnd := caret == e.text.Len() && len(e.carets) == 0
+	if gtx.Locale.Direction.Progression() != system.FromOrigin {
+		atEnd, atBeginning = atBeginning, atEnd
+	}
+	filters := []event.Filter{
+		key.Filter{Focus: e, Name: key.NameEnter, Optional: key.ModShift},
+		key.Filter{Focus: e, Name: key.NameReturn, Optional: key.ModShift},
+
+		condFilter(len(e.carets) > 0 || e.snippets.active(), key.Filter{Focus: e, Name: key.NameEscape}),
+
+		key.Filter{Focus: e, Name: key.NameDeleteBackward, Optional: key.ModShortcutAlt | key.ModShift},
+		key.Filter{Focus: e, Name: key.NameDeleteForward, Optional: key.ModShortcutAlt | key.ModShift},
+
+		key.Filter{Focus: e, Name: key.NameHome, Optional: key.ModShortcut | key.ModShift},
+		key.Filter{Focus: e, Name: key.NameEnd, Optional: key.ModShortcut | key.ModShift},
+		key.Filter{Focus: e, Name: key.NamePageDown, Optional: key.ModShift},
+		key.Filter{Focus: e, Name: key.NamePageUp, Optional: key.ModShift},
+		key.Filter{Focus: e, Name: key.NameTab},
+		condFilter(e.snippets.active(), key.Filter{Focus: e, Name: key.NameTab, Required: key.ModShift}),
+		condFilter(!atBeginning, key.Filter{Focus: e, Name: key.NameLeftArrow, Optional: key.ModShortcutAlt | key.ModShift}),
+		condFilter(!atBeginning, key.Filter{Focus: e, Name: key.NameUpArrow, Optional: key.ModShortcutAlt | key.ModShift}),
+		condFilter(!atEnd, key.Filter{Focus: e, Name: key.NameRightArrow, Optional: key.ModShortcutAlt | key.ModShift}),
+		condFilter(!atEnd, key.Filter{Focus: e, Name: key.NameDownArrow, Optional: key.ModShortcutAlt | key.ModShift}),
+	}
+	filters = append(filters, e.Commands.Filters(e, editCommands...)...)
+	if len(e.completion.providers) > 0 {
+		filters = append(filters, e.Commands.Filters(e, keybinding.CompleteCommand)...)
+	}
+	return filters
+}
+
+func (defaultKeymap) Text(gtx layout.Context, e *Editor, s string) bool {
+	return false
+}
+
+func (defaultKeymap) Key(gtx layout.Context, e *Editor, k key.Event) {
+	direction := 1
+	if gtx.Locale.Direction.Progression() == system.TowardOrigin {
+		direction = -1
+	}
+	moveByWord := k.Modifiers.Contain(key.ModShortcutAlt)
+	selAct := selectionClear
+	if k.Modifiers.Contain(key.ModShift) {
+		selAct = selectionExtend
+	}
+	if id, ok := e.editCommand(k); ok {
+		e.runCommand(gtx, id)
+		return
+	}
+	if k.Modifiers.Contain(key.ModShortcut) {
+		switch k.Name {
+		case key.NameHome:
+			e.RemoveCarets()
+			e.text.MoveTextStart(selAct)
+		case key.NameEnd:
+			e.RemoveCarets()
+			e.text.MoveTextEnd(selAct)
+		}
+		return
+	}
+	switch k.Name {
+	case key.NameReturn, key.NameEnter:
+		if !e.ReadOnly {
+			e.Insert("\n")
+		}
+	case key.NameTab:
+		dir := 1
+		if k.Modifiers.Contain(key.ModShift) {
+			dir = -1
+		}
+		if e.moveSnippetStop(dir) {
+			break
+		}
+		if !e.ReadOnly && dir > 0 {
+			if !e.expandSnippet() {
+				e.Insert(e.TabCharacter)
+			}
+		}
+	case key.NameDeleteBackward:
+		if !e.ReadOnly {
+			if moveByWord {
+				e.editCarets(func() int { return e.deleteWord(-1) })
+			} else {
+				e.recordAs(editDeleting, func() int { return e.Delete(-1) })
+			}
+		}
+	case key.NameDeleteForward:
+		if !e.ReadOnly {
+			if moveByWord {
+				e.editCarets(func() int { return e.deleteWord(1) })
+			} else {
+				e.recordAs(editDeleting, func() int { return e.Delete(1) })
+			}
+		}
+	case key.NameEscape:
+		e.endSnippet()
+		e.RemoveCarets()
+	case key.NameUpArrow:
+		e.forEachCaret(func() { e.text.MoveLines(-1, selAct) })
+	case key.NameDownArrow:
+		e.forEachCaret(func() { e.text.MoveLines(+1, selAct) })
+	case key.NameLeftArrow:
+		e.forEachCaret(func() {
+			if moveByWord {
+				e.text.MoveWord(-1*direction, selAct)
+			} else {
+				if selAct == selectionClear {
+					e.text.ClearSelection()
+				}
+				e.text.MoveCaret(-1*direction, -1*direction*int(selAct))
+			}
+		})
+	case key.NameRightArrow:
+		e.forEachCaret(func() {
+			if moveByWord {
+				e.text.MoveWord(1*direction, selAct)
+			} else {
+				if selAct == selectionClear {
+					e.text.ClearSelection()
+				}
+				e.text.MoveCaret(1*direction, int(selAct)*direction)
+			}
+		})
+	case key.NamePageUp:
+		e.RemoveCarets()
+		e.text.MovePages(-1, selAct)
+	case key.NamePageDown:
+		e.RemoveCarets()
+		e.text.MovePages(+1, selAct)
+	case key.NameHome:
+		e.forEachCaret(func() { e.text.MoveLineStart(selAct) })
+	case key.NameEnd:
+		e.forEachCaret(func() { e.text.MoveLineEnd(selAct) })
+	}
+}
+
+func writeClipboard(gtx layout.Context, text string) {
+	gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
+}
+
+// readRune returns the rune at the rune offset, or -1 outside of the text.
+func (e *Editor) readRune(pos int) rune {
+	if pos < 0 || pos >= e.text.Len() {
+		return -1
+	}
+	r, _, _ := e.text.ReadRuneAt(e.text.ByteOffset(pos))
+	return r
+}
+
+// lineBounds returns the rune offsets of the start and the end of the logical
+// line of the rune offset, before its line break.
+func (e *Editor) lineBounds(pos int) (start, end int) {
+	pos = max(0, min(pos, e.text.Len()))
+	start, end = pos, pos
+	for off := e.text.ByteOffset(pos); ; start-- {
+		r, n, _ := e.text.ReadRuneBefore(off)
+		if n == 0 || r == '\n' {
+			break
+		}
+		off -= int64(n)
+	}
+	for off := e.text.ByteOffset(pos); ; end++ {
+		r, n, _ := e.text.ReadRuneAt(off)
+		if n == 0 || r == '\n' {
+			break
+		}
+		off += int64(n)
+	}
+	return start, end
+}
+
+// firstNonBlank returns the rune offset of the first rune of the line of the
+// rune offset which is not a space or a tab.
+func (e *Editor) firstNonBlank(pos int) int {
+	start, end := e.lineBounds(pos)
+	for start < end && (e.readRune(start) == ' ' || e.readRune(start) == '\t') {
+		start++
+	}
+	return start
+}
@@ -0,0 +1,260 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"slices"
+
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+)
+
+// killRingSize is the number of kills kept by EmacsKeymap.
+const killRingSize = 60
+
+// EmacsKeymap is a keymap in the manner of Emacs:
+//
+//   - C-f, C-b, M-f, M-b, C-n, C-p, C-a, C-e, C-v and M-v move the point, and
+//     extend the region while the mark set by C-Space is active.
+//   - C-k, M-d, M-Backspace and C-w kill text into the kill ring, and
+//     consecutive kills are joined. M-w copies the region.
+//   - C-y yanks the last kill, and M-y after it replaces it with the previous
+//     kills.
+//   - C-d deletes, C-o opens a line, C-/ and C-x u undo, C-x h selects all,
+//     C-x C-x exchanges the point and the mark, M-/ shows the completions and
+//     C-g quits.
+//
+// Other keys are those of DefaultKeymap.
+type EmacsKeymap struct {
+	// mark is the rune offset of the mark, or -1 if it is inactive.
+	mark int
+	// kills is the kill ring, whose last kill is yanked first.
+	kills []string
+	// killing tells whether the last command killed text, and yanking
+	// whether it yanked the kill at yankIndex from yankStart to yankEnd.
+	killing, yanking   bool
+	yankStart, yankEnd int
+	yankIndex          int
+	// prefix tells whether C-x is typed.
+	prefix bool
+}
+
+// NewEmacsKeymap returns an Emacs keymap.
+func NewEmacsKeymap() *EmacsKeymap {
+	return &EmacsKeymap{mark: -1}
+}
+
+// Kills returns the kill ring, from the oldest kill to the latest.
+func (m *EmacsKeymap) Kills() []string {
+	return m.kills
+}
+
+func (m *EmacsKeymap) Filters(gtx layout.Context, e *Editor) []event.Filter {
+	filters := defaultKeymap{}.Filters(gtx, e)
+	for _, name := range []key.Name{"A", "B", "D", "E", "F", "G", "K", "N", "O", "P", "V", "W", "X", "Y", "/", key.NameSpace} {
+		filters = append(filters, key.Filter{Focus: e, Name: name, Required: key.ModCtrl})
+	}
+	for _, name := range []key.Name{"B", "D", "F", "V", "W", "Y", "/", key.NameDeleteBackward} {
+		filters = append(filters, key.Filter{Focus: e, Name: name, Required: key.ModAlt})
+	}
+	return filters
+}
+
+func (m *EmacsKeymap) Text(gtx layout.Context, e *Editor, s string) bool {
+	m.killing, m.yanking = false, false
+	m.mark = -1
+	if !m.prefix {
+		return false
+	}
+	m.prefix = false
+	switch s {
+	case "u":
+		if !e.ReadOnly {
+			e.undo()
+		}
+	case "h":
+		e.SetCaret(e.Len(), 0)
+	}
+	return true
+}
+
+func (m *EmacsKeymap) Key(gtx layout.Context, e *Editor, k key.Event) {
+	killing, yanking := m.killing, m.yanking
+	m.killing, m.yanking = false, false
+	ctrl := k.Modifiers == key.ModCtrl
+	alt := k.Modifiers == key.ModAlt
+	if m.prefix {
+		m.prefix = false
+		if ctrl && k.Name == "X" && m.mark >= 0 {
+			caret, _ := e.Selection()
+			e.SetCaret(m.mark, caret)
+			m.mark = caret
+		}
+		return
+	}
+	switch {
+	case ctrl && k.Name == key.NameSpace:
+		caret, _ := e.Selection()
+		e.SetCaret(caret, caret)
+		m.mark = caret
+	case ctrl && k.Name == "G":
+		m.mark = -1
+		m.quit(e)
+	case ctrl && k.Name == "X":
+		m.prefix = true
+	case ctrl && slices.Contains([]key.Name{"F", "B", "N", "P", "A", "E", "V"}, k.Name),
+		alt && slices.Contains([]key.Name{"F", "B", "V"}, k.Name):
+		m.move(e, k)
+	case e.ReadOnly && (ctrl && slices.Contains([]key.Name{"D", "K", "W", "Y", "O", "/"}, k.Name) ||
+		alt && slices.Contains([]key.Name{"D", "Y", key.NameDeleteBackward}, k.Name)):
+		// The commands changing the text are disabled.
+	case ctrl && k.Name == "D":
+		e.Delete(1)
+	case ctrl && k.Name == "K":
+		caret, _ := e.Selection()
+		_, end := e.lineBounds(caret)
+		if end == caret {
+			end = min(end+1, e.Len())
+		}
+		m.kill(gtx, e, caret, end, killing)
+	case alt && (k.Name == "D" || k.Name == key.NameDeleteBackward):
+		caret, _ := e.Selection()
+		dir := 1
+		if k.Name == key.NameDeleteBackward {
+			dir = -1
+		}
+		e.text.MoveWord(dir, selectionClear)
+		end, _ := e.Selection()
+		m.kill(gtx, e, caret, end, killing)
+	case ctrl && k.Name == "W":
+		start, end := e.Selection()
+		m.kill(gtx, e, start, end, killing)
+	case alt && k.Name == "W":
+		m.copyRegion(gtx, e)
+	case ctrl && k.Name == "Y":
+		if len(m.kills) > 0 {
+			m.yankIndex = len(m.kills) - 1
+			m.yankText(e)
+		}
+	case alt && k.Name == "Y":
+		if yanking && len(m.kills) > 0 {
+			m.yankIndex = (m.yankIndex + len(m.kills) - 1) % len(m.kills)
+			e.SetCaret(m.yankEnd, m.yankStart)
+			m.yankText(e)
+		}
+	case ctrl && k.Name == "O":
+		caret, _ := e.Selection()
+		e.Insert("\n")
+		e.SetCaret(caret, caret)
+	case ctrl && k.Name == "/":
+		e.undo()
+	case alt && k.Name == "/":
+		e.TriggerCompletion()
+	default:
+		defaultKeymap{}.Key(gtx, e, k)
+		if k.Name == key.NameEscape {
+			m.mark = -1
+		}
+	}
+}
+
+// move runs a motion command. The region from the mark to the point is
+// selected while the mark is active.
+func (m *EmacsKeymap) move(e *Editor, k key.Event) {
+	e.RemoveCarets()
+	selAct := selectionClear
+	if m.mark >= 0 {
+		selAct = selectionExtend
+	} else {
+		e.text.ClearSelection()
+	}
+	alt := k.Modifiers == key.ModAlt
+	switch k.Name {
+	case "F", "B":
+		dir := 1
+		if k.Name == "B" {
+			dir = -1
+		}
+		if alt {
+			e.text.MoveWord(dir, selAct)
+		} else {
+			e.text.MoveCaret(dir, dir*int(selAct))
+		}
+	case "N":
+		e.text.MoveLines(1, selAct)
+	case "P":
+		e.text.MoveLines(-1, selAct)
+	case "A":
+		e.text.MoveLineStart(selAct)
+	case "E":
+		e.text.MoveLineEnd(selAct)
+	case "V":
+		if alt {
+			e.text.MovePages(-1, selAct)
+		} else {
+			e.text.MovePages(1, selAct)
+		}
+	}
+	e.scrollCaret = true
+}
+
+// kill deletes the text from start to end into the kill ring, and into the
+// clipboard. If appending, the text is joined to the last kill.
+func (m *EmacsKeymap) kill(gtx layout.Context, e *Editor, start, end int, appending bool) {
+	m.mark = -1
+	if start == end {
+		return
+	}
+	backward := start > end
+	start, end = min(start, end), max(start, end)
+	text := e.textRange(start, end)
+	switch {
+	case appending && len(m.kills) > 0 && backward:
+		m.kills[len(m.kills)-1] = text + m.kills[len(m.kills)-1]
+	case appending && len(m.kills) > 0:
+		m.kills[len(m.kills)-1] += text
+	default:
+		m.push(text)
+	}
+	e.replace(start, end, "", true)
+	e.SetCaret(start, start)
+	m.killing = true
+	writeClipboard(gtx, m.kills[len(m.kills)-1])
+}
+
+// copyRegion copies the region into the kill ring, and deactivates the mark.
+func (m *EmacsKeymap) copyRegion(gtx layout.Context, e *Editor) {
+	if text := e.SelectedText(); text != "" {
+		m.push(text)
+		writeClipboard(gtx, text)
+	}
+	m.mark = -1
+	caret, _ := e.Selection()
+	e.SetCaret(caret, caret)
+}
+
+func (m *EmacsKeymap) push(text string) {
+	m.kills = append(m.kills, text)
+	if len(m.kills) > killRingSize {
+		m.kills = m.kills[len(m.kills)-killRingSize:]
+	}
+}
+
+// yankText replaces the selection by the kill at yankIndex.
+func (m *EmacsKeymap) yankText(e *Editor) {
+	m.mark = -1
+	start, end := e.Selection()
+	start = min(start, end)
+	n := e.Insert(m.kills[m.yankIndex])
+	m.yankStart, m.yankEnd = start, start+n
+	m.yanking = true
+}
+
+// quit cancels the commands and the selection.
+func (m *EmacsKeymap) quit(e *Editor) {
+	e.endSnippet()
+	e.HideCompletions()
+	caret, _ := e.Selection()
+	e.SetCaret(caret, caret)
+}
@@ -0,0 +1,260 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+
+	"github.com/oligo/gioview/keybinding"
+)
+
+func TestDefaultKeymapCut(t *testing.T) {
+	e := &Editor{}
+	e.SetText("a\n\n\nb", false)
+	layoutEditor(e, image.Pt(400, 400))
+	var r input.Router
+	gtx := layout.Context{Source: r.Source()}
+
+	// a selection of blank lines is cut too.
+	e.SetCaret(1, 3)
+	defaultKeymap{}.Key(gtx, e, key.Event{Name: "X", Modifiers: key.ModShortcut, State: key.Press})
+	if _, content, ok := r.WriteClipboard(); !ok || string(content) != "\n\n" {
+		t.Fatalf("unexpected clipboard: %q, %v", content, ok)
+	}
+	if got := e.Text(); got != "a\nb" {
+		t.Fatalf("got %q", got)
+	}
+
+	// nothing is copied without a selection.
+	defaultKeymap{}.Key(gtx, e, key.Event{Name: "C", Modifiers: key.ModShortcut, State: key.Press})
+	if _, _, ok := r.WriteClipboard(); ok {
+		t.Fatal("empty selection is copied")
+	}
+}
+
+func TestKeymapCommands(t *testing.T) {
+	e := &Editor{Commands: &keybinding.Registry{}}
+	e.SetText("one two", false)
+	layoutEditor(e, image.Pt(400, 400))
+	gtx := layout.Context{}
+	selectAll := key.Event{Name: "A", Modifiers: key.ModShortcut, State: key.Press}
+
+	// The edit commands are run by the key chords of the registry.
+	e.Commands.SetBinding(keybinding.SelectAllCommand, keybinding.KeyChord{Name: "A", Modifiers: key.ModShortcut | key.ModShift})
+	defaultKeymap{}.Key(gtx, e, selectAll)
+	if start, end := e.Selection(); start != end {
+		t.Fatalf("default key chord selects %d, %d", start, end)
+	}
+	selectAll.Modifiers |= key.ModShift
+	NewVimKeymap().Key(gtx, e, selectAll)
+	if start, end := e.Selection(); start != 0 || end != e.Len() {
+		t.Fatalf("got selection %d, %d", start, end)
+	}
+}
+
+func TestParseVimCommand(t *testing.T) {
+	tests := []struct {
+		keys  string
+		cmd   vimCommand
+		state vimParse
+	}{
+		{"w", vimCommand{name: "w"}, vimComplete},
+		{"3", vimCommand{count: 3}, vimIncomplete},
+		{"2d3w", vimCommand{count: 6, op: 'd', name: "w"}, vimComplete},
+		{`"a2yy`, vimCommand{register: 'a', count: 2, op: 'y', name: "y"}, vimComplete},
+		{"ciw", vimCommand{op: 'c', name: "iw"}, vimComplete},
+		{"dfx", vimCommand{op: 'd', name: "f", arg: 'x'}, vimComplete},
+		{"0", vimCommand{name: "0"}, vimComplete},
+		{"gg", vimCommand{name: "gg"}, vimComplete},
+		{"gx", vimCommand{}, vimInvalid},
+		{"dq", vimCommand{op: 'd'}, vimInvalid},
+		{"dr", vimCommand{op: 'd'}, vimInvalid},
+	}
+	for _, tc := range tests {
+		cmd, state := parseVimCommand([]rune(tc.keys), false)
+		if state != tc.state || state != vimInvalid && cmd != tc.cmd {
+			t.Errorf("%q: got %+v, %d, want %+v, %d", tc.keys, cmd, state, tc.cmd, tc.state)
+		}
+	}
+}
+
+func TestVimKeymap(t *testing.T) {
+	e := &Editor{}
+	e.SetText("one two three\n  four five\nsix", false)
+	layoutEditor(e, image.Pt(400, 400))
+	v := NewVimKeymap()
+	e.SetKeymap(v)
+	gtx := layout.Context{}
+	typeKeys := func(keys string) {
+		if !v.Text(gtx, e, keys) {
+			t.Fatalf("%q is not consumed", keys)
+		}
+	}
+	expect := func(text string, caret int) {
+		t.Helper()
+		if got := e.Text(); got != text {
+			t.Fatalf("got %q, want %q", got, text)
+		}
+		if start, end := e.Selection(); start != caret || end != caret {
+			t.Fatalf("got caret %d, %d, want %d", start, end, caret)
+		}
+	}
+
+	typeKeys("2w")
+	expect("one two three\n  four five\nsix", 8)
+	typeKeys("de")
+	expect("one two \n  four five\nsix", 7)
+	typeKeys("u")
+	expect("one two three\n  four five\nsix", 8)
+	typeKeys("j")
+	expect("one two three\n  four five\nsix", 22)
+
+	// Deleting lines stores them in the register, which puts them as lines.
+	typeKeys(`"add`)
+	expect("one two three\nsix", 14)
+	typeKeys(`k"ap`)
+	expect("one two three\n  four five\nsix", 16)
+	if reg := v.registers['a']; reg != (vimRegister{text: "  four five\n", linewise: true}) {
+		t.Fatalf("got register %+v", reg)
+	}
+
+	typeKeys("$")
+	expect("one two three\n  four five\nsix", 24)
+	typeKeys("cw")
+	if v.Mode() != VimInsert {
+		t.Fatalf("got mode %v", v.Mode())
+	}
+	// Typed text is inserted in the insert mode.
+	if v.Text(gtx, e, "x") {
+		t.Fatal("text consumed in the insert mode")
+	}
+	e.Insert("x")
+	v.Key(gtx, e, key.Event{Name: key.NameEscape})
+	expect("one two three\n  four fivx\nsix", 24)
+
+	// The visual modes select text for the operators.
+	typeKeys("0vey")
+	if reg := v.registers['"']; reg.text != "  four" || v.Mode() != VimNormal {
+		t.Fatalf("got register %+v, mode %v", reg, v.Mode())
+	}
+	typeKeys("Vjd")
+	expect("one two three", 0)
+	typeKeys("fwr_")
+	expect("one t_o three", 5)
+}
+
+func TestVimChangeUndo(t *testing.T) {
+	e := &Editor{}
+	e.SetText("one two three", false)
+	layoutEditor(e, image.Pt(400, 400))
+	v := NewVimKeymap()
+	e.SetKeymap(v)
+	gtx := layout.Context{}
+
+	// A change and the text typed after it are undone together.
+	v.Text(gtx, e, "wcw")
+	for _, r := range "foo" {
+		e.recordAs(editTyping, func() int { return e.Insert(string(r)) })
+	}
+	v.Key(gtx, e, key.Event{Name: key.NameEscape})
+	if got := e.Text(); got != "one foo three" {
+		t.Fatalf("got %q", got)
+	}
+	v.Text(gtx, e, "u")
+	if got := e.Text(); got != "one two three" {
+		t.Fatalf("got %q after undo", got)
+	}
+	if e.history.depth != 0 {
+		t.Fatalf("batch left open: %d", e.history.depth)
+	}
+	v.Key(gtx, e, key.Event{Name: "R", Modifiers: key.ModCtrl})
+	if got := e.Text(); got != "one foo three" {
+		t.Fatalf("got %q after redo", got)
+	}
+}
+
+func TestVimKeymapInput(t *testing.T) {
+	e := &Editor{}
+	e.SetText("one\ntwo\nthree", false)
+	vim := NewVimKeymap()
+	e.SetKeymap(vim)
+	h := newHarness(e, image.Pt(400, 200), EditorConf{})
+	h.Click(image.Pt(10, 10))
+
+	// Typed keys are commands in the normal mode.
+	h.Type("j")
+	h.Type("d")
+	h.Type("d")
+	if e.Text() != "one\nthree" {
+		t.Fatalf("unexpected text: %q", e.Text())
+	}
+	if start, end := e.Selection(); start != 4 || end != 4 {
+		t.Fatalf("unexpected caret: %d, %d", start, end)
+	}
+	h.Type("A")
+	h.Type("!")
+	h.Key(key.NameEscape, 0)
+	if e.Text() != "one\nthree!" || vim.Mode() != VimNormal {
+		t.Fatalf("unexpected text: %q, mode %v", e.Text(), vim.Mode())
+	}
+
+	// The keymap can be switched at any time.
+	e.SetKeymap(nil)
+	h.Type("?")
+	if e.Text() != "one\nthree?!" {
+		t.Fatalf("unexpected text: %q", e.Text())
+	}
+}
+
+func TestEmacsKeymap(t *testing.T) {
+	e := &Editor{}
+	e.SetText("one two\nthree", false)
+	layoutEditor(e, image.Pt(400, 400))
+	m := NewEmacsKeymap()
+	e.SetKeymap(m)
+	gtx := layout.Context{}
+	press := func(mods key.Modifiers, names ...key.Name) {
+		for _, name := range names {
+			m.Key(gtx, e, key.Event{Name: name, Modifiers: mods, State: key.Press})
+		}
+	}
+
+	// Consecutive kills are joined.
+	press(key.ModAlt, "F")
+	press(key.ModCtrl, "K", "K")
+	if got := e.Text(); got != "onethree" {
+		t.Fatalf("got %q", got)
+	}
+	if got := m.Kills(); !slices.Equal(got, []string{" two\n"}) {
+		t.Fatalf("got kills %q", got)
+	}
+	press(key.ModCtrl, "A")
+	press(key.ModAlt, "D")
+	press(key.ModCtrl, "E")
+	press(key.ModCtrl, "Y")
+	if got := e.Text(); got != "onethree" {
+		t.Fatalf("got %q", got)
+	}
+	// M-y replaces the yanked kill by the previous one.
+	press(key.ModAlt, "Y")
+	if got := e.Text(); got != " two\n" {
+		t.Fatalf("got %q", got)
+	}
+
+	// The mark selects the region as the point moves.
+	press(key.ModCtrl, key.NameSpace, "B", "B", "B")
+	if got := e.SelectedText(); got != "wo\n" {
+		t.Fatalf("got %q", got)
+	}
+	press(key.ModAlt, "W")
+	press(key.ModCtrl, "Y")
+	if got := e.Text(); got != " two\nwo\n" {
+		t.Fatalf("got %q", got)
+	}
+}
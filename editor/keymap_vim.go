@@ -0,0 +1,918 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gioui.org/io/clipboard"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+)
+
+// VimMode is a mode of VimKeymap.
+type VimMode uint8
+
+const (
+	// VimNormal is the mode of the commands.
+	VimNormal VimMode = iota
+	// VimInsert is the mode of typing text.
+	VimInsert
+	// VimVisual selects text by runes.
+	VimVisual
+	// VimVisualLine selects text by lines.
+	VimVisualLine
+)
+
+func (m VimMode) String() string {
+	switch m {
+	case VimInsert:
+		return "INSERT"
+	case VimVisual:
+		return "VISUAL"
+	case VimVisualLine:
+		return "VISUAL LINE"
+	default:
+		return "NORMAL"
+	}
+}
+
+const (
+	vimOperators = "dcy"
+	vimMotions   = "hjklwbeWBE0^$G+-"
+	vimCommands  = "iaIAoOxXsSDCYpPJu~vVdcy"
+	// vimEdits are the commands changing the text.
+	vimEdits = "iaIAoOxXsSDCpPJru~dc"
+)
+
+// VimKeymap is a modal keymap in the manner of Vim, which starts in the normal
+// mode. The normal mode has counts, the registers "a to "z ("A to "Z append
+// to them), "0 of the last yank and "+ of the clipboard, the operators d, c and
+// y with the motions h j k l w b e W B E 0 ^ $ + - gg G f F t T and the text
+// objects iw and aw, and the commands i a I A o O x X s S D C Y p P J r ~ u and
+// Ctrl+R. v and V start the visual modes, and Escape returns to the normal
+// mode. The insert mode has the keys of DefaultKeymap.
+type VimKeymap struct {
+	mode VimMode
+	// pending are the keys of the command being typed.
+	pending   []rune
+	registers map[rune]vimRegister
+	// anchor and cursor are the ends of the visual selection, and sel is the
+	// selection set in the editor for it.
+	anchor, cursor int
+	sel            [2]int
+	// column is the column kept by vertical motions, or -1.
+	column int
+	// batch reports whether a batch is open for the insert mode, which makes
+	// the command entering it and the typed text a single undo step.
+	batch bool
+}
+
+type vimRegister struct {
+	text     string
+	linewise bool
+}
+
+// vimCommand is a command of the normal or the visual mode.
+type vimCommand struct {
+	register rune
+	// count is the count of the command, or zero if none is typed.
+	count int
+	// op is the operator applied to the motion, if any.
+	op rune
+	// name is the motion, the text object or the command, or the operator
+	// for a linewise operation such as dd.
+	name string
+	// arg is the rune argument of f, F, t, T and r.
+	arg rune
+}
+
+type vimParse uint8
+
+const (
+	vimInvalid vimParse = iota
+	vimIncomplete
+	vimComplete
+)
+
+// vimMotion is the result of a motion.
+type vimMotion struct {
+	target              int
+	linewise, inclusive bool
+}
+
+// NewVimKeymap returns a Vim keymap in the normal mode.
+func NewVimKeymap() *VimKeymap {
+	return &VimKeymap{registers: make(map[rune]vimRegister), column: -1}
+}
+
+// Mode returns the current mode.
+func (v *VimKeymap) Mode() VimMode {
+	return v.mode
+}
+
+// Pending returns the keys typed of an incomplete command, e.g., to show them
+// in a status bar.
+func (v *VimKeymap) Pending() string {
+	return string(v.pending)
+}
+
+func (v *VimKeymap) Filters(gtx layout.Context, e *Editor) []event.Filter {
+	return append(defaultKeymap{}.Filters(gtx, e),
+		key.Filter{Focus: e, Name: key.NameEscape},
+		key.Filter{Focus: e, Name: "R", Required: key.ModCtrl},
+	)
+}
+
+// vimKeys are the keys of the normal mode which run commands.
+var vimKeys = map[key.Name]string{
+	key.NameLeftArrow:      "h",
+	key.NameRightArrow:     "l",
+	key.NameUpArrow:        "k",
+	key.NameDownArrow:      "j",
+	key.NameHome:           "0",
+	key.NameEnd:            "$",
+	key.NameDeleteBackward: "h",
+	key.NameDeleteForward:  "x",
+	key.NameReturn:         "+",
+	key.NameEnter:          "+",
+}
+
+func (v *VimKeymap) Key(gtx layout.Context, e *Editor, k key.Event) {
+	if v.mode == VimInsert {
+		defaultKeymap{}.Key(gtx, e, k)
+		if k.Name == key.NameEscape {
+			v.mode = VimNormal
+			if v.batch {
+				v.batch = false
+				e.EndBatch()
+			}
+			caret, _ := e.Selection()
+			if start, _ := e.lineBounds(caret); caret > start {
+				caret--
+			}
+			v.moveTo(e, caret)
+		}
+		return
+	}
+	switch {
+	case k.Name == "R" && k.Modifiers.Contain(key.ModCtrl):
+		v.pending = v.pending[:0]
+		if !e.ReadOnly {
+			v.mode = VimNormal
+			e.redo()
+			_, start := e.Selection()
+			v.moveTo(e, start)
+		}
+	case k.Modifiers.Contain(key.ModShortcut) || e.hasEditCommand(k):
+		defaultKeymap{}.Key(gtx, e, k)
+	case k.Name == key.NameEscape:
+		defaultKeymap{}.Key(gtx, e, k)
+		v.pending = v.pending[:0]
+		if v.mode != VimNormal {
+			v.mode = VimNormal
+			v.moveTo(e, v.cursor)
+		}
+	case k.Name == key.NamePageUp || k.Name == key.NamePageDown:
+		if v.mode == VimNormal {
+			defaultKeymap{}.Key(gtx, e, k)
+			caret, _ := e.Selection()
+			v.moveTo(e, caret)
+		}
+	default:
+		for _, r := range vimKeys[k.Name] {
+			v.feed(gtx, e, r)
+		}
+	}
+}
+
+func (v *VimKeymap) Text(gtx layout.Context, e *Editor, s string) bool {
+	if v.mode == VimInsert {
+		return false
+	}
+	for i, r := range s {
+		v.feed(gtx, e, r)
+		if v.mode == VimInsert {
+			// The rest is typed in the insert mode.
+			if i+utf8.RuneLen(r) < len(s) && !e.ReadOnly {
+				e.Insert(s[i+utf8.RuneLen(r):])
+			}
+			break
+		}
+	}
+	return true
+}
+
+// feed adds the key to the pending command, and runs it once complete.
+func (v *VimKeymap) feed(gtx layout.Context, e *Editor, r rune) {
+	v.pending = append(v.pending, r)
+	visual := v.mode == VimVisual || v.mode == VimVisualLine
+	c, state := parseVimCommand(v.pending, visual)
+	if state == vimIncomplete {
+		return
+	}
+	v.pending = v.pending[:0]
+	if state == vimInvalid || e.ReadOnly && c.edits() {
+		return
+	}
+	e.BeginBatch()
+	defer e.EndBatch()
+	if c.name != "j" && c.name != "k" {
+		v.column = -1
+	}
+	if visual {
+		v.runVisual(gtx, e, c)
+	} else {
+		v.run(gtx, e, c)
+	}
+	if v.mode == VimInsert && !v.batch {
+		// The batch is ended by Escape.
+		v.batch = true
+		e.BeginBatch()
+	}
+}
+
+func (c vimCommand) edits() bool {
+	return c.op == 'd' || c.op == 'c' || len(c.name) == 1 && strings.Contains(vimEdits, c.name)
+}
+
+func isVimMotion(name string) bool {
+	return name == "gg" || len(name) == 1 && strings.Contains(vimMotions+"fFtT", name)
+}
+
+// parseVimCommand parses the keys of a command:
+//
+//	["x] [count] (motion | command | op [count] (motion | op | iw | aw))
+func parseVimCommand(keys []rune, visual bool) (vimCommand, vimParse) {
+	var c vimCommand
+	i := 0
+	next := func() (rune, bool) {
+		if i == len(keys) {
+			return 0, false
+		}
+		i++
+		return keys[i-1], true
+	}
+	count := func(r rune, ok bool) (int, rune, bool) {
+		n := 0
+		for ok && ('1' <= r && r <= '9' || n > 0 && r == '0') {
+			n = n*10 + int(r-'0')
+			r, ok = next()
+		}
+		return n, r, ok
+	}
+
+	r, ok := next()
+	if r == '"' {
+		if c.register, ok = next(); !ok {
+			return c, vimIncomplete
+		}
+		r, ok = next()
+	}
+	c.count, r, ok = count(r, ok)
+	if !ok {
+		return c, vimIncomplete
+	}
+	if !visual && strings.ContainsRune(vimOperators, r) {
+		c.op = r
+		var n int
+		if n, r, ok = count(next()); !ok {
+			return c, vimIncomplete
+		}
+		if n > 0 {
+			c.count = max(c.count, 1) * n
+		}
+		switch r {
+		case c.op:
+			c.name = string(r)
+			return c, vimComplete
+		case 'i', 'a':
+			obj, ok := next()
+			if !ok {
+				return c, vimIncomplete
+			}
+			if obj != 'w' {
+				return c, vimInvalid
+			}
+			c.name = string([]rune{r, obj})
+			return c, vimComplete
+		}
+	}
+	switch {
+	case r == 'g':
+		g, ok := next()
+		if !ok {
+			return c, vimIncomplete
+		}
+		if g != 'g' {
+			return c, vimInvalid
+		}
+		c.name = "gg"
+	case strings.ContainsRune("fFtT", r) || r == 'r' && c.op == 0:
+		c.name = string(r)
+		if c.arg, ok = next(); !ok {
+			return c, vimIncomplete
+		}
+	case strings.ContainsRune(vimMotions, r):
+		c.name = string(r)
+	case c.op == 0 && strings.ContainsRune(vimCommands, r):
+		c.name = string(r)
+	default:
+		return c, vimInvalid
+	}
+	return c, vimComplete
+}
+
+// run runs a command of the normal mode.
+func (v *VimKeymap) run(gtx layout.Context, e *Editor, c vimCommand) {
+	pos, _ := e.Selection()
+	count := max(c.count, 1)
+	if c.op != 0 {
+		v.operate(gtx, e, c, pos)
+		return
+	}
+	if isVimMotion(c.name) {
+		if m, ok := v.motion(e, c, pos); ok {
+			v.moveTo(e, m.target)
+		}
+		return
+	}
+	start, end := e.lineBounds(pos)
+	switch c.name {
+	case "i":
+		v.insertAt(e, pos)
+	case "a":
+		v.insertAt(e, min(pos+1, end))
+	case "I":
+		v.insertAt(e, e.firstNonBlank(pos))
+	case "A":
+		v.insertAt(e, end)
+	case "o":
+		indent := e.textRange(start, e.firstNonBlank(pos))
+		e.replace(end, end, "\n"+indent, true)
+		v.insertAt(e, end+1+len(indent))
+	case "O":
+		indent := e.textRange(start, e.firstNonBlank(pos))
+		e.replace(start, start, indent+"\n", true)
+		v.insertAt(e, start+len(indent))
+	case "x":
+		if pos < end {
+			v.apply(gtx, e, 'd', c.register, pos, min(pos+count, end), false)
+		}
+	case "X":
+		if pos > start {
+			v.apply(gtx, e, 'd', c.register, max(pos-count, start), pos, false)
+		}
+	case "s":
+		v.apply(gtx, e, 'c', c.register, pos, min(pos+count, end), false)
+	case "S":
+		v.operate(gtx, e, vimCommand{register: c.register, count: c.count, op: 'c', name: "c"}, pos)
+	case "D", "C":
+		op := unicode.ToLower(rune(c.name[0]))
+		v.operate(gtx, e, vimCommand{register: c.register, count: c.count, op: op, name: "$"}, pos)
+	case "Y":
+		v.operate(gtx, e, vimCommand{register: c.register, count: c.count, op: 'y', name: "y"}, pos)
+	case "p", "P":
+		v.put(gtx, e, c, pos)
+	case "J":
+		v.join(e, pos, max(count, 2))
+	case "r":
+		if pos+count <= end {
+			e.replace(pos, pos+count, strings.Repeat(string(c.arg), count), true)
+			v.moveTo(e, pos+count-1)
+		}
+	case "~":
+		n := min(count, end-pos)
+		e.replace(pos, pos+n, toggleCase(e.textRange(pos, pos+n)), true)
+		v.moveTo(e, pos+n)
+	case "u":
+		for range count {
+			e.undo()
+		}
+		_, start := e.Selection()
+		v.moveTo(e, start)
+	case "v", "V":
+		v.mode = VimVisual
+		if c.name == "V" {
+			v.mode = VimVisualLine
+		}
+		v.anchor, v.cursor = pos, pos
+		v.showVisual(e)
+	}
+}
+
+// runVisual runs a command of the visual modes.
+func (v *VimKeymap) runVisual(gtx layout.Context, e *Editor, c vimCommand) {
+	// The selection is changed by other means, e.g., the mouse.
+	if caret, end := e.Selection(); caret != v.sel[0] || end != v.sel[1] {
+		v.mode = VimNormal
+		v.moveTo(e, caret)
+		return
+	}
+	if isVimMotion(c.name) {
+		if m, ok := v.motion(e, c, v.cursor); ok {
+			v.cursor = v.clamp(e, m.target)
+			v.showVisual(e)
+		}
+		return
+	}
+	start, end := min(v.anchor, v.cursor), max(v.anchor, v.cursor)
+	linewise := v.mode == VimVisualLine
+	if !linewise {
+		end = min(end+1, e.Len())
+	}
+	mode := v.mode
+	v.mode = VimNormal
+	switch c.name {
+	case "v", "V":
+		if m := map[string]VimMode{"v": VimVisual, "V": VimVisualLine}[c.name]; m != mode {
+			v.mode = m
+			v.showVisual(e)
+			return
+		}
+		v.moveTo(e, v.cursor)
+	case "o":
+		v.mode = mode
+		v.anchor, v.cursor = v.cursor, v.anchor
+		v.showVisual(e)
+	case "d", "x":
+		v.apply(gtx, e, 'd', c.register, start, end, linewise)
+	case "c", "s":
+		v.apply(gtx, e, 'c', c.register, start, end, linewise)
+	case "y":
+		v.apply(gtx, e, 'y', c.register, start, end, linewise)
+	case "D", "X":
+		v.apply(gtx, e, 'd', c.register, start, end, true)
+	case "C", "S":
+		v.apply(gtx, e, 'c', c.register, start, end, true)
+	case "p", "P":
+		reg, ok := v.registers[vimRegisterName(c.register)]
+		if !ok {
+			v.moveTo(e, v.cursor)
+			return
+		}
+		v.apply(gtx, e, 'd', 0, start, end, linewise)
+		at, _ := e.Selection()
+		if linewise {
+			at, _ = e.lineBounds(at)
+			if !reg.linewise {
+				reg.text += "\n"
+			}
+		}
+		n := e.replace(at, at, reg.text, true)
+		v.moveTo(e, at+n-1)
+	case "J":
+		lineEnds := strings.Count(e.textRange(start, end), "\n")
+		v.join(e, start, max(lineEnds+1, 2))
+	case "~":
+		e.replace(start, end, toggleCase(e.textRange(start, end)), true)
+		v.moveTo(e, start)
+	case "r":
+		text := []rune(e.textRange(start, end))
+		for i, r := range text {
+			if r != '\n' {
+				text[i] = c.arg
+			}
+		}
+		e.replace(start, end, string(text), true)
+		v.moveTo(e, start)
+	default:
+		v.mode = mode
+	}
+}
+
+// operate applies the operator of the command to the text its motion or text
+// object moves over.
+func (v *VimKeymap) operate(gtx layout.Context, e *Editor, c vimCommand, pos int) {
+	count := max(c.count, 1)
+	var start, end int
+	linewise := false
+	switch c.name {
+	case string(c.op):
+		// The operator doubled applies to count lines.
+		start, end = pos, pos
+		for range count - 1 {
+			if _, lineEnd := e.lineBounds(end); lineEnd < e.Len() {
+				end = lineEnd + 1
+			}
+		}
+		linewise = true
+	case "iw", "aw":
+		start, end = e.vimWordObject(pos, c.name == "aw")
+	case "w", "W":
+		if big := c.name == "W"; c.op == 'c' && vimClass(e.readRune(pos), big) != 0 {
+			// cw changes to the end of the word, rather than to the next one.
+			cls := vimClass(e.readRune(pos), big)
+			end = pos
+			for end+1 < e.Len() && vimClass(e.readRune(end+1), big) == cls {
+				end++
+			}
+			for range count - 1 {
+				end = e.vimWordEnd(end, big)
+			}
+			v.apply(gtx, e, c.op, c.register, pos, end+1, false)
+			return
+		}
+		fallthrough
+	default:
+		m, ok := v.motion(e, c, pos)
+		if !ok {
+			return
+		}
+		start, end = min(pos, m.target), max(pos, m.target)
+		if m.inclusive && e.readRune(end) != '\n' {
+			end = min(end+1, e.Len())
+		}
+		linewise = m.linewise
+	}
+	v.apply(gtx, e, c.op, c.register, start, end, linewise)
+}
+
+// apply applies the operator to the text from start to end, or to its lines
+// if linewise.
+func (v *VimKeymap) apply(gtx layout.Context, e *Editor, op, register rune, start, end int, linewise bool) {
+	caret, _ := e.Selection()
+	if !linewise {
+		v.store(gtx, e, register, e.textRange(start, end), false, op == 'y')
+		switch op {
+		case 'y':
+			v.moveTo(e, start)
+		case 'd':
+			e.replace(start, end, "", true)
+			v.moveTo(e, start)
+		case 'c':
+			e.replace(start, end, "", true)
+			v.insertAt(e, start)
+		}
+		return
+	}
+	start, _ = e.lineBounds(start)
+	_, end = e.lineBounds(end)
+	v.store(gtx, e, register, e.textRange(start, end)+"\n", true, op == 'y')
+	switch op {
+	case 'y':
+		if caret < start || caret > end {
+			v.moveTo(e, start)
+		}
+	case 'd':
+		// The line break before the last line is deleted with it.
+		delStart, delEnd := start, end+1
+		if delEnd > e.Len() {
+			delStart, delEnd = max(start-1, 0), e.Len()
+		}
+		e.replace(delStart, delEnd, "", true)
+		v.moveTo(e, e.firstNonBlank(min(start, e.Len())))
+	case 'c':
+		indent := e.textRange(start, e.firstNonBlank(start))
+		e.replace(start, end, indent, true)
+		v.insertAt(e, start+len(indent))
+	}
+}
+
+// motion returns the result of the motion of the command from the position.
+func (v *VimKeymap) motion(e *Editor, c vimCommand, pos int) (vimMotion, bool) {
+	count := max(c.count, 1)
+	m := vimMotion{target: pos}
+	start, end := e.lineBounds(pos)
+	switch c.name {
+	case "h":
+		m.target = max(pos-count, start)
+	case "l":
+		m.target = min(pos+count, end)
+	case "j", "k", "+", "-":
+		m.linewise = true
+		lineStart := start
+		for range count {
+			if c.name == "j" || c.name == "+" {
+				_, lineEnd := e.lineBounds(lineStart)
+				if lineEnd == e.Len() {
+					break
+				}
+				lineStart = lineEnd + 1
+			} else {
+				if lineStart == 0 {
+					break
+				}
+				lineStart, _ = e.lineBounds(lineStart - 1)
+			}
+		}
+		if c.name == "+" || c.name == "-" {
+			m.target = e.firstNonBlank(lineStart)
+			break
+		}
+		if v.column < 0 {
+			v.column = pos - start
+		}
+		_, lineEnd := e.lineBounds(lineStart)
+		m.target = min(lineStart+v.column, lineEnd)
+	case "0":
+		m.target = start
+	case "^":
+		m.target = e.firstNonBlank(pos)
+	case "$":
+		for range count - 1 {
+			if end < e.Len() {
+				_, end = e.lineBounds(end + 1)
+			}
+		}
+		m.target = max(end-1, start)
+		m.inclusive = true
+	case "w", "W":
+		for i := range count {
+			next := e.vimWordStart(m.target, c.name == "W")
+			// The last word of a line is operated on up to the line end.
+			if _, lineEnd := e.lineBounds(m.target); c.op != 0 && i == count-1 && next > lineEnd {
+				next = lineEnd
+			}
+			m.target = next
+		}
+	case "b", "B":
+		for range count {
+			m.target = e.vimWordBack(m.target, c.name == "B")
+		}
+	case "e", "E":
+		for range count {
+			m.target = e.vimWordEnd(m.target, c.name == "E")
+		}
+		m.inclusive = true
+	case "G", "gg":
+		m.linewise = true
+		line := c.count
+		if line == 0 && c.name == "gg" {
+			line = 1
+		}
+		lineStart := 0
+		for n := 1; line == 0 || n < line; n++ {
+			_, lineEnd := e.lineBounds(lineStart)
+			if lineEnd == e.Len() {
+				break
+			}
+			lineStart = lineEnd + 1
+		}
+		m.target = e.firstNonBlank(lineStart)
+	case "f", "t":
+		found := pos
+		for range count {
+			found++
+			for found < end && e.readRune(found) != c.arg {
+				found++
+			}
+			if found >= end {
+				return m, false
+			}
+		}
+		m.target = found
+		if c.name == "t" {
+			m.target--
+		}
+		m.inclusive = true
+	case "F", "T":
+		found := pos
+		for range count {
+			found--
+			for found >= start && e.readRune(found) != c.arg {
+				found--
+			}
+			if found < start {
+				return m, false
+			}
+		}
+		m.target = found
+		if c.name == "T" {
+			m.target++
+		}
+	default:
+		return m, false
+	}
+	return m, true
+}
+
+// vimClass returns the class of the rune in words: 0 for spaces, 1 for word
+// runes and 2 for punctuation. Big words are made of any non-space runes.
+func vimClass(r rune, big bool) int {
+	switch {
+	case r < 0 || unicode.IsSpace(r):
+		return 0
+	case big || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// vimWordStart returns the start of the next word. Empty lines are words.
+func (e *Editor) vimWordStart(pos int, big bool) int {
+	n := e.Len()
+	if cls := vimClass(e.readRune(pos), big); cls != 0 {
+		for pos < n && vimClass(e.readRune(pos), big) == cls {
+			pos++
+		}
+	}
+	for pos < n && vimClass(e.readRune(pos), big) == 0 {
+		if e.readRune(pos) == '\n' && e.readRune(pos+1) == '\n' {
+			return pos + 1
+		}
+		pos++
+	}
+	return pos
+}
+
+// vimWordBack returns the start of the word before the position.
+func (e *Editor) vimWordBack(pos int, big bool) int {
+	pos--
+	for pos > 0 && vimClass(e.readRune(pos), big) == 0 {
+		if e.readRune(pos) == '\n' && e.readRune(pos-1) == '\n' {
+			return pos
+		}
+		pos--
+	}
+	if cls := vimClass(e.readRune(pos), big); cls != 0 {
+		for pos > 0 && vimClass(e.readRune(pos-1), big) == cls {
+			pos--
+		}
+	}
+	return max(pos, 0)
+}
+
+// vimWordEnd returns the last rune of the word after the position.
+func (e *Editor) vimWordEnd(pos int, big bool) int {
+	n := e.Len()
+	pos++
+	for pos < n && vimClass(e.readRune(pos), big) == 0 {
+		pos++
+	}
+	cls := vimClass(e.readRune(pos), big)
+	for pos+1 < n && vimClass(e.readRune(pos+1), big) == cls {
+		pos++
+	}
+	return max(min(pos, n-1), 0)
+}
+
+// vimWordObject returns the range of the word or the spaces at the position.
+// With around, the spaces after the word, or else before it, are included.
+func (e *Editor) vimWordObject(pos int, around bool) (start, end int) {
+	lineStart, lineEnd := e.lineBounds(pos)
+	cls := vimClass(e.readRune(pos), false)
+	start, end = pos, pos
+	for start > lineStart && vimClass(e.readRune(start-1), false) == cls {
+		start--
+	}
+	for end < lineEnd && vimClass(e.readRune(end), false) == cls {
+		end++
+	}
+	if around && cls != 0 {
+		spaces := end
+		for spaces < lineEnd && vimClass(e.readRune(spaces), false) == 0 {
+			spaces++
+		}
+		if spaces > end {
+			return start, spaces
+		}
+		for start > lineStart && vimClass(e.readRune(start-1), false) == 0 {
+			start--
+		}
+	}
+	return start, end
+}
+
+// join joins the lines from the line of the position, separating them by a
+// space.
+func (v *VimKeymap) join(e *Editor, pos int, lines int) {
+	for range lines - 1 {
+		start, end := e.lineBounds(pos)
+		if end == e.Len() {
+			break
+		}
+		next := e.firstNonBlank(end + 1)
+		sep := " "
+		if r := e.readRune(next); end == start || r == '\n' || r == ')' || r < 0 || e.readRune(end-1) == ' ' {
+			sep = ""
+		}
+		e.replace(end, next, sep, true)
+		pos = end
+	}
+	v.moveTo(e, pos)
+}
+
+// put puts the text of the register after the cursor, or before it for P.
+func (v *VimKeymap) put(gtx layout.Context, e *Editor, c vimCommand, pos int) {
+	start, end := e.lineBounds(pos)
+	after := c.name == "p"
+	if c.register == '+' || c.register == '*' {
+		if after && pos < end {
+			e.SetCaret(pos+1, pos+1)
+		}
+		gtx.Execute(clipboard.ReadCmd{Tag: e})
+		return
+	}
+	reg, ok := v.registers[vimRegisterName(c.register)]
+	if !ok {
+		return
+	}
+	text := strings.Repeat(reg.text, max(c.count, 1))
+	if !reg.linewise {
+		at := pos
+		if after && pos < end {
+			at++
+		}
+		n := e.replace(at, at, text, true)
+		v.moveTo(e, at+n-1)
+		return
+	}
+	at := start
+	if after {
+		if end == e.Len() {
+			e.replace(end, end, "\n"+strings.TrimSuffix(text, "\n"), true)
+			v.moveTo(e, e.firstNonBlank(end+1))
+			return
+		}
+		at = end + 1
+	}
+	e.replace(at, at, text, true)
+	v.moveTo(e, e.firstNonBlank(at))
+}
+
+// vimRegisterName returns the name of the register a command refers to.
+func vimRegisterName(r rune) rune {
+	if r == 0 {
+		return '"'
+	}
+	return unicode.ToLower(r)
+}
+
+// store stores the text yanked or deleted in the register, and in the unnamed
+// register. Yanks to the unnamed register are stored in "0 too.
+func (v *VimKeymap) store(gtx layout.Context, e *Editor, register rune, text string, linewise, yank bool) {
+	name := vimRegisterName(register)
+	switch name {
+	case '_':
+		return
+	case '+', '*':
+		writeClipboard(gtx, text)
+	}
+	reg := vimRegister{text: text, linewise: linewise}
+	if unicode.IsUpper(register) {
+		if old, ok := v.registers[name]; ok {
+			reg = vimRegister{text: old.text + text, linewise: old.linewise || linewise}
+		}
+	}
+	v.registers[name] = reg
+	v.registers['"'] = reg
+	if yank && name == '"' {
+		v.registers['0'] = reg
+	}
+}
+
+// clamp keeps the position on the runes of its line.
+func (v *VimKeymap) clamp(e *Editor, pos int) int {
+	pos = max(0, min(pos, e.Len()))
+	if start, end := e.lineBounds(pos); pos >= end && end > start {
+		pos = end - 1
+	}
+	return pos
+}
+
+// moveTo moves the cursor of the normal mode.
+func (v *VimKeymap) moveTo(e *Editor, pos int) {
+	pos = v.clamp(e, pos)
+	e.SetCaret(pos, pos)
+}
+
+func (v *VimKeymap) insertAt(e *Editor, pos int) {
+	v.mode = VimInsert
+	e.SetCaret(pos, pos)
+}
+
+// showVisual selects the text of the visual mode in the editor.
+func (v *VimKeymap) showVisual(e *Editor) {
+	caret, end := v.cursor, v.anchor
+	switch {
+	case v.mode == VimVisualLine && caret >= end:
+		_, caret = e.lineBounds(caret)
+		caret++
+		end, _ = e.lineBounds(end)
+	case v.mode == VimVisualLine:
+		caret, _ = e.lineBounds(caret)
+		_, end = e.lineBounds(end)
+		end++
+	case caret >= end:
+		caret++
+	default:
+		end++
+	}
+	caret, end = min(caret, e.Len()), min(end, e.Len())
+	e.SetCaret(caret, end)
+	v.sel = [2]int{caret, end}
+}
+
+func toggleCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
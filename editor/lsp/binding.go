@@ -0,0 +1,326 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gioui.org/layout"
+
+	"github.com/oligo/gioview/editor"
+)
+
+const (
+	// requestTimeout bounds the requests of a binding.
+	requestTimeout = 5 * time.Second
+	// decorationSource is the source of the decorations of the diagnostics.
+	decorationSource = "lsp"
+)
+
+// severityColors are the colors of the diagnostics by severity.
+var severityColors = map[DiagnosticSeverity]color.NRGBA{
+	SeverityError:       {R: 0xe5, G: 0x39, B: 0x35, A: 0xff},
+	SeverityWarning:     {R: 0xf9, G: 0xa8, B: 0x25, A: 0xff},
+	SeverityInformation: {R: 0x1e, G: 0x88, B: 0xe5, A: 0xff},
+	SeverityHint:        {R: 0x9e, G: 0x9e, B: 0x9e, A: 0xff},
+}
+
+// Binding keeps a document of a language server in sync with an editor. It
+// shows the diagnostics of the server as decorations, its hover texts in the
+// hover popups of the editor, and its completions in the completion popup.
+type Binding struct {
+	// Invalidate is called when the results of a request arrive, e.g., with the
+	// Invalidate method of the window. The fields are to be set before the
+	// first call of Update.
+	Invalidate func()
+	// OnDefinition is called with the definitions found by GotoDefinition in
+	// other documents. The caret is moved to the definitions in the document.
+	OnDefinition func(locs []Location)
+	// OnError is called with the errors of the requests.
+	OnError func(err error)
+
+	client     *Client
+	editor     *editor.Editor
+	uri        string
+	languageID string
+	// version is the version of the document sent to the server, and revision
+	// is the editor revision of it.
+	version  int
+	revision int
+	index    *lineIndex
+
+	mu sync.Mutex
+	// updated is set by the first call of Update. The diagnostics published
+	// before it only wait for it, as the fields may not be set yet.
+	updated bool
+	// diagnostics are the last published diagnostics not shown yet.
+	diagnostics *PublishDiagnosticsParams
+	hover       hoverResult
+	completion  *completionResult
+	definitions []Location
+}
+
+type hoverResult struct {
+	revision   int
+	start, end int
+	text       string
+	pending    bool
+}
+
+type completionResult struct {
+	revision int
+	start    int
+	items    []editor.CompletionItem
+}
+
+// Bind opens the document of the editor in the server, which must be
+// initialized.
+func Bind(c *Client, e *editor.Editor, uri, languageID string) (*Binding, error) {
+	b := &Binding{
+		client:     c,
+		editor:     e,
+		uri:        uri,
+		languageID: languageID,
+		version:    1,
+		revision:   e.Revision(),
+		hover:      hoverResult{revision: -1},
+	}
+	text := e.Text()
+	b.index = newLineIndex(text)
+	c.OnDiagnostics(uri, b.publishDiagnostics)
+	if err := c.DidOpen(uri, languageID, b.version, text); err != nil {
+		c.OnDiagnostics(uri, nil)
+		return nil, err
+	}
+	e.SetHoverProvider(b)
+	return b, nil
+}
+
+// Layout lays out the editor widget, and syncs the document with the server
+// before and after it, as the editor is edited while it is laid out.
+func (b *Binding) Layout(gtx layout.Context, w layout.Widget) layout.Dimensions {
+	b.Update()
+	dims := w(gtx)
+	b.Update()
+	return dims
+}
+
+// Update sends the changes of the editor to the server, and applies the
+// results of the server to the editor.
+func (b *Binding) Update() {
+	e := b.editor
+	if rev := e.Revision(); rev != b.revision {
+		text := e.Text()
+		b.version++
+		b.revision = rev
+		b.index = newLineIndex(text)
+		if err := b.client.DidChange(b.uri, b.version, text); err != nil {
+			b.error(err)
+		}
+		b.triggerCompletion()
+	}
+
+	b.mu.Lock()
+	b.updated = true
+	diagnostics, completion, definitions := b.diagnostics, b.completion, b.definitions
+	b.diagnostics, b.completion, b.definitions = nil, nil, nil
+	b.mu.Unlock()
+
+	// Outdated diagnostics are dropped, as newer ones will be published.
+	if diagnostics != nil && (diagnostics.Version == 0 || diagnostics.Version == b.version) {
+		e.SetDecorations(decorationSource, b.decorations(diagnostics.Diagnostics))
+	}
+	if completion != nil && completion.revision == b.revision {
+		e.ShowCompletions(completion.start, completion.items)
+	}
+	if len(definitions) > 0 {
+		if definitions[0].URI == b.uri {
+			offset := b.index.Offset(definitions[0].Range.Start)
+			e.SetCaret(offset, offset)
+		} else if b.OnDefinition != nil {
+			b.OnDefinition(definitions)
+		}
+	}
+}
+
+// triggerCompletion requests the completions if a trigger character of the
+// server is typed.
+func (b *Binding) triggerCompletion() {
+	provider := b.client.Capabilities().CompletionProvider
+	start, end := b.editor.Selection()
+	if provider == nil || start != end || start == 0 {
+		return
+	}
+	if slices.Contains(provider.TriggerCharacters, string(b.index.runeAt(start-1))) {
+		b.Complete()
+	}
+}
+
+// Complete requests the completions at the caret, which are shown in the
+// completion popup of the editor.
+func (b *Binding) Complete() {
+	caret, _ := b.editor.Selection()
+	revision, index := b.revision, b.index
+	pos := index.Position(caret)
+	b.request(func(ctx context.Context) error {
+		list, err := b.client.Completion(ctx, b.uri, pos)
+		if err != nil {
+			return err
+		}
+		result := &completionResult{revision: revision, start: wordStart(index, caret)}
+		for _, item := range list.Items {
+			text := item.InsertText
+			if item.TextEdit != nil {
+				text = item.TextEdit.NewText
+				result.start = index.Offset(item.TextEdit.Range.Start)
+			}
+			result.items = append(result.items, editor.CompletionItem{Label: item.Label, Detail: item.Detail, Text: text})
+		}
+		b.mu.Lock()
+		b.completion = result
+		b.mu.Unlock()
+		return nil
+	})
+}
+
+// GotoDefinition moves the caret to the definition of the symbol at the caret,
+// or calls OnDefinition if it is in another document.
+func (b *Binding) GotoDefinition() {
+	caret, _ := b.editor.Selection()
+	pos := b.index.Position(caret)
+	b.request(func(ctx context.Context) error {
+		locs, err := b.client.Definition(ctx, b.uri, pos)
+		if err != nil {
+			return err
+		}
+		b.mu.Lock()
+		b.definitions = locs
+		b.mu.Unlock()
+		return nil
+	})
+}
+
+// HoverText implements editor.HoverProvider. The hover text is requested
+// when the pointer rests on a rune, and shown when it arrives.
+func (b *Binding) HoverText(r int) string {
+	if b.editor.Revision() != b.revision {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.hover
+	if h.revision == b.revision && r >= h.start && r < h.end {
+		return h.text
+	}
+	if h.pending {
+		return ""
+	}
+	b.hover = hoverResult{revision: b.revision, start: r, end: r + 1, pending: true}
+	revision, index := b.revision, b.index
+	pos := index.Position(r)
+	b.request(func(ctx context.Context) error {
+		hover, err := b.client.Hover(ctx, b.uri, pos)
+		result := hoverResult{revision: revision, start: r, end: r + 1}
+		if hover != nil {
+			result.text = strings.TrimSpace(hover.Value)
+			if hover.Range != nil {
+				result.start = index.Offset(hover.Range.Start)
+				result.end = max(index.Offset(hover.Range.End), result.start+1)
+			}
+		}
+		b.mu.Lock()
+		b.hover = result
+		b.mu.Unlock()
+		return err
+	})
+	return ""
+}
+
+// Close closes the document in the server, and clears the diagnostics.
+func (b *Binding) Close() error {
+	b.client.OnDiagnostics(b.uri, nil)
+	b.editor.SetHoverProvider(nil)
+	b.editor.SetDecorations(decorationSource, nil)
+	return b.client.DidClose(b.uri)
+}
+
+// request runs the request in the background, and invalidates the window when
+// it is done.
+func (b *Binding) request(fn func(ctx context.Context) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			b.error(err)
+		}
+		if b.Invalidate != nil {
+			b.Invalidate()
+		}
+	}()
+}
+
+func (b *Binding) error(err error) {
+	if b.OnError != nil {
+		b.OnError(err)
+	}
+}
+
+func (b *Binding) publishDiagnostics(p PublishDiagnosticsParams) {
+	b.mu.Lock()
+	b.diagnostics = &p
+	updated := b.updated
+	b.mu.Unlock()
+	if updated && b.Invalidate != nil {
+		b.Invalidate()
+	}
+}
+
+// decorations converts the diagnostics to decorations of the editor.
+func (b *Binding) decorations(diagnostics []Diagnostic) []editor.Decoration {
+	decorations := make([]editor.Decoration, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		severity := d.Severity
+		if severity == 0 {
+			severity = SeverityError
+		}
+		message := d.Message
+		if d.Source != "" {
+			message = fmt.Sprintf("%s: %s", d.Source, d.Message)
+		}
+		start, end := b.index.Offset(d.Range.Start), b.index.Offset(d.Range.End)
+		if start == end {
+			// Underline the rune at empty ranges.
+			end = min(end+1, b.index.length)
+		}
+		underline := editor.UnderlineWavy
+		if severity == SeverityHint {
+			underline = editor.UnderlineStraight
+		}
+		decorations = append(decorations, editor.Decoration{
+			Start:     start,
+			End:       end,
+			Underline: underline,
+			Color:     severityColors[severity],
+			Hover:     message,
+		})
+	}
+	return decorations
+}
+
+// wordStart returns the start of the word before the rune offset.
+func wordStart(index *lineIndex, offset int) int {
+	start := min(offset, index.length)
+	for start > 0 {
+		r := index.runeAt(start - 1)
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			break
+		}
+		start--
+	}
+	return start
+}
@@ -0,0 +1,286 @@
+// Package lsp connects an editor.Editor to a language server, which provides
+// the completions, the hover texts, the definitions and the diagnostics of a
+// document using the Language Server Protocol.
+//
+// A Client talks to a server process over its standard input and output, and a
+// Binding keeps a document of the server in sync with an editor:
+//
+//	client, err := lsp.Start(ctx, "gopls")
+//	...
+//	err = client.Initialize(ctx, lsp.FileURI(root))
+//	...
+//	b, err := lsp.Bind(client, ed, lsp.FileURI(path), "go")
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shutdownTimeout bounds the shutdown of a server.
+const shutdownTimeout = 2 * time.Second
+
+var errMethodNotFound = &ResponseError{Code: -32601, Message: "method not found"}
+
+// Client is a client of a language server.
+type Client struct {
+	conn *conn
+	cmd  *exec.Cmd
+	caps ServerCapabilities
+
+	mu sync.Mutex
+	// diagnostics are the diagnostics handlers of the documents.
+	diagnostics map[string]func(PublishDiagnosticsParams)
+}
+
+// Start starts the server command, and connects to it over its standard input
+// and output. The server is not bound to ctx, which only aborts the start: it
+// runs until the client is closed, so a short-lived context may be passed.
+func Start(ctx context.Context, command string, args ...string) (*Client, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(command, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	c := NewClient(&stdio{ReadCloser: stdout, WriteCloser: stdin})
+	c.cmd = cmd
+	return c, nil
+}
+
+// NewClient returns a client talking to a server over the connection, e.g., an
+// in-process server in tests.
+func NewClient(rwc io.ReadWriteCloser) *Client {
+	c := &Client{diagnostics: make(map[string]func(PublishDiagnosticsParams))}
+	c.conn = newConn(rwc, c.handle)
+	return c
+}
+
+// Initialize initializes the server for the workspace.
+func (c *Client) Initialize(ctx context.Context, rootURI string) error {
+	params := InitializeParams{
+		ProcessID: os.Getpid(),
+		RootURI:   rootURI,
+		Capabilities: map[string]any{
+			"textDocument": map[string]any{
+				"synchronization": map[string]any{"didSave": false},
+				"completion":      map[string]any{"completionItem": map[string]any{"snippetSupport": false}},
+				"hover":           map[string]any{"contentFormat": []string{"plaintext", "markdown"}},
+				"definition":      map[string]any{},
+				"publishDiagnostics": map[string]any{
+					"relatedInformation": false,
+				},
+			},
+		},
+	}
+	var result InitializeResult
+	if err := c.conn.call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	c.caps = result.Capabilities
+	return c.conn.notify("initialized", struct{}{})
+}
+
+// Capabilities returns the capabilities of the initialized server.
+func (c *Client) Capabilities() ServerCapabilities {
+	return c.caps
+}
+
+// DidOpen opens a document.
+func (c *Client) DidOpen(uri, languageID string, version int, text string) error {
+	return c.conn.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: languageID, Version: version, Text: text},
+	})
+}
+
+// DidChange sends the full text of a changed document.
+func (c *Client) DidChange(uri string, version int, text string) error {
+	return c.conn.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: version},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: text}},
+	})
+}
+
+// DidClose closes a document.
+func (c *Client) DidClose(uri string) error {
+	return c.conn.notify("textDocument/didClose", DidCloseTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+}
+
+// Completion returns the completions at the position.
+func (c *Client) Completion(ctx context.Context, uri string, pos Position) (*CompletionList, error) {
+	var list CompletionList
+	err := c.conn.call(ctx, "textDocument/completion", positionParams(uri, pos), &list)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Hover returns the hover text at the position, or nil if there is none.
+func (c *Client) Hover(ctx context.Context, uri string, pos Position) (*Hover, error) {
+	var hover *Hover
+	if err := c.conn.call(ctx, "textDocument/hover", positionParams(uri, pos), &hover); err != nil {
+		return nil, err
+	}
+	return hover, nil
+}
+
+// Definition returns the locations of the definition of the symbol at the
+// position.
+func (c *Client) Definition(ctx context.Context, uri string, pos Position) ([]Location, error) {
+	var raw json.RawMessage
+	if err := c.conn.call(ctx, "textDocument/definition", positionParams(uri, pos), &raw); err != nil {
+		return nil, err
+	}
+	// The result is a location, a list of locations, or a list of location
+	// links.
+	var loc Location
+	if err := json.Unmarshal(raw, &loc); err == nil && loc.URI != "" {
+		return []Location{loc}, nil
+	}
+	var links []struct {
+		Location
+		TargetURI            string `json:"targetUri"`
+		TargetSelectionRange Range  `json:"targetSelectionRange"`
+	}
+	if err := json.Unmarshal(raw, &links); err != nil {
+		return nil, fmt.Errorf("lsp: invalid definition result: %w", err)
+	}
+	locs := make([]Location, 0, len(links))
+	for _, l := range links {
+		if l.TargetURI != "" {
+			l.Location = Location{URI: l.TargetURI, Range: l.TargetSelectionRange}
+		}
+		locs = append(locs, l.Location)
+	}
+	return locs, nil
+}
+
+// OnDiagnostics sets the handler of the diagnostics published for the
+// document, or removes it if fn is nil. It is called from the goroutine
+// reading the connection.
+func (c *Client) OnDiagnostics(uri string, fn func(PublishDiagnosticsParams)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fn == nil {
+		delete(c.diagnostics, uri)
+	} else {
+		c.diagnostics[uri] = fn
+	}
+}
+
+// Close shuts the server down and closes the connection. A server process
+// which has not exited after the shutdown timeout is killed.
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := c.conn.call(ctx, "shutdown", nil, nil)
+	if err == nil {
+		err = c.conn.notify("exit", nil)
+	}
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	if c.cmd != nil {
+		c.wait()
+	}
+	if errors.Is(err, ErrClosed) {
+		err = nil
+	}
+	return err
+}
+
+// wait waits for the server process to exit, and kills it if it does not.
+func (c *Client) wait() {
+	done := make(chan struct{})
+	go func() {
+		c.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		c.cmd.Process.Kill()
+		<-done
+	}
+}
+
+// handle handles the messages from the server.
+func (c *Client) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p PublishDiagnosticsParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		fn := c.diagnostics[p.URI]
+		c.mu.Unlock()
+		if fn != nil {
+			fn(p)
+		}
+		return nil, nil
+	case "workspace/configuration":
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		json.Unmarshal(params, &p)
+		return make([]any, len(p.Items)), nil
+	case "client/registerCapability", "client/unregisterCapability", "window/workDoneProgress/create":
+		return nil, nil
+	}
+	return nil, errMethodNotFound
+}
+
+func positionParams(uri string, pos Position) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: pos}
+}
+
+// FileURI returns the file URI of the path.
+func FileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths start with the volume name.
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// stdio is the connection to a server process.
+type stdio struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+func (s *stdio) Close() error {
+	err := s.WriteCloser.Close()
+	if rerr := s.ReadCloser.Close(); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,233 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oligo/gioview/editor"
+)
+
+// fakeServer is an in-process language server.
+type fakeServer struct {
+	conn *conn
+
+	mu   sync.Mutex
+	text map[string]string
+	// definition replaces the result of the definition requests if set.
+	definition any
+}
+
+func newFakeServer(t *testing.T) (*Client, *fakeServer) {
+	client, server := net.Pipe()
+	s := &fakeServer{text: make(map[string]string)}
+	s.conn = newConn(server, s.handle)
+	c := NewClient(client)
+	t.Cleanup(func() {
+		c.Close()
+		s.conn.Close()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.Initialize(ctx, "file:///tmp"); err != nil {
+		t.Fatal(err)
+	}
+	return c, s
+}
+
+func (s *fakeServer) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return map[string]any{"capabilities": map[string]any{
+			"completionProvider": map[string]any{"triggerCharacters": []string{"."}},
+			"hoverProvider":      true,
+		}}, nil
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		json.Unmarshal(params, &p)
+		s.publish(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		json.Unmarshal(params, &p)
+		s.publish(p.TextDocument.URI, p.TextDocument.Version, p.ContentChanges[0].Text)
+	case "textDocument/completion":
+		return []CompletionItem{{Label: "Println", Detail: "func(a ...any)"}, {Label: "Printf"}}, nil
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		json.Unmarshal(params, &p)
+		return map[string]any{
+			"contents": map[string]string{"kind": "plaintext", "value": "package fmt"},
+			"range":    Range{Start: Position{0, 0}, End: Position{0, 3}},
+		}, nil
+	case "textDocument/definition":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.definition != nil {
+			return s.definition, nil
+		}
+		return []Location{{URI: "file:///tmp/a.go", Range: Range{Start: Position{1, 0}}}}, nil
+	case "shutdown", "initialized", "exit", "textDocument/didClose":
+	default:
+		return nil, errMethodNotFound
+	}
+	return nil, nil
+}
+
+// publish reports a diagnostic at every "?" of the text.
+func (s *fakeServer) publish(uri string, version int, text string) {
+	var diagnostics []Diagnostic
+	li := newLineIndex(text)
+	for i := range li.length {
+		if li.runeAt(i) == '?' {
+			pos := li.Position(i)
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    Range{Start: pos, End: Position{pos.Line, pos.Character + 1}},
+				Severity: SeverityWarning,
+				Source:   "fake",
+				Message:  "unexpected ?",
+			})
+		}
+	}
+	go s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Version: version, Diagnostics: diagnostics})
+}
+
+func TestClient(t *testing.T) {
+	c, _ := newFakeServer(t)
+	if caps := c.Capabilities(); caps.CompletionProvider == nil || caps.CompletionProvider.TriggerCharacters[0] != "." {
+		t.Fatalf("unexpected capabilities: %+v", caps)
+	}
+
+	ctx := context.Background()
+	list, err := c.Completion(ctx, "file:///tmp/a.go", Position{})
+	if err != nil || len(list.Items) != 2 || list.Items[0].Label != "Println" {
+		t.Fatalf("unexpected completions: %v, %v", list, err)
+	}
+	hover, err := c.Hover(ctx, "file:///tmp/a.go", Position{})
+	if err != nil || hover.Value != "package fmt" || hover.Range == nil {
+		t.Fatalf("unexpected hover: %v, %v", hover, err)
+	}
+	locs, err := c.Definition(ctx, "file:///tmp/a.go", Position{})
+	if err != nil || len(locs) != 1 || locs[0].Range.Start.Line != 1 {
+		t.Fatalf("unexpected definitions: %v, %v", locs, err)
+	}
+	if err := c.conn.call(ctx, "unknown", nil, nil); err == nil {
+		t.Fatal("expected an error for an unknown method")
+	}
+}
+
+func TestMalformedMessages(t *testing.T) {
+	c, s := newFakeServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	s.mu.Lock()
+	s.definition = "not a location"
+	s.mu.Unlock()
+	if locs, err := c.Definition(ctx, "file:///tmp/a.go", Position{}); err == nil {
+		t.Fatalf("expected an error for a malformed definition, got %v", locs)
+	}
+
+	// A frame with an invalid body is dropped, and the next ones are read.
+	frame := "{not json"
+	go func() {
+		s.conn.writeMu.Lock()
+		defer s.conn.writeMu.Unlock()
+		fmt.Fprintf(s.conn.rwc, "Content-Length: %d\r\n\r\n%s", len(frame), frame)
+	}()
+	if _, err := c.Completion(ctx, "file:///tmp/a.go", Position{}); err != nil {
+		t.Fatalf("unexpected error after a malformed frame: %v", err)
+	}
+}
+
+func TestInvalidContentLength(t *testing.T) {
+	for _, length := range []string{"-1", fmt.Sprint(maxMessageSize + 1)} {
+		r := bufio.NewReader(strings.NewReader("Content-Length: " + length + "\r\n\r\n{}"))
+		if _, err := readMessage(r); err == nil || errors.Is(err, errInvalidMessage) {
+			t.Errorf("Content-Length %s: got error %v", length, err)
+		}
+	}
+
+	// The connection is closed, as the next frame cannot be found.
+	c, s := newFakeServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.conn.writeMu.Lock()
+	fmt.Fprint(s.conn.rwc, "Content-Length: -1\r\n\r\n")
+	s.conn.writeMu.Unlock()
+	if _, err := c.Completion(ctx, "file:///tmp/a.go", Position{}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("want ErrClosed, got %v", err)
+	}
+}
+
+func TestBinding(t *testing.T) {
+	c, _ := newFakeServer(t)
+	e := &editor.Editor{}
+	e.SetText("fmt?\nx", false)
+
+	invalidated := make(chan struct{}, 16)
+	b, err := Bind(c, e, "file:///tmp/a.go", "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Invalidate = func() { invalidated <- struct{}{} }
+	wait := func(cond func() bool) {
+		t.Helper()
+		timeout := time.After(time.Second)
+		for {
+			b.Update()
+			if cond() {
+				return
+			}
+			select {
+			case <-invalidated:
+			case <-time.After(10 * time.Millisecond):
+			case <-timeout:
+				t.Fatal("timeout")
+			}
+		}
+	}
+
+	// The diagnostics are shown as decorations, and follow the edits.
+	wait(func() bool { return len(e.Decorations()) == 1 })
+	if d := e.Decorations()[0]; d.Start != 3 || d.End != 4 || d.Hover != "fake: unexpected ?" {
+		t.Fatalf("unexpected decoration: %+v", d)
+	}
+	e.SetCaret(6, 6)
+	e.Insert("?")
+	wait(func() bool { return len(e.Decorations()) == 2 && e.Decorations()[1].Start == 6 })
+
+	// Hover texts are requested when asked for, and cached by range.
+	if got := b.HoverText(1); got != "" {
+		t.Fatalf("got %q before the response", got)
+	}
+	wait(func() bool { return b.HoverText(1) == "package fmt" })
+	if got := b.HoverText(2); got != "package fmt" {
+		t.Fatalf("got %q, want the cached hover", got)
+	}
+
+	// Typing a trigger character shows the completions.
+	e.SetCaret(3, 3)
+	e.Insert(".")
+	wait(e.Completing)
+
+	e.SetCaret(0, 0)
+	b.GotoDefinition()
+	wait(func() bool {
+		start, _ := e.Selection()
+		return start == 6
+	})
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Decorations()) != 0 {
+		t.Fatal("decorations not cleared")
+	}
+}
@@ -0,0 +1,246 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// maxMessageSize bounds the size of the messages read.
+const maxMessageSize = 64 << 20
+
+// ErrClosed is returned by the requests of a closed connection.
+var ErrClosed = errors.New("lsp: connection closed")
+
+// errInvalidMessage is returned by readMessage for a message which is read
+// but cannot be decoded. Unlike the other errors, the messages after it can
+// still be read.
+var errInvalidMessage = errors.New("lsp: invalid message")
+
+// ResponseError is an error returned by the server.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("lsp: %s (%d)", e.Message, e.Code)
+}
+
+// message is a JSON-RPC 2.0 request, notification or response.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+// handler handles the notifications and the requests from the other end. The
+// result is only sent for requests.
+type handler func(method string, params json.RawMessage) (any, error)
+
+// conn is a JSON-RPC 2.0 connection using the base protocol of LSP: every
+// message is preceded by a Content-Length header. A message with an invalid
+// body is dropped, while a read error or an invalid header, after which the
+// next message cannot be found, closes the connection.
+type conn struct {
+	rwc     io.ReadWriteCloser
+	handler handler
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan *message
+	err     error
+}
+
+func newConn(rwc io.ReadWriteCloser, h handler) *conn {
+	c := &conn{
+		rwc:     rwc,
+		handler: h,
+		pending: make(map[int]chan *message),
+	}
+	go c.readLoop()
+	return c
+}
+
+// call sends a request and decodes its result into result, unless it is nil.
+func (c *conn) call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	rawID := json.RawMessage(strconv.Itoa(id))
+	if err := c.write(method, &rawID, params); err != nil {
+		return err
+	}
+	select {
+	case msg := <-ch:
+		if msg == nil {
+			return ErrClosed
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, result)
+	case <-ctx.Done():
+		c.write("$/cancelRequest", nil, map[string]int{"id": id})
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification.
+func (c *conn) notify(method string, params any) error {
+	return c.write(method, nil, params)
+}
+
+func (c *conn) write(method string, id *json.RawMessage, params any) error {
+	msg := message{JSONRPC: "2.0", ID: id, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = data
+	}
+	return c.send(&msg)
+}
+
+func (c *conn) send(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.rwc, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.rwc.Write(data)
+	return err
+}
+
+func (c *conn) readLoop() {
+	r := bufio.NewReader(c.rwc)
+	for {
+		msg, err := readMessage(r)
+		if errors.Is(err, errInvalidMessage) {
+			// The frame is skipped. A response which cannot be decoded leaves
+			// its request to the context of the call.
+			continue
+		}
+		if err != nil {
+			break
+		}
+		switch {
+		case msg.Method == "" && msg.ID != nil:
+			id, _ := strconv.Atoi(string(*msg.ID))
+			c.mu.Lock()
+			if ch := c.pending[id]; ch != nil {
+				ch <- msg
+				delete(c.pending, id)
+			}
+			c.mu.Unlock()
+		case msg.ID != nil:
+			go c.handle(msg)
+		case msg.Method != "":
+			// Notifications are handled in order.
+			c.handle(msg)
+		}
+	}
+	c.close()
+}
+
+// handle handles a request or a notification, and responds to requests.
+func (c *conn) handle(msg *message) {
+	var result any
+	var err error
+	if c.handler != nil {
+		result, err = c.handler(msg.Method, msg.Params)
+	} else if msg.ID != nil {
+		err = &ResponseError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	if msg.ID == nil {
+		return
+	}
+	resp := message{JSONRPC: "2.0", ID: msg.ID}
+	if err != nil {
+		var respErr *ResponseError
+		if !errors.As(err, &respErr) {
+			respErr = &ResponseError{Code: -32603, Message: err.Error()}
+		}
+		resp.Error = respErr
+	} else {
+		data, _ := json.Marshal(result)
+		resp.Result = data
+	}
+	c.send(&resp)
+}
+
+// close fails the pending requests.
+func (c *conn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = ErrClosed
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
+
+// Close closes the underlying connection.
+func (c *conn) Close() error {
+	err := c.rwc.Close()
+	c.close()
+	return err
+}
+
+// readMessage reads a message preceded by its headers.
+func readMessage(r *bufio.Reader) (*message, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("lsp: invalid Content-Length: %w", err)
+	}
+	if length < 0 || length > maxMessageSize {
+		return nil, fmt.Errorf("lsp: invalid Content-Length: %d", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	msg := new(message)
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidMessage, err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,88 @@
+package lsp
+
+import "sort"
+
+// lineIndex maps the rune offsets of the editor to LSP positions, which count
+// the characters of a line in UTF-16 code units.
+type lineIndex struct {
+	text string
+	// bytes and runes are the byte and the rune offsets of the line starts.
+	bytes []int
+	runes []int
+	// length is the rune count of the text.
+	length int
+}
+
+func newLineIndex(text string) *lineIndex {
+	li := &lineIndex{text: text, bytes: []int{0}, runes: []int{0}}
+	runes := 0
+	for i, r := range text {
+		runes++
+		if r == '\n' {
+			li.bytes = append(li.bytes, i+1)
+			li.runes = append(li.runes, runes)
+		}
+	}
+	li.length = runes
+	return li
+}
+
+// Position returns the position of the rune offset. Offsets out of the text
+// are clamped.
+func (li *lineIndex) Position(offset int) Position {
+	line := max(sort.SearchInts(li.runes, offset+1)-1, 0)
+	pos := Position{Line: line}
+	n := offset - li.runes[line]
+	for _, r := range li.text[li.bytes[line]:] {
+		if n <= 0 || r == '\n' {
+			break
+		}
+		pos.Character += utf16Len(r)
+		n--
+	}
+	return pos
+}
+
+// Offset returns the rune offset of the position. Positions past the end of a
+// line are clamped to the line end.
+func (li *lineIndex) Offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(li.bytes) {
+		return li.length
+	}
+	offset := li.runes[pos.Line]
+	units := 0
+	for _, r := range li.text[li.bytes[pos.Line]:] {
+		if r == '\n' || units >= pos.Character {
+			break
+		}
+		units += utf16Len(r)
+		offset++
+	}
+	return offset
+}
+
+// runeAt returns the rune at the offset, or 0 if it is out of the text.
+func (li *lineIndex) runeAt(offset int) rune {
+	if offset < 0 || offset >= li.length {
+		return 0
+	}
+	line := sort.SearchInts(li.runes, offset+1) - 1
+	n := offset - li.runes[line]
+	for _, r := range li.text[li.bytes[line]:] {
+		if n == 0 {
+			return r
+		}
+		n--
+	}
+	return 0
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
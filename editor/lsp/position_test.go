@@ -0,0 +1,40 @@
+package lsp
+
+import "testing"
+
+func TestLineIndex(t *testing.T) {
+	li := newLineIndex("ab\n😀c\n\nxyz")
+	tests := []struct {
+		offset int
+		pos    Position
+	}{
+		{0, Position{0, 0}},
+		{2, Position{0, 2}},
+		{3, Position{1, 0}},
+		// The emoji is two UTF-16 code units.
+		{4, Position{1, 2}},
+		{5, Position{1, 3}},
+		{6, Position{2, 0}},
+		{7, Position{3, 0}},
+		{10, Position{3, 3}},
+	}
+	for _, tc := range tests {
+		if got := li.Position(tc.offset); got != tc.pos {
+			t.Errorf("Position(%d) = %v, want %v", tc.offset, got, tc.pos)
+		}
+		if got := li.Offset(tc.pos); got != tc.offset {
+			t.Errorf("Offset(%v) = %d, want %d", tc.pos, got, tc.offset)
+		}
+	}
+
+	// Positions out of the text are clamped.
+	if got := li.Offset(Position{0, 10}); got != 2 {
+		t.Errorf("got %d, want 2", got)
+	}
+	if got := li.Offset(Position{10, 0}); got != 10 {
+		t.Errorf("got %d, want 10", got)
+	}
+	if got := li.runeAt(3); got != '😀' {
+		t.Errorf("got %q, want 😀", got)
+	}
+}
@@ -0,0 +1,187 @@
+package lsp
+
+import "encoding/json"
+
+// The subset of the Language Server Protocol types used by the client. See
+// https://microsoft.github.io/language-server-protocol/specification for the
+// full protocol.
+
+// Position is a position in a text document. Character is counted in UTF-16
+// code units.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range in a text document. End is exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a document.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// TextDocumentContentChangeEvent is a change of a document. The client always
+// sends the full text.
+type TextDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type InitializeParams struct {
+	ProcessID    int            `json:"processId"`
+	RootURI      string         `json:"rootUri,omitempty"`
+	Capabilities map[string]any `json:"capabilities"`
+}
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+}
+
+// ServerCapabilities are the capabilities of the server the client makes use
+// of.
+type ServerCapabilities struct {
+	CompletionProvider *struct {
+		TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+	} `json:"completionProvider,omitempty"`
+	HoverProvider      json.RawMessage `json:"hoverProvider,omitempty"`
+	DefinitionProvider json.RawMessage `json:"definitionProvider,omitempty"`
+}
+
+// DiagnosticSeverity is the severity of a diagnostic.
+type DiagnosticSeverity int
+
+const (
+	SeverityError DiagnosticSeverity = iota + 1
+	SeverityWarning
+	SeverityInformation
+	SeverityHint
+)
+
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type CompletionItem struct {
+	Label      string    `json:"label"`
+	Kind       int       `json:"kind,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	InsertText string    `json:"insertText,omitempty"`
+	TextEdit   *TextEdit `json:"textEdit,omitempty"`
+}
+
+// CompletionList is the result of a completion request. Servers may also
+// return a plain array of items, which is converted to a list.
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+func (l *CompletionList) UnmarshalJSON(data []byte) error {
+	var items []CompletionItem
+	if err := json.Unmarshal(data, &items); err == nil {
+		*l = CompletionList{Items: items}
+		return nil
+	}
+	type list CompletionList
+	return json.Unmarshal(data, (*list)(l))
+}
+
+// Hover is the result of a hover request. The contents may be a string, a
+// MarkupContent or a list of them, which are joined into Value.
+type Hover struct {
+	Value string
+	Range *Range
+}
+
+func (h *Hover) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Contents json.RawMessage `json:"contents"`
+		Range    *Range          `json:"range"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	h.Range = raw.Range
+	h.Value = markedString(raw.Contents)
+	return nil
+}
+
+// markedString returns the text of hover contents.
+func markedString(data json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return s
+	}
+	var content struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &content); err == nil && content.Value != "" {
+		return content.Value
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err == nil {
+		var out string
+		for _, item := range list {
+			if s := markedString(item); s != "" {
+				if out != "" {
+					out += "\n\n"
+				}
+				out += s
+			}
+		}
+		return out
+	}
+	return ""
+}
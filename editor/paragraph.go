@@ -0,0 +1,388 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"gioui.org/text"
+)
+
+// paragraph is a logical line of the text, including its trailing newline.
+type paragraph struct {
+	// byteOff and runeOff are the offsets of the paragraph in the text.
+	byteOff, runeOff int
+	// line is the index of the first screen line of the paragraph. It is only
+	// valid for paragraphs before paragraphIndex.dirty.
+	line   int
+	layout *paragraphLayout
+	// folded is the number of paragraphs after this one hidden by folding
+	// it.
+	folded int
+	// hidden tells whether the paragraph is hidden by a fold, which makes it
+	// take no screen lines.
+	hidden bool
+}
+
+// paragraphLayout is the shaped text of a paragraph.
+type paragraphLayout struct {
+	// gen is the generation of the layout parameters the paragraph was
+	// shaped with.
+	gen int
+	// last tells whether the paragraph was shaped as the last one of the
+	// text, which has an extra line after the trailing newline, if any.
+	last bool
+	// glyphs of the paragraph. The baseline of the first line is at zero.
+	glyphs []text.Glyph
+	// graphemes contains the grapheme cluster boundaries of the paragraph,
+	// in runes relative to the start of the paragraph.
+	graphemes []int
+	// lines is the number of screen lines of the paragraph.
+	lines int
+	// ascent is the baseline of the first line when the paragraph is laid out
+	// alone.
+	ascent int
+	// bounds is the logical bounding box of the glyphs.
+	bounds image.Rectangle
+}
+
+// lineMetrics is the vertical metrics shared by all the screen lines.
+type lineMetrics struct {
+	// ascent is the baseline of the first line of the text.
+	ascent int
+	// height is the distance between the baselines of two lines.
+	height  int
+	descent int
+}
+
+// paragraphIndex splits the text into paragraphs and caches the shaped
+// paragraphs. Only the paragraphs in or near the viewport are shaped, so the
+// cost of layout does not depend on the size of the text. Paragraphs not shaped
+// yet are assumed to be a single line.
+type paragraphIndex struct {
+	paragraphs []paragraph
+	// bytes and runes are the size of the text.
+	bytes, runes int
+	// monolithic disables the splitting, and the text is laid out as a whole.
+	// It is used for single line text, and for text truncated to a max number
+	// of lines.
+	monolithic bool
+	// gen is incremented when the layout parameters change, which outdates
+	// all the shaped paragraphs.
+	gen int
+	// dirty is the first paragraph with an outdated line.
+	dirty   int
+	metrics lineMetrics
+	// minX and maxX is the horizontal extent of the paragraphs shaped with the
+	// current layout parameters.
+	minX, maxX int
+}
+
+// reset splits the whole text into paragraphs.
+func (pi *paragraphIndex) reset(src textSource, monolithic bool) {
+	pi.monolithic = monolithic
+	pi.bytes = int(src.Size())
+	pi.paragraphs, pi.runes = pi.scan(src, 0, pi.bytes, 0, pi.paragraphs[:0])
+	if len(pi.paragraphs) == 0 {
+		pi.paragraphs = append(pi.paragraphs, paragraph{})
+	}
+	pi.dirty = 0
+	pi.invalidate()
+}
+
+// invalidate outdates all the shaped paragraphs.
+func (pi *paragraphIndex) invalidate() {
+	pi.gen++
+	pi.minX, pi.maxX = math.MaxInt, 0
+}
+
+// scan splits the text in [start, end) into paragraphs, which are appended to
+// dst. The text must start at the beginning of a paragraph, at runeOff. It
+// returns the paragraphs and the number of runes scanned.
+func (pi *paragraphIndex) scan(src io.ReaderAt, start, end, runeOff int, dst []paragraph) ([]paragraph, int) {
+	var buf [32 * 1024]byte
+	runes := 0
+	newParagraph := true
+	for off := start; off < end; {
+		n, _ := src.ReadAt(buf[:min(len(buf), end-off)], int64(off))
+		if n == 0 {
+			break
+		}
+
+		chunk := buf[:n]
+		for i := 0; i < len(chunk); {
+			if newParagraph {
+				dst = append(dst, paragraph{byteOff: off + i, runeOff: runeOff + runes})
+				newParagraph = false
+			}
+
+			j := len(chunk)
+			if !pi.monolithic {
+				if idx := bytes.IndexByte(chunk[i:], '\n'); idx >= 0 {
+					j = i + idx + 1
+					newParagraph = true
+				}
+			}
+			runes += countRunes(chunk[i:j])
+			i = j
+		}
+		off += n
+	}
+
+	return dst, runes
+}
+
+// countRunes counts the runes of valid UTF-8 text. Unlike utf8.RuneCount, the
+// text may start or end in the middle of a rune.
+func countRunes(b []byte) int {
+	n := 0
+	for _, c := range b {
+		if c&0xc0 != 0x80 {
+			n++
+		}
+	}
+	return n
+}
+
+// replace updates the paragraphs after the bytes in [start, end) of the text
+// were replaced with n bytes.
+func (pi *paragraphIndex) replace(src textSource, start, end, n int) {
+	if pi.monolithic {
+		pi.reset(src, true)
+		return
+	}
+
+	first, last := pi.paragraphAtByte(start), pi.paragraphAtByte(end)
+	regionStart := pi.paragraphs[first].byteOff
+	regionEnd, runeEnd := pi.bytes, pi.runes
+	if last+1 < len(pi.paragraphs) {
+		regionEnd = pi.paragraphs[last+1].byteOff
+		runeEnd = pi.paragraphs[last+1].runeOff
+	}
+
+	delta := n - (end - start)
+	scanned, runes := pi.scan(src, regionStart, regionEnd+delta, pi.paragraphs[first].runeOff, nil)
+	runeDelta := runes - (runeEnd - pi.paragraphs[first].runeOff)
+
+	// Edits within the first line of a fold keep it, as its line break is
+	// kept, and other edits of folded lines unfold them.
+	if first == last && len(scanned) > 0 && !pi.paragraphs[first].hidden {
+		scanned[len(scanned)-1].folded = pi.paragraphs[first].folded
+	} else {
+		pi.unfoldEdited(first, last)
+	}
+
+	for i := last + 1; i < len(pi.paragraphs); i++ {
+		pi.paragraphs[i].byteOff += delta
+		pi.paragraphs[i].runeOff += runeDelta
+	}
+	pi.paragraphs = slices.Replace(pi.paragraphs, first, last+1, scanned...)
+	if len(pi.paragraphs) == 0 {
+		pi.paragraphs = append(pi.paragraphs, paragraph{})
+	}
+	pi.bytes += delta
+	pi.runes += runeDelta
+	// The previous paragraph might become the last one.
+	pi.dirty = min(pi.dirty, max(first-1, 0))
+}
+
+// paragraphAtByte returns the index of the paragraph containing the byte offset.
+func (pi *paragraphIndex) paragraphAtByte(off int) int {
+	i := sort.Search(len(pi.paragraphs), func(i int) bool {
+		return pi.paragraphs[i].byteOff > off
+	})
+	return max(i-1, 0)
+}
+
+// paragraphAtRune returns the index of the paragraph containing the rune.
+func (pi *paragraphIndex) paragraphAtRune(r int) int {
+	i := sort.Search(len(pi.paragraphs), func(i int) bool {
+		return pi.paragraphs[i].runeOff > r
+	})
+	return max(i-1, 0)
+}
+
+// paragraphAtLine returns the index of the paragraph containing the screen line.
+func (pi *paragraphIndex) paragraphAtLine(line int) int {
+	pi.updateLines()
+	i := sort.Search(len(pi.paragraphs), func(i int) bool {
+		return pi.paragraphs[i].line > line
+	})
+	return max(i-1, 0)
+}
+
+// paragraphAtY returns the index of the paragraph containing the first line
+// whose bottom is below the y coordinate.
+func (pi *paragraphIndex) paragraphAtY(y int) int {
+	if pi.metrics.height <= 0 {
+		return 0
+	}
+	dy := max(y-pi.metrics.descent-pi.baseline(0), 0)
+	return pi.paragraphAtLine((dy + pi.metrics.height - 1) / pi.metrics.height)
+}
+
+// lines returns the number of screen lines of the paragraph.
+func (pi *paragraphIndex) lines(idx int) int {
+	if pi.paragraphs[idx].hidden {
+		return 0
+	}
+	if l := pi.paragraphs[idx].layout; l != nil {
+		return l.lines
+	}
+	return 1
+}
+
+// updateLines recalculates the outdated screen lines of the paragraphs.
+func (pi *paragraphIndex) updateLines() {
+	for i := pi.dirty; i < len(pi.paragraphs); i++ {
+		if i == 0 {
+			pi.paragraphs[i].line = 0
+			continue
+		}
+		pi.paragraphs[i].line = pi.paragraphs[i-1].line + pi.lines(i-1)
+	}
+	pi.dirty = len(pi.paragraphs)
+}
+
+// size returns the size of the paragraph in bytes and runes.
+func (pi *paragraphIndex) size(idx int) (bytes, runes int) {
+	p := pi.paragraphs[idx]
+	if idx+1 < len(pi.paragraphs) {
+		next := pi.paragraphs[idx+1]
+		return next.byteOff - p.byteOff, next.runeOff - p.runeOff
+	}
+	return pi.bytes - p.byteOff, pi.runes - p.runeOff
+}
+
+// valid reports whether the paragraph is shaped with the current layout parameters.
+func (pi *paragraphIndex) valid(idx int) bool {
+	l := pi.paragraphs[idx].layout
+	return l != nil && l.gen == pi.gen && l.last == (idx == len(pi.paragraphs)-1)
+}
+
+// baseline returns the y coordinate of the first line of the paragraph.
+func (pi *paragraphIndex) baseline(idx int) int {
+	pi.updateLines()
+	ascent := pi.metrics.ascent
+	if pi.valid(0) {
+		ascent = pi.paragraphs[0].layout.ascent
+	}
+	return ascent + pi.paragraphs[idx].line*pi.metrics.height
+}
+
+// setLayout caches the shaped paragraph.
+func (pi *paragraphIndex) setLayout(idx int, l *paragraphLayout) {
+	if pi.lines(idx) != l.lines {
+		pi.dirty = min(pi.dirty, idx+1)
+	}
+	pi.paragraphs[idx].layout = l
+	if !l.bounds.Empty() || len(l.glyphs) > 0 {
+		pi.minX = min(pi.minX, l.bounds.Min.X)
+		pi.maxX = max(pi.maxX, l.bounds.Max.X)
+	}
+}
+
+// measure shapes an empty line to find the line metrics.
+func (pi *paragraphIndex) measure(lt *text.Shaper, params text.Parameters) {
+	pi.metrics = lineMetrics{}
+	if lt == nil {
+		return
+	}
+
+	params.MaxLines = 0
+	lt.LayoutString(params, "\n")
+	var ys []int
+	for {
+		g, ok := lt.NextGlyph()
+		if !ok {
+			break
+		}
+		if g.Flags&text.FlagLineBreak != 0 {
+			ys = append(ys, int(g.Y))
+			pi.metrics.descent = g.Descent.Ceil()
+		}
+	}
+	if len(ys) > 0 {
+		pi.metrics.ascent = ys[0]
+	}
+	if len(ys) > 1 {
+		pi.metrics.height = ys[1] - ys[0]
+	}
+}
+
+// shapeParagraph shapes the paragraph of the text.
+func (e *textView) shapeParagraph(lt *text.Shaper, idx int) *paragraphLayout {
+	paras := &e.paras
+	last := idx == len(paras.paragraphs)-1
+	l := &paragraphLayout{gen: paras.gen, last: last}
+	if old := paras.paragraphs[idx].layout; old != nil {
+		// Reuse the memory of the outdated layout.
+		l.glyphs, l.graphemes = old.glyphs[:0], old.graphemes[:0]
+	}
+
+	size, _ := paras.size(idx)
+	buf := make([]byte, size)
+	n, _ := e.rr.ReadAt(buf, int64(paras.paragraphs[idx].byteOff))
+	str := string(buf[:n])
+
+	it := textIterator{viewport: image.Rectangle{Max: image.Point{X: math.MaxInt, Y: math.MaxInt}}}
+	if lt != nil {
+		lt.LayoutString(e.params, str)
+		for {
+			g, ok := lt.NextGlyph()
+			if !it.processGlyph(g, ok) {
+				break
+			}
+			l.glyphs = append(l.glyphs, g)
+			// Skip the position after the trailing newline, which only the
+			// last paragraph has.
+			if !last && g.Flags&text.FlagParagraphBreak != 0 {
+				break
+			}
+		}
+	} else {
+		// Make a fake glyph for every rune in the paragraph.
+		for range str {
+			g := text.Glyph{Runes: 1, Flags: text.FlagClusterBreak}
+			_ = it.processGlyph(g, true)
+			l.glyphs = append(l.glyphs, g)
+		}
+		if len(l.glyphs) == 0 || !last {
+			l.glyphs = append(l.glyphs, text.Glyph{})
+		}
+		l.glyphs[len(l.glyphs)-1].Flags |= text.FlagLineBreak | text.FlagRunBreak | text.FlagClusterBreak
+	}
+
+	// Move the first baseline to zero.
+	if len(l.glyphs) > 0 {
+		y0 := l.glyphs[0].Y
+		l.ascent = int(y0)
+		for i := range l.glyphs {
+			l.glyphs[i].Y -= y0
+		}
+		it.bounds = it.bounds.Sub(image.Pt(0, int(y0)))
+	}
+	for _, g := range l.glyphs {
+		if g.Flags&text.FlagLineBreak != 0 {
+			l.lines++
+		}
+	}
+	l.lines = max(l.lines, 1)
+	l.bounds = it.bounds
+
+	e.paragraphReader.SetSource(strings.NewReader(str))
+	for g := e.paragraphReader.Graphemes(); len(g) > 0; g = e.paragraphReader.Graphemes() {
+		if len(l.graphemes) > 0 && g[0] == l.graphemes[len(l.graphemes)-1] {
+			g = g[1:]
+		}
+		l.graphemes = append(l.graphemes, g...)
+	}
+
+	return l
+}
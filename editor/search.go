@@ -0,0 +1,300 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sort"
+	"unicode/utf8"
+)
+
+// SearchQuery describes the text to find in the editor.
+type SearchQuery struct {
+	// Text is the text to find, or a regular expression in the syntax of
+	// package regexp if Regexp is set.
+	Text   string
+	Regexp bool
+	// WholeWord only matches whole words. Word boundaries are ASCII based, as
+	// defined by \b of package regexp.
+	WholeWord  bool
+	IgnoreCase bool
+}
+
+// Compile returns the regular expression matching the query.
+func (q SearchQuery) Compile() (*regexp.Regexp, error) {
+	expr := q.Text
+	if !q.Regexp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if q.WholeWord {
+		expr = `\b(?:` + expr + `)\b`
+	}
+	if q.IgnoreCase {
+		expr = `(?i)` + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// searchState is the state of the search started by Editor.Find.
+type searchState struct {
+	active bool
+	query  SearchQuery
+	re     *regexp.Regexp
+	// lineBound tells whether the matches never span lines, so that an edit
+	// only affects the matches in the edited lines.
+	lineBound bool
+	// suspended stops updating the matches while the editor replaces them.
+	suspended bool
+}
+
+// Find searches the text for the query, and the first match at or after the
+// caret becomes the current match. The matches are kept up to date as the
+// text changes, until ClearSearch or SetMatches is called. It returns the
+// number of matches.
+func (e *Editor) Find(query SearchQuery) (int, error) {
+	e.initBuffer()
+	if query.Text == "" {
+		e.ClearSearch()
+		return 0, nil
+	}
+	re, err := query.Compile()
+	if err != nil {
+		return 0, err
+	}
+
+	e.search = searchState{active: true, query: query, re: re, lineBound: lineBound(re)}
+	e.findAll()
+	caret := min(e.text.Selection())
+	e.currentMatch = sort.Search(len(e.matches), func(i int) bool {
+		return e.matches[i].Start >= caret
+	})
+	if e.currentMatch == len(e.matches) {
+		e.currentMatch = 0
+	}
+	return len(e.matches), nil
+}
+
+// ClearSearch stops the search started by Find, and clears the matches.
+func (e *Editor) ClearSearch() {
+	e.search = searchState{}
+	e.matches = nil
+	e.currentMatch = 0
+}
+
+// Matches returns the matches, and the index of the current one. The index is
+// -1 if there is no match.
+func (e *Editor) Matches() ([]MatchRange, int) {
+	if len(e.matches) == 0 {
+		return nil, -1
+	}
+	return e.matches, min(e.currentMatch, len(e.matches)-1)
+}
+
+// FindNext selects the first match after the selection, wrapping around the
+// end of the text. It reports whether there is a match.
+func (e *Editor) FindNext() bool {
+	e.initBuffer()
+	if len(e.matches) == 0 {
+		return false
+	}
+	start, end := e.text.Selection()
+	from := max(start, end)
+	if start == end {
+		from = start
+	}
+	idx := sort.Search(len(e.matches), func(i int) bool {
+		return e.matches[i].Start >= from
+	})
+	if idx < len(e.matches) && start != end && e.matches[idx].Start == min(start, end) && e.matches[idx].End == from {
+		idx++
+	}
+	e.NextMatch(idx % len(e.matches))
+	return true
+}
+
+// FindPrevious selects the last match before the selection, wrapping around
+// the start of the text. It reports whether there is a match.
+func (e *Editor) FindPrevious() bool {
+	e.initBuffer()
+	if len(e.matches) == 0 {
+		return false
+	}
+	from := min(e.text.Selection())
+	idx := sort.Search(len(e.matches), func(i int) bool {
+		return e.matches[i].End > from
+	})
+	e.NextMatch((idx - 1 + len(e.matches)) % len(e.matches))
+	return true
+}
+
+// ReplaceMatch replaces the current match with repl if it is selected, and
+// selects the next match. Otherwise the next match is only selected, so that it
+// can be checked before it is replaced. For regular expression queries, $1 or
+// ${name} in repl is replaced with the submatches, as in
+// regexp.Regexp.Expand. It reports whether a match is replaced.
+func (e *Editor) ReplaceMatch(repl string) bool {
+	e.initBuffer()
+	matches, current := e.Matches()
+	if current < 0 {
+		return false
+	}
+	m := matches[current]
+	start, end := e.text.Selection()
+	if min(start, end) != m.Start || max(start, end) != m.End {
+		e.FindNext()
+		return false
+	}
+
+	e.RemoveCarets()
+	s := e.expandReplacements([]MatchRange{m}, repl)[0]
+	n := e.replace(m.Start, m.End, s, true)
+	e.text.SetCaret(m.Start+n, m.Start+n)
+	e.FindNext()
+	return true
+}
+
+// expandReplacements returns the replacements of the matches for regular
+// expression queries, expanding the submatches in the template. The text is
+// searched again rather than the matched substrings, so the anchors at the
+// edges of the matches see the same context as Find.
+func (e *Editor) expandReplacements(matches []MatchRange, template string) []string {
+	repls := make([]string, len(matches))
+	for i := range repls {
+		repls[i] = template
+	}
+	if !e.search.active || !e.search.query.Regexp || len(matches) == 0 {
+		return repls
+	}
+
+	// Line bound queries only depend on the lines of the matches.
+	start, end := 0, e.text.Len()
+	if e.search.lineBound && !e.text.monolithic() {
+		paras := &e.text.paras
+		first, last := paras.paragraphAtRune(matches[0].Start), paras.paragraphAtRune(matches[len(matches)-1].End)
+		start = paras.paragraphs[first].runeOff
+		_, size := paras.size(last)
+		end = paras.paragraphs[last].runeOff + size
+	}
+	text := e.textRange(start, end)
+	base := e.text.ByteOffset(start)
+	// offset returns the byte offset of the match in text.
+	offset := func(m MatchRange) int {
+		return int(e.text.ByteOffset(m.Start) - base)
+	}
+
+	i := 0
+	for _, idx := range e.search.re.FindAllStringSubmatchIndex(text, -1) {
+		for i < len(matches) && offset(matches[i]) < idx[0] {
+			i++
+		}
+		if i == len(matches) {
+			break
+		}
+		if idx[0] != idx[1] && offset(matches[i]) == idx[0] {
+			repls[i] = string(e.search.re.ExpandString(nil, template, text, idx))
+		}
+	}
+	return repls
+}
+
+// textRange returns the text of the rune range [start, end).
+func (e *Editor) textRange(start, end int) string {
+	startOff, endOff := e.text.ByteOffset(start), e.text.ByteOffset(end)
+	buf := make([]byte, endOff-startOff)
+	n, _ := e.text.ReadAt(buf, startOff)
+	return string(buf[:n])
+}
+
+// findAll searches the whole text for the matches.
+func (e *Editor) findAll() {
+	e.scratch = e.text.Text(e.scratch)
+	e.matches = appendMatches(e.matches[:0], e.search.re, e.scratch, 0)
+}
+
+// updateMatches updates the matches after the runes in [start, end) are
+// replaced with n runes. Only the edited lines are searched again if the
+// matches never span lines.
+func (e *Editor) updateMatches(start, end, n int) {
+	if !e.search.active || e.search.suspended {
+		return
+	}
+	if !e.search.lineBound || e.text.monolithic() {
+		e.findAll()
+		e.currentMatch = min(e.currentMatch, max(len(e.matches)-1, 0))
+		return
+	}
+
+	// The edited lines in the new text.
+	paras := &e.text.paras
+	first, last := paras.paragraphAtRune(start), paras.paragraphAtRune(start+n)
+	lineStart := paras.paragraphs[first].runeOff
+	_, size := paras.size(last)
+	lineEnd := paras.paragraphs[last].runeOff + size
+	delta := n - (end - start)
+
+	i := sort.Search(len(e.matches), func(i int) bool {
+		return e.matches[i].End > lineStart
+	})
+	j := sort.Search(len(e.matches), func(i int) bool {
+		return e.matches[i].Start >= lineEnd-delta
+	})
+	tail := slices.Clone(e.matches[j:])
+	for k := range tail {
+		tail[k].Start += delta
+		tail[k].End += delta
+	}
+
+	byteStart, byteEnd := e.text.ByteOffset(lineStart), e.text.ByteOffset(lineEnd)
+	buf := make([]byte, byteEnd-byteStart)
+	read, _ := e.text.ReadAt(buf, byteStart)
+	e.matches = appendMatches(e.matches[:i], e.search.re, buf[:read], lineStart)
+	e.matches = append(e.matches, tail...)
+	e.currentMatch = min(e.currentMatch, max(len(e.matches)-1, 0))
+}
+
+// appendMatches appends the non-empty matches of re in text, which starts at
+// the rune offset runeOff.
+func appendMatches(matches []MatchRange, re *regexp.Regexp, text []byte, runeOff int) []MatchRange {
+	runes, last := runeOff, 0
+	for _, m := range re.FindAllIndex(text, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		runes += utf8.RuneCount(text[last:m[0]])
+		start := runes
+		runes += utf8.RuneCount(text[m[0]:m[1]])
+		last = m[1]
+		matches = append(matches, MatchRange{Start: start, End: runes})
+	}
+	return matches
+}
+
+// lineBound reports whether the matches of re never span lines, and do not
+// depend on the text outside of the lines they are in.
+func lineBound(re *regexp.Regexp) bool {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return false
+	}
+	var bound func(re *syntax.Regexp) bool
+	bound = func(re *syntax.Regexp) bool {
+		switch re.Op {
+		case syntax.OpBeginText, syntax.OpEndText, syntax.OpAnyChar:
+			return false
+		case syntax.OpLiteral:
+			if slices.Contains(re.Rune, '\n') {
+				return false
+			}
+		case syntax.OpCharClass:
+			for i := 0; i+1 < len(re.Rune); i += 2 {
+				if re.Rune[i] <= '\n' && '\n' <= re.Rune[i+1] {
+					return false
+				}
+			}
+		}
+		return !slices.ContainsFunc(re.Sub, func(sub *syntax.Regexp) bool { return !bound(sub) })
+	}
+	return bound(parsed)
+}
@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+)
+
+func matchedTexts(e *Editor) []string {
+	text := []rune(e.Text())
+	matches, _ := e.Matches()
+	var out []string
+	for _, m := range matches {
+		out = append(out, string(text[m.Start:m.End]))
+	}
+	return out
+}
+
+func TestFind(t *testing.T) {
+	e := &Editor{}
+	e.SetText("Foo foo.bar\nfoobar 日本foo\nFOO", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	tests := []struct {
+		query SearchQuery
+		want  []string
+	}{
+		{SearchQuery{Text: "foo"}, []string{"foo", "foo", "foo"}},
+		{SearchQuery{Text: "foo", IgnoreCase: true}, []string{"Foo", "foo", "foo", "foo", "FOO"}},
+		{SearchQuery{Text: "foo", IgnoreCase: true, WholeWord: true}, []string{"Foo", "foo", "foo", "FOO"}},
+		{SearchQuery{Text: "o.b"}, []string{"o.b"}},
+		{SearchQuery{Text: `o.b`, Regexp: true}, []string{"o.b", "oob"}},
+		{SearchQuery{Text: `本\w+`, Regexp: true}, []string{"本foo"}},
+		{SearchQuery{Text: `r\n`, Regexp: true}, []string{"r\n"}},
+	}
+	for _, tt := range tests {
+		if _, err := e.Find(tt.query); err != nil {
+			t.Fatal(err)
+		}
+		if got := matchedTexts(e); !slices.Equal(got, tt.want) {
+			t.Errorf("%+v: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	if _, err := e.Find(SearchQuery{Text: "(", Regexp: true}); err == nil {
+		t.Error("expected an error for an invalid regexp")
+	}
+}
+
+func TestFindNext(t *testing.T) {
+	e := &Editor{}
+	e.SetText("ab ab ab", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	e.SetCaret(4, 4)
+	if n, _ := e.Find(SearchQuery{Text: "ab"}); n != 3 {
+		t.Fatalf("got %d matches", n)
+	}
+	if _, current := e.Matches(); current != 2 {
+		t.Fatalf("the match after the caret is not current: %d", current)
+	}
+
+	for _, want := range []int{6, 0, 3} {
+		e.FindNext()
+		if start, end := e.Selection(); start != want || end != want+2 {
+			t.Fatalf("got selection %d-%d, want %d", start, end, want)
+		}
+	}
+	e.FindPrevious()
+	e.FindPrevious()
+	if start, _ := e.Selection(); start != 6 {
+		t.Fatalf("got selection at %d, want 6", start)
+	}
+}
+
+func TestIncrementalFind(t *testing.T) {
+	e := &Editor{}
+	e.SetText("cat\ndog cat\nbird\ncat", false)
+	layoutEditor(e, image.Pt(400, 400))
+	e.Find(SearchQuery{Text: "cat"})
+
+	e.SetCaret(8, 8)
+	e.Insert("ca")
+	e.SetCaret(0, 0)
+	e.Insert("t\n")
+	want := []MatchRange{{2, 5}, {12, 15}, {21, 24}}
+	if got, _ := e.Matches(); !slices.Equal(got, want) {
+		t.Fatalf("got matches %v, want %v", got, want)
+	}
+
+	// Joining lines can create matches across them with a multiline pattern.
+	e.Find(SearchQuery{Text: `t\s+c`, Regexp: true})
+	e.SetCaret(21, 21)
+	e.Delete(-5)
+	if got := matchedTexts(e); !slices.Equal(got, []string{"t\nc", "t\nc"}) {
+		t.Fatalf("unexpected matches: %q", got)
+	}
+
+	e.undo()
+	if got := matchedTexts(e); !slices.Equal(got, []string{"t\nc"}) {
+		t.Fatalf("unexpected matches after undo: %q", got)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	e := &Editor{}
+	e.SetText("a=1, b=22\nc=333", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	e.Find(SearchQuery{Text: `(\w)=(\d+)`, Regexp: true})
+	if e.ReplaceMatch("$2:$1") {
+		t.Fatal("an unselected match is replaced")
+	}
+	if !e.ReplaceMatch("$2:$1") {
+		t.Fatal("the selected match is not replaced")
+	}
+	if got, want := e.Text(), "1:a, b=22\nc=333"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := e.SelectedText(); got != "b=22" {
+		t.Fatalf("the next match is not selected: %q", got)
+	}
+
+	if n := e.ReplaceAll("${2}_$1"); n != 2 {
+		t.Fatalf("got %d replacements", n)
+	}
+	if got, want := e.Text(), "1:a, 22_b\n333_c"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if matches, _ := e.Matches(); len(matches) != 0 {
+		t.Fatalf("the matches are not updated: %v", matches)
+	}
+
+	// Replacing all the matches is a single undo step.
+	e.undo()
+	if got, want := e.Text(), "1:a, b=22\nc=333"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := matchedTexts(e); !slices.Equal(got, []string{"b=22", "c=333"}) {
+		t.Fatalf("unexpected matches after undo: %q", got)
+	}
+}
+
+func TestReplaceAnchored(t *testing.T) {
+	e := &Editor{}
+	e.SetText("fo1 o2 fo3\nfo4", false)
+	layoutEditor(e, image.Pt(400, 400))
+
+	// The submatches are expanded with the context of the matches.
+	e.Find(SearchQuery{Text: `\Bo(\d)`, Regexp: true})
+	e.FindNext()
+	if !e.ReplaceMatch("<$1>") {
+		t.Fatal("the selected match is not replaced")
+	}
+	if got, want := e.Text(), "f<1> o2 fo3\nfo4"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if n := e.ReplaceAll("[$1]"); n != 2 {
+		t.Fatalf("got %d replacements", n)
+	}
+	if got, want := e.Text(), "f<1> o2 f[3]\nf[4]"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestFindBar(t *testing.T) {
+	e := &Editor{}
+	e.SetText("one two\nTwo three", false)
+	fb := &FindBar{}
+	h := newHarness(e, image.Pt(500, 300), EditorConf{})
+	editorWidget := h.Widget
+	open := true
+	h.Widget = func(gtx layout.Context) layout.Dimensions {
+		if open {
+			fb.Open(gtx, e)
+			open = false
+		}
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions { return fb.Layout(gtx, h.Theme, e) }),
+			layout.Flexed(1, editorWidget),
+		)
+	}
+	h.Frame()
+
+	h.Type("two")
+	h.Frame()
+	if matches, _ := e.Matches(); len(matches) != 1 {
+		t.Fatalf("got %d matches", len(matches))
+	}
+
+	h.Key(key.NameReturn, 0)
+	if e.SelectedText() != "two" {
+		t.Fatalf("the match is not selected: %q", e.SelectedText())
+	}
+
+	fb.Close(e)
+	h.Frame()
+	if matches, _ := e.Matches(); len(matches) != 0 || fb.Opened() {
+		t.Fatal("closing the bar should clear the search")
+	}
+}
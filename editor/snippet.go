@@ -0,0 +1,390 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Snippet is a template of text with tab stops, in the TextMate syntax: $1 and
+// $2 are tab stops, ${1:default} is a placeholder with a default text, and
+// ${1|one,two|} is a placeholder of the first choice. Placeholders of the same
+// number are linked, and edited together. $0 is the final caret position,
+// which defaults to the end of the snippet. \$, \} and \\ escape the
+// characters.
+type Snippet struct {
+	// Prefix is the word which expands to the snippet when Tab is pressed
+	// after it.
+	Prefix string
+	// Body is the template of the snippet.
+	Body string
+	// Description is shown in the completion popup.
+	Description string
+}
+
+// LoadSnippets reads snippet definitions in the JSON format of VS Code: an
+// object of snippets keyed by their names, whose prefix may be a list of
+// prefixes and whose body may be a list of lines.
+//
+//	{
+//		"For Loop": {
+//			"prefix": ["for", "fori"],
+//			"body": ["for ${1:i} := 0; $1 < ${2:n}; $1++ {", "\t$0", "}"],
+//			"description": "A for loop"
+//		}
+//	}
+func LoadSnippets(r io.Reader) ([]Snippet, error) {
+	var defs map[string]struct {
+		Prefix      stringList `json:"prefix"`
+		Body        stringList `json:"body"`
+		Description string     `json:"description"`
+	}
+	if err := json.NewDecoder(r).Decode(&defs); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var snippets []Snippet
+	for _, name := range names {
+		def := defs[name]
+		body := strings.Join(def.Body, "\n")
+		if _, _, err := parseSnippet(body, "", "\t"); err != nil {
+			return nil, fmt.Errorf("snippet %q: %w", name, err)
+		}
+		desc := def.Description
+		if desc == "" {
+			desc = name
+		}
+		for _, prefix := range def.Prefix {
+			snippets = append(snippets, Snippet{Prefix: prefix, Body: body, Description: desc})
+		}
+	}
+	return snippets, nil
+}
+
+// stringList is a JSON string or a list of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = stringList{s}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+var errUnterminatedPlaceholder = errors.New("unterminated placeholder")
+
+// snippetStop is a tab stop of an expanded snippet. start and end are rune
+// offsets.
+type snippetStop struct {
+	index      int
+	start, end int
+}
+
+// parseSnippet expands the body of a snippet. Line breaks are followed by the
+// indent, and tabs are replaced by tab. The stops are in the order of their
+// starts.
+func parseSnippet(body, indent, tab string) (string, []snippetStop, error) {
+	p := &snippetParser{body: []rune(body), indent: []rune(indent), tab: []rune(tab)}
+	if err := p.parse(false); err != nil {
+		return "", nil, err
+	}
+	// Tab stops mirror the default text of the placeholders of their number,
+	// which may come after them.
+	defaults := make(map[int][]rune)
+	mirrors := false
+	for _, stop := range p.stops {
+		if stop.start == stop.end {
+			mirrors = true
+		} else if _, ok := defaults[stop.index]; !ok {
+			defaults[stop.index] = slices.Clone(p.out[stop.start:stop.end])
+		}
+	}
+	if mirrors && len(defaults) > 0 {
+		p = &snippetParser{body: p.body, indent: p.indent, tab: p.tab, defaults: defaults}
+		p.parse(false)
+	}
+	slices.SortStableFunc(p.stops, func(a, b snippetStop) int { return a.start - b.start })
+	return string(p.out), p.stops, nil
+}
+
+type snippetParser struct {
+	body        []rune
+	pos         int
+	indent, tab []rune
+	out         []rune
+	stops       []snippetStop
+	// defaults are the texts of the tab stops without placeholders.
+	defaults map[int][]rune
+}
+
+// parse parses the body up to its end, or up to the closing brace of a
+// placeholder if nested.
+func (p *snippetParser) parse(nested bool) error {
+	for p.pos < len(p.body) {
+		r := p.body[p.pos]
+		switch {
+		case r == '\\' && p.pos+1 < len(p.body) && strings.ContainsRune(`$}\`, p.body[p.pos+1]):
+			p.out = append(p.out, p.body[p.pos+1])
+			p.pos += 2
+		case r == '}' && nested:
+			p.pos++
+			return nil
+		case r == '$':
+			ok, err := p.parseStop()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				p.out = append(p.out, r)
+				p.pos++
+			}
+		case r == '\n':
+			p.out = append(p.out, r)
+			p.out = append(p.out, p.indent...)
+			p.pos++
+		case r == '\t':
+			p.out = append(p.out, p.tab...)
+			p.pos++
+		default:
+			p.out = append(p.out, r)
+			p.pos++
+		}
+	}
+	if nested {
+		return errUnterminatedPlaceholder
+	}
+	return nil
+}
+
+// parseStop parses a tab stop or a placeholder at a dollar sign, and reports
+// false if there is none.
+func (p *snippetParser) parseStop() (bool, error) {
+	pos := p.pos + 1
+	braced := pos < len(p.body) && p.body[pos] == '{'
+	if braced {
+		pos++
+	}
+	digits := pos
+	for pos < len(p.body) && unicode.IsDigit(p.body[pos]) {
+		pos++
+	}
+	if pos == digits {
+		return false, nil
+	}
+	index, _ := strconv.Atoi(string(p.body[digits:pos]))
+	start := len(p.out)
+	if !braced {
+		p.pos = pos
+		p.out = append(p.out, p.defaults[index]...)
+		p.stops = append(p.stops, snippetStop{index: index, start: start, end: len(p.out)})
+		return true, nil
+	}
+	if pos >= len(p.body) {
+		return false, errUnterminatedPlaceholder
+	}
+	switch p.body[pos] {
+	case '}':
+		p.pos = pos + 1
+		p.out = append(p.out, p.defaults[index]...)
+	case ':':
+		p.pos = pos + 1
+		if err := p.parse(true); err != nil {
+			return false, err
+		}
+	case '|':
+		end := strings.Index(string(p.body[pos+1:]), "|}")
+		if end < 0 {
+			return false, errUnterminatedPlaceholder
+		}
+		choices := []rune(string(p.body[pos+1:])[:end])
+		p.pos = pos + 1 + len(choices) + 2
+		first, _, _ := strings.Cut(string(choices), ",")
+		p.out = append(p.out, []rune(first)...)
+	default:
+		return false, nil
+	}
+	p.stops = append(p.stops, snippetStop{index: index, start: start, end: len(p.out)})
+	return true, nil
+}
+
+// snippetState is the state of the snippets of an editor.
+type snippetState struct {
+	snippets []Snippet
+	// stops are the tab stops of the expanded snippet being edited, if any.
+	stops []snippetStop
+	// order are the indexes of the stops in the order they are visited, and
+	// current is the position of the current one.
+	order   []int
+	current int
+}
+
+func (s *snippetState) active() bool {
+	return len(s.order) > 0
+}
+
+// SetSnippets sets the snippets which expand when Tab is pressed after their
+// prefixes.
+func (e *Editor) SetSnippets(snippets []Snippet) {
+	e.snippets.snippets = snippets
+}
+
+// InsertSnippet replaces the selection with the snippet body, and selects its
+// first tab stop. Tab and Shift-Tab move between the tab stops.
+func (e *Editor) InsertSnippet(body string) error {
+	e.initBuffer()
+	e.RemoveCarets()
+	start, end := e.Selection()
+	start = min(start, end)
+	text, stops, err := parseSnippet(body, e.lineIndent(start), e.TabCharacter)
+	if err != nil {
+		return err
+	}
+	e.endSnippet()
+	e.Insert(text)
+	s := &e.snippets
+	hasFinal := false
+	for _, stop := range stops {
+		stop.start += start
+		stop.end += start
+		s.stops = append(s.stops, stop)
+		if !slices.Contains(s.order, stop.index) {
+			s.order = append(s.order, stop.index)
+		}
+		hasFinal = hasFinal || stop.index == 0
+	}
+	if !hasFinal {
+		end := start + len([]rune(text))
+		s.stops = append(s.stops, snippetStop{index: 0, start: end, end: end})
+		s.order = append(s.order, 0)
+	}
+	// $0 is the last stop.
+	slices.SortFunc(s.order, func(a, b int) int {
+		if a == 0 || b == 0 {
+			return b - a
+		}
+		return a - b
+	})
+	s.current = 0
+	e.selectSnippetStop()
+	return nil
+}
+
+// lineIndent returns the indentation of the line of the rune offset.
+func (e *Editor) lineIndent(offset int) string {
+	text := e.textRange(offset-e.text.closestToRune(offset).lineCol.col, offset)
+	return text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+}
+
+// expandSnippet expands the snippet whose prefix is before the caret, and
+// reports whether there is one.
+func (e *Editor) expandSnippet() bool {
+	caret, end := e.Selection()
+	if caret != end || len(e.carets) > 0 || len(e.snippets.snippets) == 0 {
+		return false
+	}
+	_, col := e.text.CaretPos()
+	word := wordPrefix(e.textRange(caret-col, caret))
+	if word == "" {
+		return false
+	}
+	for _, snippet := range e.snippets.snippets {
+		if snippet.Prefix == word {
+			e.SetCaret(caret-len([]rune(word)), caret)
+			return e.InsertSnippet(snippet.Body) == nil
+		}
+	}
+	return false
+}
+
+// moveSnippetStop moves to the next tab stop, or to the previous one if dir is
+// negative, and reports whether a snippet is being edited. Reaching the last
+// stop ends the editing.
+func (e *Editor) moveSnippetStop(dir int) bool {
+	s := &e.snippets
+	if !s.active() {
+		return false
+	}
+	// The editing ends if the caret leaves the current stop.
+	caret, _ := e.Selection()
+	inStop := false
+	for _, stop := range s.stops {
+		if stop.index == s.order[s.current] && caret >= stop.start && caret <= stop.end {
+			inStop = true
+		}
+	}
+	if !inStop {
+		e.endSnippet()
+		return false
+	}
+	s.current = max(s.current+dir, 0)
+	e.selectSnippetStop()
+	return true
+}
+
+// selectSnippetStop selects the placeholders of the current tab stop, and ends
+// the editing at the last stop.
+func (e *Editor) selectSnippetStop() {
+	s := &e.snippets
+	index := s.order[s.current]
+	var sels []Selection
+	for _, stop := range s.stops {
+		if stop.index == index {
+			sels = append(sels, Selection{Start: stop.end, End: stop.start})
+		}
+	}
+	e.RemoveCarets()
+	e.SetSelections(sels)
+	if s.current == len(s.order)-1 {
+		e.endSnippet()
+	}
+}
+
+// endSnippet ends the editing of the snippet.
+func (e *Editor) endSnippet() {
+	e.snippets.stops = e.snippets.stops[:0]
+	e.snippets.order = e.snippets.order[:0]
+}
+
+// updateSnippetStops moves the tab stops after a replacement of the runes from
+// start to end by n runes. The placeholders of the current stop grow with the
+// text typed at their ends, while the others don't.
+func (e *Editor) updateSnippetStops(start, end, n int) {
+	s := &e.snippets
+	if !s.active() {
+		return
+	}
+	newEnd := start + n
+	delta := newEnd - end
+	current := s.order[s.current]
+	for i := range s.stops {
+		stop := &s.stops[i]
+		grow := stop.index == current
+		switch {
+		case stop.start >= end && !(grow && stop.start == start):
+			stop.start += delta
+		case stop.start > start:
+			stop.start = start
+		}
+		switch {
+		case stop.end > end, stop.end == end && (grow || start < end):
+			stop.end += delta
+		case stop.end > start:
+			stop.end = start
+		}
+		stop.end = max(stop.end, stop.start)
+	}
+}
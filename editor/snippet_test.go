@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"slices"
+	"strings"
+	"testing"
+
+	"gioui.org/io/key"
+)
+
+func TestParseSnippet(t *testing.T) {
+	tests := []struct {
+		body  string
+		text  string
+		stops []snippetStop
+	}{
+		{"for $1 {\n\t$0\n}", "for  {\n  \t\n  }", []snippetStop{{1, 4, 4}, {0, 10, 10}}},
+		{"${1:name} := ${2:value}", "name := value", []snippetStop{{1, 0, 4}, {2, 8, 13}}},
+		{"${1:a ${2:b}}", "a b", []snippetStop{{1, 0, 3}, {2, 2, 3}}},
+		{"${1|one,two|}", "one", []snippetStop{{1, 0, 3}}},
+		{`\$1 costs $ 5 \}`, "$1 costs $ 5 }", nil},
+		{"${2}x$TM", "x$TM", []snippetStop{{2, 0, 0}}},
+	}
+	for _, tc := range tests {
+		text, stops, err := parseSnippet(tc.body, "  ", "\t")
+		if err != nil {
+			t.Errorf("%q: %v", tc.body, err)
+			continue
+		}
+		if text != tc.text || !slices.Equal(stops, tc.stops) {
+			t.Errorf("%q: got %q, %v, want %q, %v", tc.body, text, stops, tc.text, tc.stops)
+		}
+	}
+	for _, body := range []string{"${1:name", "${1|a,b}"} {
+		if _, _, err := parseSnippet(body, "", "\t"); err == nil {
+			t.Errorf("%q: expected an error", body)
+		}
+	}
+}
+
+func TestLoadSnippets(t *testing.T) {
+	snippets, err := LoadSnippets(strings.NewReader(`{
+		"For Loop": {"prefix": ["for", "fori"], "body": ["for $1 {", "\t$0", "}"], "description": "loop"},
+		"Print": {"prefix": "pr", "body": "println($1)"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Snippet{
+		{Prefix: "for", Body: "for $1 {\n\t$0\n}", Description: "loop"},
+		{Prefix: "fori", Body: "for $1 {\n\t$0\n}", Description: "loop"},
+		{Prefix: "pr", Body: "println($1)", Description: "Print"},
+	}
+	if !slices.Equal(snippets, want) {
+		t.Fatalf("got %v", snippets)
+	}
+	if _, err := LoadSnippets(strings.NewReader(`{"Bad": {"prefix": "x", "body": "${1:x"}}`)); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSnippetSession(t *testing.T) {
+	e := &Editor{TabCharacter: "\t"}
+	e.SetText("\tx", false)
+	layoutEditor(e, image.Pt(400, 400))
+	e.SetCaret(2, 2)
+	if err := e.InsertSnippet("${1:i} := ${2:0}; $1 < n\n$0"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Text(); got != "\txi := 0; i < n\n\t" {
+		t.Fatalf("got %q", got)
+	}
+	// The linked placeholders are selected, and edited together.
+	if got, want := e.Selections(), []Selection{{3, 2}, {11, 10}}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	e.Insert("idx")
+	if got := e.Text(); got != "\txidx := 0; idx < n\n\t" {
+		t.Fatalf("got %q", got)
+	}
+
+	if !e.moveSnippetStop(1) {
+		t.Fatal("not in the snippet")
+	}
+	if got := e.SelectedText(); got != "0" {
+		t.Fatalf("got %q", got)
+	}
+	if !e.moveSnippetStop(-1) || e.SelectedText() != "idx" {
+		t.Fatalf("got %q", e.SelectedText())
+	}
+	e.moveSnippetStop(1)
+	e.moveSnippetStop(1)
+	// The last stop ends the snippet.
+	if start, end := e.Selection(); start != 21 || end != 21 || e.snippets.active() {
+		t.Fatalf("got %d, %d", start, end)
+	}
+	if e.moveSnippetStop(1) {
+		t.Fatal("the snippet is not ended")
+	}
+}
+
+func TestSnippetExpand(t *testing.T) {
+	e := &Editor{TabCharacter: "\t"}
+	e.SetSnippets([]Snippet{{Prefix: "fn", Body: "func ${1:name}() {\n\t$0\n}"}})
+	e.SetText("x fn", false)
+	layoutEditor(e, image.Pt(400, 400))
+	e.SetCaret(4, 4)
+	if !e.expandSnippet() {
+		t.Fatal("the snippet is not expanded")
+	}
+	if got := e.Text(); got != "x func name() {\n\t\n}" || e.SelectedText() != "name" {
+		t.Fatalf("got %q, selected %q", got, e.SelectedText())
+	}
+
+	// Leaving the placeholder ends the snippet.
+	e.SetCaret(0, 0)
+	if e.moveSnippetStop(1) || e.snippets.active() {
+		t.Fatal("the snippet is not ended")
+	}
+	e.SetCaret(1, 1)
+	if e.expandSnippet() {
+		t.Fatal("expanded without a prefix")
+	}
+}
+
+func TestSnippetTabStops(t *testing.T) {
+	e := &Editor{}
+	e.SetSnippets([]Snippet{{Prefix: "for", Body: "for ${1:i} := 0; $1 < ${2:n}; $1++ {\n\t$0\n}"}})
+	h := newHarness(e, image.Pt(400, 200), EditorConf{})
+	h.Click(image.Pt(10, 10))
+
+	// Tab after the prefix expands the snippet, and typing edits the linked
+	// placeholders.
+	h.Type("for")
+	h.Key(key.NameTab, 0)
+	h.Type("j")
+	if e.Text() != "for j := 0; j < n; j++ {\n\t\n}" {
+		t.Fatalf("unexpected text: %q", e.Text())
+	}
+	h.Key(key.NameTab, 0)
+	if e.SelectedText() != "n" {
+		t.Fatalf("unexpected selection: %q", e.SelectedText())
+	}
+	h.Key(key.NameTab, key.ModShift)
+	if e.SelectedText() != "j" {
+		t.Fatalf("unexpected selection: %q", e.SelectedText())
+	}
+
+	// The last Tab moves to the end, and the next one inserts a tab.
+	h.Key(key.NameTab, 0)
+	h.Key(key.NameTab, 0)
+	h.Key(key.NameTab, 0)
+	if e.Text() != "for j := 0; j < n; j++ {\n\t\t\n}" {
+		t.Fatalf("unexpected text: %q", e.Text())
+	}
+}
@@ -0,0 +1,104 @@
+package syntax
+
+import "strings"
+
+const (
+	goBlockComment State = iota + 1
+	goRawString
+)
+
+var (
+	goKeywords = set(`break case chan const continue default defer else fallthrough
+		for func go goto if import interface map package range return select
+		struct switch type var`)
+	goTypes = set(`any bool byte comparable complex64 complex128 error float32
+		float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32
+		uint64 uintptr`)
+	goBuiltins = set(`append cap clear close complex copy delete imag len make max
+		min new panic print println real recover`)
+	goConstants = set(`true false iota nil`)
+)
+
+func tokenizeGo(line string, state State) ([]Token, State) {
+	s := &scanner{line: line}
+
+	switch state {
+	case goBlockComment:
+		if !s.blockComment() {
+			s.emit(Comment, 0)
+			return s.tokens, goBlockComment
+		}
+		s.emit(Comment, 0)
+	case goRawString:
+		if !s.quoted('`', false) {
+			s.emit(String, 0)
+			return s.tokens, goRawString
+		}
+		s.emit(String, 0)
+	}
+
+	for !s.done() {
+		start := s.pos
+		c := s.line[s.pos]
+		switch {
+		case s.hasPrefix("//"):
+			s.pos = len(s.line)
+			s.emit(Comment, start)
+		case s.hasPrefix("/*"):
+			s.pos += 2
+			closed := s.blockComment()
+			s.emit(Comment, start)
+			if !closed {
+				return s.tokens, goBlockComment
+			}
+		case c == '`':
+			s.pos++
+			closed := s.quoted('`', false)
+			s.emit(String, start)
+			if !closed {
+				return s.tokens, goRawString
+			}
+		case c == '"' || c == '\'':
+			s.pos++
+			s.quoted(c, true)
+			s.emit(String, start)
+		case isDigit(c) || c == '.' && isDigit(s.peek(1)):
+			s.number()
+			s.emit(Number, start)
+		case isIdentStart(s.line, s.pos):
+			word := s.ident()
+			switch {
+			case goKeywords[word]:
+				s.emit(Keyword, start)
+			case goConstants[word]:
+				s.emit(Constant, start)
+			case goTypes[word]:
+				s.emit(Type, start)
+			case s.peek(0) == '(' && goBuiltins[word]:
+				s.emit(Builtin, start)
+			case s.peek(0) == '(':
+				s.emit(Function, start)
+			}
+		case strings.IndexByte("+-*/%&|^<>=!:~", c) >= 0:
+			s.pos++
+			s.emit(Operator, start)
+		case strings.IndexByte("()[]{},;.", c) >= 0:
+			s.pos++
+			s.emit(Punctuation, start)
+		default:
+			s.pos++
+		}
+	}
+	return s.tokens, 0
+}
+
+// blockComment moves the cursor past the end of a C style block comment, or
+// to the end of line if the comment is not closed.
+func (s *scanner) blockComment() bool {
+	if i := strings.Index(s.line[s.pos:], "*/"); i >= 0 {
+		s.pos += i + 2
+		return true
+	}
+	s.pos = len(s.line)
+	return false
+}
@@ -0,0 +1,87 @@
+package syntax
+
+// Highlighter caches the tokens of the lines of a text. The cache is filled
+// lazily, up to the last line asked for, and an edit only outdates the edited
+// lines: the lines after them are tokenized again only if the state they
+// start with has changed, e.g., when a block comment is opened.
+type Highlighter struct {
+	tokenizer Tokenizer
+	lines     []lineTokens
+	// valid is the number of lines known to be up to date.
+	valid int
+}
+
+type lineTokens struct {
+	// start and end are the states at the start and at the end of the line.
+	start, end State
+	tokens     []Token
+	dirty      bool
+}
+
+// NewHighlighter returns a highlighter using the tokenizer.
+func NewHighlighter(tokenizer Tokenizer) *Highlighter {
+	return &Highlighter{tokenizer: tokenizer}
+}
+
+// Tokenizer returns the tokenizer of the highlighter.
+func (h *Highlighter) Tokenizer() Tokenizer {
+	return h.tokenizer
+}
+
+// Reset drops all the cached tokens, e.g., after the whole text is replaced.
+func (h *Highlighter) Reset() {
+	h.lines = h.lines[:0]
+	h.valid = 0
+}
+
+// Edit updates the cache after the lines [line, line+removed] are replaced
+// with inserted+1 lines. removed and inserted are the number of line breaks
+// removed and inserted by the edit.
+func (h *Highlighter) Edit(line, removed, inserted int) {
+	h.valid = min(h.valid, line)
+	if line >= len(h.lines) {
+		return
+	}
+	if line+removed+1 >= len(h.lines) {
+		// The cache ends within the edited lines.
+		h.lines = h.lines[:line]
+		return
+	}
+
+	if removed == inserted {
+		for i := line; i <= line+removed; i++ {
+			h.lines[i].dirty = true
+		}
+		return
+	}
+	edited := make([]lineTokens, inserted+1)
+	for i := range edited {
+		edited[i].dirty = true
+	}
+	rest := h.lines[line+removed+1:]
+	h.lines = append(h.lines[:line], append(edited, rest...)...)
+}
+
+// Tokens returns the tokens of the line. text returns the content of a line,
+// without the line break, and it is called for the line and the lines before
+// it which are not tokenized yet.
+func (h *Highlighter) Tokens(line int, text func(line int) string) []Token {
+	for len(h.lines) <= line {
+		h.lines = append(h.lines, lineTokens{dirty: true})
+	}
+
+	for i := h.valid; i <= line; i++ {
+		var state State
+		if i > 0 {
+			state = h.lines[i-1].end
+		}
+		l := &h.lines[i]
+		if l.dirty || l.start != state {
+			l.tokens, l.end = h.tokenizer.Tokenize(text(i), state)
+			l.start = state
+			l.dirty = false
+		}
+	}
+	h.valid = max(h.valid, line+1)
+	return h.lines[line].tokens
+}
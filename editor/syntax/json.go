@@ -0,0 +1,41 @@
+package syntax
+
+import "strings"
+
+func tokenizeJSON(line string, state State) ([]Token, State) {
+	s := &scanner{line: line}
+
+	for !s.done() {
+		start := s.pos
+		c := s.line[s.pos]
+		switch {
+		case c == '"':
+			s.pos++
+			s.quoted('"', true)
+			end := s.pos
+			// A string followed by a colon is an object key.
+			s.skipSpaces()
+			kind := String
+			if s.peek(0) == ':' {
+				kind = Key
+			}
+			s.pos = end
+			s.emit(kind, start)
+		case isDigit(c) || c == '-':
+			s.pos++
+			s.number()
+			s.emit(Number, start)
+		case isLetter(c):
+			switch s.ident() {
+			case "true", "false", "null":
+				s.emit(Constant, start)
+			}
+		case strings.IndexByte("{}[],:", c) >= 0:
+			s.pos++
+			s.emit(Punctuation, start)
+		default:
+			s.pos++
+		}
+	}
+	return s.tokens, 0
+}
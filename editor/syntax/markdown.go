@@ -0,0 +1,209 @@
+package syntax
+
+import "strings"
+
+const (
+	// markdownBacktickFence and markdownTildeFence are the states inside a
+	// fenced code block.
+	markdownBacktickFence State = iota + 1
+	markdownTildeFence
+)
+
+func tokenizeMarkdown(line string, state State) ([]Token, State) {
+	s := &scanner{line: line}
+
+	// Block structures may be indented up to 3 spaces.
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	trimmed := line[indent:]
+	if indent > 3 {
+		trimmed = ""
+	}
+
+	if state != 0 {
+		fence := "```"
+		if state == markdownTildeFence {
+			fence = "~~~"
+		}
+		s.pos = len(line)
+		s.emit(Code, 0)
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]+" \t") == "" {
+			return s.tokens, 0
+		}
+		return s.tokens, state
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "```"):
+		s.pos = len(line)
+		s.emit(Code, 0)
+		return s.tokens, markdownBacktickFence
+	case strings.HasPrefix(trimmed, "~~~"):
+		s.pos = len(line)
+		s.emit(Code, 0)
+		return s.tokens, markdownTildeFence
+	case isHeading(trimmed):
+		s.pos = len(line)
+		s.emit(Heading, 0)
+		return s.tokens, 0
+	case isThematicBreak(trimmed):
+		s.pos = len(line)
+		s.emit(Punctuation, 0)
+		return s.tokens, 0
+	}
+
+	base := Text
+	s.pos = indent
+	if trimmed != "" && trimmed[0] == '>' {
+		for s.peek(0) == '>' || s.peek(0) == ' ' {
+			s.pos++
+		}
+		s.emit(Quote, indent)
+		base = Quote
+	} else if n := listMarker(trimmed); n > 0 {
+		s.pos += n
+		s.emit(ListMarker, indent)
+	}
+
+	s.inline(base)
+	return s.tokens, 0
+}
+
+// inline tokenizes the inline elements of markdown from the cursor to the end
+// of line. Plain text is emitted with the base kind.
+func (s *scanner) inline(base Kind) {
+	start := s.pos
+	for !s.done() {
+		pos := s.pos
+		c := s.line[pos]
+		var kind Kind
+		switch {
+		case c == '\\':
+			s.pos = min(s.pos+2, len(s.line))
+			continue
+		case c == '`':
+			n := len(s.line[pos:]) - len(strings.TrimLeft(s.line[pos:], "`"))
+			delim := s.line[pos : pos+n]
+			kind = Code
+			if !s.span(delim) {
+				s.pos += len(delim)
+				continue
+			}
+		case c == '*' && s.peek(1) == '*' || c == '_' && s.peek(1) == '_' && wordStart(s.line, pos):
+			kind = Strong
+			if !s.span(s.line[pos : pos+2]) {
+				s.pos += 2
+				continue
+			}
+		case c == '*' || c == '_' && wordStart(s.line, pos):
+			kind = Emphasis
+			if s.peek(1) == ' ' || !s.span(s.line[pos:pos+1]) {
+				s.pos++
+				continue
+			}
+		case c == '[' || c == '!' && s.peek(1) == '[':
+			kind = Link
+			if !s.link() {
+				s.pos++
+				continue
+			}
+		case c == '<' && (s.hasPrefix("<http://") || s.hasPrefix("<https://")):
+			kind = Link
+			if !s.span(">") {
+				s.pos++
+				continue
+			}
+		default:
+			s.pos++
+			continue
+		}
+
+		end := s.pos
+		s.pos = pos
+		s.emit(base, start)
+		s.pos = end
+		s.emit(kind, pos)
+		start = end
+	}
+	s.emit(base, start)
+}
+
+// span moves the cursor past a span enclosed in delim, if it is closed in the
+// line.
+func (s *scanner) span(delim string) bool {
+	i := strings.Index(s.line[s.pos+len(delim):], delim)
+	if i < 0 {
+		return false
+	}
+	s.pos += 2*len(delim) + i
+	return true
+}
+
+// link moves the cursor past a link or an image, i.e., [text](url), or a
+// reference link [text][ref].
+func (s *scanner) link() bool {
+	open := s.pos + strings.IndexByte(s.line[s.pos:], '[')
+	closing := strings.IndexByte(s.line[open:], ']')
+	if closing < 0 {
+		return false
+	}
+	end := open + closing + 1
+	if end < len(s.line) && (s.line[end] == '(' || s.line[end] == '[') {
+		closer := byte(')')
+		if s.line[end] == '[' {
+			closer = ']'
+		}
+		if i := strings.IndexByte(s.line[end:], closer); i >= 0 {
+			end += i + 1
+		}
+	}
+	s.pos = end
+	return true
+}
+
+func wordStart(line string, pos int) bool {
+	return pos == 0 || !isLetter(line[pos-1]) && !isDigit(line[pos-1])
+}
+
+func isHeading(line string) bool {
+	n := len(line) - len(strings.TrimLeft(line, "#"))
+	return n >= 1 && n <= 6 && (n == len(line) || line[n] == ' ' || line[n] == '\t')
+}
+
+func isThematicBreak(line string) bool {
+	stripped := strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+	if len(stripped) < 3 || strings.IndexByte("-*_", stripped[0]) < 0 {
+		return false
+	}
+	return strings.Count(stripped, stripped[:1]) == len(stripped)
+}
+
+// listMarker returns the length of the list item marker at the start of the
+// line, including the following space, or zero if there is none.
+func listMarker(line string) int {
+	n := 0
+	switch {
+	case line == "":
+		return 0
+	case strings.IndexByte("-*+", line[0]) >= 0:
+		n = 1
+	default:
+		for n < len(line) && isDigit(line[n]) {
+			n++
+		}
+		if n == 0 || n > 9 || n >= len(line) || (line[n] != '.' && line[n] != ')') {
+			return 0
+		}
+		n++
+	}
+	if n < len(line) && line[n] != ' ' && line[n] != '\t' {
+		return 0
+	}
+	n = min(n+1, len(line))
+	// A task list item.
+	for _, box := range []string{"[ ] ", "[x] ", "[X] "} {
+		if strings.HasPrefix(line[n:], box) {
+			n += len(box)
+		}
+	}
+	return n
+}
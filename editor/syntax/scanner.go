@@ -0,0 +1,131 @@
+package syntax
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// scanner is a cursor over a line used by the built-in tokenizers.
+type scanner struct {
+	line   string
+	pos    int
+	tokens []Token
+}
+
+func (s *scanner) done() bool {
+	return s.pos >= len(s.line)
+}
+
+// peek returns the byte at the cursor plus i, or zero past the end of line.
+func (s *scanner) peek(i int) byte {
+	if s.pos+i < len(s.line) {
+		return s.line[s.pos+i]
+	}
+	return 0
+}
+
+func (s *scanner) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(s.line[s.pos:], prefix)
+}
+
+// emit adds a token from start to the cursor. Empty tokens are dropped, and
+// a token is merged with the previous one of the same kind if they are
+// adjacent.
+func (s *scanner) emit(kind Kind, start int) {
+	if start >= s.pos {
+		return
+	}
+	if n := len(s.tokens); n > 0 && s.tokens[n-1].Kind == kind && s.tokens[n-1].End == start {
+		s.tokens[n-1].End = s.pos
+		return
+	}
+	s.tokens = append(s.tokens, Token{Kind: kind, Start: start, End: s.pos})
+}
+
+// skipSpaces moves the cursor past spaces and tabs.
+func (s *scanner) skipSpaces() {
+	for !s.done() && (s.line[s.pos] == ' ' || s.line[s.pos] == '\t') {
+		s.pos++
+	}
+}
+
+// ident moves the cursor past an identifier and returns it.
+func (s *scanner) ident() string {
+	start := s.pos
+	for !s.done() {
+		r, n := utf8.DecodeRuneInString(s.line[s.pos:])
+		if r != '_' && !unicode.IsLetter(r) && (s.pos == start || !unicode.IsDigit(r)) {
+			break
+		}
+		s.pos += n
+	}
+	return s.line[start:s.pos]
+}
+
+// quoted moves the cursor to the closing quote, or to the end of line if the
+// quote is not closed. Escapes are skipped if escapes is true. It reports
+// whether the closing quote is found.
+func (s *scanner) quoted(quote byte, escapes bool) bool {
+	for !s.done() {
+		c := s.line[s.pos]
+		s.pos++
+		switch {
+		case c == '\\' && escapes:
+			if !s.done() {
+				s.pos++
+			}
+		case c == quote:
+			return true
+		}
+	}
+	return false
+}
+
+// number moves the cursor past a number literal with digits, letters and
+// underscores, e.g., 0x1F, 1_000 or 1.5e-3.
+func (s *scanner) number() {
+	start := s.pos
+	for !s.done() {
+		c := s.line[s.pos]
+		switch {
+		case isDigit(c) || isLetter(c) || c == '_' || c == '.':
+			s.pos++
+		case (c == '+' || c == '-') && s.pos > start:
+			// The sign of an exponent.
+			if exp := s.line[s.pos-1] | 0x20; exp == 'p' || exp == 'e' && !isHex(s.line[start:]) {
+				s.pos++
+				continue
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+func isHex(lit string) bool {
+	return len(lit) > 1 && lit[0] == '0' && lit[1]|0x20 == 'x'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c|0x20 && c|0x20 <= 'z'
+}
+
+func isIdentStart(line string, pos int) bool {
+	r, _ := utf8.DecodeRuneInString(line[pos:])
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// set makes a lookup set of words.
+func set(words string) map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		m[w] = true
+	}
+	return m
+}
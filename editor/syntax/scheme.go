@@ -0,0 +1,167 @@
+package syntax
+
+import (
+	"image/color"
+	"strings"
+	"sync"
+)
+
+// Style is the colors of a kind of token. A zero color is not painted, and the
+// text color of the editor is used for a zero Color.
+type Style struct {
+	Color      color.NRGBA
+	Background color.NRGBA
+}
+
+// Scheme maps kinds of tokens to styles.
+type Scheme struct {
+	Name   string
+	Styles map[Kind]Style
+}
+
+// Style returns the style of the kind.
+func (s *Scheme) Style(kind Kind) Style {
+	if s == nil {
+		return Style{}
+	}
+	return s.Styles[kind]
+}
+
+func rgb(c uint32) color.NRGBA {
+	return color.NRGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 0xff}
+}
+
+func argb(c uint32) color.NRGBA {
+	return color.NRGBA{A: uint8(c >> 24), R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c)}
+}
+
+var (
+	// Light is a scheme for light backgrounds. It is also named "default".
+	Light = &Scheme{
+		Name: "light",
+		Styles: map[Kind]Style{
+			Keyword:     {Color: rgb(0xaf00db)},
+			Type:        {Color: rgb(0x267f99)},
+			Builtin:     {Color: rgb(0x795e26)},
+			Function:    {Color: rgb(0x795e26)},
+			String:      {Color: rgb(0xa31515)},
+			Number:      {Color: rgb(0x098658)},
+			Comment:     {Color: rgb(0x008000)},
+			Operator:    {Color: rgb(0x383a42)},
+			Constant:    {Color: rgb(0x0000ff)},
+			Variable:    {Color: rgb(0x001080)},
+			Key:         {Color: rgb(0x0451a5)},
+			Heading:     {Color: rgb(0x800000)},
+			Emphasis:    {Color: rgb(0x6f42c1)},
+			Strong:      {Color: rgb(0x000080)},
+			Link:        {Color: rgb(0x0366d6)},
+			Code:        {Color: rgb(0xa31515), Background: argb(0x18000000)},
+			Quote:       {Color: rgb(0x6a737d)},
+			ListMarker:  {Color: rgb(0x0451a5)},
+			Punctuation: {Color: rgb(0x6a737d)},
+		},
+	}
+
+	// Dark is a scheme for dark backgrounds.
+	Dark = &Scheme{
+		Name: "dark",
+		Styles: map[Kind]Style{
+			Keyword:     {Color: rgb(0xc586c0)},
+			Type:        {Color: rgb(0x4ec9b0)},
+			Builtin:     {Color: rgb(0xdcdcaa)},
+			Function:    {Color: rgb(0xdcdcaa)},
+			String:      {Color: rgb(0xce9178)},
+			Number:      {Color: rgb(0xb5cea8)},
+			Comment:     {Color: rgb(0x6a9955)},
+			Operator:    {Color: rgb(0xd4d4d4)},
+			Constant:    {Color: rgb(0x569cd6)},
+			Variable:    {Color: rgb(0x9cdcfe)},
+			Key:         {Color: rgb(0x9cdcfe)},
+			Heading:     {Color: rgb(0x569cd6)},
+			Emphasis:    {Color: rgb(0xc586c0)},
+			Strong:      {Color: rgb(0x569cd6)},
+			Link:        {Color: rgb(0x3794ff)},
+			Code:        {Color: rgb(0xce9178), Background: argb(0x20ffffff)},
+			Quote:       {Color: rgb(0x8b949e)},
+			ListMarker:  {Color: rgb(0x6796e6)},
+			Punctuation: {Color: rgb(0x808080)},
+		},
+	}
+
+	// Monokai is the popular dark scheme.
+	Monokai = &Scheme{
+		Name: "monokai",
+		Styles: map[Kind]Style{
+			Keyword:     {Color: rgb(0xf92672)},
+			Type:        {Color: rgb(0x66d9ef)},
+			Builtin:     {Color: rgb(0x66d9ef)},
+			Function:    {Color: rgb(0xa6e22e)},
+			String:      {Color: rgb(0xe6db74)},
+			Number:      {Color: rgb(0xae81ff)},
+			Comment:     {Color: rgb(0x75715e)},
+			Operator:    {Color: rgb(0xf92672)},
+			Constant:    {Color: rgb(0xae81ff)},
+			Variable:    {Color: rgb(0xfd971f)},
+			Key:         {Color: rgb(0xa6e22e)},
+			Heading:     {Color: rgb(0xa6e22e)},
+			Emphasis:    {Color: rgb(0xfd971f)},
+			Strong:      {Color: rgb(0xf92672)},
+			Link:        {Color: rgb(0x66d9ef)},
+			Code:        {Color: rgb(0xe6db74), Background: argb(0x20ffffff)},
+			Quote:       {Color: rgb(0x75715e)},
+			ListMarker:  {Color: rgb(0xf92672)},
+			Punctuation: {Color: rgb(0xf8f8f2)},
+		},
+	}
+
+	// SolarizedLight is the light variant of Solarized.
+	SolarizedLight = &Scheme{
+		Name: "solarized-light",
+		Styles: map[Kind]Style{
+			Keyword:     {Color: rgb(0x859900)},
+			Type:        {Color: rgb(0xb58900)},
+			Builtin:     {Color: rgb(0x268bd2)},
+			Function:    {Color: rgb(0x268bd2)},
+			String:      {Color: rgb(0x2aa198)},
+			Number:      {Color: rgb(0xd33682)},
+			Comment:     {Color: rgb(0x93a1a1)},
+			Operator:    {Color: rgb(0x859900)},
+			Constant:    {Color: rgb(0xcb4b16)},
+			Variable:    {Color: rgb(0x268bd2)},
+			Key:         {Color: rgb(0x268bd2)},
+			Heading:     {Color: rgb(0xcb4b16)},
+			Emphasis:    {Color: rgb(0x6c71c4)},
+			Strong:      {Color: rgb(0xdc322f)},
+			Link:        {Color: rgb(0x268bd2)},
+			Code:        {Color: rgb(0x2aa198), Background: argb(0x18000000)},
+			Quote:       {Color: rgb(0x93a1a1)},
+			ListMarker:  {Color: rgb(0xcb4b16)},
+			Punctuation: {Color: rgb(0x93a1a1)},
+		},
+	}
+)
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]*Scheme{
+		"default":         Light,
+		"light":           Light,
+		"dark":            Dark,
+		"monokai":         Monokai,
+		"solarized-light": SolarizedLight,
+	}
+)
+
+// RegisterScheme adds a scheme, replacing any scheme with the same name.
+func RegisterScheme(s *Scheme) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[strings.ToLower(s.Name)] = s
+}
+
+// LookupScheme returns the scheme with the name, or nil if there is none.
+func LookupScheme(name string) *Scheme {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	return schemes[strings.ToLower(name)]
+}
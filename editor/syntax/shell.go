@@ -0,0 +1,140 @@
+package syntax
+
+import "strings"
+
+const (
+	shellDoubleQuote State = iota + 1
+	shellSingleQuote
+)
+
+var (
+	shellKeywords = set(`if then else elif fi for while until do done case esac in
+		function select time`)
+	shellBuiltins = set(`alias bg break cd command continue declare echo eval exec
+		exit export false fg getopts hash jobs kill let local printf pwd read
+		readonly return set shift source test trap true type typeset ulimit umask
+		unalias unset wait`)
+)
+
+// shellMeta are the characters ending a word.
+const shellMeta = " \t|&;<>()$\"'`"
+
+func tokenizeShell(line string, state State) ([]Token, State) {
+	s := &scanner{line: line}
+
+	switch state {
+	case shellDoubleQuote:
+		if !s.doubleQuoted(0) {
+			return s.tokens, shellDoubleQuote
+		}
+	case shellSingleQuote:
+		closed := s.quoted('\'', false)
+		s.emit(String, 0)
+		if !closed {
+			return s.tokens, shellSingleQuote
+		}
+	}
+
+	for !s.done() {
+		start := s.pos
+		c := s.line[s.pos]
+		switch {
+		case c == '#' && (start == 0 || strings.IndexByte(" \t;&|(", s.line[start-1]) >= 0):
+			s.pos = len(s.line)
+			s.emit(Comment, start)
+		case c == '\'':
+			s.pos++
+			closed := s.quoted('\'', false)
+			s.emit(String, start)
+			if !closed {
+				return s.tokens, shellSingleQuote
+			}
+		case c == '"':
+			s.pos++
+			if !s.doubleQuoted(start) {
+				return s.tokens, shellDoubleQuote
+			}
+		case c == '$':
+			s.variable()
+		case c == '\\':
+			s.pos = min(s.pos+2, len(s.line))
+		case strings.IndexByte("|&;<>()`!=", c) >= 0:
+			s.pos++
+			s.emit(Operator, start)
+		case strings.IndexByte("[]{}", c) >= 0:
+			s.pos++
+			s.emit(Punctuation, start)
+		case c == ' ' || c == '\t':
+			s.pos++
+		default:
+			for !s.done() && strings.IndexByte(shellMeta+"=", s.line[s.pos]) < 0 {
+				s.pos++
+			}
+			word := s.line[start:s.pos]
+			switch {
+			case shellKeywords[word]:
+				s.emit(Keyword, start)
+			case shellBuiltins[word]:
+				s.emit(Builtin, start)
+			case strings.Trim(word, "0123456789") == "":
+				s.emit(Number, start)
+			case s.hasPrefix("()"):
+				s.emit(Function, start)
+			case s.peek(0) == '=' && isIdentStart(word, 0):
+				// An assignment.
+				s.emit(Variable, start)
+			}
+		}
+	}
+	return s.tokens, 0
+}
+
+// doubleQuoted moves the cursor past the closing double quote of a string
+// starting at start, highlighting the variables in it. It reports whether the
+// closing quote is found.
+func (s *scanner) doubleQuoted(start int) bool {
+	for !s.done() {
+		switch s.line[s.pos] {
+		case '\\':
+			s.pos = min(s.pos+2, len(s.line))
+		case '"':
+			s.pos++
+			s.emit(String, start)
+			return true
+		case '$':
+			s.emit(String, start)
+			s.variable()
+			start = s.pos
+		default:
+			s.pos++
+		}
+	}
+	s.emit(String, start)
+	return false
+}
+
+// variable moves the cursor past a parameter expansion such as $HOME, ${x:-y}
+// or $1. A command substitution "$(" is an operator.
+func (s *scanner) variable() {
+	start := s.pos
+	s.pos++
+	switch c := s.peek(0); {
+	case c == '{':
+		if i := strings.IndexByte(s.line[s.pos:], '}'); i >= 0 {
+			s.pos += i + 1
+		} else {
+			s.pos = len(s.line)
+		}
+	case c == '(':
+		s.pos++
+		s.emit(Operator, start)
+		return
+	case isDigit(c) || strings.IndexByte("@#?$!*-", c) >= 0:
+		s.pos++
+	case !s.done() && isIdentStart(s.line, s.pos):
+		s.ident()
+	default:
+		return
+	}
+	s.emit(Variable, start)
+}
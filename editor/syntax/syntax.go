@@ -0,0 +1,145 @@
+// Package syntax tokenizes source text for syntax highlighting in the editor.
+//
+// Text is tokenized line by line. A Tokenizer is given the state left by the
+// previous line, so constructs spanning several lines, such as block comments,
+// are handled without looking at the whole text. Highlighter caches the tokens
+// and states of the lines, so only the lines affected by an edit are
+// tokenized again.
+package syntax
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Kind classifies a token.
+type Kind uint8
+
+const (
+	Text Kind = iota
+	Keyword
+	Type
+	Builtin
+	Function
+	String
+	Number
+	Comment
+	Operator
+	Punctuation
+	Constant
+	Variable
+	// Key is an object key, e.g., in JSON.
+	Key
+	Heading
+	Emphasis
+	Strong
+	Link
+	Code
+	Quote
+	ListMarker
+
+	kindCount
+)
+
+var kindNames = [kindCount]string{
+	"text", "keyword", "type", "builtin", "function", "string", "number",
+	"comment", "operator", "punctuation", "constant", "variable", "key",
+	"heading", "emphasis", "strong", "link", "code", "quote", "list-marker",
+}
+
+func (k Kind) String() string {
+	if k < kindCount {
+		return kindNames[k]
+	}
+	return "unknown"
+}
+
+// Token is a span of a line. Start and End are byte offsets in the line.
+type Token struct {
+	Kind       Kind
+	Start, End int
+}
+
+// State is the tokenizer state at the start of a line. Its meaning is up to
+// the tokenizer, except that zero is the state at the start of the text.
+type State uint32
+
+// Tokenizer splits text into tokens.
+type Tokenizer interface {
+	// Tokenize returns the tokens of a line, which does not include the line
+	// break. state is the state left by the previous line, and the returned
+	// state is passed to the next line. Text not covered by any token is
+	// plain text.
+	Tokenize(line string, state State) ([]Token, State)
+}
+
+// TokenizerFunc adapts a function to the Tokenizer interface.
+type TokenizerFunc func(line string, state State) ([]Token, State)
+
+func (f TokenizerFunc) Tokenize(line string, state State) ([]Token, State) {
+	return f(line, state)
+}
+
+// Language is a tokenizer registered by name.
+type Language struct {
+	Name string
+	// Aliases are alternative names of the language.
+	Aliases []string
+	// Extensions are the file name extensions of the language, including
+	// the dot.
+	Extensions []string
+	Tokenizer  Tokenizer
+}
+
+var (
+	languagesMu sync.RWMutex
+	languages   = map[string]*Language{}
+	extensions  = map[string]*Language{}
+)
+
+// Register adds a language, replacing any language registered with the same
+// names or extensions.
+func Register(lang Language) {
+	languagesMu.Lock()
+	defer languagesMu.Unlock()
+
+	l := &lang
+	for _, name := range append([]string{lang.Name}, lang.Aliases...) {
+		languages[strings.ToLower(name)] = l
+	}
+	for _, ext := range lang.Extensions {
+		extensions[strings.ToLower(ext)] = l
+	}
+}
+
+// Lookup returns the tokenizer of the language with the name or alias, or nil
+// if there is none.
+func Lookup(name string) Tokenizer {
+	languagesMu.RLock()
+	defer languagesMu.RUnlock()
+
+	if l := languages[strings.ToLower(name)]; l != nil {
+		return l.Tokenizer
+	}
+	return nil
+}
+
+// ForFile returns the tokenizer for the file name, based on its extension, or
+// nil if there is none.
+func ForFile(name string) Tokenizer {
+	languagesMu.RLock()
+	defer languagesMu.RUnlock()
+
+	if l := extensions[strings.ToLower(filepath.Ext(name))]; l != nil {
+		return l.Tokenizer
+	}
+	return nil
+}
+
+func init() {
+	Register(Language{Name: "go", Aliases: []string{"golang"}, Extensions: []string{".go"}, Tokenizer: TokenizerFunc(tokenizeGo)})
+	Register(Language{Name: "markdown", Aliases: []string{"md"}, Extensions: []string{".md", ".markdown"}, Tokenizer: TokenizerFunc(tokenizeMarkdown)})
+	Register(Language{Name: "json", Extensions: []string{".json"}, Tokenizer: TokenizerFunc(tokenizeJSON)})
+	Register(Language{Name: "shell", Aliases: []string{"sh", "bash", "zsh"}, Extensions: []string{".sh", ".bash", ".zsh"}, Tokenizer: TokenizerFunc(tokenizeShell)})
+}
@@ -0,0 +1,128 @@
+package syntax
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// tokenize returns the tokens of the text as "kind:text", one line per line
+// of text.
+func tokenize(t Tokenizer, text string) string {
+	var state State
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		var tokens []Token
+		tokens, state = t.Tokenize(line, state)
+		var parts []string
+		for _, tok := range tokens {
+			parts = append(parts, fmt.Sprintf("%s:%s", tok.Kind, line[tok.Start:tok.End]))
+		}
+		out = append(out, strings.Join(parts, " "))
+	}
+	return strings.Join(out, "\n")
+}
+
+func TestGrammars(t *testing.T) {
+	tests := []struct {
+		lang string
+		text string
+		want string
+	}{
+		{
+			lang: "go",
+			text: "func main() { // start\n\tx := len(`a\nb`) + 0x1F /* c\nd */ + 1.5e-3\n\treturn fmt.Sprintf(\"%d\\\"\", 'x', true)",
+			want: "keyword:func function:main punctuation:() punctuation:{ comment:// start\n" +
+				"operator::= builtin:len punctuation:( string:`a\n" +
+				"string:b` punctuation:) operator:+ number:0x1F comment:/* c\n" +
+				"comment:d */ operator:+ number:1.5e-3\n" +
+				"keyword:return punctuation:. function:Sprintf punctuation:( string:\"%d\\\"\" punctuation:, string:'x' punctuation:, constant:true punctuation:)",
+		},
+		{
+			lang: "json",
+			text: `{"name": "gio", "n": -1.5e3, "ok": [true, null]}`,
+			want: `punctuation:{ key:"name" punctuation:: string:"gio" punctuation:, key:"n" punctuation:: number:-1.5e3 punctuation:, key:"ok" punctuation:: punctuation:[ constant:true punctuation:, constant:null punctuation:]}`,
+		},
+		{
+			lang: "sh",
+			text: "# comment\nif [ -f \"$HOME/x\" ]; then echo ${A:-b} 42 # done\nmsg='multi\nline' && f() { local x=1; }",
+			want: "comment:# comment\n" +
+				"keyword:if punctuation:[ string:\" variable:$HOME string:/x\" punctuation:] operator:; keyword:then builtin:echo variable:${A:-b} number:42 comment:# done\n" +
+				"variable:msg operator:= string:'multi\n" +
+				"string:line' operator:&& function:f operator:() punctuation:{ builtin:local variable:x operator:= number:1 operator:; punctuation:}",
+		},
+		{
+			lang: "markdown",
+			text: "# Title\n> quote *em*\n- [x] item with `code` and **bold**\n```go\nfunc x() {}\n```\nsee [link](http://x.y) and snake_case_word\n---",
+			want: "heading:# Title\n" +
+				"quote:> quote  emphasis:*em*\n" +
+				"list-marker:- [x]  text:item with  code:`code` text: and  strong:**bold**\n" +
+				"code:```go\n" +
+				"code:func x() {}\n" +
+				"code:```\n" +
+				"text:see  link:[link](http://x.y) text: and snake_case_word\n" +
+				"punctuation:---",
+		},
+	}
+
+	for _, tt := range tests {
+		tokenizer := Lookup(tt.lang)
+		if tokenizer == nil {
+			t.Fatalf("no tokenizer for %s", tt.lang)
+		}
+		if got := tokenize(tokenizer, tt.text); got != tt.want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if ForFile("/a/b/main.go") == nil || ForFile("README.MD") == nil || ForFile("x.txt") != nil {
+		t.Fatal("unexpected tokenizers for files")
+	}
+	if LookupScheme("Dark") != Dark || LookupScheme("default") != Light || LookupScheme("none") != nil {
+		t.Fatal("unexpected schemes")
+	}
+}
+
+func TestHighlighter(t *testing.T) {
+	lines := []string{"a := 1", "b := 2", "c := 3", "d := 4"}
+	calls := 0
+	text := func(i int) string {
+		calls++
+		return lines[i]
+	}
+
+	h := NewHighlighter(Lookup("go"))
+	if tokens := h.Tokens(3, text); len(tokens) != 2 || calls != 4 {
+		t.Fatalf("got %d tokens and %d calls", len(tokens), calls)
+	}
+
+	// Editing a line does not tokenize the lines after it.
+	calls = 0
+	lines[1] = "b := 20"
+	h.Edit(1, 0, 0)
+	h.Tokens(3, text)
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1", calls)
+	}
+
+	// Opening a block comment changes the state of the lines after it.
+	calls = 0
+	lines[1] = "/* b"
+	h.Edit(1, 0, 0)
+	if tokens := h.Tokens(3, text); len(tokens) != 1 || tokens[0].Kind != Comment || calls != 3 {
+		t.Fatalf("got %v and %d calls", tokens, calls)
+	}
+
+	// Inserting a line shifts the cached lines.
+	calls = 0
+	lines = []string{"a := 1", "/* b", "*/", "c := 3", "d := 4"}
+	h.Edit(1, 0, 1)
+	if tokens := h.Tokens(4, text); len(tokens) != 2 || calls != 4 {
+		t.Fatalf("got %v and %d calls", tokens, calls)
+	}
+	if tokens := h.Tokens(1, text); len(tokens) != 1 || tokens[0].Kind != Comment {
+		t.Fatalf("got %v", tokens)
+	}
+}
@@ -1,11 +1,12 @@
 package editor
 
 import (
-	"bufio"
+	"bytes"
 	"errors"
 	"image"
 	"io"
 	"math"
+	"slices"
 	"sort"
 	"unicode"
 	"unicode/utf8"
@@ -18,7 +19,6 @@ import (
 	"gioui.org/op/paint"
 	"gioui.org/text"
 	"gioui.org/unit"
-	"golang.org/x/exp/slices"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -68,20 +68,31 @@ type textView struct {
 	seekCursor int64
 	rr         textSource
 	// maskReader maskReader
-	// graphemes tracks the indices of grapheme cluster boundaries within rr.
+	// graphemes tracks the indices of grapheme cluster boundaries within the
+	// indexed paragraphs.
 	graphemes []int
 	// paragraphReader is used to populate graphemes.
 	paragraphReader graphemeReader
 	// lastMask        rune
 	viewSize image.Point
 	valid    bool
-	regions  []Region
-	dims     layout.Dimensions
+	// measuredGen is the generation of the layout parameters the line
+	// metrics are measured with.
+	measuredGen int
+	regions     []Region
+	dims        layout.Dimensions
 
 	// offIndex is an index of rune index to byte offsets.
 	offIndex []offEntry
 
-	index glyphIndex
+	// paras splits the text into paragraphs, and caches their layout.
+	paras paragraphIndex
+	// window contains the sorted indices of the paragraphs in the index, which
+	// are the paragraphs in the viewport, the paragraphs of the caret, and the
+	// paragraphs pinned since the last call of Layout.
+	window []int
+	pinned []int
+	index  glyphIndex
 
 	caret struct {
 		// xoff is the offset to the current position when moving between lines.
@@ -118,10 +129,17 @@ func (e *textView) FullDimensions() layout.Dimensions {
 // must be done before invoking any other methods on Text.
 func (e *textView) SetSource(source textSource) {
 	e.rr = source
+	e.paras.reset(source, e.monolithic())
 	e.invalidate()
 	e.seekCursor = 0
 }
 
+// monolithic reports whether the text must be laid out as a whole instead of
+// by paragraphs.
+func (e *textView) monolithic() bool {
+	return e.SingleLine || e.MaxLines > 0
+}
+
 // ReadRuneAt reads the rune starting at the given byte offset, if any.
 func (e *textView) ReadRuneAt(off int64) (rune, int, error) {
 	var buf [utf8.UTFMax]byte
@@ -156,19 +174,41 @@ func (e *textView) makeValid() {
 	e.valid = true
 }
 
-func (e *textView) closestToRune(runeIdx int) combinedPos {
+// ensureParagraph makes sure that the paragraph and its neighbors are
+// indexed, so that the positions in and around the paragraph are available.
+func (e *textView) ensureParagraph(idx int) {
 	e.makeValid()
+	if e.paras.monolithic {
+		return
+	}
+
+	last := len(e.paras.paragraphs) - 1
+	for i := max(idx-1, 0); i <= min(idx+1, last); i++ {
+		if e.paras.paragraphs[i].hidden {
+			continue
+		}
+		if _, found := slices.BinarySearch(e.window, i); !found {
+			e.pinned = append(e.pinned, idx)
+			e.valid = false
+			e.makeValid()
+			return
+		}
+	}
+}
+
+func (e *textView) closestToRune(runeIdx int) combinedPos {
+	e.ensureParagraph(e.paras.paragraphAtRune(runeIdx))
 	pos, _ := e.index.closestToRune(runeIdx)
 	return pos
 }
 
 func (e *textView) closestToLineCol(line, col int) combinedPos {
-	e.makeValid()
+	e.ensureParagraph(e.paras.paragraphAtLine(line))
 	return e.index.closestToLineCol(screenPos{line: line, col: col})
 }
 
 func (e *textView) closestToXY(x fixed.Int26_6, y int) combinedPos {
-	e.makeValid()
+	e.ensureParagraph(e.paras.paragraphAtY(y))
 	return e.index.closestToXY(x, y)
 }
 
@@ -215,7 +255,7 @@ func (e *textView) searchForLineRange(screenLine int) [2]combinedPos {
 			start = p
 			break
 		}
-		p = e.index.closestToLineCol(screenPos{line: p.lineCol.line - 1, col: 0})
+		p = e.closestToLineCol(p.lineCol.line-1, 0)
 	}
 
 	p = spos
@@ -232,7 +272,7 @@ func (e *textView) searchForLineRange(screenLine int) [2]combinedPos {
 			end = p
 			break
 		}
-		p = e.index.closestToLineCol(screenPos{line: p.lineCol.line + 1, col: 0})
+		p = e.closestToLineCol(p.lineCol.line+1, 0)
 	}
 
 	// When start line = end line, the line is a empty line, no need to
@@ -245,11 +285,15 @@ func (e *textView) searchForLineRange(screenLine int) [2]combinedPos {
 }
 
 // VisibleLines finds all visible logical line positions in the viewport, marking them with line numbers.
+// Folded lines are skipped.
 func (e *textView) VisibleLines() ([]*LineInfo, error) {
 	e.makeValid()
 	if e.viewSize.Y <= 0 {
 		return nil, nil
 	}
+	if !e.paras.monolithic {
+		return e.visibleParagraphLines()
+	}
 
 	firstPos := e.closestToXYGraphemes(0, e.scrollOff.Y)
 	lastPos := e.closestToXYGraphemes(0, e.viewSize.Y+e.scrollOff.Y)
@@ -281,7 +325,7 @@ func (e *textView) VisibleLines() ([]*LineInfo, error) {
 					End:     rng[1].runes,
 				})
 			} else {
-				startLine := e.rr.(*editBuffer).countLinesBeforeOffset(int64(e.runeOffset(rng[0].runes)))
+				startLine := e.logicalLine(e.runeOffset(rng[0].runes))
 				lines = append(lines, &LineInfo{
 					LineNum: startLine + 1,
 					YOffset: rng[0].y - e.ScrollOff().Y - rng[0].ascent.Ceil(),
@@ -305,6 +349,48 @@ func (e *textView) VisibleLines() ([]*LineInfo, error) {
 	return lines, nil
 }
 
+// visibleParagraphLines returns the visible logical lines, which are the
+// paragraphs not hidden by folding.
+func (e *textView) visibleParagraphLines() ([]*LineInfo, error) {
+	first, last, ok := e.visibleParagraphs()
+	if !ok {
+		return nil, errors.New("no lines found")
+	}
+	paras := &e.paras
+	var lines []*LineInfo
+	for i := first; i <= last && i < len(paras.paragraphs); i++ {
+		p := paras.paragraphs[i]
+		if p.hidden {
+			continue
+		}
+		_, n := paras.size(i)
+		end := p.runeOff + n
+		if i < len(paras.paragraphs)-1 {
+			// Exclude the line break.
+			end--
+		}
+		pos := e.closestToRune(p.runeOff)
+		lines = append(lines, &LineInfo{
+			LineNum: i + 1,
+			YOffset: pos.y - e.scrollOff.Y - pos.ascent.Ceil(),
+			Start:   p.runeOff,
+			End:     end,
+		})
+	}
+	return lines, nil
+}
+
+// logicalLine returns the index of the logical line containing the byte offset.
+func (e *textView) logicalLine(byteOff int) int {
+	if !e.paras.monolithic {
+		return e.paras.paragraphAtByte(byteOff)
+	}
+
+	buf := make([]byte, byteOff)
+	n, _ := e.rr.ReadAt(buf, 0)
+	return bytes.Count(buf[:n], []byte{'\n'})
+}
+
 // caretCurrentLine returns the current logical line that the carent is in.
 // Only the start position is checked.
 func (e *textView) caretCurrentLine() (start combinedPos, end combinedPos) {
@@ -350,6 +436,10 @@ func (e *textView) calculateViewSize(gtx layout.Context) image.Point {
 
 // Layout the text, reshaping it as necessary.
 func (e *textView) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp) {
+	if len(e.pinned) > 0 {
+		e.pinned = e.pinned[:0]
+		e.valid = false
+	}
 	if e.params.Locale != gtx.Locale {
 		e.params.Locale = gtx.Locale
 		e.invalidate()
@@ -408,7 +498,7 @@ func (e *textView) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, s
 
 	if viewSize := e.calculateViewSize(gtx); viewSize != e.viewSize {
 		e.viewSize = viewSize
-		e.invalidate()
+		e.valid = false
 	}
 	e.makeValid()
 }
@@ -416,10 +506,25 @@ func (e *textView) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, s
 // PaintSelection clips and paints the visible text selection rectangles using
 // the provided material to fill the rectangles.
 func (e *textView) PaintSelection(gtx layout.Context, material op.CallOp) {
+	e.paintRange(gtx, e.caret.start, e.caret.end, material)
+}
+
+// paintRange paints the visible part of the rune range [start, end).
+func (e *textView) paintRange(gtx layout.Context, start, end int, material op.CallOp) {
+	e.makeValid()
+	if start > end {
+		start, end = end, start
+	}
+	if first, last, ok := e.visibleRunes(); ok {
+		start, end = max(start, first), min(end, last)
+		if start >= end {
+			return
+		}
+	}
 	localViewport := image.Rectangle{Max: e.viewSize}
 	docViewport := image.Rectangle{Max: e.viewSize}.Add(e.scrollOff)
 	defer clip.Rect(localViewport).Push(gtx.Ops).Pop()
-	e.regions = e.index.locate(docViewport, e.caret.start, e.caret.end, e.regions)
+	e.regions = e.index.locate(docViewport, start, end, e.regions)
 	for _, region := range e.regions {
 		area := clip.Rect(region.Bounds).Push(gtx.Ops)
 		material.Add(gtx.Ops)
@@ -450,23 +555,23 @@ func (e *textView) paintLineHighlight(gtx layout.Context, material op.CallOp) {
 }
 
 func (e *textView) paintMatches(gtx layout.Context, matches []MatchRange, material op.CallOp) {
-	localViewport := image.Rectangle{Max: e.viewSize}
-	docViewport := image.Rectangle{Max: e.viewSize}.Add(e.scrollOff)
-	defer clip.Rect(localViewport).Push(gtx.Ops).Pop()
+	e.makeValid()
+	if start, end, ok := e.visibleRunes(); ok {
+		// Only paint the visible matches, which are in text order.
+		i := sort.Search(len(matches), func(i int) bool { return matches[i].End > start })
+		j := sort.Search(len(matches), func(i int) bool { return matches[i].Start >= end })
+		matches = matches[i:max(i, j)]
+	}
 	for _, match := range matches {
-		e.regions = e.index.locate(docViewport, match.Start, match.End, e.regions)
-		for _, region := range e.regions {
-			area := clip.Rect(region.Bounds).Push(gtx.Ops)
-			material.Add(gtx.Ops)
-			paint.PaintOp{}.Add(gtx.Ops)
-			area.Pop()
-		}
+		e.paintRange(gtx, match.Start, match.End, material)
 	}
 }
 
 // PaintText clips and paints the visible text glyph outlines using the provided
-// material to fill the glyphs.
-func (e *textView) PaintText(gtx layout.Context, material op.CallOp, textStyles []*TextStyle) {
+// material to fill the glyphs. A glyph is painted with the first style
+// covering it, looking up the layers of styles in order.
+func (e *textView) PaintText(gtx layout.Context, material op.CallOp, textStyles ...[]*TextStyle) {
+	e.makeValid()
 	m := op.Record(gtx.Ops)
 	viewport := image.Rectangle{
 		Min: e.scrollOff,
@@ -512,8 +617,16 @@ func (e *textView) caretWidth(gtx layout.Context) int {
 // PaintCaret clips and paints the caret rectangle, adding material immediately
 // before painting to set the appropriate paint material.
 func (e *textView) PaintCaret(gtx layout.Context, material op.CallOp) {
+	e.paintCaretAt(gtx, e.caret.start, material)
+}
+
+// paintCaretAt paints a caret at the rune offset if it is visible.
+func (e *textView) paintCaretAt(gtx layout.Context, runes int, material op.CallOp) {
+	if first, last, ok := e.visibleRunes(); ok && (runes < first || runes > last) {
+		return
+	}
 	carWidth2 := e.caretWidth(gtx)
-	caretPos, carAsc, carDesc := e.CaretInfo()
+	caretPos, carAsc, carDesc := e.caretInfo(runes)
 
 	carRect := image.Rectangle{
 		Min: caretPos.Sub(image.Pt(carWidth2, carAsc)),
@@ -529,7 +642,11 @@ func (e *textView) PaintCaret(gtx layout.Context, material op.CallOp) {
 }
 
 func (e *textView) CaretInfo() (pos image.Point, ascent, descent int) {
-	caretStart := e.closestToRune(e.caret.start)
+	return e.caretInfo(e.caret.start)
+}
+
+func (e *textView) caretInfo(runes int) (pos image.Point, ascent, descent int) {
+	caretStart := e.closestToRune(runes)
 
 	ascent = caretStart.ascent.Ceil()
 	descent = caretStart.descent.Ceil()
@@ -550,8 +667,7 @@ func (e *textView) ByteOffset(runeOffset int) int64 {
 
 // Len is the length of the editor contents, in runes.
 func (e *textView) Len() int {
-	e.makeValid()
-	return e.closestToRune(math.MaxInt).runes
+	return e.paras.runes
 }
 
 // Text returns the contents of the editor. If the provided buf is large enough, it will
@@ -596,6 +712,7 @@ func (e *textView) ScrollOff() image.Point {
 }
 
 func (e *textView) scrollAbs(x, y int) {
+	defer e.updateWindow()
 	e.scrollOff.X = x
 	e.scrollOff.Y = y
 	b := e.ScrollBounds()
@@ -629,40 +746,153 @@ func (e *textView) Truncated() bool {
 }
 
 func (e *textView) layoutText(lt *text.Shaper) {
-	e.Seek(0, io.SeekStart)
-	var r io.Reader = e
+	paras := &e.paras
+	if paras.monolithic != e.monolithic() {
+		paras.reset(e.rr, e.monolithic())
+	}
+	if e.measuredGen != paras.gen {
+		paras.measure(lt, e.params)
+		e.measuredGen = paras.gen
+	}
+
+	// Shaping the paragraphs may change their line count, which moves other
+	// paragraphs into the viewport, so repeat until all of them are shaped.
+	// As unshaped paragraphs are assumed to be a single line, which is the
+	// minimum, this ends quickly.
+	for range 8 {
+		e.window = e.collectWindow(e.window[:0])
+		shaped := false
+		for _, idx := range e.window {
+			if !paras.valid(idx) {
+				paras.setLayout(idx, e.shapeParagraph(lt, idx))
+				shaped = true
+			}
+		}
+		if !shaped {
+			break
+		}
+	}
 
+	paras.updateLines()
 	e.index.reset()
-	it := textIterator{viewport: image.Rectangle{Max: image.Point{X: math.MaxInt, Y: math.MaxInt}}}
-	if lt != nil {
-		lt.Layout(e.params, r)
-		for {
-			g, ok := lt.NextGlyph()
-			if !it.processGlyph(g, ok) {
-				break
-			}
+	e.graphemes = e.graphemes[:0]
+	for _, idx := range e.window {
+		p := paras.paragraphs[idx]
+		e.index.startParagraph(p.runeOff, p.line)
+		dy := int32(paras.baseline(idx))
+		for _, g := range p.layout.glyphs {
+			g.Y += dy
 			e.index.Glyph(g)
 		}
-	} else {
-		// Make a fake glyph for every rune in the reader.
-		b := bufio.NewReader(r)
-		for _, _, err := b.ReadRune(); err != io.EOF; _, _, err = b.ReadRune() {
-			g := text.Glyph{Runes: 1, Flags: text.FlagClusterBreak}
-			_ = it.processGlyph(g, true)
-			e.index.Glyph(g)
+
+		graphemes := p.layout.graphemes
+		if n := len(e.graphemes); n > 0 && len(graphemes) > 0 && e.graphemes[n-1] == p.runeOff+graphemes[0] {
+			graphemes = graphemes[1:]
+		}
+		for _, g := range graphemes {
+			e.graphemes = append(e.graphemes, p.runeOff+g)
 		}
 	}
-	e.paragraphReader.SetSource(e.rr)
-	e.graphemes = e.graphemes[:0]
-	for g := e.paragraphReader.Graphemes(); len(g) > 0; g = e.paragraphReader.Graphemes() {
-		if len(e.graphemes) > 0 && g[0] == e.graphemes[len(e.graphemes)-1] {
-			g = g[1:]
+
+	e.dims = e.fullDimensions()
+}
+
+// collectWindow appends the indices of the paragraphs to index to window.
+func (e *textView) collectWindow(window []int) []int {
+	paras := &e.paras
+	if paras.monolithic {
+		return append(window, 0)
+	}
+
+	last := len(paras.paragraphs) - 1
+	add := func(first, end int) {
+		for i := max(first, 0); i <= min(end, last); i++ {
+			if !paras.paragraphs[i].hidden {
+				window = append(window, i)
+			}
+		}
+	}
+
+	if first, end, ok := e.visibleParagraphs(); ok {
+		add(first-1, end+1)
+	}
+	for _, r := range [...]int{e.caret.start, e.caret.end} {
+		idx := paras.paragraphAtRune(r)
+		add(idx-1, idx+1)
+	}
+	for _, idx := range e.pinned {
+		add(idx-1, idx+1)
+	}
+
+	slices.Sort(window)
+	return slices.Compact(window)
+}
+
+// visibleParagraphs returns the range of the paragraphs in the viewport.
+func (e *textView) visibleParagraphs() (first, last int, ok bool) {
+	if e.paras.metrics.height <= 0 || e.viewSize.Y <= 0 {
+		return 0, 0, false
+	}
+	first = e.paras.paragraphAtY(e.scrollOff.Y)
+	last = e.paras.paragraphAtY(e.scrollOff.Y + e.viewSize.Y)
+	return first, last, true
+}
+
+// visibleRunes returns the rune range of the paragraphs in the viewport.
+func (e *textView) visibleRunes() (start, end int, ok bool) {
+	first, last, ok := e.visibleParagraphs()
+	if !ok || e.paras.monolithic {
+		return 0, 0, false
+	}
+	last = min(last, len(e.paras.paragraphs)-1)
+	_, n := e.paras.size(last)
+	return e.paras.paragraphs[first].runeOff, e.paras.paragraphs[last].runeOff + n, true
+}
+
+// updateWindow invalidates the index if the viewport is scrolled to
+// paragraphs not indexed yet.
+func (e *textView) updateWindow() {
+	if !e.valid || e.paras.monolithic {
+		return
+	}
+
+	first, last, ok := e.visibleParagraphs()
+	if !ok {
+		return
+	}
+	for i := first; i <= last; i++ {
+		if e.paras.paragraphs[i].hidden {
+			continue
 		}
-		e.graphemes = append(e.graphemes, g...)
+		if _, found := slices.BinarySearch(e.window, i); !found {
+			e.valid = false
+			return
+		}
+	}
+}
+
+// fullDimensions returns the dimensions of the whole text. The size of
+// paragraphs not shaped yet is estimated.
+func (e *textView) fullDimensions() layout.Dimensions {
+	paras := &e.paras
+	first, last := 0, paras.lastVisible()
+
+	top := 0
+	if paras.valid(first) {
+		top = paras.baseline(first) + paras.paragraphs[first].layout.bounds.Min.Y
+	}
+	bottom := paras.baseline(last) + (paras.lines(last)-1)*paras.metrics.height + paras.metrics.descent
+	if paras.valid(last) {
+		bottom = paras.baseline(last) + paras.paragraphs[last].layout.bounds.Max.Y
 	}
-	dims := layout.Dimensions{Size: it.bounds.Size()}
-	dims.Baseline = dims.Size.Y - it.baseline
-	e.dims = dims
+	width := 0
+	if paras.minX <= paras.maxX {
+		width = paras.maxX - paras.minX
+	}
+
+	dims := layout.Dimensions{Size: image.Pt(width, bottom-top)}
+	dims.Baseline = dims.Size.Y - paras.baseline(first)
+	return dims
 }
 
 // CaretPos returns the line & column numbers of the caret.
@@ -700,6 +930,10 @@ func (e *textView) indexRune(r int) offEntry {
 func (e *textView) runeOffset(r int) int {
 	const runesPerIndexEntry = 50
 	entry := e.indexRune(r)
+	if p := e.paras.paragraphs[e.paras.paragraphAtRune(r)]; p.runeOff > entry.runes {
+		// Start from the paragraph, which is closer.
+		entry = offEntry{runes: p.runeOff, bytes: p.byteOff}
+	}
 	lastEntry := e.offIndex[len(e.offIndex)-1].runes
 	for entry.runes < r {
 		if entry.runes > lastEntry && entry.runes%runesPerIndexEntry == runesPerIndexEntry-1 {
@@ -712,8 +946,19 @@ func (e *textView) runeOffset(r int) int {
 	return entry.bytes
 }
 
+// runeAtByte returns the rune offset of the byte offset into e.rr.
+func (e *textView) runeAtByte(off int) int {
+	p := e.paras.paragraphs[e.paras.paragraphAtByte(off)]
+	buf := make([]byte, max(off-p.byteOff, 0))
+	n, _ := e.rr.ReadAt(buf, int64(p.byteOff))
+	return p.runeOff + countRunes(buf[:n])
+}
+
+// invalidate outdates the layout of the text, e.g., after the layout
+// parameters are changed.
 func (e *textView) invalidate() {
 	e.offIndex = e.offIndex[:0]
+	e.paras.invalidate()
 	e.valid = false
 }
 
@@ -726,11 +971,14 @@ func (e *textView) Replace(start, end int, s string) int {
 	startPos := e.closestToRune(start)
 	endPos := e.closestToRune(end)
 	startOff := e.runeOffset(startPos.runes)
+	endOff := e.runeOffset(endPos.runes)
 	replaceSize := endPos.runes - startPos.runes
 	sc := utf8.RuneCountInString(s)
 	newEnd := startPos.runes + sc
 
+	size := e.rr.Size()
 	e.rr.ReplaceRunes(int64(startOff), int64(replaceSize), s)
+	e.paras.replace(e.rr, startOff, endOff, endOff-startOff+int(e.rr.Size()-size))
 	adjust := func(pos int) int {
 		switch {
 		case newEnd < pos && pos <= endPos.runes:
@@ -743,7 +991,9 @@ func (e *textView) Replace(start, end int, s string) int {
 	}
 	e.caret.start = adjust(e.caret.start)
 	e.caret.end = adjust(e.caret.end)
-	e.invalidate()
+	// Only the replaced paragraphs need to be shaped again.
+	e.offIndex = e.offIndex[:0]
+	e.valid = false
 	return sc
 }
 
@@ -761,14 +1011,17 @@ func (e *textView) MovePages(pages int, selAct selectionAction) {
 
 // moveByGraphemes returns the rune index resulting from moving the
 // specified number of grapheme clusters from startRuneidx.
+// Folded lines are skipped.
 func (e *textView) moveByGraphemes(startRuneidx, graphemes int) int {
+	startRuneidx = e.skipFolded(startRuneidx, graphemes > 0)
+	e.ensureParagraph(e.paras.paragraphAtRune(startRuneidx))
 	if len(e.graphemes) == 0 {
 		return startRuneidx
 	}
 	startGraphemeIdx, _ := slices.BinarySearch(e.graphemes, startRuneidx)
 	startGraphemeIdx = max(startGraphemeIdx+graphemes, 0)
 	startGraphemeIdx = min(startGraphemeIdx, len(e.graphemes)-1)
-	startRuneIdx := e.graphemes[startGraphemeIdx]
+	startRuneIdx := e.skipFolded(e.graphemes[startGraphemeIdx], graphemes > 0)
 	return e.closestToRune(startRuneIdx).runes
 }
 
@@ -987,6 +1240,7 @@ func (e *textView) ReadAt(p []byte, offset int64) (int, error) {
 
 // Regions returns visible regions covering the rune range [start,end).
 func (e *textView) Regions(start, end int, regions []Region) []Region {
+	e.makeValid()
 	viewport := image.Rectangle{
 		Min: e.scrollOff,
 		Max: e.viewSize.Add(e.scrollOff),
@@ -994,31 +1248,33 @@ func (e *textView) Regions(start, end int, regions []Region) []Region {
 	return e.index.locate(viewport, start, end, regions)
 }
 
-func (e *textView) styleForGlyph(g text.Glyph, detaultMaterial op.CallOp, styles []*TextStyle) glyphStyle {
-	gs := glyphStyle{g: g}
+func (e *textView) styleForGlyph(g text.Glyph, detaultMaterial op.CallOp, layers [][]*TextStyle) glyphStyle {
+	gs := glyphStyle{g: g, fg: detaultMaterial}
 
-	pos := e.index.closestToXY(g.X, int(g.Y))
-	idx := sort.Search(len(styles), func(i int) bool {
-		s := styles[i]
-		return s.Start > pos.runes
-	})
+	var pos combinedPos
+	located := false
+	for _, styles := range layers {
+		if len(styles) == 0 {
+			continue
+		}
+		if !located {
+			pos = e.index.closestToXY(g.X, int(g.Y))
+			located = true
+		}
+		idx := sort.Search(len(styles), func(i int) bool {
+			return styles[i].Start > pos.runes
+		})
+		if idx == 0 || styles[idx-1].End <= pos.runes {
+			continue
+		}
 
-	if idx >= len(styles) {
-		gs.fg = detaultMaterial
+		style := styles[idx-1]
+		gs.bg = style.Background
+		if style.Color != (op.CallOp{}) {
+			gs.fg = style.Color
+		}
 		return gs
 	}
 
-	if idx > 0 {
-		idx--
-	}
-
-	style := styles[idx]
-	gs.fg = style.Color
-	gs.bg = style.Background
-
-	if style.Color == (op.CallOp{}) {
-		gs.fg = detaultMaterial
-	}
-
 	return gs
 }
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package editor
+
+import (
+	"image"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+
+	"github.com/oligo/gioview/uitest"
+)
+
+var (
+	testShaper     *text.Shaper
+	testShaperOnce sync.Once
+)
+
+func shaper() *text.Shaper {
+	testShaperOnce.Do(func() {
+		testShaper = text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	})
+	return testShaper
+}
+
+func layoutEditor(e *Editor, size image.Point) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Metric:      unit.Metric{PxPerDp: 1, PxPerSp: 1},
+		Constraints: layout.Exact(size),
+	}
+	e.Layout(gtx, shaper(), font.Font{}, 14, op.CallOp{}, op.CallOp{}, op.CallOp{}, op.CallOp{})
+}
+
+// newHarness returns a harness laying out the editor styled by conf, whose
+// shaper, text color and text size are set to the ones of the harness theme,
+// and lays out a first frame.
+func newHarness(e *Editor, size image.Point, conf EditorConf) *uitest.Harness {
+	h := uitest.New(size, nil)
+	conf.Shaper = h.Theme.Shaper
+	conf.TextColor = h.Theme.Fg
+	conf.TextSize = h.Theme.TextSize
+	h.Widget = func(gtx layout.Context) layout.Dimensions {
+		return NewEditor(e, &conf, "").Layout(gtx)
+	}
+	h.Frame()
+	return h
+}
+
+// referenceIndex lays out the whole text at once.
+func referenceIndex(e *Editor) *glyphIndex {
+	lt := shaper()
+	lt.LayoutString(e.text.params, e.Text())
+	idx := &glyphIndex{}
+	for {
+		g, ok := lt.NextGlyph()
+		if !ok {
+			break
+		}
+		idx.Glyph(g)
+	}
+	return idx
+}
+
+func TestParagraphLayout(t *testing.T) {
+	texts := []string{
+		"",
+		"\n",
+		"one line",
+		"trailing newline\n",
+		"a\n\nb\n\n\n",
+		"wrapped " + strings.Repeat("lorem ipsum dolor sit amet ", 20) + "\nend\n",
+		"日本語のテキスト\nمرحبا بالعالم\nmixed العربية text\n",
+	}
+
+	for _, txt := range texts {
+		e := &Editor{}
+		e.SetText(txt, false)
+		layoutEditor(e, image.Pt(300, 2000))
+
+		want := referenceIndex(e)
+		got := &e.text.index
+		if len(got.positions) != len(want.positions) {
+			t.Fatalf("%q: got %d positions, want %d", txt, len(got.positions), len(want.positions))
+		}
+		for i := range want.positions {
+			if got.positions[i] != want.positions[i] {
+				t.Fatalf("%q: position %d: got %+v, want %+v", txt, i, got.positions[i], want.positions[i])
+			}
+		}
+		if len(got.lines) != len(want.lines) {
+			t.Fatalf("%q: got %d lines, want %d", txt, len(got.lines), len(want.lines))
+		}
+		if e.Len() != len([]rune(txt)) {
+			t.Fatalf("%q: got length %d", txt, e.Len())
+		}
+	}
+}
+
+func TestParagraphWindow(t *testing.T) {
+	lines := make([]string, 5000)
+	for i := range lines {
+		lines[i] = strings.Repeat("x", i%40)
+	}
+	txt := strings.Join(lines, "\n")
+
+	e := &Editor{}
+	e.SetText(txt, false)
+	layoutEditor(e, image.Pt(1000, 200))
+	if n := len(e.text.window); n > 30 {
+		t.Fatalf("too many paragraphs indexed: %d", n)
+	}
+
+	// Scroll to the middle of the text, which must be laid out like the
+	// whole text is.
+	e.text.ScrollRel(0, e.text.FullDimensions().Size.Y/2)
+	layoutEditor(e, image.Pt(1000, 200))
+	want := referenceIndex(e)
+	for _, pos := range e.text.index.positions {
+		ref, _ := want.closestToRune(pos.runes)
+		if pos != ref {
+			t.Fatalf("got %+v, want %+v", pos, ref)
+		}
+	}
+
+	visible, err := e.VisibleLines()
+	if err != nil || len(visible) == 0 {
+		t.Fatal("no visible lines", err)
+	}
+	first := visible[0]
+	if n := strings.Count(string([]rune(txt)[:first.Start]), "\n"); first.LineNum != n+1 || n < 2000 {
+		t.Fatalf("unexpected first visible line: %+v", first)
+	}
+
+	// Moving the caret to the end indexes the last paragraph.
+	e.SetCaret(math.MaxInt, math.MaxInt)
+	if start, _ := e.Selection(); start != e.Len() {
+		t.Fatalf("caret is not at the end: %d", start)
+	}
+	e.text.MoveLines(-1, selectionClear)
+	if line, _ := e.CaretPos(); line != len(lines)-2 {
+		t.Fatalf("unexpected caret line: %d", line)
+	}
+}
+
+func TestParagraphEditing(t *testing.T) {
+	e := &Editor{}
+	e.SetText("first\nsecond line\n\nlast", false)
+	layoutEditor(e, image.Pt(200, 400))
+
+	edits := []struct {
+		start, end int
+		s          string
+	}{
+		{0, 0, "0"},
+		{6, 7, ""},
+		{5, 5, "\nnew\n"},
+		{0, 3, "日本"},
+		{e.Len() + 5, e.Len() + 5, "\n"},
+		{3, 12, ""},
+		{0, 100, "all replaced " + strings.Repeat("wrap ", 30)},
+		{0, 200, ""},
+	}
+	for _, edit := range edits {
+		want := []rune(e.Text())
+		start, end := min(edit.start, len(want)), min(edit.end, len(want))
+		want = append(want[:start:start], append([]rune(edit.s), want[end:]...)...)
+
+		e.SetCaret(start, end)
+		e.Insert(edit.s)
+		layoutEditor(e, image.Pt(200, 400))
+
+		if e.Text() != string(want) || e.Len() != len(want) {
+			t.Fatalf("got %q, want %q", e.Text(), string(want))
+		}
+		ref := referenceIndex(e)
+		if len(ref.positions) != len(e.text.index.positions) {
+			t.Fatalf("%q: got %d positions, want %d", e.Text(), len(e.text.index.positions), len(ref.positions))
+		}
+		for i, pos := range ref.positions {
+			if pos != e.text.index.positions[i] {
+				t.Fatalf("%q: position %d: got %+v, want %+v", e.Text(), i, e.text.index.positions[i], pos)
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"github.com/oligo/gioview/view"
+)
+
+// keyBindingsFile holds the user key bindings of the built-in commands and of
+// the commands of the example app, e.g.:
+//
+//	{"view.splitRight": "Ctrl+Alt+S", "edit.selectAll": "Ctrl+Shift+A"}
+const keyBindingsFile = "keybindings.json"
+
+// loadKeyBindings loads the user key bindings.
+func loadKeyBindings(vm view.ViewManager) {
+	f, err := os.Open(keyBindingsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	} else if err != nil {
+		log.Println(err)
+		return
+	}
+	defer f.Close()
+	if err := vm.Commands().LoadBindings(f); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log"
+
+	"github.com/oligo/gioview/navi"
+	"github.com/oligo/gioview/theme"
+	"github.com/oligo/gioview/view"
+
+	"gioui.org/app"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+)
+
+// DetachedWindow is a window holding tabs dragged out of the main window.
+// Tabs dragged out of it, or left when it is closed, are merged back to
+// the main window.
+type DetachedWindow struct {
+	window *app.Window
+	vm     view.ViewManager
+	main   view.ViewManager
+	tabbar *navi.Tabbar
+}
+
+// detachTab moves the tab at idx of the main window to a new window.
+func detachTab(main view.ViewManager, th *theme.Theme, idx int) {
+	w := &app.Window{}
+	w.Option(app.Size(unit.Dp(800), unit.Dp(600)))
+
+	dw := &DetachedWindow{window: w, main: main}
+	dw.vm = main.ForWindow(w)
+	dw.tabbar = navi.NewTabbar(dw.vm, &navi.TabbarOptions{OnDetach: dw.mergeTab})
+
+	if err := main.MoveTabTo(idx, dw.vm); err != nil {
+		log.Printf("detach tab error: %v", err)
+		return
+	}
+
+	go dw.Loop(th)
+}
+
+func (dw *DetachedWindow) mergeTab(idx int) {
+	if err := dw.vm.MoveTabTo(idx, dw.main); err != nil {
+		log.Printf("merge tab error: %v", err)
+	}
+
+	if len(dw.vm.OpenedViews()) <= 0 {
+		dw.window.Perform(system.ActionClose)
+	}
+}
+
+func (dw *DetachedWindow) Loop(th *theme.Theme) {
+	var ops op.Ops
+	for {
+		switch e := dw.window.Event().(type) {
+		case app.DestroyEvent:
+			// keep the tabs by merging them back.
+			for len(dw.vm.OpenedViews()) > 0 {
+				if err := dw.vm.MoveTabTo(0, dw.main); err != nil {
+					dw.vm.Reset()
+				}
+			}
+			dw.main.Invalidate()
+			return
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, e)
+			dw.layout(gtx, th)
+			e.Frame(gtx.Ops)
+		}
+	}
+}
+
+func (dw *DetachedWindow) layout(gtx C, th *theme.Theme) D {
+	gtx.Constraints.Min = gtx.Constraints.Max
+	rect := clip.Rect{Max: gtx.Constraints.Max}
+	paint.FillShape(gtx.Ops, th.Bg, rect.Op())
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return dw.tabbar.Layout(gtx, th)
+		}),
+		layout.Flexed(1, func(gtx C) D {
+			vw := dw.vm.CurrentView()
+			if vw == nil {
+				return view.EmptyView{}.Layout(gtx, th)
+			}
+			return vw.Layout(gtx, th)
+		}),
+	)
+}
@@ -3,14 +3,19 @@ package main
 import (
 	//"image"
 
+	"fmt"
 	"image/color"
+	"log"
 	"regexp"
+	"strings"
 
 	"github.com/oligo/gioview/editor"
+	"github.com/oligo/gioview/menu"
 	"github.com/oligo/gioview/theme"
 	"github.com/oligo/gioview/view"
 	"github.com/oligo/gioview/widget"
 
+	"gioui.org/io/pointer"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/paint"
@@ -24,10 +29,19 @@ var (
 	EditorExampleViewID = view.NewViewID("EditorExampleView")
 )
 
+// markdownSnippets expand when Tab is pressed after their prefixes.
+const markdownSnippets = `{
+	"Link": {"prefix": "link", "body": "[${1:title}](${2:https://})$0"},
+	"Image": {"prefix": "img", "body": "![${1:alt}](${2:path})$0"},
+	"Code Block": {"prefix": "code", "body": ["~~~${1:go}", "$0", "~~~"]},
+	"Task": {"prefix": "todo", "body": "- [ ] ${1:task}"}
+}`
+
 type EditorExample struct {
 	*view.BaseView
 	ed           *editor.Editor
 	patternInput widget.TextField
+	bookmarks    *editor.MarkerColumn
 }
 
 func (vw *EditorExample) ID() view.ViewID {
@@ -81,8 +95,12 @@ func (vw *EditorExample) Layout(gtx layout.Context, th *theme.Theme) layout.Dime
 				TextSize:           th.TextSize,
 				LineHeightScale:    1.6,
 				ColorScheme:        "default",
+				Language:           "markdown",
+				Folding:            "heading",
 				ShowLineNum:        true,
 				LineNumPadding:     unit.Dp(24),
+				Gutter:             []editor.GutterColumn{vw.bookmarks},
+				Popup:              menu.NewStyle(th),
 			}
 
 			vw.ed.UpdateTextStyles(stylingText(vw.ed.Text(), vw.patternInput.Text()))
@@ -103,10 +121,33 @@ func (va *EditorExample) OnFinish() {
 	// Put your cleanup code here.
 }
 
-func NewEditorExample() view.View {
+func NewEditorExample(vm view.ViewManager) view.View {
 	v := &EditorExample{
 		BaseView: &view.BaseView{},
-		ed:       &editor.Editor{},
+		ed:       &editor.Editor{Commands: vm.Commands()},
+	}
+	snippets, err := editor.LoadSnippets(strings.NewReader(markdownSnippets))
+	if err != nil {
+		log.Fatal(err)
+	}
+	v.ed.SetSnippets(snippets)
+	v.ed.SetCompletionProviders(
+		editor.WordCompletion("gioview", "editor", "explorer", "navigation", "markdown", "widget"),
+		editor.SnippetCompletion(snippets),
+		editor.FileCompletion("."),
+	)
+	// Clicking the bookmark column toggles a bookmark.
+	v.bookmarks = &editor.MarkerColumn{Markers: map[int]editor.Marker{}}
+	v.bookmarks.OnClick = func(line int, buttons pointer.Buttons) {
+		if _, ok := v.bookmarks.Markers[line]; ok {
+			delete(v.bookmarks.Markers, line)
+			return
+		}
+		v.bookmarks.Markers[line] = editor.Marker{
+			Color:   color.NRGBA{R: 0x42, G: 0x85, B: 0xf4, A: 0xff},
+			Shape:   editor.MarkerTriangle,
+			Tooltip: fmt.Sprintf("Bookmark at line %d", line),
+		}
 	}
 
 	v.ed.SetText(sampleText, false)
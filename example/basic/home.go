@@ -1,8 +1,12 @@
 package main
 
 import (
+	"log"
+	"slices"
+
 	"github.com/oligo/gioview/explorer"
 	"github.com/oligo/gioview/navi"
+	"github.com/oligo/gioview/palette"
 	"github.com/oligo/gioview/theme"
 	"github.com/oligo/gioview/view"
 
@@ -19,7 +23,8 @@ import (
 type HomeView struct {
 	view.ViewManager
 	sidebar *NavDrawer
-	tabbar  *navi.Tabbar
+	// tab bars of the tab groups in the workspace.
+	tabbars map[*view.TabGroup]*navi.Tabbar
 }
 
 func (hv *HomeView) ID() string {
@@ -27,6 +32,7 @@ func (hv *HomeView) ID() string {
 }
 func (hv *HomeView) update(gtx C) {
 	// handle events and states update
+	hv.Commands().Update(gtx)
 }
 
 func (hv *HomeView) Layout(gtx C, th *theme.Theme) layout.Dimensions {
@@ -60,28 +66,21 @@ func (hv *HomeView) LayoutMain(gtx C, th *theme.Theme) layout.Dimensions {
 			rect := clip.Rect{Max: gtx.Constraints.Max}
 			paint.FillShape(gtx.Ops, th.Bg, rect.Op())
 
-			return layout.Flex{
-				Axis:      layout.Vertical,
-				Alignment: layout.Middle,
-			}.Layout(gtx,
-				// horizontal navbar
-				layout.Rigid(func(gtx C) D {
-					return hv.tabbar.Layout(gtx, th)
-				}),
-				layout.Rigid(func(gtx C) D {
-					return layout.Spacer{Height: unit.Dp(1)}.Layout(gtx)
-				}),
-
-				layout.Flexed(1, func(gtx C) D {
-					if hv.CurrentView() == nil {
-						return view.EmptyView{}.Layout(gtx, th)
-					}
-					return hv.CurrentView().Layout(gtx, th)
-				}),
-			)
+			return view.SplitView{
+				VM:          hv.ViewManager,
+				LayoutGroup: hv.layoutGroup,
+			}.Layout(gtx, th)
 		}),
 	)
 
+	// drop tab bars of the removed tab groups.
+	groups := hv.TabGroups()
+	for group := range hv.tabbars {
+		if !slices.Contains(groups, group) {
+			delete(hv.tabbars, group)
+		}
+	}
+
 	modalIter := hv.ModalViews()
 
 	var allModals []*view.ModalView
@@ -116,6 +115,36 @@ func (hv *HomeView) LayoutMain(gtx C, th *theme.Theme) layout.Dimensions {
 	return dims
 }
 
+func (hv *HomeView) layoutGroup(gtx C, th *theme.Theme, group *view.TabGroup) D {
+	tabbar, ok := hv.tabbars[group]
+	if !ok {
+		tabbar = navi.NewGroupTabbar(hv.ViewManager, group, &navi.TabbarOptions{
+			OnDetach: func(idx int) { detachTab(hv.ViewManager, th, idx) },
+		})
+		hv.tabbars[group] = tabbar
+	}
+
+	return layout.Flex{
+		Axis:      layout.Vertical,
+		Alignment: layout.Middle,
+	}.Layout(gtx,
+		// horizontal navbar
+		layout.Rigid(func(gtx C) D {
+			return tabbar.Layout(gtx, th)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layout.Spacer{Height: unit.Dp(1)}.Layout(gtx)
+		}),
+
+		layout.Flexed(1, func(gtx C) D {
+			if group.CurrentView() == nil {
+				return view.EmptyView{}.Layout(gtx, th)
+			}
+			return group.CurrentView().Layout(gtx, th)
+		}),
+	)
+}
+
 func newHome(window *app.Window) *HomeView {
 	vm := view.DefaultViewManager(window)
 
@@ -140,6 +169,18 @@ func newHome(window *app.Window) *HomeView {
 		_ = vm.RequestSwitch(intent)
 	}))
 
+	sidebar.AddSection(SimpleItemSection(viewIcon, "Markdown", func(item *navi.NavTree) {
+		sidebar.OnItemSelected(item)
+		intent := view.Intent{Target: MarkdownExampleViewID, ShowAsModal: false}
+		_ = vm.RequestSwitch(intent)
+	}))
+
+	sidebar.AddSection(SimpleItemSection(viewIcon, "Split Editor", func(item *navi.NavTree) {
+		sidebar.OnItemSelected(item)
+		vm.SplitGroup(layout.Horizontal)
+		_ = vm.RequestSwitch(view.Intent{Target: EditorExampleViewID, RequireNew: true})
+	}))
+
 	fileTree, _ := explorer.NewEntryNavItem("../../")
 	sidebar.AddSection(NewFileTreeNav("File Explorer", fileTree, func(item *navi.NavTree) {
 		sidebar.OnItemSelected(item)
@@ -147,13 +188,18 @@ func newHome(window *app.Window) *HomeView {
 		//_ = vm.RequestSwitch(intent)
 	}))
 
+	loadKeyBindings(vm)
+	if _, err := palette.NewCommandPalette(vm); err != nil {
+		log.Println(err)
+	}
 	vm.Register(ExampleViewID, func() view.View { return NewExampleView(vm) })
-	vm.Register(EditorExampleViewID, NewEditorExample)
+	vm.Register(EditorExampleViewID, func() view.View { return NewEditorExample(vm) })
 	vm.Register(ExplorerViewID, NewFileExplorerView)
+	vm.Register(MarkdownExampleViewID, func() view.View { return NewMarkdownExample(vm) })
 
 	return &HomeView{
 		ViewManager: vm,
-		tabbar:      navi.NewTabbar(vm, &navi.TabbarOptions{}),
+		tabbars:     make(map[*view.TabGroup]*navi.Tabbar),
 		sidebar:     sidebar,
 	}
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/oligo/gioview/markdown"
+	"github.com/oligo/gioview/page"
+	"github.com/oligo/gioview/theme"
+	"github.com/oligo/gioview/view"
+
+	"gioui.org/layout"
+	"gioui.org/unit"
+)
+
+var (
+	MarkdownExampleViewID = view.NewViewID("MarkdownExampleView")
+)
+
+const markdownExample = "# Markdown\n\n" +
+	"A read-only widget rendering **CommonMark** with _tables_, ~~no HTML~~, task lists and fenced code. " +
+	"Drag to select text, double click to select a word, and press Ctrl/Cmd+C to copy.\n\n" +
+	"Links open in [views](%s) or [elsewhere](https://gioui.org).\n\n" +
+	"## Lists\n\n" +
+	"1. Ordered\n2. Lists\n   - nested\n   - bullets\n\n" +
+	"- [x] Parse the document\n- [ ] Write the docs\n\n" +
+	"> Block quotes are rendered with a bar.\n> > And nest.\n\n" +
+	"## Code\n\n" +
+	"```go\nfunc main() {\n\tfmt.Println(\"hello\") // greet\n}\n```\n\n" +
+	"| Widget | Package | Status |\n|:--|:--|--:|\n| Editor | `editor` | done |\n| Markdown | `markdown` | new |\n\n" +
+	"---\n\n" +
+	"![Gio logo](gioui_logo.png)\n"
+
+type MarkdownExample struct {
+	*view.BaseView
+	page.PageStyle
+	vm view.ViewManager
+	md markdown.Markdown
+}
+
+func (vw *MarkdownExample) ID() view.ViewID {
+	return MarkdownExampleViewID
+}
+
+func (vw *MarkdownExample) Title() string {
+	return "Markdown"
+}
+
+func (vw *MarkdownExample) Layout(gtx layout.Context, th *theme.Theme) layout.Dimensions {
+	vw.Padding = unit.Dp(30)
+	return vw.PageStyle.Layout(gtx, th, func(gtx C) D {
+		return layout.Inset{Top: unit.Dp(20), Bottom: unit.Dp(20)}.Layout(gtx, func(gtx C) D {
+			return markdown.NewMarkdown(th, &vw.md).Layout(gtx)
+		})
+	})
+}
+
+func (vw *MarkdownExample) openLink(dest string) error {
+	if strings.HasPrefix(dest, view.URLScheme+"://") {
+		return vw.vm.OpenURL(dest)
+	}
+
+	log.Println("link clicked: ", dest)
+	return nil
+}
+
+func NewMarkdownExample(vm view.ViewManager) view.View {
+	v := &MarkdownExample{
+		BaseView: &view.BaseView{},
+		vm:       vm,
+	}
+	v.md.OnLinkClicked = v.openLink
+	v.md.ImageDir = "."
+	editorURL := view.BuildURL(EditorExampleViewID, nil)
+	v.md.SetText(fmt.Sprintf(markdownExample, editorURL.String()))
+	return v
+}
@@ -62,9 +62,8 @@ func (vw *ExampleView) Actions() []view.ViewAction {
 			OnClicked: func(gtx C) {},
 		},
 		{
-			Name:      "History",
-			Icon:      historyIcon,
-			OnClicked: func(gtx C) {},
+			Icon:    historyIcon,
+			Command: vw.vm.Commands().Command(view.GoBackCommand),
 		},
 	}
 }
@@ -188,7 +187,12 @@ func (vw *ExampleView) Layout(gtx layout.Context, th *theme.Theme) layout.Dimens
 								},
 							},
 						},
+						{
+							menu.MenuOption{Command: vw.vm.Commands().Command(view.SplitRightCommand)},
+							menu.MenuOption{Command: vw.vm.Commands().Command(view.CloseTabCommand)},
+						},
 					})
+					vw.menu.Commands = vw.vm.Commands()
 				}
 
 				if vw.showMenuBtn.Clicked(gtx) {
@@ -2,6 +2,7 @@ package explorer
 
 import (
 	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"os"
@@ -187,6 +188,52 @@ func newFileExplorer() *FileExplorer {
 	}
 }
 
+// NewFileExplorer creates a file explorer showing the home directory. Entries
+// rejected by the filter are not shown.
+func NewFileExplorer(filter EntryFilter) *FileExplorer {
+	exp := newFileExplorer()
+	exp.entryFilter = filter
+	return exp
+}
+
+// OpenDir shows the entries of the directory.
+func (exp *FileExplorer) OpenDir(dir string) error {
+	st, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("not a directory: %s", dir)
+	}
+
+	exp.viewer = newEntryViewer(dir, exp.history, exp.entryFilter)
+	exp.favorites.lastSelected = -1
+	exp.locations.lastSelected = -1
+	return nil
+}
+
+// CurrentDir returns the directory shown in the explorer.
+func (exp *FileExplorer) CurrentDir() string {
+	if exp.viewer == nil {
+		return ""
+	}
+	return exp.viewer.entryTree.Path
+}
+
+// SelectedPaths returns the paths of the selected entries.
+func (exp *FileExplorer) SelectedPaths() []string {
+	if exp.viewer == nil {
+		return nil
+	}
+
+	var paths []string
+	for item := range exp.viewer.selectedItems {
+		paths = append(paths, item.node.Path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
 func (exp *FileExplorer) Update(gtx C) {
 	if exp.favorites.update(gtx) {
 		exp.viewer = newEntryViewer(exp.favorites.dirs[exp.favorites.lastSelected], exp.history, exp.entryFilter)
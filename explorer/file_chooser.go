@@ -34,22 +34,20 @@ const (
 
 var (
 	fileChooserID = view.NewViewID("FileChooser")
-)
 
-type result struct {
-	paths []string
-	err   error
-}
+	opParam       = view.NewParamKey[opKind]("op").Required()
+	filenameParam = view.NewParamKey[string]("filename")
+	filterParam   = view.NewParamKey[EntryFilter]("filter")
+)
 
 type FileChooser struct {
-	vm         view.ViewManager
-	resultChan chan result
+	vm view.ViewManager
 }
 
 type FileChooserDialog struct {
 	*view.BaseView
 	fileExplorer *FileExplorer
-	resultChan   chan result
+	result       *view.ModalResult[[]string]
 	op           opKind
 }
 
@@ -77,8 +75,7 @@ func NewFileChooser(vm view.ViewManager) (*FileChooser, error) {
 	}
 
 	return &FileChooser{
-		vm:         vm,
-		resultChan: make(chan result),
+		vm: vm,
 	}, nil
 }
 
@@ -86,12 +83,15 @@ func NewFileChooser(vm view.ViewManager) (*FileChooser, error) {
 // some file, which the use can choose the location. It's important to
 // close the `io.WriteCloser`.
 //
-// It's a blocking call, you should call it on a separated goroutine.
+// It's a blocking call, you should call it on a separated goroutine. If the user
+// cancels the file chooser, view.ErrModalCancelled is returned.
 func (fc *FileChooser) CreateFile(name string) (io.WriteCloser, error) {
-	fc.show(saveFileOp, name)
+	paths, err := fc.show(saveFileOp, name)
+	if err != nil {
+		return nil, err
+	}
 
-	resp := <-fc.resultChan
-	return os.Create(resp.paths[0])
+	return os.Create(paths[0])
 }
 
 // ChooseFile shows the file chooser, allowing the user to select a single file. It returns the
@@ -102,10 +102,12 @@ func (fc *FileChooser) CreateFile(name string) (io.WriteCloser, error) {
 // Optionally, it's possible to set which file extensions is supported to
 // be selected (such as `.jpg`, `.png`).
 func (fc *FileChooser) ChooseFile(extensions ...string) (io.ReadCloser, error) {
-	fc.show(openFileOp, "", extensions...)
+	paths, err := fc.show(openFileOp, "", extensions...)
+	if err != nil {
+		return nil, err
+	}
 
-	resp := <-fc.resultChan
-	return os.Open(resp.paths[0])
+	return os.Open(paths[0])
 }
 
 // ChooseFile shows the file chooser, allowing the user to select multiple files. It returns the files as
@@ -115,11 +117,13 @@ func (fc *FileChooser) ChooseFile(extensions ...string) (io.ReadCloser, error) {
 // Optionally, it's possible to set which file extensions is supported to
 // be selected (such as `.jpg`, `.png`).
 func (fc *FileChooser) ChooseFiles(extensions ...string) ([]io.ReadCloser, error) {
-	fc.show(openFilesOp, "", extensions...)
+	paths, err := fc.show(openFilesOp, "", extensions...)
+	if err != nil {
+		return nil, err
+	}
 
-	resp := <-fc.resultChan
-	readers := make([]io.ReadCloser, len(resp.paths))
-	for idx, path := range resp.paths {
+	readers := make([]io.ReadCloser, len(paths))
+	for idx, path := range paths {
 		d, err := os.Open(path)
 		if err != nil {
 			return nil, err
@@ -134,23 +138,27 @@ func (fc *FileChooser) ChooseFiles(extensions ...string) ([]io.ReadCloser, error
 // ChooseFolder shows the file chooser, allowing the user to select a single folder. It returns the folder
 // path to user. This is a blocking call, you should call it in a seperated goroutine.
 func (fc *FileChooser) ChooseFolder() (string, error) {
-	fc.show(openFolderOp, "")
+	paths, err := fc.show(openFolderOp, "")
+	if err != nil {
+		return "", err
+	}
 
-	resp := <-fc.resultChan
-	return resp.paths[0], nil
+	return paths[0], nil
 }
 
-func (fc *FileChooser) show(op opKind, filename string, extensions ...string) {
-	params := map[string]interface{}{"resultChan": fc.resultChan, "op": op}
+// show opens the file chooser dialog and waits for the chosen paths.
+func (fc *FileChooser) show(op opKind, filename string, extensions ...string) ([]string, error) {
+	params := view.NewParams(
+		opParam.With(op),
+		filterParam.With(chooserFilter(op, extensions...)),
+	)
 	if op == saveFileOp {
-		params["filename"] = filename
+		filenameParam.Set(params, filename)
 	}
 
-	params["filter"] = chooserFilter(op, extensions...)
-	fc.vm.RequestSwitch(view.Intent{
-		Target:      fileChooserID,
-		ShowAsModal: true,
-		Params:      params,
+	return view.AwaitModal[[]string](fc.vm, view.Intent{
+		Target: fileChooserID,
+		Params: params,
 	})
 }
 
@@ -189,30 +197,35 @@ func (vw *FileChooserDialog) Title() string {
 	return "File Chooser"
 }
 
+func (vw *FileChooserDialog) DeclaredParams() []view.ParamSpec {
+	return []view.ParamSpec{opParam, filenameParam, filterParam}
+}
+
 func (vw *FileChooserDialog) OnNavTo(intent view.Intent) error {
 	vw.BaseView.OnNavTo(intent)
-	rc, ok := intent.Params["resultChan"]
-	if !ok {
-		return errors.New("missing mandatory params")
+	result, err := view.ModalResultOf[[]string](intent)
+	if err != nil {
+		return err
 	}
 
-	opVal, ok := intent.Params["op"]
-	if !ok {
-		return errors.New("missing mandatory params")
+	op, err := opParam.Get(intent.Params)
+	if err != nil {
+		return err
 	}
 
-	op := opVal.(opKind)
-	vw.resultChan = rc.(chan result)
+	vw.result = result
 	vw.fileExplorer.bottomPanel.op = op
 	vw.op = op
 	if op == saveFileOp {
-		param := intent.Params["filename"]
-		vw.fileExplorer.bottomPanel.saveFileInput.SetText(param.(string))
+		vw.fileExplorer.bottomPanel.saveFileInput.SetText(filenameParam.Value(intent.Params))
 	}
 
 	vw.fileExplorer.bottomPanel.addFolderInput.SetText("untitled folder")
 
-	vw.fileExplorer.bottomPanel.cancelCb = func() { vw.OnFinish() }
+	vw.fileExplorer.bottomPanel.cancelCb = func() {
+		vw.result.Cancel()
+		vw.OnFinish()
+	}
 	vw.fileExplorer.bottomPanel.confirmCb = func() error {
 		currentPath := vw.fileExplorer.viewer.entryTree.Path
 
@@ -223,7 +236,7 @@ func (vw *FileChooserDialog) OnNavTo(intent view.Intent) error {
 				return errors.New("Empty filename")
 			}
 
-			vw.resultChan <- result{paths: []string{filepath.Join(currentPath, filename)}}
+			vw.result.Resolve([]string{filepath.Join(currentPath, filename)})
 		case openFileOp, openFilesOp, openFolderOp:
 			paths := make([]string, 0)
 
@@ -246,7 +259,7 @@ func (vw *FileChooserDialog) OnNavTo(intent view.Intent) error {
 				paths = append(paths, item.node.Path)
 			}
 
-			vw.resultChan <- result{paths: paths}
+			vw.result.Resolve(paths)
 		}
 
 		vw.OnFinish()
@@ -262,8 +275,8 @@ func (vw *FileChooserDialog) OnNavTo(intent view.Intent) error {
 		return err
 	}
 
-	if f, ok := intent.Params["filter"]; ok {
-		vw.fileExplorer.entryFilter = f.(EntryFilter)
+	if f, err := filterParam.Get(intent.Params); err == nil {
+		vw.fileExplorer.entryFilter = f
 	}
 
 	return nil
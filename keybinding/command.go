@@ -0,0 +1,352 @@
+// Package keybinding implements the commands of an application and the key
+// chords they are bound to. A Registry runs the commands of the application
+// on their key chords, while the widgets of gioview handle the key chords of
+// the widget commands themselves, looking them up in the registry they are
+// given.
+package keybinding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+)
+
+// ErrBindingConflict is returned by LoadBindings if a key chord is bound to more
+// than one command.
+var ErrBindingConflict = errors.New("key chord bound to multiple commands")
+
+// CommandID identifies a command, e.g., "view.closeTab".
+type CommandID string
+
+// Command is an application command, which can be run by its key chord, or
+// be referenced by a view action or a menu option.
+type Command struct {
+	ID CommandID
+	// Title is the human readable name of the command.
+	Title string
+	// Key is the default key chord of the command. It can be overridden by
+	// the user bindings of the registry.
+	Key KeyChord
+	// Handler runs the command. A command without a handler is a widget
+	// command, which is run by the focused widget handling its key chord
+	// rather than by the registry, e.g., the copy command of an editor.
+	Handler func(gtx layout.Context)
+	// Enabled reports whether the command can be run. A command without
+	// the predicate is always enabled.
+	Enabled func() bool
+
+	registry *Registry
+}
+
+// IsEnabled reports whether the command can be run.
+func (c *Command) IsEnabled() bool {
+	return c.Enabled == nil || c.Enabled()
+}
+
+// Run runs the command if it is enabled, and reports whether it was run.
+func (c *Command) Run(gtx layout.Context) bool {
+	if c.Handler == nil || !c.IsEnabled() {
+		return false
+	}
+	c.Handler(gtx)
+	return true
+}
+
+// Binding returns the key chord the command is bound to. It is the zero
+// KeyChord if the command is not bound.
+func (c *Command) Binding() KeyChord {
+	if c.registry == nil {
+		return c.Key
+	}
+	return c.registry.Binding(c.ID)
+}
+
+// Shortcut returns the key chord of the command for display, or an empty
+// string if the command is not bound.
+func (c *Command) Shortcut() string {
+	return c.Binding().String()
+}
+
+// BindingConflict is a key chord bound to more than one command.
+type BindingConflict struct {
+	Key      KeyChord
+	Commands []CommandID
+}
+
+// Registry holds the commands of an application, and the key chords they
+// are bound to. The view managers of all the windows share one, see
+// view.ViewManager.Commands. The zero value is ready to use.
+//
+// The key chords are handled by Update, which is to be called at every frame
+// before the views are laid out. Key events consumed by the focused widget
+// are not seen by the registry. If a key chord is bound to more than one
+// command, the first enabled one in the registration order is run.
+//
+// Widgets look up the key chords of the widget commands with Filters and
+// Match. A nil registry has the default key chords of the built-in widget
+// commands.
+type Registry struct {
+	mu       sync.RWMutex
+	commands []*Command
+	// user bindings overriding the default key chords. A zero KeyChord
+	// unbinds the command.
+	bindings map[CommandID]KeyChord
+}
+
+// Register adds a command to the registry.
+func (r *Registry) Register(cmd *Command) error {
+	if cmd.ID == "" {
+		return errors.New("cannot register empty command ID")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lookup(cmd.ID) != nil {
+		return fmt.Errorf("command %s is already registered", cmd.ID)
+	}
+	cmd.registry = r
+	r.commands = append(r.commands, cmd)
+	return nil
+}
+
+// Unregister removes the command of the ID from the registry.
+func (r *Registry) Unregister(id CommandID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = slices.DeleteFunc(r.commands, func(cmd *Command) bool {
+		if cmd.ID == id {
+			cmd.registry = nil
+			return true
+		}
+		return false
+	})
+}
+
+// Command returns the command of the ID, or nil if there is none.
+func (r *Registry) Command(id CommandID) *Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lookup(id)
+}
+
+func (r *Registry) lookup(id CommandID) *Command {
+	for _, cmd := range r.commands {
+		if cmd.ID == id {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Commands returns the registered commands in their registration order.
+func (r *Registry) Commands() []*Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.commands)
+}
+
+// Run runs the command of the ID if it is enabled, and reports whether it was
+// run.
+func (r *Registry) Run(gtx layout.Context, id CommandID) bool {
+	cmd := r.Command(id)
+	return cmd != nil && cmd.Run(gtx)
+}
+
+// Binding returns the key chord the command of the ID is bound to, which is
+// the user binding if there is one, or the default key chord of the command.
+func (r *Registry) Binding(id CommandID) KeyChord {
+	if r == nil {
+		return defaultKey(id)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.binding(id)
+}
+
+func (r *Registry) binding(id CommandID) KeyChord {
+	if k, ok := r.bindings[id]; ok {
+		return k
+	}
+	if cmd := r.lookup(id); cmd != nil {
+		return cmd.Key
+	}
+	return defaultKey(id)
+}
+
+// SetBinding binds the command of the ID to the key chord, overriding its
+// default one. The zero KeyChord unbinds the command. The command needs not
+// be registered yet.
+func (r *Registry) SetBinding(id CommandID, k KeyChord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.bindings == nil {
+		r.bindings = make(map[CommandID]KeyChord)
+	}
+	r.bindings[id] = k
+}
+
+// ResetBindings removes the user bindings, restoring the default key chords.
+func (r *Registry) ResetBindings() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bindings = nil
+}
+
+// LoadBindings reads user bindings from a JSON object mapping command IDs to
+// key chords, e.g.:
+//
+//	{"view.closeTab": "Mod+W", "view.splitRight": ""}
+//
+// An empty key chord unbinds the command. The bindings are added to the ones
+// set before. If the resulting bindings conflict, the bindings are kept and an
+// error wrapping ErrBindingConflict is returned.
+func (r *Registry) LoadBindings(reader io.Reader) error {
+	var raw map[CommandID]string
+	if err := json.NewDecoder(reader).Decode(&raw); err != nil {
+		return fmt.Errorf("invalid key bindings: %w", err)
+	}
+
+	bindings := make(map[CommandID]KeyChord, len(raw))
+	for id, s := range raw {
+		var k KeyChord
+		if s != "" {
+			var err error
+			if k, err = ParseKeyChord(s); err != nil {
+				return fmt.Errorf("invalid key binding of %s: %w", id, err)
+			}
+		}
+		bindings[id] = k
+	}
+
+	for id, k := range bindings {
+		r.SetBinding(id, k)
+	}
+
+	if conflicts := r.Conflicts(); len(conflicts) > 0 {
+		var descs []string
+		for _, c := range conflicts {
+			descs = append(descs, fmt.Sprintf("%s: %v", c.Key, c.Commands))
+		}
+		return fmt.Errorf("%w: %s", ErrBindingConflict, strings.Join(descs, "; "))
+	}
+	return nil
+}
+
+// Conflicts returns the key chords bound to more than one registered command.
+// The widget commands only conflict with each other, as the focused widget
+// handles their key chords before the registry.
+func (r *Registry) Conflicts() []BindingConflict {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var conflicts []BindingConflict
+	for i, cmd := range r.commands {
+		k := r.binding(cmd.ID)
+		same := func(c *Command) bool {
+			return (c.Handler == nil) == (cmd.Handler == nil) && r.binding(c.ID) == k
+		}
+		if k == (KeyChord{}) || slices.ContainsFunc(r.commands[:i], same) {
+			continue
+		}
+		conflict := BindingConflict{Key: k, Commands: []CommandID{cmd.ID}}
+		for _, other := range r.commands[i+1:] {
+			if same(other) {
+				conflict.Commands = append(conflict.Commands, other.ID)
+			}
+		}
+		if len(conflict.Commands) > 1 {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts
+}
+
+// Update runs the commands whose key chords are pressed.
+func (r *Registry) Update(gtx layout.Context) {
+	r.mu.RLock()
+	var filters []event.Filter
+	var chords []KeyChord
+	for _, cmd := range r.commands {
+		k := r.binding(cmd.ID)
+		if cmd.Handler == nil || k == (KeyChord{}) || slices.Contains(chords, k) {
+			continue
+		}
+		chords = append(chords, k)
+		filters = append(filters, key.Filter{Name: k.Name, Required: k.Modifiers})
+	}
+	r.mu.RUnlock()
+
+	if len(filters) == 0 {
+		return
+	}
+
+	for {
+		e, ok := gtx.Event(filters...)
+		if !ok {
+			break
+		}
+		if e, ok := e.(key.Event); ok && e.State == key.Press {
+			r.dispatch(gtx, KeyChord{Name: e.Name, Modifiers: e.Modifiers})
+		}
+	}
+}
+
+// dispatch runs the first enabled command bound to the key chord.
+func (r *Registry) dispatch(gtx layout.Context, k KeyChord) bool {
+	r.mu.RLock()
+	var matched []*Command
+	for _, cmd := range r.commands {
+		if cmd.Handler != nil && r.binding(cmd.ID) == k {
+			matched = append(matched, cmd)
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, cmd := range matched {
+		if cmd.Run(gtx) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filters returns the filters of the key chords of the widget commands of the
+// IDs, for a widget focused as tag. The keypad Enter key is filtered with the
+// key chords of Enter.
+func (r *Registry) Filters(tag event.Tag, ids ...CommandID) []event.Filter {
+	var filters []event.Filter
+	for _, id := range ids {
+		k := r.Binding(id)
+		if k == (KeyChord{}) {
+			continue
+		}
+		filters = append(filters, key.Filter{Focus: tag, Name: k.Name, Required: k.Modifiers})
+		if k.Name == key.NameReturn {
+			filters = append(filters, key.Filter{Focus: tag, Name: key.NameEnter, Required: k.Modifiers})
+		}
+	}
+	return filters
+}
+
+// Match returns the first command of the IDs whose key chord is the one of the
+// key event, whatever its state.
+func (r *Registry) Match(e key.Event, ids ...CommandID) (CommandID, bool) {
+	k := KeyChord{Name: e.Name, Modifiers: e.Modifiers}
+	if k.Name == key.NameEnter {
+		k.Name = key.NameReturn
+	}
+	for _, id := range ids {
+		if b := r.Binding(id); b != (KeyChord{}) && b == k {
+			return id, true
+		}
+	}
+	return "", false
+}
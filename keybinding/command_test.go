@@ -0,0 +1,166 @@
+package keybinding
+
+import (
+	"errors"
+	"image"
+	"slices"
+	"strings"
+	"testing"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+
+	"github.com/oligo/gioview/uitest"
+)
+
+func TestParseKeyChord(t *testing.T) {
+	cases := []struct {
+		chord string
+		want  KeyChord
+	}{
+		{"Ctrl+Shift+P", KeyChord{Name: "P", Modifiers: key.ModCtrl | key.ModShift}},
+		{"mod+w", KeyChord{Name: "W", Modifiers: key.ModShortcut}},
+		{"Alt+Left", KeyChord{Name: key.NameLeftArrow, Modifiers: key.ModAlt}},
+		{"F12", KeyChord{Name: key.NameF12}},
+		{"Ctrl++", KeyChord{Name: "+", Modifiers: key.ModCtrl}},
+	}
+	for _, tc := range cases {
+		k, err := ParseKeyChord(tc.chord)
+		if err != nil || k != tc.want {
+			t.Errorf("%q: got %+v, %v, want %+v", tc.chord, k, err, tc.want)
+		}
+		if parsed, _ := ParseKeyChord(k.String()); parsed != k {
+			t.Errorf("%q: %q does not parse back", tc.chord, k.String())
+		}
+	}
+
+	for _, chord := range []string{"", "Ctrl+", "Hyper+A", "Ctrl+Foo", "F13"} {
+		if _, err := ParseKeyChord(chord); err == nil {
+			t.Errorf("%q: no error", chord)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	var reg Registry
+	var ran []CommandID
+	newCommand := func(id CommandID, chord string, enabled *bool) *Command {
+		k, _ := ParseKeyChord(chord)
+		return &Command{
+			ID:      id,
+			Key:     k,
+			Handler: func(gtx layout.Context) { ran = append(ran, id) },
+			Enabled: func() bool { return enabled == nil || *enabled },
+		}
+	}
+
+	saveEnabled := false
+	for _, cmd := range []*Command{
+		newCommand("save", "Ctrl+S", &saveEnabled),
+		newCommand("saveAll", "Ctrl+Shift+S", nil),
+		newCommand("search", "Ctrl+F", nil),
+	} {
+		if err := reg.Register(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := reg.Register(newCommand("save", "", nil)); err == nil {
+		t.Fatal("duplicate command is registered")
+	}
+
+	// The user bindings override the default key chords, and conflict.
+	err := reg.LoadBindings(strings.NewReader(`{"search": "Ctrl+S", "saveAll": ""}`))
+	if !errors.Is(err, ErrBindingConflict) {
+		t.Fatalf("got error %v", err)
+	}
+	conflicts := reg.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Key.String() != "Ctrl+S" || !slices.Equal(conflicts[0].Commands, []CommandID{"save", "search"}) {
+		t.Fatalf("got conflicts %+v", conflicts)
+	}
+	if s := reg.Command("saveAll").Shortcut(); s != "" {
+		t.Fatalf("unbound command has shortcut %q", s)
+	}
+
+	// The first enabled command of the key chord is run.
+	ctrlS, _ := ParseKeyChord("Ctrl+S")
+	reg.dispatch(layout.Context{}, ctrlS)
+	saveEnabled = true
+	reg.dispatch(layout.Context{}, ctrlS)
+	if !slices.Equal(ran, []CommandID{"search", "save"}) {
+		t.Fatalf("got commands run %v", ran)
+	}
+
+	reg.ResetBindings()
+	if len(reg.Conflicts()) != 0 || reg.Binding("saveAll").String() != "Ctrl+Shift+S" {
+		t.Fatal("default key chords are not restored")
+	}
+
+	if err := reg.LoadBindings(strings.NewReader(`{"save": "Ctrl+Hyper+S"}`)); err == nil || errors.Is(err, ErrBindingConflict) {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestCommandKeys(t *testing.T) {
+	var reg Registry
+	runs, enabled := 0, true
+	err := reg.Register(&Command{
+		ID:      "note.new",
+		Title:   "New Note",
+		Key:     KeyChord{Name: "N", Modifiers: key.ModShortcut},
+		Handler: func(gtx layout.Context) { runs++ },
+		Enabled: func() bool { return enabled },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := uitest.New(image.Pt(100, 100), func(gtx layout.Context) layout.Dimensions {
+		reg.Update(gtx)
+		return layout.Dimensions{Size: gtx.Constraints.Max}
+	})
+	h.Frame()
+
+	h.Key("N", key.ModShortcut)
+	h.Key("N", key.ModShortcut|key.ModShift)
+	if runs != 1 {
+		t.Fatalf("command run %d times by its key chord", runs)
+	}
+	enabled = false
+	h.Key("N", key.ModShortcut)
+	if runs != 1 {
+		t.Fatalf("disabled command is run")
+	}
+}
+
+func TestWidgetCommands(t *testing.T) {
+	copyKey := key.Event{Name: "C", Modifiers: key.ModShortcut}
+	enter := key.Event{Name: key.NameEnter}
+
+	// A nil registry has the default key chords.
+	var reg *Registry
+	if id, ok := reg.Match(copyKey, CutCommand, CopyCommand); !ok || id != CopyCommand {
+		t.Fatalf("got %v, %v", id, ok)
+	}
+	if id, ok := reg.Match(enter, MenuSelectCommand); !ok || id != MenuSelectCommand {
+		t.Fatalf("keypad Enter does not match: %v, %v", id, ok)
+	}
+	if filters := reg.Filters(new(int), MenuSelectCommand, CopyCommand); len(filters) != 3 {
+		t.Fatalf("got filters %v", filters)
+	}
+
+	// The widget commands are rebound, and are not run by the registry.
+	reg = &Registry{}
+	reg.SetBinding(CopyCommand, KeyChord{Name: "C", Modifiers: key.ModShortcut | key.ModShift})
+	if _, ok := reg.Match(copyKey, CopyCommand); ok {
+		t.Fatal("rebound command matches its default key chord")
+	}
+	copyKey.Modifiers |= key.ModShift
+	if _, ok := reg.Match(copyKey, CopyCommand); !ok {
+		t.Fatal("rebound command does not match its key chord")
+	}
+	if err := reg.Register(&Command{ID: CopyCommand, Title: "Copy"}); err != nil {
+		t.Fatal(err)
+	}
+	if reg.dispatch(layout.Context{}, reg.Binding(CopyCommand)) {
+		t.Fatal("widget command is run by the registry")
+	}
+}
@@ -0,0 +1,131 @@
+package keybinding
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"gioui.org/io/key"
+)
+
+// KeyChord is a key pressed with modifiers.
+type KeyChord struct {
+	Name      key.Name
+	Modifiers key.Modifiers
+}
+
+// keyNames maps the readable names of the special keys to their key names.
+var keyNames = map[string]key.Name{
+	"Left":      key.NameLeftArrow,
+	"Right":     key.NameRightArrow,
+	"Up":        key.NameUpArrow,
+	"Down":      key.NameDownArrow,
+	"Enter":     key.NameReturn,
+	"Escape":    key.NameEscape,
+	"Home":      key.NameHome,
+	"End":       key.NameEnd,
+	"Backspace": key.NameDeleteBackward,
+	"Delete":    key.NameDeleteForward,
+	"PageUp":    key.NamePageUp,
+	"PageDown":  key.NamePageDown,
+	"Tab":       key.NameTab,
+	"Space":     key.NameSpace,
+}
+
+var functionKeys = []key.Name{
+	key.NameF1, key.NameF2, key.NameF3, key.NameF4, key.NameF5, key.NameF6,
+	key.NameF7, key.NameF8, key.NameF9, key.NameF10, key.NameF11, key.NameF12,
+}
+
+// ParseKeyChord parses a key chord of modifiers and a key name joined by '+',
+// e.g., "Ctrl+Shift+P" or "Mod+S". The modifiers are Ctrl, Shift, Alt (or
+// Option), Cmd, Super, and Mod for the shortcut modifier of the platform, which
+// is Cmd on macOS and Ctrl elsewhere. Special keys are named Left, Right, Up,
+// Down, Enter, Escape, Home, End, Backspace, Delete, PageUp, PageDown, Tab,
+// Space and F1 to F12. Names are not case sensitive.
+func ParseKeyChord(s string) (KeyChord, error) {
+	parts := strings.Split(strings.TrimSpace(s), "+")
+	if n := len(parts); n > 1 && parts[n-1] == "" && parts[n-2] == "" {
+		// The key is '+' itself.
+		parts = append(parts[:n-2], "+")
+	}
+
+	var k KeyChord
+	for _, mod := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(mod)) {
+		case "ctrl", "control":
+			k.Modifiers |= key.ModCtrl
+		case "shift":
+			k.Modifiers |= key.ModShift
+		case "alt", "option":
+			k.Modifiers |= key.ModAlt
+		case "cmd", "command":
+			k.Modifiers |= key.ModCommand
+		case "super":
+			k.Modifiers |= key.ModSuper
+		case "mod", "shortcut":
+			k.Modifiers |= key.ModShortcut
+		default:
+			return KeyChord{}, fmt.Errorf("unknown modifier %q in key chord %q", mod, s)
+		}
+	}
+
+	name := strings.TrimSpace(parts[len(parts)-1])
+	switch {
+	case name == "":
+		return KeyChord{}, fmt.Errorf("no key in key chord %q", s)
+	case len([]rune(name)) == 1:
+		k.Name = key.Name(strings.ToUpper(name))
+	default:
+		for readable, n := range keyNames {
+			if strings.EqualFold(name, readable) {
+				k.Name = n
+			}
+		}
+		for _, n := range functionKeys {
+			if strings.EqualFold(name, string(n)) {
+				k.Name = n
+			}
+		}
+		if k.Name == "" {
+			return KeyChord{}, fmt.Errorf("unknown key %q in key chord %q", name, s)
+		}
+	}
+	return k, nil
+}
+
+// String returns the key chord in the form parsed by ParseKeyChord, e.g.,
+// "Ctrl+Shift+P". It is empty for the zero KeyChord.
+func (k KeyChord) String() string {
+	if k == (KeyChord{}) {
+		return ""
+	}
+	var parts []string
+	if k.Modifiers.Contain(key.ModCtrl) {
+		parts = append(parts, "Ctrl")
+	}
+	if k.Modifiers.Contain(key.ModAlt) {
+		if runtime.GOOS == "darwin" {
+			parts = append(parts, "Option")
+		} else {
+			parts = append(parts, "Alt")
+		}
+	}
+	if k.Modifiers.Contain(key.ModShift) {
+		parts = append(parts, "Shift")
+	}
+	if k.Modifiers.Contain(key.ModCommand) {
+		parts = append(parts, "Cmd")
+	}
+	if k.Modifiers.Contain(key.ModSuper) {
+		parts = append(parts, "Super")
+	}
+
+	name := string(k.Name)
+	for readable, n := range keyNames {
+		if n == k.Name {
+			name = readable
+		}
+	}
+	return strings.Join(append(parts, name), "+")
+}
@@ -0,0 +1,62 @@
+package keybinding
+
+import (
+	"gioui.org/io/key"
+)
+
+// The widget commands of gioview. They have no handler: the focused editor,
+// Transferable or menu runs them, using the key chords of the registry it is
+// given, see Registry.Filters. WidgetCommands returns them to be registered.
+const (
+	CopyCommand                 CommandID = "edit.copy"
+	CutCommand                  CommandID = "edit.cut"
+	PasteCommand                CommandID = "edit.paste"
+	SelectAllCommand            CommandID = "edit.selectAll"
+	SelectNextOccurrenceCommand CommandID = "edit.selectNextOccurrence"
+	UndoCommand                 CommandID = "edit.undo"
+	RedoCommand                 CommandID = "edit.redo"
+	CompleteCommand             CommandID = "edit.complete"
+
+	MenuNextCommand     CommandID = "menu.next"
+	MenuPreviousCommand CommandID = "menu.previous"
+	MenuSelectCommand   CommandID = "menu.select"
+	MenuCloseCommand    CommandID = "menu.close"
+)
+
+// widgetCommands are the widget commands with their default key chords.
+var widgetCommands = []Command{
+	{ID: CopyCommand, Title: "Copy", Key: KeyChord{Name: "C", Modifiers: key.ModShortcut}},
+	{ID: CutCommand, Title: "Cut", Key: KeyChord{Name: "X", Modifiers: key.ModShortcut}},
+	{ID: PasteCommand, Title: "Paste", Key: KeyChord{Name: "V", Modifiers: key.ModShortcut}},
+	{ID: SelectAllCommand, Title: "Select All", Key: KeyChord{Name: "A", Modifiers: key.ModShortcut}},
+	{ID: SelectNextOccurrenceCommand, Title: "Select Next Occurrence", Key: KeyChord{Name: "D", Modifiers: key.ModShortcut}},
+	{ID: UndoCommand, Title: "Undo", Key: KeyChord{Name: "Z", Modifiers: key.ModShortcut}},
+	{ID: RedoCommand, Title: "Redo", Key: KeyChord{Name: "Z", Modifiers: key.ModShortcut | key.ModShift}},
+	{ID: CompleteCommand, Title: "Trigger Completion", Key: KeyChord{Name: key.NameSpace, Modifiers: key.ModCtrl}},
+
+	{ID: MenuNextCommand, Title: "Next Menu Option", Key: KeyChord{Name: key.NameDownArrow}},
+	{ID: MenuPreviousCommand, Title: "Previous Menu Option", Key: KeyChord{Name: key.NameUpArrow}},
+	{ID: MenuSelectCommand, Title: "Select Menu Option", Key: KeyChord{Name: key.NameReturn}},
+	{ID: MenuCloseCommand, Title: "Close Menu", Key: KeyChord{Name: key.NameEscape}},
+}
+
+// WidgetCommands returns new copies of the widget commands, with their
+// default key chords.
+func WidgetCommands() []*Command {
+	commands := make([]*Command, len(widgetCommands))
+	for i := range widgetCommands {
+		cmd := widgetCommands[i]
+		commands[i] = &cmd
+	}
+	return commands
+}
+
+// defaultKey returns the default key chord of a widget command.
+func defaultKey(id CommandID) KeyChord {
+	for _, cmd := range widgetCommands {
+		if cmd.ID == id {
+			return cmd.Key
+		}
+	}
+	return KeyChord{}
+}
@@ -0,0 +1,136 @@
+// Package markdown parses CommonMark, with the GitHub Flavored Markdown tables,
+// task lists, strikethrough and autolinks, and renders it as a read-only rich
+// text widget with syntax colored code blocks, images, clickable links and
+// text selection.
+package markdown
+
+import (
+	"strings"
+)
+
+// BlockKind is the kind of a block.
+type BlockKind uint8
+
+const (
+	Paragraph BlockKind = iota
+	Heading
+	ThematicBreak
+	CodeBlock
+	BlockQuote
+	List
+	ListItem
+	Table
+)
+
+// Alignment is the alignment of a table column.
+type Alignment uint8
+
+const (
+	AlignNone Alignment = iota
+	AlignLeft
+	AlignCenter
+	AlignRight
+)
+
+// Document is a parsed markdown document.
+type Document struct {
+	Blocks []*Block
+}
+
+// Block is a block of a document. Fields not used by the kind of the block are
+// left zero.
+type Block struct {
+	Kind BlockKind
+	// Level is the level of a heading, from 1 to 6.
+	Level int
+	// Inlines is the content of a paragraph or a heading.
+	Inlines []Inline
+	// Children are the blocks of a block quote or a list item, or the items
+	// of a list.
+	Children []*Block
+	// Info is the info string of a fenced code block, whose first word is
+	// the language of the code.
+	Info string
+	// Literal is the text of a code block.
+	Literal string
+	// Ordered reports whether a list is numbered, from Start.
+	Ordered bool
+	Start   int
+	// Tight reports whether the items of a list are not separated by blank
+	// lines, in which case their paragraphs are laid out without spacing.
+	Tight bool
+	// Task reports whether a list item is a task list item, and Checked
+	// whether it is done.
+	Task, Checked bool
+	// Align is the alignment of the columns of a table.
+	Align []Alignment
+	// Rows are the rows of a table, the header row first.
+	Rows [][]Cell
+
+	// raw is the unparsed inline content of a paragraph or a heading.
+	raw string
+}
+
+// Lang returns the language of a fenced code block.
+func (b *Block) Lang() string {
+	lang, _, _ := strings.Cut(b.Info, " ")
+	return lang
+}
+
+// Cell is a cell of a table.
+type Cell struct {
+	Inlines []Inline
+	raw     string
+}
+
+// InlineKind is the kind of an inline.
+type InlineKind uint8
+
+const (
+	Text InlineKind = iota
+	// SoftBreak is a line break in the source, laid out as a space.
+	SoftBreak
+	// LineBreak is a hard line break.
+	LineBreak
+	CodeSpan
+	Emphasis
+	Strong
+	Strikethrough
+	Link
+	Image
+)
+
+// Inline is the inline content of a paragraph, a heading or a table cell.
+type Inline struct {
+	Kind InlineKind
+	// Text is the content of a text or a code span.
+	Text string
+	// Dest is the destination of a link, or the source of an image.
+	Dest  string
+	Title string
+	// Children are the content of an emphasis or a link, or the description
+	// of an image.
+	Children []Inline
+}
+
+// PlainText returns the text of the inlines without any markup.
+func PlainText(inlines []Inline) string {
+	var b strings.Builder
+	writePlainText(&b, inlines)
+	return b.String()
+}
+
+func writePlainText(b *strings.Builder, inlines []Inline) {
+	for _, in := range inlines {
+		switch in.Kind {
+		case Text, CodeSpan:
+			b.WriteString(in.Text)
+		case SoftBreak:
+			b.WriteByte(' ')
+		case LineBreak:
+			b.WriteByte('\n')
+		default:
+			writePlainText(b, in.Children)
+		}
+	}
+}
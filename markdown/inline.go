@@ -0,0 +1,687 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	uriAutolink   = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9.+-]{1,31}:[^<>\x00-\x20]*)>`)
+	emailAutolink = regexp.MustCompile(`^<([A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*)>`)
+	entity        = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	// extended autolinks of GitHub Flavored Markdown.
+	wwwAutolink = regexp.MustCompile(`^(?:https?://|www\.)[A-Za-z0-9_-]+(?:\.[A-Za-z0-9_-]+)*[^\s<]*`)
+)
+
+// node is an inline node while parsing. The nodes are kept in doubly linked
+// lists, so emphasis and links can take the nodes between their delimiters as
+// their children.
+type node struct {
+	kind        InlineKind
+	text        string
+	dest, title string
+
+	parent      *node
+	first, last *node
+	prev, next  *node
+}
+
+func (n *node) append(child *node) {
+	child.parent = n
+	child.prev = n.last
+	child.next = nil
+	if n.last != nil {
+		n.last.next = child
+	} else {
+		n.first = child
+	}
+	n.last = child
+}
+
+func (n *node) unlink() {
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else if n.parent != nil {
+		n.parent.first = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else if n.parent != nil {
+		n.parent.last = n.prev
+	}
+	n.parent, n.prev, n.next = nil, nil, nil
+}
+
+func (n *node) insertAfter(sibling *node) {
+	sibling.parent = n.parent
+	sibling.prev = n
+	sibling.next = n.next
+	if n.next != nil {
+		n.next.prev = sibling
+	} else if n.parent != nil {
+		n.parent.last = sibling
+	}
+	n.next = sibling
+}
+
+// delimiter is a run of '*', '_' or '~' which may open or close an emphasis.
+type delimiter struct {
+	node      *node
+	char      byte
+	count     int
+	origCount int
+	canOpen   bool
+	canClose  bool
+	prev      *delimiter
+	next      *delimiter
+}
+
+// bracket is an opening '[' or '![' of a link or an image.
+type bracket struct {
+	node  *node
+	image bool
+	// active is false for a '[' of a link containing another link.
+	active bool
+	// pos is the source position after the bracket.
+	pos int
+	// delim is the top of the delimiter stack when the bracket is pushed.
+	delim *delimiter
+	prev  *bracket
+}
+
+// inlineParser parses inline content as specified by the CommonMark spec.
+type inlineParser struct {
+	src      string
+	pos      int
+	refs     map[string]linkRef
+	root     *node
+	delims   *delimiter
+	brackets *bracket
+}
+
+func parseInline(src string, refs map[string]linkRef) []Inline {
+	p := &inlineParser{src: src, refs: refs, root: &node{}}
+	for p.pos < len(p.src) {
+		p.parseNext()
+	}
+	p.processEmphasis(nil)
+	return toInlines(p.root)
+}
+
+func (p *inlineParser) text(s string) *node {
+	n := &node{kind: Text, text: s}
+	p.root.append(n)
+	return n
+}
+
+func (p *inlineParser) parseNext() {
+	c := p.src[p.pos]
+	switch c {
+	case '\n':
+		p.lineBreak()
+	case '\\':
+		p.pos++
+		switch {
+		case p.pos < len(p.src) && p.src[p.pos] == '\n':
+			p.pos++
+			p.root.append(&node{kind: LineBreak})
+			p.pos = skipSpaces(p.src, p.pos, false)
+		case p.pos < len(p.src) && isASCIIPunct(p.src[p.pos]):
+			p.text(p.src[p.pos : p.pos+1])
+			p.pos++
+		default:
+			p.text("\\")
+		}
+	case '`':
+		p.codeSpan()
+	case '*', '_', '~':
+		p.delimiterRun(c)
+	case '[':
+		p.pushBracket(p.text("["), false, p.pos+1)
+		p.pos++
+	case '!':
+		if p.pos+1 < len(p.src) && p.src[p.pos+1] == '[' {
+			p.pushBracket(p.text("!["), true, p.pos+2)
+			p.pos += 2
+		} else {
+			p.text("!")
+			p.pos++
+		}
+	case ']':
+		p.closeBracket()
+	case '<':
+		p.autolink()
+	case '&':
+		if m := entity.FindString(p.src[p.pos:]); m != "" {
+			p.text(html.UnescapeString(m))
+			p.pos += len(m)
+		} else {
+			p.text("&")
+			p.pos++
+		}
+	default:
+		if p.extendedAutolink() {
+			return
+		}
+		end := p.pos + 1
+		for end < len(p.src) && !strings.ContainsRune("\n\\`*_~[]!<&hw", rune(p.src[end])) {
+			end++
+		}
+		p.text(p.src[p.pos:end])
+		p.pos = end
+	}
+}
+
+// lineBreak parses a line ending, which is a hard line break if it follows
+// two or more spaces.
+func (p *inlineParser) lineBreak() {
+	kind := SoftBreak
+	if last := p.root.last; last != nil && last.kind == Text {
+		trimmed := strings.TrimRight(last.text, " ")
+		if len(last.text)-len(trimmed) >= 2 {
+			kind = LineBreak
+		}
+		last.text = trimmed
+	}
+	p.root.append(&node{kind: kind})
+	p.pos = skipSpaces(p.src, p.pos+1, false)
+}
+
+func (p *inlineParser) codeSpan() {
+	start := p.pos
+	n := backtickRun(p.src, start)
+	for pos := start + n; pos < len(p.src); {
+		i := strings.IndexByte(p.src[pos:], '`')
+		if i < 0 {
+			break
+		}
+		pos += i
+		m := backtickRun(p.src, pos)
+		if m == n {
+			code := strings.ReplaceAll(p.src[start+n:pos], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			p.root.append(&node{kind: CodeSpan, text: code})
+			p.pos = pos + m
+			return
+		}
+		pos += m
+	}
+	// no matching backtick string.
+	p.text(p.src[start : start+n])
+	p.pos = start + n
+}
+
+func backtickRun(s string, pos int) int {
+	n := 0
+	for pos+n < len(s) && s[pos+n] == '`' {
+		n++
+	}
+	return n
+}
+
+func (p *inlineParser) delimiterRun(c byte) {
+	start := p.pos
+	end := start
+	for end < len(p.src) && p.src[end] == c {
+		end++
+	}
+	p.pos = end
+	n := end - start
+	if c == '~' && n > 2 {
+		p.text(p.src[start:end])
+		return
+	}
+
+	before, after := ' ', ' '
+	if start > 0 {
+		before, _ = utf8.DecodeLastRuneInString(p.src[:start])
+	}
+	if end < len(p.src) {
+		after, _ = utf8.DecodeRuneInString(p.src[end:])
+	}
+	leftFlanking := !unicode.IsSpace(after) &&
+		(!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	rightFlanking := !unicode.IsSpace(before) &&
+		(!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+
+	canOpen, canClose := leftFlanking, rightFlanking
+	if c == '_' {
+		canOpen = leftFlanking && (!rightFlanking || isPunct(before))
+		canClose = rightFlanking && (!leftFlanking || isPunct(after))
+	}
+
+	d := &delimiter{
+		node:      p.text(p.src[start:end]),
+		char:      c,
+		count:     n,
+		origCount: n,
+		canOpen:   canOpen,
+		canClose:  canClose,
+		prev:      p.delims,
+	}
+	if d.prev != nil {
+		d.prev.next = d
+	}
+	p.delims = d
+}
+
+func (p *inlineParser) removeDelimiter(d *delimiter) {
+	if d.prev != nil {
+		d.prev.next = d.next
+	}
+	if d.next != nil {
+		d.next.prev = d.prev
+	} else {
+		p.delims = d.prev
+	}
+}
+
+// processEmphasis matches the openers and closers of the delimiter stack above
+// bottom, and wraps the nodes between them in emphasis nodes.
+func (p *inlineParser) processEmphasis(bottom *delimiter) {
+	// openersBottom is the lower bound to look for openers of closers, by
+	// their kinds, which avoids quadratic behavior.
+	openersBottom := make(map[[3]int]*delimiter)
+
+	closer := p.delims
+	for closer != nil && closer.prev != bottom {
+		closer = closer.prev
+	}
+	for closer != nil {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+
+		key := [3]int{int(closer.char), closer.origCount % 3, 0}
+		if closer.canOpen {
+			key[2] = 1
+		}
+		opener := closer.prev
+		found := false
+		for opener != nil && opener != bottom && opener != openersBottom[key] {
+			if opener.char == closer.char && opener.canOpen {
+				if closer.char == '~' {
+					found = opener.count == closer.count
+				} else {
+					oddMatch := (closer.canOpen || opener.canClose) &&
+						closer.origCount%3 != 0 && (opener.origCount+closer.origCount)%3 == 0
+					found = !oddMatch
+				}
+				if found {
+					break
+				}
+			}
+			opener = opener.prev
+		}
+
+		if !found {
+			openersBottom[key] = closer.prev
+			next := closer.next
+			if !closer.canOpen {
+				p.removeDelimiter(closer)
+			}
+			closer = next
+			continue
+		}
+
+		use, kind := 1, Emphasis
+		switch {
+		case closer.char == '~':
+			use, kind = closer.count, Strikethrough
+		case closer.count >= 2 && opener.count >= 2:
+			use, kind = 2, Strong
+		}
+		opener.count -= use
+		closer.count -= use
+		opener.node.text = opener.node.text[:opener.count]
+		closer.node.text = closer.node.text[:closer.count]
+
+		emph := &node{kind: kind}
+		for n := opener.node.next; n != nil && n != closer.node; {
+			next := n.next
+			n.unlink()
+			emph.append(n)
+			n = next
+		}
+		opener.node.insertAfter(emph)
+
+		// remove the delimiters between the opener and the closer.
+		for d := closer.prev; d != nil && d != opener; {
+			prev := d.prev
+			p.removeDelimiter(d)
+			d = prev
+		}
+		if opener.count == 0 {
+			opener.node.unlink()
+			p.removeDelimiter(opener)
+		}
+		if closer.count == 0 {
+			next := closer.next
+			closer.node.unlink()
+			p.removeDelimiter(closer)
+			closer = next
+		}
+	}
+
+	// remove the remaining delimiters, which are left as literal text.
+	for p.delims != nil && p.delims != bottom {
+		p.removeDelimiter(p.delims)
+	}
+}
+
+func (p *inlineParser) pushBracket(n *node, image bool, pos int) {
+	p.brackets = &bracket{node: n, image: image, active: true, pos: pos, delim: p.delims, prev: p.brackets}
+}
+
+// closeBracket parses a ']', which closes a link or an image if it is followed
+// by a destination or matches a link reference definition.
+func (p *inlineParser) closeBracket() {
+	closePos := p.pos
+	p.pos++
+	opener := p.brackets
+	if opener == nil {
+		p.text("]")
+		return
+	}
+	p.brackets = opener.prev
+	if !opener.active {
+		p.text("]")
+		return
+	}
+
+	dest, title, end, ok := p.parseInlineLink(p.pos)
+	if !ok {
+		// a reference link: full, collapsed or shortcut.
+		label := p.src[opener.pos:closePos]
+		end = p.pos
+		if l, e, ok := scanLinkLabel(p.src, p.pos); ok {
+			if l != "" {
+				label = l
+			}
+			end = e
+		}
+		var ref linkRef
+		if ref, ok = p.refs[normalizeLabel(label)]; ok {
+			dest, title = ref.dest, ref.title
+		}
+	}
+	if !ok {
+		p.text("]")
+		return
+	}
+	p.pos = end
+
+	kind := Link
+	if opener.image {
+		kind = Image
+	}
+	link := &node{kind: kind, dest: dest, title: title}
+	for n := opener.node.next; n != nil; {
+		next := n.next
+		n.unlink()
+		link.append(n)
+		n = next
+	}
+	p.root.append(link)
+
+	// process the emphasis in the link text with the link as the root.
+	root := p.root
+	p.root = link
+	p.processEmphasis(opener.delim)
+	p.root = root
+	opener.node.unlink()
+
+	// links may not contain other links.
+	if !opener.image {
+		for b := p.brackets; b != nil; b = b.prev {
+			if !b.image {
+				b.active = false
+			}
+		}
+	}
+}
+
+// parseInlineLink parses the destination and the optional title of an inline
+// link, like `(/url "title")`.
+func (p *inlineParser) parseInlineLink(pos int) (dest, title string, end int, ok bool) {
+	if pos >= len(p.src) || p.src[pos] != '(' {
+		return
+	}
+	pos = skipSpaces(p.src, pos+1, true)
+	if pos < len(p.src) && p.src[pos] != ')' {
+		if dest, pos, ok = scanLinkDest(p.src, pos); !ok {
+			return
+		}
+	}
+	if start := skipSpaces(p.src, pos, true); start > pos {
+		if t, tEnd, ok := scanLinkTitle(p.src, start); ok {
+			title, pos = t, tEnd
+		}
+	}
+	pos = skipSpaces(p.src, pos, true)
+	if pos >= len(p.src) || p.src[pos] != ')' {
+		return "", "", 0, false
+	}
+	return dest, title, pos + 1, true
+}
+
+func (p *inlineParser) autolink() {
+	if m := uriAutolink.FindStringSubmatch(p.src[p.pos:]); m != nil {
+		p.link(m[1], m[1])
+		p.pos += len(m[0])
+		return
+	}
+	if m := emailAutolink.FindStringSubmatch(p.src[p.pos:]); m != nil {
+		p.link("mailto:"+m[1], m[1])
+		p.pos += len(m[0])
+		return
+	}
+	p.text("<")
+	p.pos++
+}
+
+// extendedAutolink parses a URL starting with "http://", "https://" or "www."
+// at the start of a word.
+func (p *inlineParser) extendedAutolink() bool {
+	if p.pos > 0 && !strings.ContainsRune(" \n*_~(", rune(p.src[p.pos-1])) {
+		return false
+	}
+	m := wwwAutolink.FindString(p.src[p.pos:])
+	if m == "" {
+		return false
+	}
+
+	// trailing punctuation and unbalanced parentheses are not part of the
+	// link.
+	for len(m) > 0 {
+		last := m[len(m)-1]
+		if strings.IndexByte("?!.,:*_~'\"", last) >= 0 ||
+			last == ')' && strings.Count(m, "(") < strings.Count(m, ")") {
+			m = m[:len(m)-1]
+			continue
+		}
+		break
+	}
+	if m == "" || strings.HasSuffix(m, "://") {
+		return false
+	}
+
+	dest := m
+	if strings.HasPrefix(m, "www.") {
+		dest = "http://" + m
+	}
+	p.link(dest, m)
+	p.pos += len(m)
+	return true
+}
+
+func (p *inlineParser) link(dest, text string) {
+	link := &node{kind: Link, dest: dest}
+	link.append(&node{kind: Text, text: text})
+	p.root.append(link)
+}
+
+// toInlines converts the children of the node to inlines, merging adjacent
+// texts.
+func toInlines(n *node) []Inline {
+	var inlines []Inline
+	for c := n.first; c != nil; c = c.next {
+		if c.kind == Text {
+			if c.text == "" {
+				continue
+			}
+			if k := len(inlines) - 1; k >= 0 && inlines[k].Kind == Text {
+				inlines[k].Text += c.text
+				continue
+			}
+		}
+		inlines = append(inlines, Inline{
+			Kind:     c.kind,
+			Text:     c.text,
+			Dest:     c.dest,
+			Title:    c.title,
+			Children: toInlines(c),
+		})
+	}
+	return inlines
+}
+
+// scanLinkLabel scans the link label starting with the '[' at pos, and returns
+// the label and the position after the closing ']'.
+func scanLinkLabel(s string, pos int) (string, int, bool) {
+	if pos >= len(s) || s[pos] != '[' {
+		return "", 0, false
+	}
+	for i := pos + 1; i < len(s) && i-pos <= 1000; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			return "", 0, false
+		case ']':
+			return s[pos+1 : i], i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// scanLinkDest scans a link destination, either enclosed in '<' and '>', or
+// a run of non-space characters with balanced parentheses.
+func scanLinkDest(s string, pos int) (string, int, bool) {
+	if pos < len(s) && s[pos] == '<' {
+		for i := pos + 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '\n', '<':
+				return "", 0, false
+			case '>':
+				return unescape(s[pos+1 : i]), i + 1, true
+			}
+		}
+		return "", 0, false
+	}
+
+	depth := 0
+	i := pos
+loop:
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			i++
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		case c <= ' ':
+			break loop
+		}
+	}
+	if i == pos || depth != 0 {
+		return "", 0, false
+	}
+	return unescape(s[pos:i]), i, true
+}
+
+// scanLinkTitle scans a link title enclosed in double quotes, single quotes
+// or parentheses.
+func scanLinkTitle(s string, pos int) (string, int, bool) {
+	if pos >= len(s) {
+		return "", 0, false
+	}
+	closing := s[pos]
+	switch closing {
+	case '"', '\'':
+	case '(':
+		closing = ')'
+	default:
+		return "", 0, false
+	}
+	for i := pos + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case closing:
+			return unescape(s[pos+1 : i]), i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// skipSpaces skips the spaces at pos, and at most one line ending among them
+// if newline is set.
+func skipSpaces(s string, pos int, newline bool) int {
+	for pos < len(s) {
+		switch {
+		case s[pos] == ' ':
+		case s[pos] == '\n' && newline:
+			newline = false
+		default:
+			return pos
+		}
+		pos++
+	}
+	return pos
+}
+
+// unescape replaces the backslash escapes and the entities of the text.
+func unescape(s string) string {
+	if !strings.ContainsAny(s, "\\&") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			i++
+			b.WriteByte(s[i])
+		case s[i] == '&':
+			if m := entity.FindString(s[i:]); m != "" {
+				b.WriteString(html.UnescapeString(m))
+				i += len(m) - 1
+				continue
+			}
+			b.WriteByte('&')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,329 @@
+package markdown
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/oligo/gioview/editor/syntax"
+	gvimage "github.com/oligo/gioview/image"
+	"github.com/oligo/gioview/misc"
+	"github.com/oligo/gioview/theme"
+	gvwidget "github.com/oligo/gioview/widget"
+
+	"gioui.org/font"
+	"gioui.org/io/clipboard"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// doubleClickDuration is the maximum duration between the presses of a double
+// or triple click.
+const doubleClickDuration = 300 * time.Millisecond
+
+// Markdown is the state of a read-only markdown widget. It holds the parsed
+// document and the selection, and handles the clicks of the links.
+//
+// The text can be selected by dragging the pointer, a double click selects a
+// word, and a triple click selects a paragraph. Shortcut+C copies the selected
+// text to the clipboard, and Shortcut+A selects all the text.
+type Markdown struct {
+	// OnLinkClicked is called with the destination of a clicked link.
+	OnLinkClicked func(dest string) error
+	// ImageDir is the directory the relative image sources are resolved
+	// against. It must be set before SetText.
+	ImageDir string
+
+	src    string
+	doc    *Document
+	elems  []element
+	texts  []*richText
+	links  []*gvwidget.Link[string]
+	images map[string]*gvimage.ImageSource
+	// length is the length of the selectable text in runes.
+	length int
+
+	// anchor is where the selection starts, and caret is where it ends.
+	// They are rune offsets of the texts of the document, each text followed
+	// by a separator.
+	anchor, caret int
+	dragging      bool
+	clicks        int
+	lastPress     time.Duration
+}
+
+// SetText parses the markdown source, and clears the selection.
+func (md *Markdown) SetText(src string) {
+	md.src = src
+	md.doc = Parse(src)
+	md.texts = md.texts[:0]
+	md.links = md.links[:0]
+	if md.images == nil {
+		md.images = make(map[string]*gvimage.ImageSource)
+	}
+
+	b := &builder{md: md}
+	md.elems = b.blocks(md.doc.Blocks)
+	md.length = max(0, b.offset-1)
+	md.anchor, md.caret = 0, 0
+}
+
+// Text returns the markdown source.
+func (md *Markdown) Text() string {
+	return md.src
+}
+
+// Document returns the parsed document.
+func (md *Markdown) Document() *Document {
+	return md.doc
+}
+
+// Selection returns the start and the end of the selection. The offsets count
+// the runes of the texts of the document, each followed by one separator.
+func (md *Markdown) Selection() (start, end int) {
+	return min(md.anchor, md.caret), max(md.anchor, md.caret)
+}
+
+// SetSelection selects the text between the rune offsets.
+func (md *Markdown) SetSelection(start, end int) {
+	md.anchor = max(0, min(start, md.length))
+	md.caret = max(0, min(end, md.length))
+}
+
+// Len returns the length of the text in runes.
+func (md *Markdown) Len() int {
+	return md.length
+}
+
+// SelectedText returns the selected text without markup. The paragraphs
+// are separated by line breaks, and the cells of a table row by tabs.
+func (md *Markdown) SelectedText() string {
+	var b strings.Builder
+	for _, rt := range md.texts {
+		s, e := md.selectionOf(rt)
+		if s >= e {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(rt.sep)
+		}
+		b.WriteString(string(rt.runes[s:e]))
+	}
+	return b.String()
+}
+
+// selectionOf returns the selected range of the text.
+func (md *Markdown) selectionOf(rt *richText) (int, int) {
+	if rt.start < 0 {
+		return 0, 0
+	}
+	start, end := md.Selection()
+	n := len(rt.runes)
+	return max(0, min(start-rt.start, n)), max(0, min(end-rt.start, n))
+}
+
+func (md *Markdown) openLink(intent any) error {
+	if md.OnLinkClicked == nil {
+		return nil
+	}
+	return md.OnLinkClicked(intent.(string))
+}
+
+// offsetAt returns the document offset closest to the position, and the text
+// of the offset.
+func (md *Markdown) offsetAt(pos image.Point) (int, *richText) {
+	var (
+		closest *richText
+		minDist = -1
+	)
+	for _, rt := range md.texts {
+		if len(rt.lines) == 0 {
+			continue
+		}
+		bounds := image.Rectangle{Min: rt.origin, Max: rt.origin.Add(rt.size)}
+		bounds.Max.Y = max(bounds.Max.Y, bounds.Min.Y+1)
+		// prefer the texts at the same height.
+		dist := distance(pos.Y, bounds.Min.Y, bounds.Max.Y-1)*(1<<16) + distance(pos.X, bounds.Min.X, bounds.Max.X)
+		if minDist < 0 || dist < minDist {
+			closest, minDist = rt, dist
+		}
+	}
+	if closest == nil {
+		return 0, nil
+	}
+	return closest.start + closest.offsetAt(pos.Sub(closest.origin)), closest
+}
+
+// distance returns the distance of v to the range [lo, hi].
+func distance(v, lo, hi int) int {
+	switch {
+	case v < lo:
+		return lo - v
+	case v > hi:
+		return v - hi
+	}
+	return 0
+}
+
+// Update handles the pointer and key events of the widget, and the clicks of
+// the links.
+func (md *Markdown) Update(gtx C) {
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target: md,
+			Kinds:  pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel,
+		})
+		if !ok {
+			break
+		}
+		e, ok := ev.(pointer.Event)
+		if !ok {
+			continue
+		}
+		switch e.Kind {
+		case pointer.Press:
+			if e.Buttons != pointer.ButtonPrimary {
+				break
+			}
+			gtx.Execute(key.FocusCmd{Tag: md})
+			if e.Time-md.lastPress < doubleClickDuration {
+				md.clicks++
+			} else {
+				md.clicks = 1
+			}
+			md.lastPress = e.Time
+
+			offset, rt := md.offsetAt(e.Position.Round())
+			switch {
+			case rt != nil && md.clicks == 2:
+				start, end := rt.wordAt(offset - rt.start)
+				md.anchor, md.caret = rt.start+start, rt.start+end
+			case rt != nil && md.clicks >= 3:
+				md.anchor, md.caret = rt.start, rt.start+len(rt.runes)
+			case e.Modifiers.Contain(key.ModShift):
+				md.caret = offset
+			default:
+				md.anchor, md.caret = offset, offset
+			}
+			md.dragging = true
+		case pointer.Drag:
+			if md.dragging {
+				md.caret, _ = md.offsetAt(e.Position.Round())
+			}
+		case pointer.Release, pointer.Cancel:
+			md.dragging = false
+		}
+	}
+
+	for {
+		ev, ok := gtx.Event(
+			key.FocusFilter{Target: md},
+			key.Filter{Focus: md, Name: "C", Required: key.ModShortcut},
+			key.Filter{Focus: md, Name: "A", Required: key.ModShortcut},
+		)
+		if !ok {
+			break
+		}
+		e, ok := ev.(key.Event)
+		if !ok || e.State != key.Press {
+			continue
+		}
+		switch e.Name {
+		case "C":
+			if text := md.SelectedText(); text != "" {
+				gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
+			}
+		case "A":
+			md.anchor, md.caret = 0, md.length
+		}
+	}
+
+	for _, link := range md.links {
+		// a click ending a selection does not open the link.
+		if link.Update(gtx) && md.anchor == md.caret {
+			if err := link.OnClick(); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// MarkdownStyle lays out a Markdown at its full height, so it is usually laid
+// out in a scrollable list.
+type MarkdownStyle struct {
+	state  *Markdown
+	shaper *text.Shaper
+	// Font is the font of the text, and MonoFont is the font of the code.
+	Font     font.Font
+	MonoFont font.Font
+	TextSize unit.Sp
+	// LineHeightScale scales the height of the lines of text.
+	LineHeightScale float32
+
+	Color color.NRGBA
+	// MutedColor is the color of the text in block quotes.
+	MutedColor     color.NRGBA
+	LinkColor      color.NRGBA
+	SelectionColor color.NRGBA
+	// CodeBackground is the background of code, and of the table headers.
+	CodeBackground color.NRGBA
+	// DividerColor is the color of the thematic breaks, the table borders,
+	// the bars of block quotes and the underlines of top level headings.
+	DividerColor color.NRGBA
+	// CheckColor is the color of the checks of done tasks.
+	CheckColor color.NRGBA
+	// Scheme colors the code blocks of the languages known by the syntax
+	// package.
+	Scheme *syntax.Scheme
+}
+
+// NewMarkdown returns a style for the markdown widget using the theme.
+func NewMarkdown(th *theme.Theme, md *Markdown) MarkdownStyle {
+	return MarkdownStyle{
+		state:           md,
+		shaper:          th.Shaper,
+		Font:            font.Font{Typeface: th.Face},
+		MonoFont:        font.Font{Typeface: "Go Mono"},
+		TextSize:        th.TextSize,
+		LineHeightScale: 1.4,
+		Color:           th.Fg,
+		MutedColor:      misc.WithAlpha(th.Fg, 0xb0),
+		LinkColor:       th.ContrastBg,
+		SelectionColor:  misc.WithAlpha(th.ContrastBg, 0x60),
+		CodeBackground:  misc.WithAlpha(th.Fg, 0x10),
+		DividerColor:    misc.WithAlpha(th.Fg, 0x30),
+		CheckColor:      th.ContrastFg,
+		Scheme:          syntax.Light,
+	}
+}
+
+func (ms MarkdownStyle) Layout(gtx C) D {
+	md := ms.state
+	md.Update(gtx)
+
+	r := &renderer{gtx: gtx, style: &ms, md: md}
+	macro := op.Record(gtx.Ops)
+	height, _ := r.layoutElements(md.elems, image.Point{}, gtx.Constraints.Max.X, r.gap())
+	call := macro.Stop()
+
+	size := gtx.Constraints.Constrain(image.Pt(gtx.Constraints.Max.X, height))
+	defer clip.Rect(image.Rectangle{Max: size}).Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, md)
+	pointer.CursorText.Add(gtx.Ops)
+	call.Add(gtx.Ops)
+	return D{Size: size}
+}
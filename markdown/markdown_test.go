@@ -0,0 +1,70 @@
+package markdown
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/oligo/gioview/uitest"
+
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+)
+
+func TestMarkdown(t *testing.T) {
+	var md Markdown
+	var opened string
+	md.OnLinkClicked = func(dest string) error { opened = dest; return nil }
+	md.SetText("# Title\n\nSee [the docs](https://example.org) for more.\n\n- [x] done\n- todo\n\n```go\nfunc main() {}\n```\n")
+
+	h := uitest.New(image.Pt(400, 400), nil)
+	h.Widget = func(gtx C) D {
+		gtx.Constraints.Min = image.Point{}
+		return NewMarkdown(h.Theme, &md).Layout(gtx)
+	}
+	if dims := h.Frame(); dims.Size.Y == 0 || dims.Size.Y >= 400 {
+		t.Fatalf("unexpected height: %d", dims.Size.Y)
+	}
+
+	// A double click selects a word of the title.
+	h.DoubleClick(image.Pt(20, 20))
+	if got := md.SelectedText(); got != "Title" {
+		t.Fatalf("unexpected selection: %q", got)
+	}
+
+	// Shortcut+A selects all the text, copied without markup.
+	h.Key("A", key.ModShortcut)
+	h.Key("C", key.ModShortcut)
+	if want := "Title\nSee the docs for more.\ndone\ntodo\nfunc main() {}"; h.Clipboard() != want {
+		t.Fatalf("unexpected copied text: %q", h.Clipboard())
+	}
+
+	// Dragging to the link selects the text before it.
+	h.Advance(time.Second)
+	var link image.Point
+	for y := 40; y < 120 && link == (image.Point{}); y += 4 {
+		for x := 0; x < 300; x++ {
+			h.Move(image.Pt(x, y))
+			if h.Cursor() == pointer.CursorPointer {
+				link = image.Pt(x, y)
+				break
+			}
+		}
+	}
+	if link == (image.Point{}) {
+		t.Fatal("link not found")
+	}
+	h.Drag(image.Pt(0, link.Y), link, 4)
+	if got := md.SelectedText(); got != "See " {
+		t.Fatalf("unexpected selection: %q", got)
+	}
+	if opened != "" {
+		t.Fatalf("link opened by a selection: %q", opened)
+	}
+
+	h.Advance(time.Second)
+	h.Click(link.Add(image.Pt(4, 0)))
+	if opened != "https://example.org" {
+		t.Fatalf("unexpected opened link: %q", opened)
+	}
+}
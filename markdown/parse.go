@@ -0,0 +1,613 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+)
+
+// linkRef is a link reference definition.
+type linkRef struct {
+	dest, title string
+}
+
+// parser parses the blocks of a document, and then the inline content of its
+// paragraphs, headings and table cells, once all the link reference
+// definitions are known.
+type parser struct {
+	refs map[string]linkRef
+}
+
+// Parse parses the markdown source. Any input is a valid document, so Parse
+// never fails.
+func Parse(src string) *Document {
+	p := &parser{refs: make(map[string]linkRef)}
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(src, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+
+	doc := &Document{}
+	doc.Blocks, _ = p.parseBlocks(lines)
+	p.parseInlines(doc.Blocks)
+	return doc
+}
+
+func (p *parser) parseInlines(blocks []*Block) {
+	for _, b := range blocks {
+		switch b.Kind {
+		case Paragraph, Heading:
+			b.Inlines = parseInline(b.raw, p.refs)
+		case Table:
+			for _, row := range b.Rows {
+				for i := range row {
+					row[i].Inlines = parseInline(row[i].raw, p.refs)
+				}
+			}
+		}
+		p.parseInlines(b.Children)
+	}
+}
+
+// parseBlocks parses the lines into blocks. It also reports whether two of the
+// blocks are separated by a blank line, which makes a list item loose.
+func (p *parser) parseBlocks(lines []string) ([]*Block, bool) {
+	var (
+		blocks []*Block
+		para   []string
+		// a blank line follows a block.
+		blank, loose bool
+	)
+	add := func(b *Block) {
+		if blank && len(blocks) > 0 {
+			loose = true
+		}
+		blank = false
+		blocks = append(blocks, b)
+	}
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		raw := p.parseRefs(strings.Join(para, "\n"))
+		para = nil
+		if raw = strings.TrimSpace(raw); raw != "" {
+			add(&Block{Kind: Paragraph, raw: raw})
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		indent := indentOf(line)
+		rest := line[indent:]
+
+		if rest == "" {
+			flush()
+			blank = true
+			i++
+			continue
+		}
+
+		if indent >= 4 {
+			if len(para) > 0 {
+				// Indented code can not interrupt a paragraph.
+				para = append(para, rest)
+				i++
+				continue
+			}
+			var code []string
+			for ; i < len(lines) && (isBlank(lines[i]) || indentOf(lines[i]) >= 4); i++ {
+				code = append(code, removeIndent(lines[i], 4))
+			}
+			for len(code) > 0 && isBlank(code[len(code)-1]) {
+				code = code[:len(code)-1]
+			}
+			add(&Block{Kind: CodeBlock, Literal: strings.Join(code, "\n") + "\n"})
+			continue
+		}
+
+		if fence, info, ok := fenceStart(rest); ok {
+			flush()
+			var code []string
+			for i++; i < len(lines); i++ {
+				if isFenceEnd(lines[i], fence) {
+					i++
+					break
+				}
+				code = append(code, removeIndent(lines[i], indent))
+			}
+			literal := strings.Join(code, "\n")
+			if len(code) > 0 {
+				literal += "\n"
+			}
+			add(&Block{Kind: CodeBlock, Info: info, Literal: literal})
+			continue
+		}
+
+		if level, content, ok := atxHeading(rest); ok {
+			flush()
+			add(&Block{Kind: Heading, Level: level, raw: content})
+			i++
+			continue
+		}
+
+		if level := setextLevel(rest); level > 0 && len(para) > 0 {
+			raw := strings.TrimSpace(p.parseRefs(strings.Join(para, "\n")))
+			para = nil
+			if raw != "" {
+				add(&Block{Kind: Heading, Level: level, raw: raw})
+				i++
+				continue
+			}
+		}
+
+		if isThematicBreak(rest) {
+			flush()
+			add(&Block{Kind: ThematicBreak})
+			i++
+			continue
+		}
+
+		if rest[0] == '>' {
+			flush()
+			var quote []string
+			for ; i < len(lines); i++ {
+				l := lines[i]
+				if ind := indentOf(l); ind < 4 && strings.HasPrefix(l[ind:], ">") {
+					l = l[ind+1:]
+					if strings.HasPrefix(l, " ") {
+						l = l[1:]
+					}
+					quote = append(quote, l)
+					continue
+				}
+				// lazy continuation of a paragraph.
+				if len(quote) > 0 && !isBlank(quote[len(quote)-1]) && !isBlank(l) && !startsBlock(l) {
+					quote = append(quote, l)
+					continue
+				}
+				break
+			}
+			children, _ := p.parseBlocks(quote)
+			add(&Block{Kind: BlockQuote, Children: children})
+			continue
+		}
+
+		if m, ok := parseMarker(line); ok && (len(para) == 0 || m.canInterrupt()) {
+			flush()
+			var list *Block
+			list, i = p.parseList(lines, i)
+			add(list)
+			continue
+		}
+
+		if i+1 < len(lines) && strings.Contains(rest, "|") {
+			if align, ok := parseDelimiterRow(lines[i+1]); ok {
+				if header := splitRow(rest); len(header) == len(align) {
+					flush()
+					var table *Block
+					table, i = parseTable(lines, i, align)
+					add(table)
+					continue
+				}
+			}
+		}
+
+		para = append(para, rest)
+		i++
+	}
+	flush()
+
+	return blocks, loose
+}
+
+// parseList parses the list starting at lines[start], and returns the list and
+// the index of the line after it.
+func (p *parser) parseList(lines []string, start int) (*Block, int) {
+	first, _ := parseMarker(lines[start])
+	list := &Block{Kind: List, Ordered: first.ordered, Start: first.start, Tight: true}
+
+	i := start
+	blankBefore := false
+	for i < len(lines) && !isThematicBreak(strings.TrimLeft(lines[i], " ")) {
+		m, ok := parseMarker(lines[i])
+		if !ok || m.ordered != first.ordered || m.char != first.char {
+			break
+		}
+
+		var item []string
+		if m.empty {
+			item = append(item, "")
+		} else {
+			item = append(item, lines[i][m.content:])
+		}
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if isBlank(l) {
+				// An item can begin with at most one blank line.
+				if m.empty && len(item) == 1 {
+					break
+				}
+				item = append(item, "")
+				continue
+			}
+			if indentOf(l) >= m.content {
+				item = append(item, removeIndent(l, m.content))
+				continue
+			}
+			// the next item of the list.
+			if next, ok := parseMarker(l); ok && next.ordered == first.ordered && next.char == first.char {
+				break
+			}
+			// lazy continuation of a paragraph.
+			if last := item[len(item)-1]; !isBlank(last) && !startsBlock(l) && indentOf(last) < 4 {
+				item = append(item, strings.TrimLeft(l, " "))
+				continue
+			}
+			break
+		}
+
+		n := len(item)
+		for n > 1 && isBlank(item[n-1]) {
+			n--
+		}
+		children, loose := p.parseBlocks(item[:n])
+		if loose || blankBefore && len(list.Children) > 0 {
+			list.Tight = false
+		}
+		blankBefore = n < len(item)
+
+		it := &Block{Kind: ListItem, Children: children}
+		if len(children) > 0 && children[0].Kind == Paragraph {
+			para := children[0]
+			if len(para.raw) >= 3 && para.raw[0] == '[' && para.raw[2] == ']' &&
+				(len(para.raw) == 3 || para.raw[3] == ' ' || para.raw[3] == '\n') {
+				switch para.raw[1] {
+				case ' ':
+					it.Task = true
+				case 'x', 'X':
+					it.Task, it.Checked = true, true
+				}
+				if it.Task {
+					para.raw = strings.TrimLeft(para.raw[3:], " \n")
+					if para.raw == "" {
+						it.Children = children[1:]
+					}
+				}
+			}
+		}
+		list.Children = append(list.Children, it)
+	}
+
+	return list, i
+}
+
+// marker is a list item marker.
+type marker struct {
+	ordered bool
+	// char is the bullet, or the delimiter after the number.
+	char  byte
+	start int
+	// content is the column of the content of the item.
+	content int
+	// empty reports whether nothing follows the marker.
+	empty bool
+}
+
+func parseMarker(line string) (marker, bool) {
+	var m marker
+	indent := indentOf(line)
+	if indent >= 4 || indent == len(line) {
+		return m, false
+	}
+	s := line[indent:]
+
+	n := 0
+	switch s[0] {
+	case '-', '+', '*':
+		m.char = s[0]
+		n = 1
+	default:
+		for n < len(s) && n < 9 && isDigit(s[n]) {
+			n++
+		}
+		if n == 0 || n == len(s) || (s[n] != '.' && s[n] != ')') {
+			return m, false
+		}
+		m.ordered = true
+		m.start, _ = strconv.Atoi(s[:n])
+		m.char = s[n]
+		n++
+	}
+
+	after := s[n:]
+	if after != "" && after[0] != ' ' {
+		return m, false
+	}
+	spaces := indentOf(after)
+	switch {
+	case spaces == len(after):
+		m.empty = true
+		m.content = indent + n + 1
+	case spaces > 4:
+		// The content is indented code.
+		m.content = indent + n + 1
+	default:
+		m.content = indent + n + spaces
+	}
+	return m, true
+}
+
+// canInterrupt reports whether the list starting with the marker can
+// interrupt a paragraph.
+func (m marker) canInterrupt() bool {
+	return !m.empty && (!m.ordered || m.start == 1)
+}
+
+// startsBlock reports whether the line starts a block which interrupts a
+// paragraph.
+func startsBlock(line string) bool {
+	indent := indentOf(line)
+	if indent >= 4 || indent == len(line) {
+		return false
+	}
+	rest := line[indent:]
+	if _, _, ok := fenceStart(rest); ok {
+		return true
+	}
+	if _, _, ok := atxHeading(rest); ok {
+		return true
+	}
+	if m, ok := parseMarker(line); ok && m.canInterrupt() {
+		return true
+	}
+	return rest[0] == '>' || isThematicBreak(rest)
+}
+
+// fenceStart parses the opening code fence of a fenced code block, returning
+// the fence and the info string.
+func fenceStart(s string) (string, string, bool) {
+	if len(s) < 3 || (s[0] != '`' && s[0] != '~') {
+		return "", "", false
+	}
+	n := 0
+	for n < len(s) && s[n] == s[0] {
+		n++
+	}
+	if n < 3 {
+		return "", "", false
+	}
+	info := strings.TrimSpace(s[n:])
+	if s[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+	return s[:n], unescape(info), true
+}
+
+// isFenceEnd reports whether the line closes the code block opened by the
+// fence.
+func isFenceEnd(line, fence string) bool {
+	indent := indentOf(line)
+	if indent >= 4 {
+		return false
+	}
+	s := line[indent:]
+	n := 0
+	for n < len(s) && s[n] == fence[0] {
+		n++
+	}
+	return n >= len(fence) && strings.TrimSpace(s[n:]) == ""
+}
+
+// atxHeading parses a heading starting with 1 to 6 '#'s.
+func atxHeading(s string) (int, string, bool) {
+	level := 0
+	for level < len(s) && s[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(s) && s[level] != ' ') {
+		return 0, "", false
+	}
+
+	content := strings.TrimSpace(s[level:])
+	// remove the optional closing sequence.
+	if trimmed := strings.TrimRight(content, "#"); trimmed == "" {
+		content = ""
+	} else if trimmed != content && strings.HasSuffix(trimmed, " ") {
+		content = strings.TrimSpace(trimmed)
+	}
+	return level, content, true
+}
+
+// setextLevel returns the level of the heading underlined by the line, or 0
+// if the line is not a setext heading underline.
+func setextLevel(s string) int {
+	s = strings.TrimRight(s, " ")
+	switch {
+	case s == "":
+		return 0
+	case strings.Trim(s, "=") == "":
+		return 1
+	case strings.Trim(s, "-") == "":
+		return 2
+	}
+	return 0
+}
+
+func isThematicBreak(s string) bool {
+	if s == "" || (s[0] != '-' && s[0] != '*' && s[0] != '_') {
+		return false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case s[0]:
+			n++
+		case ' ':
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
+
+// parseTable parses the table whose header row is lines[start], and returns
+// the table and the index of the line after it.
+func parseTable(lines []string, start int, align []Alignment) (*Block, int) {
+	table := &Block{Kind: Table, Align: align}
+	addRow := func(line string) {
+		cells := splitRow(line)
+		row := make([]Cell, len(align))
+		for i := range row {
+			if i < len(cells) {
+				row[i].raw = cells[i]
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	addRow(strings.TrimSpace(lines[start]))
+	i := start + 2
+	for ; i < len(lines); i++ {
+		if isBlank(lines[i]) || startsBlock(lines[i]) {
+			break
+		}
+		addRow(strings.TrimSpace(lines[i]))
+	}
+	return table, i
+}
+
+// parseDelimiterRow parses the row separating the header of a table from its
+// body, like "| :--- | :---: | ---: |".
+func parseDelimiterRow(line string) ([]Alignment, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.Contains(line, "|") && !strings.Contains(line, ":") {
+		return nil, false
+	}
+	var align []Alignment
+	for _, cell := range splitRow(line) {
+		left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		dashes := strings.TrimSuffix(strings.TrimPrefix(cell, ":"), ":")
+		if dashes == "" || strings.Trim(dashes, "-") != "" {
+			return nil, false
+		}
+		switch {
+		case left && right:
+			align = append(align, AlignCenter)
+		case left:
+			align = append(align, AlignLeft)
+		case right:
+			align = append(align, AlignRight)
+		default:
+			align = append(align, AlignNone)
+		}
+	}
+	return align, len(align) > 0
+}
+
+// splitRow splits a table row into its trimmed cells at the unescaped pipes.
+func splitRow(line string) []string {
+	line = strings.TrimPrefix(strings.TrimSpace(line), "|")
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	if last := strings.TrimSpace(cell.String()); last != "" || !strings.HasSuffix(line, "|") {
+		cells = append(cells, last)
+	}
+	return cells
+}
+
+// parseRefs parses the link reference definitions at the start of the
+// paragraph text, and returns the rest of the text.
+func (p *parser) parseRefs(text string) string {
+	for strings.HasPrefix(text, "[") {
+		label, pos, ok := scanLinkLabel(text, 0)
+		if !ok || pos >= len(text) || text[pos] != ':' {
+			break
+		}
+		dest, pos, ok := scanLinkDest(text, skipSpaces(text, pos+1, true))
+		if !ok {
+			break
+		}
+
+		// The title is optional, and it must be separated from the
+		// destination by whitespace. Nothing else may follow on the line.
+		var title string
+		end := skipSpaces(text, pos, false)
+		if start := skipSpaces(text, pos, true); start > pos {
+			if t, tEnd, ok := scanLinkTitle(text, start); ok {
+				if e := skipSpaces(text, tEnd, false); e == len(text) || text[e] == '\n' {
+					title, end = t, e
+				}
+			}
+		}
+		if end < len(text) && text[end] != '\n' {
+			break
+		}
+
+		if key := normalizeLabel(label); key != "" {
+			if _, exists := p.refs[key]; !exists {
+				p.refs[key] = linkRef{dest: dest, title: title}
+			}
+		}
+		text = strings.TrimPrefix(text[end:], "\n")
+	}
+	return text
+}
+
+// normalizeLabel folds the case and collapses the whitespace of a link label
+// to match references with their definitions.
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := 4 - col%4
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String()
+}
+
+func indentOf(line string) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+// removeIndent removes up to n leading spaces of the line.
+func removeIndent(line string, n int) string {
+	return line[min(n, indentOf(line)):]
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
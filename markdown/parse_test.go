@@ -0,0 +1,161 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		src, want string
+	}{
+		{"# Title #\n\nSetext\n===\nsub\n---", "<h1>Title</h1><h1>Setext</h1><h2>sub</h2>"},
+		{"a\nb  \nc\\\nd", "<p>a b<br>c<br>d</p>"},
+		{"***\n- - -", "<hr><hr>"},
+		{"*a* **b** _c_ __d__ ***e*** ~~f~~", "<p><em>a</em> <strong>b</strong> <em>c</em> <strong>d</strong> <em><strong>e</strong></em> <del>f</del></p>"},
+		{"snake_case_word *a **b** c*", "<p>snake_case_word <em>a <strong>b</strong> c</em></p>"},
+		{"**a*", "<p>*<em>a</em></p>"},
+		{"`a ``b`` c` `` ` ``", "<p><code>a ``b`` c</code> <code>`</code></p>"},
+		{`\*not\* &amp; &copy; &bogus;`, "<p>*not* &amp; © &amp;bogus;</p>"},
+		{`[a *b*](/url "t") ![img](i.png) <https://x.org> www.y.org.`,
+			`<p><a href="/url" title="t">a <em>b</em></a> <img src="i.png" alt="img"> <a href="https://x.org">https://x.org</a> <a href="http://www.y.org">www.y.org</a>.</p>`},
+		{"[ref] and [text][Ref]\n\n[ref]: /r 'T'", `<p><a href="/r" title="T">ref</a> and <a href="/r" title="T">text</a></p>`},
+		{"[a [b](/b)](/a) [c]", `<p>[a <a href="/b">b</a>](/a) [c]</p>`},
+		{"> quote\nlazy\n> > nested", "<blockquote><p>quote lazy</p><blockquote><p>nested</p></blockquote></blockquote>"},
+		{"```go\nfunc main() {}\n```\n\n    indented\n\n~~~\nx", `<pre lang="go">func main() {}
+</pre><pre>indented
+</pre><pre>x
+</pre>`},
+		{"- a\n- b\n  - c\n\n3) x\n4) y", "<ul><li>a</li><li>b<ul><li>c</li></ul></li></ul><ol start=3><li>x</li><li>y</li></ol>"},
+		{"- a\n\n- b", "<ul loose><li><p>a</p></li><li><p>b</p></li></ul>"},
+		{"1. a\n\n   b\n2. c", "<ol loose><li><p>a</p><p>b</p></li><li><p>c</p></li></ol>"},
+		{"- [ ] todo\n- [x] done\n- [y] no", "<ul><li>[ ] todo</li><li>[x] done</li><li>[y] no</li></ul>"},
+		{"a\n* b\n2. c", "<p>a</p><ul><li>b 2. c</li></ul>"},
+		{"| a | b | c |\n|:--|:-:|--:|\n| 1 | `\\|` \\| 2 |\nx", `<table><tr><th align=left>a</th><th align=center>b</th><th align=right>c</th></tr><tr><td align=left>1</td><td align=center><code>|</code> | 2</td><td align=right></td></tr><tr><td align=left>x</td><td align=center></td><td align=right></td></tr></table>`},
+		{"a | b\n- | -\n\nc | d", "<table><tr><th>a</th><th>b</th></tr></table><p>c | d</p>"},
+	}
+	for _, tc := range cases {
+		if got := renderHTML(Parse(tc.src).Blocks); got != tc.want {
+			t.Errorf("%q:\n got %s\nwant %s", tc.src, got, tc.want)
+		}
+	}
+}
+
+// renderHTML renders the blocks in a compact HTML-like form for comparison.
+func renderHTML(blocks []*Block) string {
+	var b strings.Builder
+	for _, block := range blocks {
+		writeBlock(&b, block, false)
+	}
+	return b.String()
+}
+
+func writeBlock(b *strings.Builder, block *Block, tight bool) {
+	switch block.Kind {
+	case Paragraph:
+		if tight {
+			writeInlines(b, block.Inlines)
+		} else {
+			b.WriteString("<p>")
+			writeInlines(b, block.Inlines)
+			b.WriteString("</p>")
+		}
+	case Heading:
+		fmt.Fprintf(b, "<h%d>", block.Level)
+		writeInlines(b, block.Inlines)
+		fmt.Fprintf(b, "</h%d>", block.Level)
+	case ThematicBreak:
+		b.WriteString("<hr>")
+	case CodeBlock:
+		if lang := block.Lang(); lang != "" {
+			fmt.Fprintf(b, "<pre lang=%q>%s</pre>", lang, block.Literal)
+		} else {
+			fmt.Fprintf(b, "<pre>%s</pre>", block.Literal)
+		}
+	case BlockQuote:
+		b.WriteString("<blockquote>")
+		for _, c := range block.Children {
+			writeBlock(b, c, false)
+		}
+		b.WriteString("</blockquote>")
+	case List:
+		tag := "ul"
+		if block.Ordered {
+			tag = "ol"
+		}
+		b.WriteString("<" + tag)
+		if block.Ordered && block.Start != 1 {
+			fmt.Fprintf(b, " start=%d", block.Start)
+		}
+		if !block.Tight {
+			b.WriteString(" loose")
+		}
+		b.WriteString(">")
+		for _, item := range block.Children {
+			b.WriteString("<li>")
+			if item.Task {
+				if item.Checked {
+					b.WriteString("[x] ")
+				} else {
+					b.WriteString("[ ] ")
+				}
+			}
+			for _, c := range item.Children {
+				writeBlock(b, c, block.Tight)
+			}
+			b.WriteString("</li>")
+		}
+		b.WriteString("</" + tag + ">")
+	case Table:
+		b.WriteString("<table>")
+		for i, row := range block.Rows {
+			tag := "td"
+			if i == 0 {
+				tag = "th"
+			}
+			b.WriteString("<tr>")
+			for j, cell := range row {
+				b.WriteString("<" + tag)
+				if align := block.Align[j]; align != AlignNone {
+					fmt.Fprintf(b, " align=%s", [...]string{"", "left", "center", "right"}[align])
+				}
+				b.WriteString(">")
+				writeInlines(b, cell.Inlines)
+				b.WriteString("</" + tag + ">")
+			}
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</table>")
+	}
+}
+
+func writeInlines(b *strings.Builder, inlines []Inline) {
+	tags := map[InlineKind]string{Emphasis: "em", Strong: "strong", Strikethrough: "del", CodeSpan: "code"}
+	for _, in := range inlines {
+		switch in.Kind {
+		case Text:
+			b.WriteString(strings.ReplaceAll(in.Text, "&", "&amp;"))
+		case SoftBreak:
+			b.WriteString(" ")
+		case LineBreak:
+			b.WriteString("<br>")
+		case CodeSpan:
+			fmt.Fprintf(b, "<code>%s</code>", in.Text)
+		case Link:
+			fmt.Fprintf(b, "<a href=%q", in.Dest)
+			if in.Title != "" {
+				fmt.Fprintf(b, " title=%q", in.Title)
+			}
+			b.WriteString(">")
+			writeInlines(b, in.Children)
+			b.WriteString("</a>")
+		case Image:
+			fmt.Fprintf(b, "<img src=%q alt=%q>", in.Dest, PlainText(in.Children))
+		default:
+			b.WriteString("<" + tags[in.Kind] + ">")
+			writeInlines(b, in.Children)
+			b.WriteString("</" + tags[in.Kind] + ">")
+		}
+	}
+}
@@ -0,0 +1,574 @@
+package markdown
+
+import (
+	"image"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/oligo/gioview/editor/syntax"
+	gvimage "github.com/oligo/gioview/image"
+	gvwidget "github.com/oligo/gioview/widget"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+// headingScales are the text sizes of the headings relative to the text size.
+var headingScales = [...]float32{2, 1.5, 1.25, 1, 0.875, 0.85}
+
+// element is a laid out block of a document.
+type element interface {
+	// layout lays out and paints the element at the position of the
+	// document, and returns its height and the baseline of its first line.
+	layout(r *renderer, pos image.Point, width int) (int, int)
+}
+
+// builder builds the elements of a document, and registers the texts and the
+// links to the Markdown.
+type builder struct {
+	md *Markdown
+	// offset is the document offset of the next text.
+	offset int
+	// quoted is set in block quotes, and depth is the depth of nested lists.
+	quoted bool
+	depth  int
+}
+
+func (b *builder) blocks(blocks []*Block) []element {
+	var elems []element
+	for _, block := range blocks {
+		elems = append(elems, b.block(block)...)
+	}
+	return elems
+}
+
+func (b *builder) block(block *Block) []element {
+	switch block.Kind {
+	case Paragraph:
+		return b.paragraph(block.Inlines, 0)
+	case Heading:
+		return b.paragraph(block.Inlines, block.Level)
+	case ThematicBreak:
+		return []element{ruleElement{}}
+	case CodeBlock:
+		return []element{&codeElement{rt: b.code(block)}}
+	case BlockQuote:
+		quoted := b.quoted
+		b.quoted = true
+		defer func() { b.quoted = quoted }()
+		return []element{&quoteElement{children: b.blocks(block.Children)}}
+	case List:
+		return []element{b.list(block)}
+	case Table:
+		return []element{b.table(block)}
+	}
+	return nil
+}
+
+// register makes the text selectable, following the texts registered before.
+func (b *builder) register(rt *richText, sep string) {
+	var runes []rune
+	for _, run := range rt.runs {
+		runes = append(runes, []rune(run.text)...)
+	}
+	rt.runes = runes
+	rt.start = b.offset
+	rt.sep = sep
+	rt.muted = b.quoted
+	b.offset += len(runes) + 1
+	b.md.texts = append(b.md.texts, rt)
+}
+
+// paragraph builds the elements of a paragraph, or of a heading of the level.
+// Images are laid out as blocks between the text around them.
+func (b *builder) paragraph(inlines []Inline, level int) []element {
+	var elems []element
+	rt := b.newText(level)
+	flush := func() {
+		if rt.trim() {
+			b.register(rt, "\n")
+			elems = append(elems, &textElement{rt: rt, level: level})
+		}
+		rt = b.newText(level)
+	}
+
+	for _, in := range inlines {
+		switch {
+		case in.Kind == Image:
+			flush()
+			elems = append(elems, b.image(in, nil))
+		case in.Kind == Link && len(in.Children) == 1 && in.Children[0].Kind == Image:
+			flush()
+			elems = append(elems, b.image(in.Children[0], b.link(in)))
+		default:
+			b.inline(rt, in, 0, nil)
+		}
+	}
+	flush()
+	return elems
+}
+
+func (b *builder) newText(level int) *richText {
+	rt := newText()
+	if level > 0 {
+		rt.scale = headingScales[level-1]
+		rt.bold = true
+	}
+	return rt
+}
+
+func (b *builder) inline(rt *richText, in Inline, flags textFlags, link *gvwidget.Link[string]) {
+	switch in.Kind {
+	case Text:
+		rt.add(textRun{text: in.Text, flags: flags, link: link})
+	case SoftBreak:
+		rt.add(textRun{text: " ", flags: flags, link: link})
+	case LineBreak:
+		rt.add(textRun{text: "\n", flags: flags, link: link})
+	case CodeSpan:
+		rt.add(textRun{text: in.Text, flags: flags | code, link: link})
+	case Emphasis, Strong, Strikethrough, Link:
+		switch in.Kind {
+		case Emphasis:
+			flags |= italic
+		case Strong:
+			flags |= bold
+		case Strikethrough:
+			flags |= strike
+		case Link:
+			if link == nil {
+				link = b.link(in)
+			}
+		}
+		for _, child := range in.Children {
+			b.inline(rt, child, flags, link)
+		}
+	case Image:
+		// images nested in other inlines are shown as their descriptions.
+		rt.add(textRun{text: PlainText(in.Children), flags: flags | italic, link: link})
+	}
+}
+
+func (b *builder) link(in Inline) *gvwidget.Link[string] {
+	link := &gvwidget.Link[string]{
+		Title:     PlainText(in.Children),
+		Src:       in.Dest,
+		OnClicked: b.md.openLink,
+	}
+	b.md.links = append(b.md.links, link)
+	return link
+}
+
+func (b *builder) image(in Inline, link *gvwidget.Link[string]) element {
+	src := in.Dest
+	if !strings.Contains(src, "://") && !filepath.IsAbs(src) && b.md.ImageDir != "" {
+		src = filepath.Join(b.md.ImageDir, src)
+	}
+	img := b.md.images[src]
+	if img == nil {
+		img = gvimage.ImageFromFile(src)
+		b.md.images[src] = img
+	}
+
+	alt := newText()
+	alt.muted = true
+	alt.add(textRun{text: PlainText(in.Children), flags: italic})
+	if !alt.trim() {
+		alt.add(textRun{text: in.Dest, flags: italic})
+	}
+	return &imageElement{img: img, alt: alt, link: link}
+}
+
+// code builds the text of a code block, colored by the tokenizer of its
+// language.
+func (b *builder) code(block *Block) *richText {
+	rt := newText()
+	rt.pre = true
+	literal := strings.TrimSuffix(block.Literal, "\n")
+
+	tokenizer := syntax.Lookup(block.Lang())
+	if tokenizer == nil {
+		rt.add(textRun{text: literal})
+		b.register(rt, "\n")
+		return rt
+	}
+
+	var state syntax.State
+	for i, line := range strings.Split(literal, "\n") {
+		if i > 0 {
+			rt.add(textRun{text: "\n"})
+		}
+		var tokens []syntax.Token
+		tokens, state = tokenizer.Tokenize(line, state)
+		pos := 0
+		for _, t := range tokens {
+			if t.Start < pos || t.End > len(line) {
+				continue
+			}
+			rt.add(textRun{text: line[pos:t.Start]})
+			rt.add(textRun{text: line[t.Start:t.End], token: t.Kind})
+			pos = t.End
+		}
+		rt.add(textRun{text: line[pos:]})
+	}
+	b.register(rt, "\n")
+	return rt
+}
+
+func (b *builder) list(block *Block) element {
+	e := &listElement{tight: block.Tight, depth: b.depth}
+	b.depth++
+	defer func() { b.depth-- }()
+
+	for i, item := range block.Children {
+		li := &listItem{task: item.Task, checked: item.Checked}
+		if block.Ordered && !item.Task {
+			li.marker = newText()
+			li.marker.muted = b.quoted
+			li.marker.add(textRun{text: strconv.Itoa(block.Start+i) + "."})
+		}
+		li.children = b.blocks(item.Children)
+		e.items = append(e.items, li)
+	}
+	return e
+}
+
+func (b *builder) table(block *Block) element {
+	e := &tableElement{}
+	for i, row := range block.Rows {
+		var cells []*richText
+		for j, cell := range row {
+			rt := newText()
+			rt.bold = i == 0
+			rt.align = block.Align[j]
+			for _, in := range cell.Inlines {
+				b.inline(rt, in, 0, nil)
+			}
+			rt.trim()
+			sep := "\t"
+			if j == 0 {
+				sep = "\n"
+			}
+			b.register(rt, sep)
+			cells = append(cells, rt)
+		}
+		e.rows = append(e.rows, cells)
+	}
+	return e
+}
+
+// layoutElements lays out the elements vertically, separated by the gap, and
+// returns their height and the baseline of the first line.
+func (r *renderer) layoutElements(elems []element, pos image.Point, width, gap int) (int, int) {
+	y, baseline := 0, 0
+	for i, e := range elems {
+		if i > 0 {
+			y += gap
+			if e, ok := e.(*textElement); ok && e.level > 0 {
+				y += gap / 2
+			}
+		}
+		h, b := e.layout(r, pos.Add(image.Pt(0, y)), width)
+		if i == 0 {
+			baseline = b
+		}
+		y += h
+	}
+	return y, baseline
+}
+
+// gap is the space between blocks.
+func (r *renderer) gap() int {
+	return r.gtx.Sp(r.style.TextSize * 0.75)
+}
+
+// textElement is a paragraph, or a heading of the level.
+type textElement struct {
+	rt    *richText
+	level int
+}
+
+func (e *textElement) layout(r *renderer, pos image.Point, width int) (int, int) {
+	size := e.rt.layout(r, width)
+	e.rt.origin = pos
+	e.rt.paint(r)
+
+	h := size.Y
+	if e.level == 1 || e.level == 2 {
+		// underline the top level headings.
+		y := pos.Y + h + r.gtx.Dp(unit.Dp(4))
+		rect := image.Rect(pos.X, y, pos.X+width, y+max(1, r.gtx.Dp(unit.Dp(1))))
+		paint.FillShape(r.gtx.Ops, r.style.DividerColor, clip.Rect(rect).Op())
+		h = rect.Max.Y - pos.Y
+	}
+	return h, e.rt.baseline()
+}
+
+type ruleElement struct{}
+
+func (ruleElement) layout(r *renderer, pos image.Point, width int) (int, int) {
+	h := r.gtx.Dp(unit.Dp(2))
+	rect := image.Rectangle{Min: pos, Max: pos.Add(image.Pt(width, h))}
+	paint.FillShape(r.gtx.Ops, r.style.DividerColor, clip.Rect(rect).Op())
+	return h, 0
+}
+
+type codeElement struct {
+	rt *richText
+}
+
+func (e *codeElement) layout(r *renderer, pos image.Point, width int) (int, int) {
+	gtx := r.gtx
+	pad := gtx.Dp(unit.Dp(8))
+	size := e.rt.layout(r, width-2*pad)
+	h := size.Y + 2*pad
+
+	rect := image.Rectangle{Min: pos, Max: pos.Add(image.Pt(width, h))}
+	paint.FillShape(gtx.Ops, r.style.CodeBackground, clip.UniformRRect(rect, gtx.Dp(unit.Dp(4))).Op(gtx.Ops))
+	e.rt.origin = pos.Add(image.Pt(pad, pad))
+	e.rt.paint(r)
+	return h, pad + e.rt.baseline()
+}
+
+type quoteElement struct {
+	children []element
+}
+
+func (e *quoteElement) layout(r *renderer, pos image.Point, width int) (int, int) {
+	gtx := r.gtx
+	bar := gtx.Dp(unit.Dp(3))
+	indent := bar + gtx.Dp(unit.Dp(12))
+	h, baseline := r.layoutElements(e.children, pos.Add(image.Pt(indent, 0)), width-indent, r.gap())
+
+	rect := image.Rectangle{Min: pos, Max: pos.Add(image.Pt(bar, h))}
+	paint.FillShape(gtx.Ops, r.style.DividerColor, clip.Rect(rect).Op())
+	return h, baseline
+}
+
+type imageElement struct {
+	img *gvimage.ImageSource
+	// alt is shown until the image is loaded.
+	alt       *richText
+	link      *gvwidget.Link[string]
+	requested bool
+}
+
+func (e *imageElement) layout(r *renderer, pos image.Point, width int) (int, int) {
+	gtx := r.gtx
+	size := e.img.Size()
+	if size.X <= 0 || size.Y <= 0 {
+		if !e.requested {
+			e.requested = true
+			e.img.OnLoaded(func() { gtx.Execute(op.InvalidateCmd{}) })
+			// start loading the image.
+			e.img.ImageOp(image.Point{})
+		}
+		pad := gtx.Dp(unit.Dp(8))
+		altSize := e.alt.layout(r, width-2*pad)
+		rect := image.Rectangle{Min: pos, Max: pos.Add(altSize.Add(image.Pt(2*pad, 2*pad)))}
+		paint.FillShape(gtx.Ops, r.style.CodeBackground, clip.UniformRRect(rect, gtx.Dp(unit.Dp(4))).Op(gtx.Ops))
+		e.alt.origin = pos.Add(image.Pt(pad, pad))
+		e.alt.paint(r)
+		return rect.Dy(), pad + e.alt.baseline()
+	}
+
+	w := min(size.X, width)
+	h := size.Y * w / size.X
+	defer op.Offset(pos).Push(gtx.Ops).Pop()
+	gtx.Constraints = layout.Exact(image.Pt(w, h))
+	gvimage.ImageStyle{Src: e.img, Fit: widget.Contain, Position: layout.NW, Radius: unit.Dp(4)}.Layout(gtx)
+	if e.link != nil {
+		e.link.LayoutArea(gtx, image.Rect(0, 0, w, h))
+	}
+	return h, 0
+}
+
+type listItem struct {
+	// marker is the number of an ordered list item.
+	marker        *richText
+	task, checked bool
+	children      []element
+}
+
+type listElement struct {
+	items []*listItem
+	tight bool
+	// depth selects the bullet of the items.
+	depth int
+}
+
+func (e *listElement) layout(r *renderer, pos image.Point, width int) (int, int) {
+	gtx := r.gtx
+	em := gtx.Sp(r.style.TextSize)
+	space := em * 2 / 5
+	gutter := em * 3 / 2
+	for _, item := range e.items {
+		if item.marker != nil {
+			item.marker.shape(r)
+			gutter = max(gutter, item.marker.natural+space)
+		}
+	}
+
+	gap := r.gap()
+	if e.tight {
+		gap = gtx.Dp(unit.Dp(4))
+	}
+	y, first := 0, 0
+	for i, item := range e.items {
+		if i > 0 {
+			y += gap
+		}
+		h, baseline := r.layoutElements(item.children, pos.Add(image.Pt(gutter, y)), width-gutter, gap)
+		if baseline == 0 {
+			baseline = em
+		}
+		if i == 0 {
+			first = baseline
+		}
+		e.paintMarker(r, item, image.Pt(pos.X+gutter-space, pos.Y+y+baseline))
+		y += max(h, em)
+	}
+	return y, first
+}
+
+// paintMarker paints the marker of the item, right aligned to the position on
+// the baseline.
+func (e *listElement) paintMarker(r *renderer, item *listItem, pos image.Point) {
+	gtx := r.gtx
+	em := gtx.Sp(r.style.TextSize)
+	color := r.style.Color
+	if item.marker != nil && item.marker.muted {
+		color = r.style.MutedColor
+	}
+
+	switch {
+	case item.task:
+		size := em * 4 / 5
+		rect := image.Rect(pos.X-size, pos.Y-size+em/10, pos.X, pos.Y+em/10)
+		radius := gtx.Dp(unit.Dp(3))
+		if !item.checked {
+			paint.FillShape(gtx.Ops, r.style.MutedColor, clip.Stroke{
+				Path:  clip.UniformRRect(rect, radius).Path(gtx.Ops),
+				Width: float32(max(1, gtx.Dp(unit.Dp(1)))),
+			}.Op())
+			return
+		}
+		paint.FillShape(gtx.Ops, r.style.LinkColor, clip.UniformRRect(rect, radius).Op(gtx.Ops))
+		s := float32(size)
+		origin := layout.FPt(rect.Min)
+		var check clip.Path
+		check.Begin(gtx.Ops)
+		check.MoveTo(origin.Add(f32.Pt(s*0.22, s*0.52)))
+		check.LineTo(origin.Add(f32.Pt(s*0.42, s*0.72)))
+		check.LineTo(origin.Add(f32.Pt(s*0.78, s*0.3)))
+		paint.FillShape(gtx.Ops, r.style.CheckColor, clip.Stroke{
+			Path:  check.End(),
+			Width: float32(gtx.Dp(unit.Dp(2))),
+		}.Op())
+
+	case item.marker != nil:
+		item.marker.layout(r, item.marker.natural)
+		item.marker.origin = image.Pt(pos.X-item.marker.natural, pos.Y-item.marker.baseline())
+		item.marker.paint(r)
+
+	default:
+		d := max(em/3, 4)
+		rect := image.Rect(pos.X-d, pos.Y-em/3-d/2, pos.X, pos.Y-em/3-d/2+d)
+		switch e.depth % 3 {
+		case 0:
+			paint.FillShape(gtx.Ops, color, clip.Ellipse(rect).Op(gtx.Ops))
+		case 1:
+			paint.FillShape(gtx.Ops, color, clip.Stroke{
+				Path:  clip.Ellipse(rect).Path(gtx.Ops),
+				Width: float32(max(1, gtx.Dp(unit.Dp(1)))),
+			}.Op())
+		default:
+			paint.FillShape(gtx.Ops, color, clip.Rect(rect).Op())
+		}
+	}
+}
+
+type tableElement struct {
+	// rows are the texts of the cells, the header row first.
+	rows [][]*richText
+}
+
+func (e *tableElement) layout(r *renderer, pos image.Point, width int) (int, int) {
+	gtx := r.gtx
+	padX, padY := gtx.Dp(unit.Dp(8)), gtx.Dp(unit.Dp(4))
+	border := max(1, gtx.Dp(unit.Dp(1)))
+	cols := len(e.rows[0])
+
+	// the columns take their natural widths if the table fits, or share the
+	// width in proportion to them otherwise.
+	widths := make([]int, cols)
+	total := border * (cols + 1)
+	for _, row := range e.rows {
+		for j, rt := range row {
+			rt.shape(r)
+			widths[j] = max(widths[j], rt.natural+2*padX)
+		}
+	}
+	for _, w := range widths {
+		total += w
+	}
+	if total > width {
+		avail := width - border*(cols+1)
+		natural := total - border*(cols+1)
+		minWidth := gtx.Sp(r.style.TextSize)*3 + 2*padX
+		for j, w := range widths {
+			widths[j] = max(minWidth, w*avail/natural)
+		}
+	}
+
+	heights := make([]int, len(e.rows))
+	for i, row := range e.rows {
+		for j, rt := range row {
+			heights[i] = max(heights[i], rt.layout(r, widths[j]-2*padX).Y+2*padY)
+		}
+	}
+
+	size := image.Pt(border*(cols+1), border*(len(e.rows)+1))
+	for _, w := range widths {
+		size.X += w
+	}
+	for _, h := range heights {
+		size.Y += h
+	}
+	header := image.Rectangle{Min: pos, Max: pos.Add(image.Pt(size.X, heights[0]+2*border))}
+	paint.FillShape(gtx.Ops, r.style.CodeBackground, clip.Rect(header).Op())
+
+	y := pos.Y + border
+	for i, row := range e.rows {
+		x := pos.X + border
+		for j, rt := range row {
+			rt.origin = image.Pt(x+padX, y+padY)
+			rt.paint(r)
+			x += widths[j] + border
+		}
+		y += heights[i] + border
+	}
+
+	// the grid.
+	x, y := pos.X, pos.Y
+	for j := 0; j <= cols; j++ {
+		paint.FillShape(gtx.Ops, r.style.DividerColor, clip.Rect(image.Rect(x, pos.Y, x+border, pos.Y+size.Y)).Op())
+		if j < cols {
+			x += widths[j] + border
+		}
+	}
+	for i := 0; i <= len(e.rows); i++ {
+		paint.FillShape(gtx.Ops, r.style.DividerColor, clip.Rect(image.Rect(pos.X, y, pos.X+size.X, y+border)).Op())
+		if i < len(e.rows) {
+			y += heights[i] + border
+		}
+	}
+
+	return size.Y, border + padY + e.rows[0][0].baseline()
+}
@@ -0,0 +1,566 @@
+package markdown
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/oligo/gioview/editor/syntax"
+	gvwidget "github.com/oligo/gioview/widget"
+
+	"gioui.org/font"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"golang.org/x/image/math/fixed"
+)
+
+// textFlags are the styles of a run of text.
+type textFlags uint8
+
+const (
+	bold textFlags = 1 << iota
+	italic
+	code
+	strike
+)
+
+// textRun is a run of text in the same style.
+type textRun struct {
+	text  string
+	flags textFlags
+	// token is the kind of the run in a code block.
+	token syntax.Kind
+	link  *gvwidget.Link[string]
+}
+
+// richText is a paragraph of styled runs, which is shaped in pieces, and laid
+// out in lines by breaking the lines between the pieces.
+type richText struct {
+	runs []textRun
+	// scale is the text size relative to the text size of the style.
+	scale float32
+	bold  bool
+	// pre is set for the text of code blocks, whose lines are only broken at
+	// line breaks and where they overflow.
+	pre   bool
+	muted bool
+	align Alignment
+
+	// runes is the plain text, and start is its offset in the document, or -1
+	// if the text can not be selected.
+	runes []rune
+	start int
+	// sep is written before the text when it is copied after other text.
+	sep string
+
+	// pieces are the shaped pieces of the runs.
+	shapeKey shapeKey
+	pieces   []piece
+	natural  int
+	// lines are the pieces laid out in lines of the width.
+	width int
+	lines []textLine
+	size  image.Point
+	// origin is the position of the text in the document.
+	origin image.Point
+}
+
+// shapeKey is the style parameters affecting the shaping of text.
+type shapeKey struct {
+	font, mono font.Font
+	size       unit.Sp
+	pxPerSp    float32
+}
+
+// piece is a shaped part of a run. Lines may only be broken between pieces,
+// unless a piece is too wide for a line.
+type piece struct {
+	run        int
+	text       string
+	start, end int
+	glyphs     []text.Glyph
+	// xs are the offsets of the rune boundaries of the piece.
+	xs              []int
+	ascent, descent int
+	// space is the width of the trailing spaces.
+	space int
+	// breakAfter reports whether a line may be broken after the piece, and
+	// newline whether the piece is a line break.
+	breakAfter, newline bool
+}
+
+func (p *piece) width() int {
+	return p.xs[len(p.xs)-1]
+}
+
+type textLine struct {
+	// x is the offset of the line for the alignment, and y is its top.
+	x, y     int
+	height   int
+	baseline int
+	width    int
+	frags    []fragment
+}
+
+// fragment is a piece laid out in a line.
+type fragment struct {
+	x     int
+	piece *piece
+}
+
+func newText() *richText {
+	return &richText{scale: 1, start: -1}
+}
+
+func (rt *richText) add(run textRun) {
+	if run.text == "" {
+		return
+	}
+	if n := len(rt.runs) - 1; n >= 0 && rt.runs[n].flags == run.flags && rt.runs[n].token == run.token && rt.runs[n].link == run.link {
+		rt.runs[n].text += run.text
+	} else {
+		rt.runs = append(rt.runs, run)
+	}
+}
+
+// trim removes the leading and trailing whitespace of the text, and reports
+// whether any text is left.
+func (rt *richText) trim() bool {
+	for len(rt.runs) > 0 {
+		rt.runs[0].text = strings.TrimLeft(rt.runs[0].text, " \n")
+		if rt.runs[0].text != "" {
+			break
+		}
+		rt.runs = rt.runs[1:]
+	}
+	for n := len(rt.runs); n > 0; n = len(rt.runs) {
+		rt.runs[n-1].text = strings.TrimRight(rt.runs[n-1].text, " \n")
+		if rt.runs[n-1].text != "" {
+			break
+		}
+		rt.runs = rt.runs[:n-1]
+	}
+	return len(rt.runs) > 0
+}
+
+// shape splits the runs into pieces after the spaces and at the line breaks,
+// and shapes them.
+func (rt *richText) shape(r *renderer) {
+	key := r.shapeKey()
+	if rt.pieces != nil && rt.shapeKey == key {
+		return
+	}
+	rt.shapeKey = key
+	rt.pieces = rt.pieces[:0]
+	rt.lines = nil
+
+	offset := 0
+	for i, run := range rt.runs {
+		f, size := r.font(rt, run)
+		seg, segStart := 0, offset
+		for j, c := range run.text {
+			switch {
+			case c == '\n':
+				if j > seg {
+					rt.pieces = append(rt.pieces, r.shapePiece(i, run.text[seg:j], segStart, f, size))
+				}
+				nl := r.shapePiece(i, "", offset, f, size)
+				nl.end, nl.xs, nl.newline = offset+1, []int{0, 0}, true
+				rt.pieces = append(rt.pieces, nl)
+				seg, segStart = j+1, offset+1
+			case c == ' ' && !rt.pre && (j+1 == len(run.text) || run.text[j+1] != ' '):
+				p := r.shapePiece(i, run.text[seg:j+1], segStart, f, size)
+				p.breakAfter = true
+				rt.pieces = append(rt.pieces, p)
+				seg, segStart = j+1, offset+1
+			}
+			offset++
+		}
+		if seg < len(run.text) {
+			rt.pieces = append(rt.pieces, r.shapePiece(i, run.text[seg:], segStart, f, size))
+		}
+	}
+
+	rt.natural = 0
+	w := 0
+	for i := range rt.pieces {
+		p := &rt.pieces[i]
+		if p.newline {
+			w = 0
+			continue
+		}
+		w += p.width()
+		rt.natural = max(rt.natural, w-p.space)
+	}
+}
+
+// layout lays out the pieces in lines of the width, and returns the size of
+// the text.
+func (rt *richText) layout(r *renderer, width int) image.Point {
+	rt.shape(r)
+	if rt.lines != nil && rt.width == width {
+		return rt.size
+	}
+	rt.width = width
+	rt.lines = rt.lines[:0]
+	rt.size = image.Point{}
+
+	var line textLine
+	x := 0
+	place := func(p *piece) {
+		line.frags = append(line.frags, fragment{x: x, piece: p})
+		x += p.width()
+		line.width = x - p.space
+	}
+	newLine := func() {
+		rt.addLine(r, line)
+		line = textLine{}
+		x = 0
+	}
+	// placeSplit places the piece, splitting it where it overflows the line.
+	placeSplit := func(p *piece) {
+		for x+p.width()-p.space > width {
+			n := 0
+			for n+1 < len(p.xs) && x+p.xs[n+1] <= width {
+				n++
+			}
+			if n == 0 {
+				if x > 0 {
+					newLine()
+					continue
+				}
+				n = 1
+			}
+			if n >= p.end-p.start {
+				break
+			}
+			head, tail := r.splitPiece(rt, p, n)
+			place(head)
+			newLine()
+			p = tail
+		}
+		place(p)
+	}
+
+	pieces := rt.pieces
+	for i := 0; i < len(pieces); {
+		if pieces[i].newline {
+			place(&pieces[i])
+			newLine()
+			i++
+			continue
+		}
+		// the pieces up to the next line break opportunity.
+		j := i
+		w := 0
+		for j < len(pieces) && !pieces[j].newline {
+			w += pieces[j].width()
+			j++
+			if pieces[j-1].breakAfter {
+				break
+			}
+		}
+		w -= pieces[j-1].space
+
+		if x > 0 && x+w > width {
+			newLine()
+		}
+		for k := i; k < j; k++ {
+			if x+w <= width {
+				place(&pieces[k])
+			} else {
+				placeSplit(&pieces[k])
+			}
+		}
+		i = j
+	}
+	if len(line.frags) > 0 || len(rt.lines) == 0 {
+		newLine()
+	}
+
+	for i := range rt.lines {
+		l := &rt.lines[i]
+		switch rt.align {
+		case AlignCenter:
+			l.x = (width - l.width) / 2
+		case AlignRight:
+			l.x = width - l.width
+		}
+	}
+	return rt.size
+}
+
+func (rt *richText) addLine(r *renderer, line textLine) {
+	ascent, descent := 0, 0
+	for _, f := range line.frags {
+		ascent = max(ascent, f.piece.ascent)
+		descent = max(descent, f.piece.descent)
+	}
+	if ascent == 0 && descent == 0 {
+		f, size := r.font(rt, textRun{})
+		p := r.shapePiece(0, " ", 0, f, size)
+		ascent, descent = p.ascent, p.descent
+	}
+	line.y = rt.size.Y
+	line.height = int(float32(ascent+descent)*r.style.LineHeightScale + 0.5)
+	line.baseline = line.y + (line.height-ascent-descent)/2 + ascent
+	rt.lines = append(rt.lines, line)
+	rt.size.X = max(rt.size.X, line.width)
+	rt.size.Y += line.height
+}
+
+// baseline returns the baseline of the first line.
+func (rt *richText) baseline() int {
+	if len(rt.lines) == 0 {
+		return 0
+	}
+	return rt.lines[0].baseline
+}
+
+// offsetAt returns the offset of the rune boundary closest to the position,
+// which is relative to the origin of the text.
+func (rt *richText) offsetAt(pos image.Point) int {
+	if len(rt.lines) == 0 {
+		return 0
+	}
+	line := rt.lines[0]
+	for _, l := range rt.lines[1:] {
+		if pos.Y < l.y {
+			break
+		}
+		line = l
+	}
+	if len(line.frags) == 0 {
+		return 0
+	}
+
+	for _, f := range line.frags {
+		p := f.piece
+		x := line.x + f.x
+		if pos.X >= x+p.width() || p.newline {
+			continue
+		}
+		closest := 0
+		for i, px := range p.xs {
+			if abs(x+px-pos.X) < abs(x+p.xs[closest]-pos.X) {
+				closest = i
+			}
+		}
+		return p.start + closest
+	}
+
+	last := line.frags[len(line.frags)-1].piece
+	if last.newline {
+		return last.start
+	}
+	if pos.X < line.x {
+		return line.frags[0].piece.start
+	}
+	return last.end
+}
+
+// wordAt returns the range of the word around the offset.
+func (rt *richText) wordAt(offset int) (int, int) {
+	start, end := offset, offset
+	for start > 0 && isWordRune(rt.runes[start-1]) {
+		start--
+	}
+	for end < len(rt.runes) && isWordRune(rt.runes[end]) {
+		end++
+	}
+	if start == end && end < len(rt.runes) {
+		end++
+	}
+	return start, end
+}
+
+// paint paints the text and its selection at its origin.
+func (rt *richText) paint(r *renderer) {
+	gtx := r.gtx
+	start, end := r.md.selectionOf(rt)
+	defer op.Offset(rt.origin).Push(gtx.Ops).Pop()
+
+	thickness := max(1, gtx.Dp(unit.Dp(1)))
+	for _, line := range rt.lines {
+		for _, f := range line.frags {
+			p := f.piece
+			run := rt.runs[p.run]
+			x := line.x + f.x
+			w := p.width() - p.space
+
+			if run.flags&code != 0 && !rt.pre && w > 0 {
+				pad := gtx.Dp(unit.Dp(2))
+				rect := image.Rect(x-pad, line.baseline-p.ascent, x+w+pad, line.baseline+p.descent)
+				paint.FillShape(gtx.Ops, r.style.CodeBackground, clip.UniformRRect(rect, pad).Op(gtx.Ops))
+			}
+
+			if start < p.end && end > p.start {
+				x0 := x + p.xs[max(start, p.start)-p.start]
+				x1 := x + p.xs[min(end, p.end)-p.start]
+				if p.newline {
+					x1 = x0 + gtx.Sp(r.style.TextSize)/3
+				}
+				rect := image.Rect(x0, line.y, x1, line.y+line.height)
+				paint.FillShape(gtx.Ops, r.style.SelectionColor, clip.Rect(rect).Op())
+			}
+
+			c := r.color(rt, run)
+			r.paintGlyphs(p.glyphs, c, image.Pt(x, line.baseline))
+
+			if run.flags&strike != 0 {
+				y := line.baseline - p.ascent*3/10
+				paint.FillShape(gtx.Ops, c, clip.Rect(image.Rect(x, y, x+w, y+thickness)).Op())
+			}
+			if run.link != nil {
+				y := line.baseline + thickness
+				paint.FillShape(gtx.Ops, c, clip.Rect(image.Rect(x, y, x+p.width(), y+thickness)).Op())
+				run.link.LayoutArea(gtx, image.Rect(x, line.y, x+p.width(), line.y+line.height))
+			}
+		}
+	}
+}
+
+// renderer lays out and paints the elements of a document in a frame.
+type renderer struct {
+	gtx   C
+	style *MarkdownStyle
+	md    *Markdown
+}
+
+func (r *renderer) shapeKey() shapeKey {
+	return shapeKey{
+		font:    r.style.Font,
+		mono:    r.style.MonoFont,
+		size:    r.style.TextSize,
+		pxPerSp: r.gtx.Metric.PxPerSp,
+	}
+}
+
+// font returns the font and the text size of the run.
+func (r *renderer) font(rt *richText, run textRun) (font.Font, unit.Sp) {
+	f := r.style.Font
+	size := r.style.TextSize * unit.Sp(rt.scale)
+	if rt.pre || run.flags&code != 0 {
+		f = r.style.MonoFont
+		size *= 0.9
+	}
+	if rt.bold || run.flags&bold != 0 {
+		f.Weight = font.Bold
+	}
+	if run.flags&italic != 0 {
+		f.Style = font.Italic
+	}
+	return f, size
+}
+
+func (r *renderer) color(rt *richText, run textRun) color.NRGBA {
+	switch {
+	case run.link != nil:
+		return r.style.LinkColor
+	case rt.pre && r.style.Scheme.Style(run.token).Color.A != 0:
+		return r.style.Scheme.Style(run.token).Color
+	case rt.muted:
+		return r.style.MutedColor
+	}
+	return r.style.Color
+}
+
+// shapePiece shapes the text of a run on a single line.
+func (r *renderer) shapePiece(run int, s string, start int, f font.Font, size unit.Sp) piece {
+	p := piece{run: run, text: s, start: start, end: start + utf8.RuneCountInString(s)}
+	p.xs = make([]int, 1, p.end-p.start+1)
+
+	params := text.Parameters{
+		Font:             f,
+		PxPerEm:          fixed.I(r.gtx.Sp(size)),
+		MaxWidth:         1 << 24,
+		Locale:           r.gtx.Locale,
+		DisableSpaceTrim: true,
+	}
+	shaper := r.style.shaper
+	if s == "" {
+		// shape a space for the metrics of the font.
+		shaper.LayoutString(params, " ")
+	} else {
+		shaper.LayoutString(params, s)
+	}
+
+	var clusterX fixed.Int26_6
+	for {
+		g, ok := shaper.NextGlyph()
+		if !ok {
+			break
+		}
+		p.ascent = max(p.ascent, g.Ascent.Ceil())
+		p.descent = max(p.descent, g.Descent.Ceil())
+		if s == "" {
+			continue
+		}
+		p.glyphs = append(p.glyphs, g)
+		if g.Flags&text.FlagClusterBreak == 0 || g.Runes == 0 {
+			continue
+		}
+		// distribute the advance of the cluster among its runes.
+		end := g.X + g.Advance
+		for i := 1; i <= int(g.Runes); i++ {
+			x := clusterX + (end-clusterX)*fixed.Int26_6(i)/fixed.Int26_6(g.Runes)
+			p.xs = append(p.xs, x.Round())
+		}
+		clusterX = end
+	}
+	for len(p.xs) < p.end-p.start+1 {
+		p.xs = append(p.xs, p.xs[len(p.xs)-1])
+	}
+	p.xs = p.xs[:p.end-p.start+1]
+
+	runes := []rune(s)
+	n := len(runes)
+	for n > 0 && runes[n-1] == ' ' {
+		n--
+	}
+	p.space = p.width() - p.xs[n]
+	return p
+}
+
+// splitPiece splits the piece after n runes, and shapes the two parts.
+func (r *renderer) splitPiece(rt *richText, p *piece, n int) (*piece, *piece) {
+	f, size := r.font(rt, rt.runs[p.run])
+	runes := []rune(p.text)
+	head := r.shapePiece(p.run, string(runes[:n]), p.start, f, size)
+	tail := r.shapePiece(p.run, string(runes[n:]), p.start+n, f, size)
+	tail.breakAfter = p.breakAfter
+	return &head, &tail
+}
+
+func (r *renderer) paintGlyphs(glyphs []text.Glyph, c color.NRGBA, pos image.Point) {
+	if len(glyphs) == 0 {
+		return
+	}
+	ops := r.gtx.Ops
+	defer op.Offset(pos).Push(ops).Pop()
+
+	shaper := r.style.shaper
+	outline := clip.Outline{Path: shaper.Shape(glyphs)}.Op().Push(ops)
+	paint.ColorOp{Color: c}.Add(ops)
+	paint.PaintOp{}.Add(ops)
+	outline.Pop()
+	if call := shaper.Bitmaps(glyphs); call != (op.CallOp{}) {
+		call.Add(ops)
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -3,8 +3,8 @@ package menu
 import (
 	"image"
 	"image/color"
-	"log"
 
+	"github.com/oligo/gioview/keybinding"
 	"github.com/oligo/gioview/misc"
 	"github.com/oligo/gioview/theme"
 
@@ -49,11 +49,70 @@ type Menu struct {
 	OptionInset layout.Inset
 	// Max width of the menu.
 	MaxWidth unit.Dp
+	// Commands has the key chords of the menu commands, which move the focus
+	// between the options, click the focused one and dismiss the menu. If it
+	// is nil, the commands have their default key chords.
+	Commands *keybinding.Registry
+}
+
+// menuCommands are the commands of a focused menu.
+var menuCommands = []keybinding.CommandID{
+	keybinding.MenuNextCommand,
+	keybinding.MenuPreviousCommand,
+	keybinding.MenuSelectCommand,
+	keybinding.MenuCloseCommand,
 }
 
 type MenuOption struct {
 	Layout    func(gtx C, th *theme.Theme) D
 	OnClicked func() error
+	// Command is run when the option is clicked if OnClicked is not set. The
+	// title of the command is shown if Layout is not set, and its shortcut is
+	// shown after the option. The option is disabled if the command is.
+	Command *keybinding.Command
+	// Enabled reports whether the option can be clicked. The option is
+	// enabled if it is nil.
+	Enabled func() bool
+}
+
+func (opt *MenuOption) enabled() bool {
+	if opt.Enabled != nil && !opt.Enabled() {
+		return false
+	}
+	return opt.Command == nil || opt.Command.IsEnabled()
+}
+
+func (opt *MenuOption) run(gtx C) {
+	if opt.OnClicked != nil {
+		opt.OnClicked()
+	} else if opt.Command != nil {
+		opt.Command.Run(gtx)
+	}
+}
+
+func (opt *MenuOption) layout(gtx C, th *theme.Theme) D {
+	w := opt.Layout
+	if w == nil && opt.Command != nil {
+		w = func(gtx C, th *theme.Theme) D {
+			return material.Label(th.Theme, th.TextSize, opt.Command.Title).Layout(gtx)
+		}
+	}
+	if opt.Command == nil || opt.Command.Shortcut() == "" {
+		return w(gtx, th)
+	}
+
+	return layout.Flex{
+		Axis:      layout.Horizontal,
+		Alignment: layout.Middle,
+		Spacing:   layout.SpaceBetween,
+	}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return w(gtx, th)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return LayoutShortcut(gtx, th, opt.Command.Shortcut())
+		}),
+	)
 }
 
 func newMenu(options [][]MenuOption) Menu {
@@ -151,39 +210,22 @@ func (m *Menu) layoutOptions(gtx C, th *theme.Theme) D {
 		gtx.Ops = originalOps
 	}
 
+	style := NewStyle(th)
+	if m.Background != (color.NRGBA{}) {
+		style.Background = m.Background
+	}
+
 	surface := component.Surface(th.Theme)
 	surface.Fill = th.Bg
 
 	return surface.Layout(gtx, func(gtx C) D {
-		macro := op.Record(gtx.Ops)
-		dims := widget.Border{
-			Color:        misc.WithAlpha(th.Fg, 0xb6),
-			CornerRadius: unit.Dp(4),
-			Width:        unit.Dp(0.5),
-		}.Layout(gtx, func(gtx C) D {
-			return layout.Inset{
-				Top:    unit.Dp(8),
-				Bottom: unit.Dp(8),
-			}.Layout(gtx, func(gtx C) D {
-				return material.List(th.Theme, &m.optionList).Layout(gtx, len(m.menuItems), func(gtx C, index int) D {
-					gtx.Constraints.Min.X = maxWidth
-					gtx.Constraints.Max.X = maxWidth
-					return m.menuItems[index](gtx)
-				})
+		return style.LayoutSurface(gtx, func(gtx C) D {
+			return material.List(th.Theme, &m.optionList).Layout(gtx, len(m.menuItems), func(gtx C, index int) D {
+				gtx.Constraints.Min.X = maxWidth
+				gtx.Constraints.Max.X = maxWidth
+				return m.menuItems[index](gtx)
 			})
 		})
-
-		call := macro.Stop()
-		defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
-		if m.Background == (color.NRGBA{}) {
-			paint.ColorOp{Color: th.Bg2}.Add(gtx.Ops)
-		} else {
-			paint.ColorOp{Color: m.Background}.Add(gtx.Ops)
-		}
-		paint.PaintOp{}.Add(gtx.Ops)
-		call.Add(gtx.Ops)
-
-		return dims
 	})
 
 }
@@ -207,55 +249,46 @@ func (m *Menu) update(gtx C) {
 		gtx.Execute(key.FocusCmd{Tag: m})
 	}
 
+	filters := append([]event.Filter{key.FocusFilter{Target: m}}, m.Commands.Filters(m, menuCommands...)...)
 	for {
-		e, ok := gtx.Event(
-			key.FocusFilter{Target: m},
-			key.Filter{Focus: m, Name: key.NameUpArrow},
-			key.Filter{Focus: m, Name: key.NameDownArrow},
-			key.Filter{Focus: m, Name: key.NameEnter},
-			key.Filter{Focus: m, Name: key.NameReturn},
-			key.Filter{Focus: m, Name: key.NameEscape},
-		)
-
+		e, ok := gtx.Event(filters...)
 		if !ok {
 			break
 		}
 
-		switch e := e.(type) {
-		case key.Event:
-			// log.Println("menu received key event", e)
-			if e.Name == key.NameDownArrow && e.State == key.Release {
-				m.focusedOption++
-				if m.focusedOption >= len(m.menuItems) {
-					m.focusedOption = 0
-				}
+		ke, isKey := e.(key.Event)
+		if !isKey || ke.State != key.Release {
+			continue
+		}
+		id, _ := m.Commands.Match(ke, menuCommands...)
+		switch id {
+		case keybinding.MenuNextCommand:
+			m.focusedOption++
+			if m.focusedOption >= len(m.menuItems) {
+				m.focusedOption = 0
 			}
-			if e.Name == key.NameUpArrow && e.State == key.Release {
-				m.focusedOption--
-				if m.focusedOption < 0 {
-					m.focusedOption = len(m.menuItems) - 1
-				}
+		case keybinding.MenuPreviousCommand:
+			m.focusedOption--
+			if m.focusedOption < 0 {
+				m.focusedOption = len(m.menuItems) - 1
 			}
-			if (e.Name == key.NameEnter || e.Name == key.NameReturn) && e.State == key.Release {
-				log.Println("enter or return key pressed")
-				if m.focusedOption >= 0 {
-					// simulate a mouse click
-					m.optionStates[m.focusedOption].Click()
-				}
-			}
-
-			if e.Name == key.NameEscape && e.State == key.Release {
-				m.requestDismiss = true
-				gtx.Execute(op.InvalidateCmd{})
+		case keybinding.MenuSelectCommand:
+			if m.focusedOption >= 0 {
+				// simulate a mouse click
+				m.optionStates[m.focusedOption].Click()
 			}
+		case keybinding.MenuCloseCommand:
+			m.requestDismiss = true
+			gtx.Execute(op.InvalidateCmd{})
 		}
 	}
 
 }
 
 func (m *Menu) layoutOption(gtx C, th *theme.Theme, state *widget.Clickable, opt *MenuOption) D {
-	if state.Clicked(gtx) {
-		opt.OnClicked()
+	enabled := opt.enabled()
+	if state.Clicked(gtx) && enabled {
+		opt.run(gtx)
 		m.requestDismiss = true
 		gtx.Execute(op.InvalidateCmd{})
 	}
@@ -270,20 +303,89 @@ func (m *Menu) layoutOption(gtx C, th *theme.Theme, state *widget.Clickable, opt
 		Bottom: unit.Dp(4),
 	}.Layout(gtx, func(gtx C) D {
 		return material.Clickable(gtx, state, func(gtx C) D {
-			macro := op.Record(gtx.Ops)
-			dims := m.OptionInset.Layout(gtx, func(gtx C) D {
-				return opt.Layout(gtx, th)
+			style := NewStyle(th)
+			style.OptionInset = m.OptionInset
+			focused := m.focusedOption >= 0 && m.focusedOption < len(m.optionStates) && m.optionStates[m.focusedOption] == state
+			return style.LayoutOption(gtx, focused, func(gtx C) D {
+				if !enabled {
+					defer paint.PushOpacity(gtx.Ops, 0.5).Pop()
+				}
+				return opt.layout(gtx, th)
 			})
-			callOp := macro.Stop()
+		})
+	})
+}
 
-			defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
-			if m.focusedOption >= 0 && m.focusedOption < len(m.optionStates) && m.optionStates[m.focusedOption] == state {
-				paint.ColorOp{Color: misc.WithAlpha(th.Fg, th.HoverAlpha)}.Add(gtx.Ops)
-				paint.PaintOp{}.Add(gtx.Ops)
-			}
+// Style is the look of the menus. Popups outside of this package, e.g., the
+// completion popup of the editor, use it to look like a menu.
+type Style struct {
+	// Background is the color of the menu background.
+	Background color.NRGBA
+	// Border is the color of the menu border.
+	Border color.NRGBA
+	// Highlight is the background color of the focused option.
+	Highlight color.NRGBA
+	// OptionInset is applied around the options.
+	OptionInset layout.Inset
+}
 
-			callOp.Add(gtx.Ops)
-			return dims
-		})
+// NewStyle returns the menu style of the theme.
+func NewStyle(th *theme.Theme) Style {
+	return Style{
+		Background:  th.Bg2,
+		Border:      misc.WithAlpha(th.Fg, 0xb6),
+		Highlight:   misc.WithAlpha(th.Fg, th.HoverAlpha),
+		OptionInset: defaultOptionInset,
+	}
+}
+
+// LayoutSurface lays out w on the menu background, inside the menu border.
+func (s Style) LayoutSurface(gtx C, w layout.Widget) D {
+	macro := op.Record(gtx.Ops)
+	dims := widget.Border{
+		Color:        s.Border,
+		CornerRadius: unit.Dp(4),
+		Width:        unit.Dp(0.5),
+	}.Layout(gtx, func(gtx C) D {
+		return layout.Inset{
+			Top:    unit.Dp(8),
+			Bottom: unit.Dp(8),
+		}.Layout(gtx, w)
+	})
+	call := macro.Stop()
+
+	defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
+	paint.ColorOp{Color: s.Background}.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	call.Add(gtx.Ops)
+	return dims
+}
+
+// LayoutShortcut lays out the key chord of a command after a menu option, or
+// nothing if shortcut is empty.
+func LayoutShortcut(gtx C, th *theme.Theme, shortcut string) D {
+	if shortcut == "" {
+		return D{}
+	}
+	return layout.Inset{Left: unit.Dp(24)}.Layout(gtx, func(gtx C) D {
+		label := material.Label(th.Theme, th.TextSize*0.9, shortcut)
+		label.Color = misc.WithAlpha(th.Fg, 0x90)
+		label.MaxLines = 1
+		return label.Layout(gtx)
 	})
 }
+
+// LayoutOption lays out an option, which is highlighted if it is focused.
+func (s Style) LayoutOption(gtx C, focused bool, w layout.Widget) D {
+	macro := op.Record(gtx.Ops)
+	dims := s.OptionInset.Layout(gtx, w)
+	call := macro.Stop()
+
+	defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
+	if focused {
+		paint.ColorOp{Color: s.Highlight}.Add(gtx.Ops)
+		paint.PaintOp{}.Add(gtx.Ops)
+	}
+	call.Add(gtx.Ops)
+	return dims
+}
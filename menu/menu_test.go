@@ -0,0 +1,37 @@
+package menu
+
+import (
+	"image"
+	"testing"
+
+	"github.com/oligo/gioview/keybinding"
+	"github.com/oligo/gioview/uitest"
+)
+
+func TestCommandOption(t *testing.T) {
+	runs, enabled := 0, true
+	cmd := &keybinding.Command{
+		ID:      "note.new",
+		Title:   "New Note",
+		Handler: func(gtx C) { runs++ },
+		Enabled: func() bool { return enabled },
+	}
+
+	m := NewContextMenu([][]MenuOption{{{Command: cmd}}}, false)
+	h := uitest.New(image.Pt(300, 300), nil)
+	h.Widget = func(gtx C) D { return m.Layout(gtx, h.Theme) }
+	h.Frame()
+
+	// The menu option runs the command, unless it is disabled.
+	enabled = false
+	h.RightClick(image.Pt(50, 50))
+	h.Click(image.Pt(80, 70))
+	if runs != 0 {
+		t.Fatalf("disabled command is run by the menu option")
+	}
+	enabled = true
+	h.Click(image.Pt(80, 70))
+	if runs != 1 {
+		t.Fatalf("command is not run by the menu option")
+	}
+}
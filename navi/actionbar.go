@@ -114,10 +114,14 @@ var actionButtonInset = layout.Inset{
 
 func (ab *ActionBar) layoutAction(gtx layout.Context, th *theme.Theme, state *widget.Clickable, action view.ViewAction) layout.Dimensions {
 	return actionButtonInset.Layout(gtx, func(gtx C) D {
-		if state.Clicked(gtx) {
-			action.OnClicked(gtx)
+		if state.Clicked(gtx) && action.Enabled() {
+			action.Run(gtx)
 		}
-		return misc.IconButton(th, action.Icon, state, action.Name).Layout(gtx)
+		description := action.Title()
+		if shortcut := action.Shortcut(); shortcut != "" {
+			description += " (" + shortcut + ")"
+		}
+		return misc.IconButton(th, action.Icon, state, description).Layout(gtx)
 	})
 }
 
@@ -139,7 +143,9 @@ func (om *overflowMenu) update(gtx C, th *theme.Theme) {
 		action := om.actionForIndex(idx)
 		options = append(options, menu.MenuOption{
 			OnClicked: func() error {
-				action.OnClicked(gtx)
+				if action.Enabled() {
+					action.Run(gtx)
+				}
 				return nil
 			},
 			Layout: func(gtx C, th *theme.Theme) D {
@@ -152,9 +158,12 @@ func (om *overflowMenu) update(gtx C, th *theme.Theme) {
 					}),
 					layout.Rigid(layout.Spacer{Width: unit.Dp(4)}.Layout),
 					layout.Rigid(func(gtx C) D {
-						label := material.Label(th.Theme, th.TextSize, action.Name)
+						label := material.Label(th.Theme, th.TextSize, action.Title())
 						return label.Layout(gtx)
 					}),
+					layout.Rigid(func(gtx C) D {
+						return menu.LayoutShortcut(gtx, th, action.Shortcut())
+					}),
 				)
 
 			},
@@ -3,7 +3,9 @@ package navi
 import (
 	"image"
 	"image/color"
+	"math"
 
+	"github.com/oligo/gioview/menu"
 	"github.com/oligo/gioview/misc"
 	"github.com/oligo/gioview/theme"
 	"github.com/oligo/gioview/view"
@@ -28,20 +30,41 @@ var (
 	backwardIcon, _ = widget.NewIcon(icons.NavigationArrowBack)
 	forwardIcon, _  = widget.NewIcon(icons.NavigationArrowForward)
 	closeIcon, _    = widget.NewIcon(icons.NavigationClose)
+	pinIcon, _      = widget.NewIcon(icons.MapsPinDrop)
+	tabListIcon, _  = widget.NewIcon(icons.NavigationArrowDropDown)
 )
 
 const (
 	TabSelectedEvent = TabEvent("TabSelected")
 	TabClosedEvent   = TabEvent("TabClosed")
+	TabDetachedEvent = TabEvent("TabDetached")
+	// TabMovedEvent is emitted when the tab is dragged horizontally and dropped.
+	TabMovedEvent       = TabEvent("TabMoved")
+	TabPinToggledEvent  = TabEvent("TabPinToggled")
+	TabDuplicatedEvent  = TabEvent("TabDuplicated")
+	TabCloseOthersEvent = TabEvent("TabCloseOthers")
+	TabCloseRightEvent  = TabEvent("TabCloseRight")
 )
 
+// a tab dragged further than this distance away from the tab bar is detached.
+const tabDetachDistance = unit.Dp(64)
+
 type TabbarOptions struct {
 	MaxTabWidth unit.Dp
+	// MinTabWidth is the width tabs shrink to before they overflow the tab
+	// bar. Overflowed tabs are scrollable and listed in a dropdown menu.
+	MinTabWidth unit.Dp
 	Height      unit.Dp
+	// OnDetach is called when the tab at idx is dragged out of the tab bar,
+	// usually to move the tab to a new window using ViewManager.MoveTabTo.
+	// Tabs are not detachable if it is nil.
+	OnDetach func(idx int)
 }
 
 type Tabbar struct {
-	vm          view.ViewManager
+	vm view.ViewManager
+	// the tab group of a split workspace. nil for the focused group.
+	group       *view.TabGroup
 	backwardBtn widget.Clickable
 	forwardBtn  widget.Clickable
 	list        *layout.List
@@ -49,22 +72,36 @@ type Tabbar struct {
 	options     *TabbarOptions
 	// calculated tab width
 	tabWidth int
+	// tabs overflow the tab bar.
+	overflowed  bool
+	tabListBtn  widget.Clickable
+	tabListMenu *tabListMenu
+	lastCurrent int
 }
 
 type Tab struct {
 	vw         view.View
 	tabClick   gesture.Click
+	drag       gesture.Drag
+	dragStart  f32.Point
+	dragOffset f32.Point
+	// horizontal distance of the last drop.
+	moveOffset float32
 	closeBtn   widget.Clickable
 	isSelected bool
+	isPinned   bool
 	hovering   bool
 	events     []TabEvent
+	// events triggered by the context menu, which are delivered in the next frame.
+	pending []TabEvent
+	menu    *menu.ContextMenu
 
 	// action bar for the current view.
 	actionBar *ActionBar
 }
 
 func (tb *Tabbar) Layout(gtx C, th *theme.Theme) D {
-	tabViews := tb.vm.OpenedViews()
+	tabViews := tb.openedViews()
 	if len(tb.tabs) != len(tabViews) {
 		// rebuilding tabs
 		if len(tb.tabs) > 0 {
@@ -75,26 +112,48 @@ func (tb *Tabbar) Layout(gtx C, th *theme.Theme) D {
 		}
 	}
 
-	var currentTab *Tab
-
+	group := tb.tabGroup()
 	for idx, v := range tabViews {
 		tab := tb.tabs[idx]
+		// Tabs may have been reordered, rebind them to the views if necessary.
+		tab.bindToView(v)
+		tab.isPinned = group.IsPinned(idx)
 		for _, evt := range tab.Update(gtx) {
 			switch evt {
 			case TabSelectedEvent:
+				tb.focusGroup()
 				tb.vm.SwitchTab(idx)
 			case TabClosedEvent:
 				// wait for the next frame to rebuild tabs
+				tb.focusGroup()
 				tb.vm.CloseTab(idx)
+			case TabDetachedEvent:
+				if tb.options.OnDetach != nil {
+					tb.focusGroup()
+					tb.options.OnDetach(idx)
+				}
+			case TabMovedEvent:
+				if tb.tabWidth > 0 {
+					to := idx + int(math.Round(float64(tab.moveOffset)/float64(tb.tabWidth)))
+					tb.focusGroup()
+					tb.vm.ReorderTab(idx, to)
+				}
+			case TabPinToggledEvent:
+				tb.focusGroup()
+				tb.vm.PinTab(idx, !tab.isPinned)
+			case TabDuplicatedEvent:
+				tb.focusGroup()
+				tb.vm.DuplicateTab(idx)
+			case TabCloseOthersEvent:
+				tb.focusGroup()
+				tb.vm.CloseOtherTabs(idx)
+			case TabCloseRightEvent:
+				tb.focusGroup()
+				tb.vm.CloseTabsToRight(idx)
 			}
 		}
 		// sync tab state
-		tab.isSelected = tb.vm.CurrentViewIndex() == idx
-		if tab.IsSelected() {
-			currentTab = tab
-			// The top most view in the stack may have changed, rebind to it if necessary.
-			currentTab.bindToView(v)
-		}
+		tab.isSelected = tb.currentIndex() == idx
 	}
 
 	if len(tb.tabs) <= 0 {
@@ -121,20 +180,19 @@ func (tb *Tabbar) Layout(gtx C, th *theme.Theme) D {
 
 func (tb *Tabbar) layoutTabs(gtx C, th *theme.Theme) D {
 
+	// the arrow buttons navigate through the history of the current tab.
 	if tb.backwardBtn.Clicked(gtx) {
-		if tb.list.Position.First > 0 {
-			tb.list.ScrollBy(-1)
-		} else {
-			tb.list.ScrollBy(-float32(tb.list.Position.Offset) / float32(tb.tabWidth))
-		}
+		tb.focusGroup()
+		tb.vm.NavBack()
 	}
 	if tb.forwardBtn.Clicked(gtx) {
-		if tb.list.Position.Count+tb.list.Position.First < len(tb.tabs) {
-			tb.list.ScrollBy(1)
-		} else {
-			num := float32(tb.list.Position.OffsetLast) / float32(tb.tabWidth)
-			tb.list.ScrollBy(-num)
-		}
+		tb.focusGroup()
+		tb.vm.NavForward()
+	}
+	hasPrev, hasNext := tb.hasHistory()
+
+	if tb.tabListBtn.Clicked(gtx) {
+		tb.tabListMenu.ToggleVisibility(gtx)
 	}
 
 	return layout.Flex{
@@ -145,7 +203,7 @@ func (tb *Tabbar) layoutTabs(gtx C, th *theme.Theme) D {
 
 		layout.Rigid(func(gtx C) D {
 			arrowAlpha := 0x30
-			if tb.list.Position.Offset > 0 {
+			if hasPrev {
 				arrowAlpha = 0xff
 			}
 
@@ -159,7 +217,7 @@ func (tb *Tabbar) layoutTabs(gtx C, th *theme.Theme) D {
 		layout.Rigid(layout.Spacer{Width: unit.Dp(8)}.Layout),
 		layout.Rigid(func(gtx C) D {
 			arrowAlpha := 0x30
-			if tb.list.Position.OffsetLast < 0 {
+			if hasNext {
 				arrowAlpha = 0xff
 			}
 
@@ -177,6 +235,12 @@ func (tb *Tabbar) layoutTabs(gtx C, th *theme.Theme) D {
 			if len(tb.tabs) > 0 {
 				tb.tabWidth = min(gtx.Constraints.Max.X/len(tb.tabs), tb.tabWidth)
 			}
+			// Tabs do not shrink further, and the list becomes scrollable.
+			tb.overflowed = tb.tabWidth < gtx.Dp(tb.options.MinTabWidth)
+			if tb.overflowed {
+				tb.tabWidth = gtx.Dp(tb.options.MinTabWidth)
+			}
+			tb.scrollToCurrent()
 
 			return tb.list.Layout(gtx, len(tb.tabs), func(gtx C, index int) D {
 				gtx.Constraints.Min.X = tb.tabWidth
@@ -199,16 +263,97 @@ func (tb *Tabbar) layoutTabs(gtx C, th *theme.Theme) D {
 				return dims
 			})
 		}),
+		layout.Rigid(func(gtx C) D {
+			if !tb.overflowed {
+				return D{}
+			}
+
+			parent := gtx.Constraints
+			dims := layout.Center.Layout(gtx, func(gtx C) D {
+				return misc.IconButton(th, tabListIcon, &tb.tabListBtn, "show all tabs").Layout(gtx)
+			})
+
+			gtx.Constraints = parent
+			tb.tabListMenu.Layout(gtx, th, dims.Size)
+			return dims
+		}),
 	)
 
 }
 
+// scrollToCurrent scrolls the tab list to make the current tab visible when
+// the current tab changes.
+func (tb *Tabbar) scrollToCurrent() {
+	current := tb.currentIndex()
+	if current == tb.lastCurrent {
+		return
+	}
+	tb.lastCurrent = current
+
+	first := tb.list.Position.First
+	if current < first || current >= first+tb.list.Position.Count {
+		tb.list.ScrollTo(current)
+	}
+}
+
+// selectTab switches to the tab at idx and makes it visible.
+func (tb *Tabbar) selectTab(idx int) {
+	tb.focusGroup()
+	tb.vm.SwitchTab(idx)
+}
+
+// tabGroup returns the tab group the tab bar shows.
+func (tb *Tabbar) tabGroup() *view.TabGroup {
+	if tb.group != nil {
+		return tb.group
+	}
+	return tb.vm.FocusedGroup()
+}
+
+func (tb *Tabbar) openedViews() []view.View {
+	if tb.group != nil {
+		return tb.group.Views()
+	}
+	return tb.vm.OpenedViews()
+}
+
+func (tb *Tabbar) currentIndex() int {
+	if tb.group != nil {
+		return tb.group.CurrentIndex()
+	}
+	return tb.vm.CurrentViewIndex()
+}
+
+// hasHistory reports if the current tab of the tab bar can navigate backward or forward.
+func (tb *Tabbar) hasHistory() (bool, bool) {
+	if tb.group != nil {
+		return tb.group.HasPrev(), tb.group.HasNext()
+	}
+	return tb.vm.HasPrev(), tb.vm.HasNext()
+}
+
+// focusGroup focuses the tab group before the tab operations, as
+// ViewManager operates on the focused group.
+func (tb *Tabbar) focusGroup() {
+	if tb.group != nil {
+		tb.vm.FocusGroup(tb.group)
+	}
+}
+
+// NewGroupTabbar creates a tab bar for a tab group in a split workspace.
+func NewGroupTabbar(vm view.ViewManager, group *view.TabGroup, options *TabbarOptions) *Tabbar {
+	tb := NewTabbar(vm, options)
+	tb.group = group
+	return tb
+}
+
 func NewTabbar(vm view.ViewManager, options *TabbarOptions) *Tabbar {
 	tb := &Tabbar{
 		vm:      vm,
 		list:    &layout.List{Axis: layout.Horizontal, Alignment: layout.Middle},
 		options: options,
 	}
+	tb.tabListMenu = &tabListMenu{tabbar: tb}
 	if options == nil {
 		tb.options = &TabbarOptions{
 			Height:      unit.Dp(28),
@@ -221,6 +366,9 @@ func NewTabbar(vm view.ViewManager, options *TabbarOptions) *Tabbar {
 	if tb.options.MaxTabWidth <= 0 {
 		tb.options.MaxTabWidth = unit.Dp(150)
 	}
+	if tb.options.MinTabWidth <= 0 {
+		tb.options.MinTabWidth = min(unit.Dp(80), tb.options.MaxTabWidth)
+	}
 
 	return tb
 }
@@ -243,6 +391,10 @@ func (tab *Tab) IsSelected() bool {
 	return tab.isSelected
 }
 
+func (tab *Tab) IsPinned() bool {
+	return tab.isPinned
+}
+
 func (tab *Tab) Layout(gtx C, th *theme.Theme) D {
 	tab.Update(gtx)
 
@@ -277,11 +429,18 @@ func (tab *Tab) Layout(gtx C, th *theme.Theme) D {
 						if tab.hovering {
 							iconAlpha = uint8(255)
 						}
+						iconSize := max(16, unit.Dp(16*th.TextSize/14))
+						if tab.isPinned {
+							// pinned tabs can not be closed by the close button.
+							return layout.Inset{Left: unit.Dp(4)}.Layout(gtx, func(gtx C) D {
+								return misc.Icon{Icon: pinIcon, Color: misc.WithAlpha(color, 0xb6), Size: iconSize}.Layout(gtx, th)
+							})
+						}
 						return layout.Inset{Left: unit.Dp(4)}.Layout(gtx, func(gtx C) D {
 							return material.Clickable(gtx, &tab.closeBtn, func(gtx C) D {
 								return misc.Icon{Icon: closeIcon,
 									Color: misc.WithAlpha(color, iconAlpha),
-									Size:  max(16, unit.Dp(16*th.TextSize/14)),
+									Size:  iconSize,
 								}.Layout(gtx, th)
 							})
 						})
@@ -300,9 +459,27 @@ func (tab *Tab) Layout(gtx C, th *theme.Theme) D {
 	}).Push(gtx.Ops).Pop()
 
 	tab.tabClick.Add(gtx.Ops)
+	tab.drag.Add(gtx.Ops)
 	// register event tag
 	event.Op(gtx.Ops, tab)
-	tabOps.Add(gtx.Ops)
+
+	if tab.dragOffset != (f32.Point{}) {
+		// draw the dragged tab above the others, following the pointer.
+		macro := op.Record(gtx.Ops)
+		op.Offset(tab.dragOffset.Round()).Add(gtx.Ops)
+		tabOps.Add(gtx.Ops)
+		op.Defer(gtx.Ops, macro.Stop())
+	} else {
+		tabOps.Add(gtx.Ops)
+	}
+
+	if tab.menu == nil {
+		tab.menu = menu.NewContextMenu(tab.contextMenuOptions(), false)
+		tab.menu.PositionHint = layout.N
+	}
+	gtx.Constraints.Min = dims.Size
+	tab.menu.Layout(gtx, th)
+
 	return dims
 }
 
@@ -366,6 +543,34 @@ func (tab *Tab) Update(gtx C) []TabEvent {
 		}
 	}
 
+	for {
+		e, ok := tab.drag.Update(gtx.Metric, gtx.Source, gesture.Both)
+		if !ok {
+			break
+		}
+
+		switch e.Kind {
+		case pointer.Press:
+			tab.dragStart = e.Position
+		case pointer.Drag:
+			tab.dragOffset = e.Position.Sub(tab.dragStart)
+		case pointer.Release:
+			offset := e.Position.Sub(tab.dragStart)
+			tab.dragOffset = f32.Point{}
+			if math.Abs(float64(offset.Y)) > float64(gtx.Dp(tabDetachDistance)) {
+				tab.events = append(tab.events, TabDetachedEvent)
+			} else if offset.X != 0 {
+				tab.moveOffset = offset.X
+				tab.events = append(tab.events, TabMovedEvent)
+			}
+		case pointer.Cancel:
+			tab.dragOffset = f32.Point{}
+		}
+	}
+
+	tab.events = append(tab.events, tab.pending...)
+	tab.pending = tab.pending[:0]
+
 	if tab.closeBtn.Clicked(gtx) {
 		tab.events = append(tab.events, TabClosedEvent)
 	}
@@ -0,0 +1,125 @@
+package navi
+
+import (
+	"image"
+
+	"github.com/oligo/gioview/menu"
+	"github.com/oligo/gioview/misc"
+	"github.com/oligo/gioview/theme"
+
+	"gioui.org/font"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+)
+
+// tabListMenu is a dropdown menu listing all the tabs when they overflow
+// the tab bar.
+type tabListMenu struct {
+	*menu.DropdownMenu
+	tabbar       *Tabbar
+	lastMenuSize int
+}
+
+func (tm *tabListMenu) update(th *theme.Theme) {
+	if tm.DropdownMenu != nil && tm.lastMenuSize == len(tm.tabbar.tabs) {
+		return
+	}
+
+	options := make([]menu.MenuOption, 0, len(tm.tabbar.tabs))
+	for idx := range tm.tabbar.tabs {
+		options = append(options, menu.MenuOption{
+			OnClicked: func() error {
+				tm.tabbar.selectTab(idx)
+				return nil
+			},
+			Layout: func(gtx C, th *theme.Theme) D {
+				if idx >= len(tm.tabbar.tabs) {
+					return D{}
+				}
+				tab := tm.tabbar.tabs[idx]
+				label := material.Label(th.Theme, th.TextSize, tab.vw.Title())
+				label.MaxLines = 1
+				if tab.isSelected {
+					label.Font.Weight = font.Bold
+				}
+				return label.Layout(gtx)
+			},
+		})
+	}
+
+	tm.DropdownMenu = menu.NewDropdownMenu([][]menu.MenuOption{options})
+	tm.DropdownMenu.Background = misc.WithAlpha(th.Fg, th.HoverAlpha)
+	tm.DropdownMenu.MaxWidth = unit.Dp(240)
+	tm.lastMenuSize = len(tm.tabbar.tabs)
+}
+
+func (tm *tabListMenu) ToggleVisibility(gtx C) bool {
+	if tm.DropdownMenu == nil {
+		return false
+	}
+	return tm.DropdownMenu.ToggleVisibility(gtx)
+}
+
+// Layout lays out the menu below the button, aligned to the right side of it.
+func (tm *tabListMenu) Layout(gtx C, th *theme.Theme, btnSize image.Point) D {
+	tm.update(th)
+
+	// Use a large constraint to let the menu overflow out of the tab bar, see
+	// overflowMenu of the ActionBar.
+	gtx.Constraints.Max.Y = 1e6
+	gtx.Constraints.Max.X = max(gtx.Dp(unit.Dp(240)), btnSize.X)
+	macro := op.Record(gtx.Ops)
+	dims := tm.DropdownMenu.Layout(gtx, th)
+	call := macro.Stop()
+
+	offset := image.Point{
+		X: btnSize.X - dims.Size.X,
+		Y: btnSize.Y,
+	}
+
+	defer op.Offset(offset).Push(gtx.Ops).Pop()
+	defer clip.Rect{Max: dims.Size}.Push(gtx.Ops).Pop()
+	call.Add(gtx.Ops)
+
+	return dims
+}
+
+func (tab *Tab) contextMenuOptions() [][]menu.MenuOption {
+	option := func(evt TabEvent, title func() string) menu.MenuOption {
+		return menu.MenuOption{
+			OnClicked: func() error {
+				tab.pending = append(tab.pending, evt)
+				return nil
+			},
+			Layout: func(gtx C, th *theme.Theme) D {
+				return material.Label(th.Theme, th.TextSize, title()).Layout(gtx)
+			},
+		}
+	}
+	text := func(s string) func() string {
+		return func() string { return s }
+	}
+
+	// pinned tabs have no close button, and are not closed from the menu either.
+	closeOption := option(TabClosedEvent, text("Close"))
+	closeOption.Enabled = func() bool { return !tab.isPinned }
+
+	return [][]menu.MenuOption{
+		{
+			closeOption,
+			option(TabCloseOthersEvent, text("Close Others")),
+			option(TabCloseRightEvent, text("Close to the Right")),
+		},
+		{
+			option(TabPinToggledEvent, func() string {
+				if tab.isPinned {
+					return "Unpin"
+				}
+				return "Pin"
+			}),
+			option(TabDuplicatedEvent, text("Duplicate")),
+		},
+	}
+}
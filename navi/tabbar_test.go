@@ -0,0 +1,16 @@
+package navi
+
+import "testing"
+
+func TestPinnedTabMenu(t *testing.T) {
+	tab := &Tab{isPinned: true}
+	closeOption := tab.contextMenuOptions()[0][0]
+	if closeOption.Enabled() {
+		t.Fatal("pinned tab should not be closed from the menu")
+	}
+
+	tab.isPinned = false
+	if !closeOption.Enabled() {
+		t.Fatal("unpinned tab should be closed from the menu")
+	}
+}
@@ -0,0 +1,117 @@
+package palette
+
+import (
+	"unicode"
+)
+
+// Scores of the fuzzy matching. A match of a rune scores more at the start of
+// a word, or after the previous match, and gaps between the matches lower the
+// score.
+const (
+	scoreMatch       = 16
+	bonusFirst       = 12
+	bonusBoundary    = 10
+	bonusCamel       = 8
+	bonusConsecutive = 12
+	penaltyGap       = 1
+	maxPenaltyLead   = 8
+)
+
+const noMatch = -1 << 30
+
+// Match reports whether the runes of the pattern are in the text in order,
+// ignoring the case. It returns the score of the best match, which is higher
+// for the runes matched at the start of words or one after another, and the
+// rune indices of the matched runes in the text. An empty pattern matches any
+// text with a zero score.
+func Match(pattern, text string) (score int, positions []int, ok bool) {
+	pat := []rune(pattern)
+	if len(pat) == 0 {
+		return 0, nil, true
+	}
+	runes := []rune(text)
+	if len(pat) > len(runes) {
+		return 0, nil, false
+	}
+	for i, r := range pat {
+		pat[i] = unicode.ToLower(r)
+	}
+
+	bonus := make([]int, len(runes))
+	for j, r := range runes {
+		switch {
+		case j == 0:
+			bonus[j] = bonusFirst
+		case isSeparator(runes[j-1]) && !isSeparator(r):
+			bonus[j] = bonusBoundary
+		case unicode.IsLower(runes[j-1]) && unicode.IsUpper(r),
+			unicode.IsLetter(runes[j-1]) && unicode.IsDigit(r):
+			bonus[j] = bonusCamel
+		}
+	}
+
+	// scores[i][j] is the best score of the first i+1 runes of the pattern
+	// with the rune i matched at j, and from[i][j] the index the rune i-1 is
+	// matched at.
+	scores := make([][]int, len(pat))
+	from := make([][]int, len(pat))
+	for i := range pat {
+		scores[i] = make([]int, len(runes))
+		from[i] = make([]int, len(runes))
+		// best is the best score of the rune i-1 matched before j-1, less
+		// the gap to j, and bestAt its index.
+		best, bestAt := noMatch, -1
+		for j, r := range runes {
+			scores[i][j] = noMatch
+			if i > 0 && j >= 2 {
+				best -= penaltyGap
+				if s := scores[i-1][j-2] - penaltyGap; s > best {
+					best, bestAt = s, j-2
+				}
+			}
+			if unicode.ToLower(r) != pat[i] {
+				continue
+			}
+			if i == 0 {
+				scores[i][j] = scoreMatch + bonus[j] - min(j, maxPenaltyLead)
+				continue
+			}
+			prev, prevAt := best, bestAt
+			if j > 0 && scores[i-1][j-1] != noMatch {
+				if s := scores[i-1][j-1] + bonusConsecutive; s > prev {
+					prev, prevAt = s, j-1
+				}
+			}
+			if prevAt >= 0 && prev > noMatch/2 {
+				scores[i][j] = prev + scoreMatch + bonus[j]
+				from[i][j] = prevAt
+			}
+		}
+	}
+
+	last := len(pat) - 1
+	end := -1
+	for j, s := range scores[last] {
+		if s != noMatch && (end < 0 || s > scores[last][end]) {
+			end = j
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	positions = make([]int, len(pat))
+	for i, j := last, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+	return scores[last][end], positions, true
+}
+
+func isSeparator(r rune) bool {
+	switch r {
+	case ' ', '_', '-', '/', '.', ':', '\\':
+		return true
+	}
+	return unicode.IsSpace(r)
+}
@@ -0,0 +1,52 @@
+package palette
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, text string
+		positions     []int
+		ok            bool
+	}{
+		{"", "Close Tab", nil, true},
+		{"ct", "Close Tab", []int{0, 6}, true},
+		{"tab", "Close Tab", []int{6, 7, 8}, true},
+		{"sr", "Split Editor Right", []int{0, 13}, true},
+		{"ev", "EditorView", []int{0, 6}, true},
+		{"xyz", "Close Tab", nil, false},
+		{"tabs", "Tab", nil, false},
+	}
+	for _, tc := range cases {
+		_, positions, ok := Match(tc.pattern, tc.text)
+		if ok != tc.ok || !slices.Equal(positions, tc.positions) {
+			t.Errorf("%q in %q: got %v, %v, want %v, %v", tc.pattern, tc.text, positions, ok, tc.positions, tc.ok)
+		}
+	}
+
+	// Matches at word starts and consecutive matches score higher.
+	better := [][2]string{
+		{"Go Back", "Toggle Bookmark"},
+		{"New File", "Open Info"},
+		{"Close Tab", "Close Other Tabs"},
+	}
+	for _, texts := range better {
+		s1, _, _ := Match(firstLetters(texts[0]), texts[0])
+		s2, _, _ := Match(firstLetters(texts[0]), texts[1])
+		if s1 <= s2 {
+			t.Errorf("%q scores %d, not higher than %q with %d", texts[0], s1, texts[1], s2)
+		}
+	}
+}
+
+func firstLetters(s string) string {
+	letters := []byte{s[0]}
+	for i := 1; i < len(s); i++ {
+		if s[i-1] == ' ' {
+			letters = append(letters, s[i])
+		}
+	}
+	return string(letters)
+}
@@ -0,0 +1,229 @@
+// Package palette provides a command palette: a modal view that fuzzy-searches
+// the registered commands, the actions of the current view, the opened views
+// and the registered views, and runs the chosen one.
+package palette
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/oligo/gioview/keybinding"
+	"github.com/oligo/gioview/view"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+)
+
+var (
+	paletteViewID = view.NewViewID("CommandPalette")
+)
+
+// ShowCommand is the ID of the command showing the palette, which is bound to
+// Ctrl+Shift+P, or Cmd+Shift+P on macOS.
+const ShowCommand keybinding.CommandID = "palette.show"
+
+const (
+	// recentSize is the number of recently run items ranked first.
+	recentSize = 10
+	// recentBonus is added to the score of an item for each item run before
+	// it among the recent items.
+	recentBonus = 4
+)
+
+// ItemKind is the kind of a palette item.
+type ItemKind uint8
+
+const (
+	// CommandItem runs a registered command.
+	CommandItem ItemKind = iota
+	// ActionItem runs an action of the current view.
+	ActionItem
+	// TabItem switches to an opened view.
+	TabItem
+	// ViewItem opens a registered view.
+	ViewItem
+)
+
+func (k ItemKind) String() string {
+	switch k {
+	case CommandItem:
+		return "Command"
+	case ActionItem:
+		return "Action"
+	case TabItem:
+		return "Opened View"
+	case ViewItem:
+		return "View"
+	default:
+		return fmt.Sprintf("ItemKind(%d)", k)
+	}
+}
+
+// Item is an entry of the palette.
+type Item struct {
+	Kind  ItemKind
+	Title string
+	// Detail is shown after the title, e.g., the shortcut of a command.
+	Detail string
+
+	// key identifies the item among the recent items.
+	key string
+	run func(gtx layout.Context)
+}
+
+// Result is an item matching a search query.
+type Result struct {
+	Item
+	// Positions are the rune indices of the title matching the query.
+	Positions []int
+	score     int
+}
+
+// CommandPalette searches and runs the items of a view manager. The palette is
+// shown as a modal view by Show, or by its command.
+type CommandPalette struct {
+	vm view.ViewManager
+	// keys of the recently run items, the latest first.
+	recent []string
+}
+
+// NewCommandPalette registers the palette view and ShowCommand to the view
+// manager.
+func NewCommandPalette(vm view.ViewManager) (*CommandPalette, error) {
+	p := &CommandPalette{vm: vm}
+	err := vm.Register(paletteViewID, func() view.View { return newPaletteView(p) })
+	if err != nil {
+		return nil, err
+	}
+
+	err = vm.Commands().Register(&keybinding.Command{
+		ID:    ShowCommand,
+		Title: "Show Command Palette",
+		Key:   keybinding.KeyChord{Name: "P", Modifiers: key.ModShortcut | key.ModShift},
+		Handler: func(gtx layout.Context) {
+			if err := p.Show(); err != nil {
+				log.Println(err)
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Show shows the palette as a modal view.
+func (p *CommandPalette) Show() error {
+	return p.vm.RequestSwitch(view.Intent{Target: paletteViewID, ShowAsModal: true})
+}
+
+// Items returns the items of the palette: the enabled commands, except for the
+// widget commands, the actions of the current view, the opened views, and the
+// registered views.
+func (p *CommandPalette) Items() []Item {
+	var items []Item
+	commands := p.vm.Commands()
+	for _, cmd := range commands.Commands() {
+		if cmd.ID == ShowCommand || cmd.Handler == nil || !cmd.IsEnabled() {
+			continue
+		}
+		items = append(items, Item{
+			Kind:   CommandItem,
+			Title:  cmd.Title,
+			Detail: cmd.Shortcut(),
+			key:    "command:" + string(cmd.ID),
+			run:    func(gtx layout.Context) { cmd.Run(gtx) },
+		})
+	}
+
+	if vw, ok := p.vm.CurrentView().(interface{ Actions() []view.ViewAction }); ok {
+		for _, action := range vw.Actions() {
+			// registered commands are already listed.
+			if action.Command != nil && commands.Command(action.Command.ID) == action.Command || !action.Enabled() {
+				continue
+			}
+			items = append(items, Item{
+				Kind:   ActionItem,
+				Title:  action.Title(),
+				Detail: action.Shortcut(),
+				key:    "action:" + action.Title(),
+				run:    action.Run,
+			})
+		}
+	}
+
+	for idx, vw := range p.vm.OpenedViews() {
+		if vw == nil {
+			continue
+		}
+		location := vw.Location()
+		items = append(items, Item{
+			Kind:   TabItem,
+			Title:  vw.Title(),
+			Detail: TabItem.String(),
+			key:    "tab:" + location.String(),
+			run:    func(gtx layout.Context) { p.vm.SwitchTab(idx) },
+		})
+	}
+
+	for _, id := range p.vm.RegisteredViews() {
+		if id == paletteViewID {
+			continue
+		}
+		items = append(items, Item{
+			Kind:   ViewItem,
+			Title:  id.Name(),
+			Detail: ViewItem.String(),
+			key:    "view:" + id.String(),
+			run: func(gtx layout.Context) {
+				if err := p.vm.RequestSwitch(view.Intent{Target: id}); err != nil {
+					log.Println(err)
+				}
+			},
+		})
+	}
+
+	return items
+}
+
+// Search returns the items whose titles fuzzy-match the query, the best match
+// first. The recently run items are ranked higher, and are listed first for an
+// empty query.
+func (p *CommandPalette) Search(items []Item, query string) []Result {
+	var results []Result
+	for _, item := range items {
+		score, positions, ok := Match(query, item.Title)
+		if !ok {
+			continue
+		}
+		if i := slices.Index(p.recent, item.key); i >= 0 {
+			score += recentBonus * (recentSize - i)
+		}
+		results = append(results, Result{Item: item, Positions: positions, score: score})
+	}
+
+	slices.SortStableFunc(results, func(a, b Result) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		if query != "" {
+			return len(a.Title) - len(b.Title)
+		}
+		return 0
+	})
+	return results
+}
+
+// Run runs the item, and ranks it first among the recent items.
+func (p *CommandPalette) Run(gtx layout.Context, item Item) {
+	p.recent = slices.DeleteFunc(p.recent, func(key string) bool { return key == item.key })
+	p.recent = slices.Insert(p.recent, 0, item.key)
+	if len(p.recent) > recentSize {
+		p.recent = p.recent[:recentSize]
+	}
+	if item.run != nil {
+		item.run(gtx)
+	}
+}
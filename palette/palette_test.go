@@ -0,0 +1,123 @@
+package palette
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/oligo/gioview/keybinding"
+	"github.com/oligo/gioview/uitest"
+	"github.com/oligo/gioview/view"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+)
+
+var noteViewID = view.NewViewID("Note")
+
+func TestCommandPalette(t *testing.T) {
+	vm := view.NewViewManager(&view.HeadlessWindow{})
+	vm.Register(noteViewID, func() view.View { return &view.SimpleView{BaseView: view.BaseView{}} })
+	p, err := NewCommandPalette(vm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ran []string
+	for _, title := range []string{"Close Tab", "Close Other Tabs", "Toggle Sidebar"} {
+		err := vm.Commands().Register(&keybinding.Command{
+			ID:      keybinding.CommandID(title),
+			Title:   title,
+			Handler: func(gtx layout.Context) { ran = append(ran, title) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	titles := func(results []Result) []string {
+		var titles []string
+		for _, r := range results {
+			titles = append(titles, r.Title)
+		}
+		return titles
+	}
+
+	items := p.Items()
+	if got := titles(p.Search(items, "")); !slices.Equal(got, []string{"Close Tab", "Close Other Tabs", "Toggle Sidebar", "Note"}) {
+		t.Fatalf("got items %q", got)
+	}
+	results := p.Search(items, "ct")
+	if got := titles(results); !slices.Equal(got, []string{"Close Tab", "Close Other Tabs"}) {
+		t.Fatalf("got results %q", got)
+	}
+	if !slices.Equal(results[0].Positions, []int{0, 6}) {
+		t.Fatalf("got positions %v", results[0].Positions)
+	}
+
+	// The recent items are ranked first.
+	p.Run(layout.Context{}, results[1].Item)
+	if got := titles(p.Search(items, "ct")); !slices.Equal(got, []string{"Close Other Tabs", "Close Tab"}) {
+		t.Fatalf("got results %q", got)
+	}
+	p.Run(layout.Context{}, p.Search(items, "sidebar")[0].Item)
+	if got := titles(p.Search(items, ""))[:3]; !slices.Equal(got, []string{"Toggle Sidebar", "Close Other Tabs", "Close Tab"}) {
+		t.Fatalf("got items %q", got)
+	}
+	if !slices.Equal(ran, []string{"Close Other Tabs", "Toggle Sidebar"}) {
+		t.Fatalf("got commands run %q", ran)
+	}
+}
+
+func TestPaletteKeys(t *testing.T) {
+	vm := view.NewViewManager(&view.HeadlessWindow{})
+	if _, err := NewCommandPalette(vm); err != nil {
+		t.Fatal(err)
+	}
+	var ran []string
+	for _, title := range []string{"Split Right", "Close Tab", "Close All Tabs"} {
+		vm.Commands().Register(&keybinding.Command{
+			ID:      keybinding.CommandID(title),
+			Title:   title,
+			Handler: func(gtx layout.Context) { ran = append(ran, title) },
+		})
+	}
+
+	h := uitest.New(image.Pt(600, 400), nil)
+	h.Widget = func(gtx C) D {
+		vm.Commands().Update(gtx)
+		for modal := range vm.ModalViews() {
+			modal.ShowUp(gtx)
+			if modal.IsClosed(gtx) {
+				vm.FinishModalView()
+			} else {
+				modal.Layout(gtx, h.Theme)
+			}
+		}
+		return D{Size: gtx.Constraints.Max}
+	}
+	h.Frame()
+
+	modals := func() int {
+		n := 0
+		for range vm.ModalViews() {
+			n++
+		}
+		return n
+	}
+	h.Key("P", key.ModShortcut|key.ModShift)
+	h.Frame()
+	if modals() != 1 {
+		t.Fatal("palette is not shown")
+	}
+
+	// The query filters the commands, and the arrows select the result run by
+	// Enter.
+	h.Type("ct")
+	h.Key(key.NameDownArrow, 0)
+	h.Key(key.NameReturn, 0)
+	h.Frame()
+	if !slices.Equal(ran, []string{"Close All Tabs"}) || modals() != 0 {
+		t.Fatalf("got commands run %q, %d modals", ran, modals())
+	}
+}
@@ -0,0 +1,212 @@
+package palette
+
+import (
+	"image/color"
+
+	"github.com/oligo/gioview/menu"
+	"github.com/oligo/gioview/misc"
+	"github.com/oligo/gioview/theme"
+	"github.com/oligo/gioview/view"
+
+	"gioui.org/font"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/styledtext"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// paletteView is the modal view of the palette.
+type paletteView struct {
+	*view.BaseView
+	palette  *CommandPalette
+	input    widget.Editor
+	list     widget.List
+	items    []Item
+	results  []Result
+	clicks   []widget.Clickable
+	selected int
+	focused  bool
+}
+
+func newPaletteView(p *CommandPalette) view.View {
+	return &paletteView{
+		BaseView: &view.BaseView{},
+		palette:  p,
+		input:    widget.Editor{SingleLine: true, Submit: true},
+		list:     widget.List{List: layout.List{Axis: layout.Vertical}},
+	}
+}
+
+func (vw *paletteView) ID() view.ViewID {
+	return paletteViewID
+}
+
+func (vw *paletteView) Title() string {
+	return "Command Palette"
+}
+
+func (vw *paletteView) search() {
+	vw.results = vw.palette.Search(vw.items, vw.input.Text())
+	vw.selected = 0
+	vw.list.ScrollTo(0)
+}
+
+// run runs the selected result and closes the palette.
+func (vw *paletteView) run(gtx C, idx int) {
+	if idx < 0 || idx >= len(vw.results) {
+		return
+	}
+	vw.OnFinish()
+	vw.palette.Run(gtx, vw.results[idx].Item)
+}
+
+func (vw *paletteView) update(gtx C) {
+	if vw.items == nil {
+		vw.items = vw.palette.Items()
+		vw.search()
+	}
+	if !vw.focused {
+		gtx.Execute(key.FocusCmd{Tag: &vw.input})
+		vw.focused = true
+	}
+
+	for {
+		e, ok := gtx.Event(
+			key.Filter{Focus: &vw.input, Name: key.NameUpArrow},
+			key.Filter{Focus: &vw.input, Name: key.NameDownArrow},
+		)
+		if !ok {
+			break
+		}
+		if e, ok := e.(key.Event); ok && e.State == key.Press && len(vw.results) > 0 {
+			switch e.Name {
+			case key.NameUpArrow:
+				vw.selected = (vw.selected + len(vw.results) - 1) % len(vw.results)
+			case key.NameDownArrow:
+				vw.selected = (vw.selected + 1) % len(vw.results)
+			}
+			vw.scrollToSelected()
+		}
+	}
+
+	for {
+		e, ok := vw.input.Update(gtx)
+		if !ok {
+			break
+		}
+		switch e.(type) {
+		case widget.ChangeEvent:
+			vw.search()
+		case widget.SubmitEvent:
+			vw.run(gtx, vw.selected)
+		}
+	}
+
+	for i := range vw.clicks {
+		if vw.clicks[i].Clicked(gtx) {
+			vw.run(gtx, i)
+		}
+	}
+}
+
+// scrollToSelected scrolls the list to make the selected result visible.
+func (vw *paletteView) scrollToSelected() {
+	pos := vw.list.Position
+	if vw.selected < pos.First {
+		vw.list.ScrollTo(vw.selected)
+	} else if pos.Count > 0 && vw.selected >= pos.First+pos.Count {
+		vw.list.ScrollTo(vw.selected - pos.Count + 1)
+	}
+}
+
+func (vw *paletteView) Layout(gtx C, th *theme.Theme) D {
+	vw.update(gtx)
+	if len(vw.clicks) < len(vw.results) {
+		vw.clicks = make([]widget.Clickable, len(vw.results))
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return widget.Border{
+				Color:        th.ContrastBg,
+				CornerRadius: unit.Dp(4),
+				Width:        unit.Dp(1),
+			}.Layout(gtx, func(gtx C) D {
+				return layout.UniformInset(unit.Dp(8)).Layout(gtx, func(gtx C) D {
+					return material.Editor(th.Theme, &vw.input, "Search commands and views").Layout(gtx)
+				})
+			})
+		}),
+		layout.Rigid(layout.Spacer{Height: unit.Dp(8)}.Layout),
+		layout.Rigid(func(gtx C) D {
+			if len(vw.results) == 0 {
+				label := material.Label(th.Theme, th.TextSize, "No matching results")
+				label.Color = misc.WithAlpha(th.Fg, 0x90)
+				return layout.UniformInset(unit.Dp(8)).Layout(gtx, label.Layout)
+			}
+			style := menu.NewStyle(th)
+			return material.List(th.Theme, &vw.list).Layout(gtx, len(vw.results), func(gtx C, idx int) D {
+				return material.Clickable(gtx, &vw.clicks[idx], func(gtx C) D {
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					return style.LayoutOption(gtx, idx == vw.selected, func(gtx C) D {
+						return layoutResult(gtx, th, vw.results[idx])
+					})
+				})
+			})
+		}),
+	)
+}
+
+// layoutResult lays out the title of the result with the matched runes
+// highlighted, followed by its detail.
+func layoutResult(gtx C, th *theme.Theme, result Result) D {
+	return layout.Flex{
+		Axis:      layout.Horizontal,
+		Alignment: layout.Middle,
+		Spacing:   layout.SpaceBetween,
+	}.Layout(gtx,
+		layout.Flexed(1, func(gtx C) D {
+			spans := highlightSpans(th, result.Title, result.Positions, th.Fg, th.ContrastBg)
+			return styledtext.Text(th.Shaper, spans...).Layout(gtx, nil)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return menu.LayoutShortcut(gtx, th, result.Detail)
+		}),
+	)
+}
+
+// highlightSpans splits the text into spans, and highlights the runes at the
+// positions in bold with the highlight color.
+func highlightSpans(th *theme.Theme, text string, positions []int, fg, highlight color.NRGBA) []styledtext.SpanStyle {
+	var spans []styledtext.SpanStyle
+	matched := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		matched[pos] = true
+	}
+
+	for i, r := range []rune(text) {
+		span := styledtext.SpanStyle{
+			Font:    font.Font{Typeface: th.Face},
+			Size:    th.TextSize,
+			Color:   fg,
+			Content: string(r),
+		}
+		if matched[i] {
+			span.Font.Weight = font.Bold
+			span.Color = highlight
+		}
+		if n := len(spans); n > 0 && spans[n-1].Font == span.Font && spans[n-1].Color == span.Color {
+			spans[n-1].Content += span.Content
+			continue
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
@@ -0,0 +1,139 @@
+package uitest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "update the golden images of uitest")
+
+// Tolerance controls how much a rendered image may differ from its golden image.
+type Tolerance struct {
+	// Channel is the max difference of a color channel for pixels to be
+	// considered equal.
+	Channel uint8
+	// Pixels is the max ratio of the pixels which may differ.
+	Pixels float64
+}
+
+// DefaultTolerance absorbs the anti-aliasing differences between renderers.
+var DefaultTolerance = Tolerance{Channel: 24, Pixels: 0.005}
+
+// MatchGolden compares the image with the golden image testdata/<name>.png,
+// using DefaultTolerance. Run the tests with -update-golden to create or update
+// the golden images. On mismatch, the image is written to
+// testdata/<name>.failed.png for inspection.
+func MatchGolden(t testing.TB, name string, img image.Image) {
+	t.Helper()
+	MatchGoldenWith(t, name, img, DefaultTolerance)
+}
+
+// MatchGoldenWith is like MatchGolden, but with a custom tolerance.
+func MatchGoldenWith(t testing.TB, name string, img image.Image, tol Tolerance) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".png")
+	failedPath := filepath.Join("testdata", name+".failed.png")
+	if *updateGolden {
+		if err := writePNG(path, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	golden, err := readPNG(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("golden image %s not found, run the test with -update-golden to create it", path)
+		}
+		t.Fatal(err)
+	}
+
+	if diff := Compare(golden, img, tol); diff != nil {
+		if err := writePNG(failedPath, img); err != nil {
+			t.Log(err)
+		}
+		t.Errorf("image does not match %s: %v", path, diff)
+		return
+	}
+
+	os.Remove(failedPath)
+}
+
+// Compare compares two images and returns a non-nil error describing the
+// difference if they do not match within the tolerance.
+func Compare(want, got image.Image, tol Tolerance) error {
+	if want.Bounds().Size() != got.Bounds().Size() {
+		return fmt.Errorf("size mismatch: want %v, got %v", want.Bounds().Size(), got.Bounds().Size())
+	}
+
+	wb, gb := want.Bounds(), got.Bounds()
+	size := wb.Size()
+	diffs := 0
+	var first image.Point
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			c1 := color.NRGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y)).(color.NRGBA)
+			c2 := color.NRGBAModel.Convert(got.At(gb.Min.X+x, gb.Min.Y+y)).(color.NRGBA)
+			if !colorEqual(c1, c2, tol.Channel) {
+				if diffs == 0 {
+					first = image.Pt(x, y)
+				}
+				diffs++
+			}
+		}
+	}
+
+	total := size.X * size.Y
+	if total == 0 || float64(diffs)/float64(total) <= tol.Pixels {
+		return nil
+	}
+
+	return fmt.Errorf("%d of %d pixels differ, first at %v", diffs, total, first)
+}
+
+func colorEqual(c1, c2 color.NRGBA, tol uint8) bool {
+	diff := func(a, b uint8) uint8 {
+		if a > b {
+			return a - b
+		}
+		return b - a
+	}
+
+	return diff(c1.R, c2.R) <= tol && diff(c1.G, c2.G) <= tol &&
+		diff(c1.B, c2.B) <= tol && diff(c1.A, c2.A) <= tol
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,307 @@
+// Package uitest drives gioview widgets and views in tests without a window.
+//
+// A Harness lays out a widget frame by frame using an input.Router, so the
+// widget receives pointer, key and clipboard events just like in a real
+// window. Frames can be rendered to images for golden image tests.
+package uitest
+
+import (
+	"image"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/oligo/gioview/theme"
+
+	"gioui.org/f32"
+	"gioui.org/io/event"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/transfer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// Harness runs frames of a widget.
+type Harness struct {
+	// Widget is laid out on every frame.
+	Widget layout.Widget
+	// Size is the size of the frames in pixels.
+	Size image.Point
+	// Metric of the frames. It defaults to one pixel per dp and sp so that
+	// the layout does not depend on the screen of the test machine.
+	Metric unit.Metric
+	// Now is the time of the last frame.
+	Now time.Time
+	// FrameInterval is added to Now before each frame. Some widgets ignore
+	// frames with an unchanged time.
+	FrameInterval time.Duration
+	// Theme uses the builtin Go fonts only, which makes text layout and
+	// rendering reproducible across machines.
+	Theme *theme.Theme
+
+	router    input.Router
+	ops       op.Ops
+	dims      D
+	frames    int
+	clipboard string
+	pointer   f32.Point
+	renderer  *renderer
+}
+
+// New creates a harness laying out the widget with the size in pixels.
+// Widgets usually need the theme of the harness, so it is created before
+// the widget is set:
+//
+//	h := uitest.New(image.Pt(400, 300), nil)
+//	h.Widget = func(gtx C) D { return tf.Layout(gtx, h.Theme, "hint") }
+func New(size image.Point, w layout.Widget) *Harness {
+	return &Harness{
+		Widget:        w,
+		Size:          size,
+		Metric:        unit.Metric{PxPerDp: 1, PxPerSp: 1},
+		Now:           time.Unix(0, 0),
+		FrameInterval: time.Second / 60,
+		Theme:         theme.NewTheme("", nil, true),
+	}
+}
+
+func (h *Harness) context() C {
+	h.ops.Reset()
+	return C{
+		Ops:         &h.ops,
+		Now:         h.Now,
+		Metric:      h.Metric,
+		Source:      h.router.Source(),
+		Constraints: layout.Exact(h.Size),
+	}
+}
+
+// Frame lays out the widget and delivers the queued events to it. It returns
+// the dimensions of the widget.
+func (h *Harness) Frame() D {
+	h.Advance(h.FrameInterval)
+	gtx := h.context()
+	h.dims = h.Widget(gtx)
+	h.router.Frame(&h.ops)
+	h.frames++
+
+	if _, content, ok := h.router.WriteClipboard(); ok {
+		h.clipboard = string(content)
+	}
+	if h.router.ClipboardRequested() {
+		text := h.clipboard
+		h.router.Queue(transfer.DataEvent{
+			Type: "application/text",
+			Open: func() io.ReadCloser {
+				return io.NopCloser(strings.NewReader(text))
+			},
+		})
+	}
+
+	return h.dims
+}
+
+// Frames runs n frames.
+func (h *Harness) Frames(n int) {
+	for range n {
+		h.Frame()
+	}
+}
+
+// FrameCount returns the number of frames run.
+func (h *Harness) FrameCount() int {
+	return h.frames
+}
+
+// Dimensions returns the dimensions of the widget in the last frame.
+func (h *Harness) Dimensions() D {
+	return h.dims
+}
+
+// Ops returns the operations of the last frame.
+func (h *Harness) Ops() *op.Ops {
+	return &h.ops
+}
+
+// Advance moves the frame time forward, e.g., to finish animations.
+func (h *Harness) Advance(d time.Duration) {
+	h.Now = h.Now.Add(d)
+}
+
+// Invalidated reports whether the widget requested a redraw in the last frame,
+// e.g., when it is animating.
+func (h *Harness) Invalidated() bool {
+	_, ok := h.router.WakeupTime()
+	return ok
+}
+
+// Queue queues raw events, which are delivered in the next frame.
+func (h *Harness) Queue(events ...event.Event) {
+	h.router.Queue(events...)
+}
+
+// Execute runs a command, such as key.FocusCmd, as if it was executed by a widget.
+func (h *Harness) Execute(cmd input.Command) {
+	h.router.Source().Execute(cmd)
+}
+
+// Focused reports whether the tag has the keyboard focus.
+func (h *Harness) Focused(tag event.Tag) bool {
+	return h.router.Source().Focused(tag)
+}
+
+// Cursor returns the pointer cursor set by the widget at the pointer position.
+func (h *Harness) Cursor() pointer.Cursor {
+	return h.router.Cursor()
+}
+
+// Clipboard returns the text last written to the clipboard.
+func (h *Harness) Clipboard() string {
+	return h.clipboard
+}
+
+// SetClipboard sets the text read by widgets from the clipboard.
+func (h *Harness) SetClipboard(text string) {
+	h.clipboard = text
+}
+
+// Move moves the pointer to pos and runs a frame.
+func (h *Harness) Move(pos image.Point) {
+	h.pointer = toF32(pos)
+	h.Queue(pointer.Event{
+		Kind:     pointer.Move,
+		Source:   pointer.Mouse,
+		Position: h.pointer,
+		Time:     h.elapsed(),
+	})
+	h.Frame()
+}
+
+// Press presses the buttons at pos and runs a frame.
+func (h *Harness) Press(pos image.Point, buttons pointer.Buttons, mods key.Modifiers) {
+	h.pointer = toF32(pos)
+	h.Queue(pointer.Event{
+		Kind:      pointer.Press,
+		Source:    pointer.Mouse,
+		Buttons:   buttons,
+		Modifiers: mods,
+		Position:  h.pointer,
+		Time:      h.elapsed(),
+	})
+	h.Frame()
+}
+
+// Release releases the buttons at pos and runs a frame.
+func (h *Harness) Release(pos image.Point, buttons pointer.Buttons, mods key.Modifiers) {
+	h.pointer = toF32(pos)
+	h.Queue(pointer.Event{
+		Kind:      pointer.Release,
+		Source:    pointer.Mouse,
+		Buttons:   buttons,
+		Modifiers: mods,
+		Position:  h.pointer,
+		Time:      h.elapsed(),
+	})
+	h.Frame()
+}
+
+// Click clicks the primary button at pos.
+func (h *Harness) Click(pos image.Point) {
+	h.ClickWith(pos, pointer.ButtonPrimary, 0)
+}
+
+// DoubleClick clicks the primary button twice at pos.
+func (h *Harness) DoubleClick(pos image.Point) {
+	h.Click(pos)
+	h.Advance(50 * time.Millisecond)
+	h.Click(pos)
+}
+
+// RightClick clicks the secondary button at pos, which opens context menus.
+func (h *Harness) RightClick(pos image.Point) {
+	h.ClickWith(pos, pointer.ButtonSecondary, 0)
+}
+
+// ClickWith clicks the buttons at pos with the modifiers held.
+func (h *Harness) ClickWith(pos image.Point, buttons pointer.Buttons, mods key.Modifiers) {
+	h.Press(pos, buttons, mods)
+	h.Release(pos, 0, mods)
+}
+
+// Drag drags with the primary button from one point to another in steps, and
+// runs a frame after each step.
+func (h *Harness) Drag(from, to image.Point, steps int) {
+	steps = max(1, steps)
+	h.Press(from, pointer.ButtonPrimary, 0)
+
+	start, end := toF32(from), toF32(to)
+	for i := 1; i <= steps; i++ {
+		h.pointer = start.Add(end.Sub(start).Mul(float32(i) / float32(steps)))
+		// pointer moves with buttons pressed are routed as drags.
+		h.Queue(pointer.Event{
+			Kind:     pointer.Move,
+			Source:   pointer.Mouse,
+			Buttons:  pointer.ButtonPrimary,
+			Position: h.pointer,
+			Time:     h.elapsed(),
+		})
+		h.Frame()
+	}
+
+	h.Release(to, 0, 0)
+}
+
+// Scroll scrolls by the distance in pixels at pos and runs a frame.
+func (h *Harness) Scroll(pos image.Point, dist image.Point) {
+	h.pointer = toF32(pos)
+	h.Queue(pointer.Event{
+		Kind:     pointer.Scroll,
+		Source:   pointer.Mouse,
+		Position: h.pointer,
+		Scroll:   toF32(dist),
+		Time:     h.elapsed(),
+	})
+	h.Frame()
+}
+
+// Key presses and releases a key with the modifiers held, then runs a frame.
+func (h *Harness) Key(name key.Name, mods key.Modifiers) {
+	h.Queue(
+		key.Event{Name: name, Modifiers: mods, State: key.Press},
+		key.Event{Name: name, Modifiers: mods, State: key.Release},
+	)
+	h.Frame()
+}
+
+// Type inserts text into the focused editor, replacing its selection like an
+// input method does, then runs a frame.
+func (h *Harness) Type(text string) {
+	sel := h.router.EditorState().Selection.Range
+	h.Queue(key.EditEvent{Range: sel, Text: text})
+
+	caret := min(sel.Start, sel.End) + len([]rune(text))
+	h.Queue(key.SelectionEvent{Start: caret, End: caret})
+	h.Frame()
+}
+
+// Focus moves the keyboard focus to the tag and runs a frame.
+func (h *Harness) Focus(tag event.Tag) {
+	h.Execute(key.FocusCmd{Tag: tag})
+	h.Frame()
+}
+
+func (h *Harness) elapsed() time.Duration {
+	return h.Now.Sub(time.Unix(0, 0))
+}
+
+func toF32(p image.Point) f32.Point {
+	return f32.Pt(float32(p.X), float32(p.Y))
+}
@@ -0,0 +1,242 @@
+package uitest_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oligo/gioview/editor"
+	"github.com/oligo/gioview/explorer"
+	"github.com/oligo/gioview/menu"
+	"github.com/oligo/gioview/navi"
+	"github.com/oligo/gioview/theme"
+	"github.com/oligo/gioview/uitest"
+	"github.com/oligo/gioview/view"
+	gvwidget "github.com/oligo/gioview/widget"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/widget/material"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+func TestTextField(t *testing.T) {
+	tf := &gvwidget.TextField{SingleLine: true}
+	h := uitest.New(image.Pt(300, 60), nil)
+	h.Widget = func(gtx C) D { return tf.Layout(gtx, h.Theme, "name") }
+	h.Frame()
+
+	h.Click(image.Pt(20, 20))
+	if !h.Focused(tf.State()) {
+		t.Fatal("text field is not focused by click")
+	}
+
+	h.Type("hello")
+	h.Type(" world")
+	if tf.Text() != "hello world" || !tf.Changed() {
+		t.Fatalf("unexpected text: %q", tf.Text())
+	}
+
+	h.Key(key.NameReturn, 0)
+	if !tf.Submitted() {
+		t.Fatal("text field is not submitted")
+	}
+
+	// select all and copy.
+	h.Key("A", key.ModShortcut)
+	h.Key("C", key.ModShortcut)
+	if h.Clipboard() != "hello world" {
+		t.Fatalf("unexpected clipboard: %q", h.Clipboard())
+	}
+
+	h.SetClipboard("gioview")
+	h.Key("V", key.ModShortcut)
+	h.Frame()
+	if tf.Text() != "gioview" {
+		t.Fatalf("clipboard is not pasted: %q", tf.Text())
+	}
+
+	h.Key(key.NameEscape, 0)
+	if h.Focused(tf.State()) {
+		t.Fatal("escape should release the focus")
+	}
+}
+
+func TestEditor(t *testing.T) {
+	ed := &editor.Editor{}
+	h := uitest.New(image.Pt(400, 200), nil)
+	conf := &editor.EditorConf{
+		Shaper:    h.Theme.Shaper,
+		TextColor: h.Theme.Fg,
+		TextSize:  h.Theme.TextSize,
+	}
+	h.Widget = func(gtx C) D { return editor.NewEditor(ed, conf, "").Layout(gtx) }
+	h.Frame()
+
+	h.Click(image.Pt(10, 10))
+	h.Type("func main() {}")
+	if ed.Text() != "func main() {}" {
+		t.Fatalf("unexpected text: %q", ed.Text())
+	}
+
+	h.Key(key.NameHome, 0)
+	h.Key(key.NameRightArrow, key.ModShift)
+	h.Key(key.NameRightArrow, key.ModShift)
+	h.Key(key.NameRightArrow, key.ModShift)
+	h.Key(key.NameRightArrow, key.ModShift)
+	if ed.SelectedText() != "func" {
+		t.Fatalf("unexpected selection: %q", ed.SelectedText())
+	}
+
+	h.Key("X", key.ModShortcut)
+	if h.Clipboard() != "func" || ed.Text() != " main() {}" {
+		t.Fatalf("cut failed, clipboard: %q, text: %q", h.Clipboard(), ed.Text())
+	}
+}
+
+func TestContextMenu(t *testing.T) {
+	var clicked string
+	option := func(name string) menu.MenuOption {
+		return menu.MenuOption{
+			OnClicked: func() error { clicked = name; return nil },
+			Layout: func(gtx C, th *theme.Theme) D {
+				return material.Label(th.Theme, th.TextSize, name).Layout(gtx)
+			},
+		}
+	}
+
+	m := menu.NewContextMenu([][]menu.MenuOption{{option("Copy"), option("Paste")}}, false)
+	h := uitest.New(image.Pt(300, 300), nil)
+	h.Widget = func(gtx C) D { return m.Layout(gtx, h.Theme) }
+	h.Frame()
+
+	h.RightClick(image.Pt(50, 50))
+
+	// The menu is placed at the clicked position. Click the second option.
+	h.Click(image.Pt(80, 90))
+	if clicked != "Paste" {
+		t.Fatalf("unexpected clicked option: %q", clicked)
+	}
+}
+
+func TestTabbar(t *testing.T) {
+	noteID := view.NewViewID("Note")
+	vm := view.NewViewManager(&view.HeadlessWindow{})
+	vm.Register(noteID, func() view.View { return &noteView{BaseView: &view.BaseView{}} })
+	for i := range 3 {
+		vm.RequestSwitch(view.Intent{Target: noteID, Params: view.Params{"id": i}, RequireNew: true})
+	}
+
+	tb := navi.NewTabbar(vm, &navi.TabbarOptions{MaxTabWidth: 100})
+	h := uitest.New(image.Pt(600, 28), nil)
+	h.Widget = func(gtx C) D { return tb.Layout(gtx, h.Theme) }
+	h.Frame()
+
+	// tabs start after the arrow buttons.
+	tabAt := func(idx int) image.Point { return image.Pt(80+idx*100, 14) }
+
+	h.Click(tabAt(0))
+	if vm.CurrentViewIndex() != 0 {
+		t.Fatalf("tab is not selected: %d", vm.CurrentViewIndex())
+	}
+
+	h.Drag(tabAt(0), tabAt(2), 4)
+	if vm.CurrentView() != vm.OpenedViews()[2] {
+		t.Fatal("tab is not moved by dragging")
+	}
+}
+
+func TestNavTree(t *testing.T) {
+	var clicked *navi.NavTree
+	tree := navi.NewNavItem(&navItem{name: "Home"}, func(item *navi.NavTree) { clicked = item })
+	h := uitest.New(image.Pt(200, 100), nil)
+	h.Widget = func(gtx C) D { return tree.Layout(gtx, h.Theme) }
+	h.Frame()
+
+	h.Click(image.Pt(20, 10))
+	if clicked != tree || !tree.IsSelected() {
+		t.Fatal("nav item is not clicked")
+	}
+}
+
+func TestFileExplorer(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := explorer.NewFileExplorer(nil)
+	if err := exp.OpenDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	h := uitest.New(image.Pt(800, 400), nil)
+	h.Widget = func(gtx C) D { return exp.Layout(gtx, h.Theme) }
+	h.Frame()
+
+	h.Click(image.Pt(400, 60))
+	selected := exp.SelectedPaths()
+	if len(selected) != 1 || selected[0] != filepath.Join(dir, "a.txt") {
+		t.Fatalf("unexpected selection: %v", selected)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	tf := &gvwidget.TextField{SingleLine: true}
+	tf.SetText("golden")
+	h := uitest.New(image.Pt(200, 60), nil)
+	defer h.Close()
+	h.Widget = func(gtx C) D { return tf.Layout(gtx, h.Theme, "hint") }
+	h.Frame()
+
+	env, set := os.LookupEnv("EGL_PLATFORM")
+	img, err := h.Render()
+	if e, s := os.LookupEnv("EGL_PLATFORM"); e != env || s != set {
+		t.Errorf("EGL_PLATFORM is changed to %q", e)
+	}
+	if errors.Is(err, uitest.ErrNoRenderer) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uitest.MatchGolden(t, "textfield", img)
+}
+
+type noteView struct {
+	*view.BaseView
+}
+
+func (vw *noteView) ID() view.ViewID { return view.NewViewID("Note") }
+func (vw *noteView) Title() string   { return "Note" }
+func (vw *noteView) Layout(gtx C, th *theme.Theme) D {
+	return D{Size: gtx.Constraints.Min}
+}
+
+type navItem struct {
+	name string
+}
+
+func (item *navItem) Layout(gtx C, th *theme.Theme, textColor color.NRGBA) D {
+	label := material.Label(th.Theme, th.TextSize, item.name)
+	label.Color = textColor
+	return label.Layout(gtx)
+}
+
+func (item *navItem) ContextMenuOptions(gtx C) ([][]menu.MenuOption, bool) {
+	return nil, false
+}
+
+func (item *navItem) Children() ([]navi.NavItem, bool) {
+	return nil, false
+}
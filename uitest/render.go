@@ -0,0 +1,76 @@
+package uitest
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"runtime"
+
+	"gioui.org/gpu/headless"
+)
+
+// ErrNoRenderer is returned by Render if the headless renderer can not be
+// created, e.g., when neither a GPU nor a software rasterizer is available.
+var ErrNoRenderer = errors.New("no headless renderer available")
+
+type renderer struct {
+	window *headless.Window
+}
+
+// newWindow creates a headless window. If there is no display, the surfaceless
+// EGL platform is selected while the window is created, which lets Mesa fall
+// back to its llvmpipe software rasterizer on machines without a GPU, like most
+// CI runners. The environment is restored afterwards, and an EGL_PLATFORM set
+// by the caller is kept.
+func newWindow(size image.Point) (*headless.Window, error) {
+	if needsSurfaceless() {
+		os.Setenv("EGL_PLATFORM", "surfaceless")
+		defer os.Unsetenv("EGL_PLATFORM")
+	}
+
+	return headless.NewWindow(size.X, size.Y)
+}
+
+func needsSurfaceless() bool {
+	if runtime.GOOS != "linux" && runtime.GOOS != "freebsd" && runtime.GOOS != "openbsd" {
+		return false
+	}
+
+	_, set := os.LookupEnv("EGL_PLATFORM")
+	return !set && os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == ""
+}
+
+// Render rasterizes the operations of the last frame to an image. The result
+// of rasterizing text and anti-aliased paths may differ slightly between
+// renderers, so compare the images with a tolerance, as MatchGolden does.
+func (h *Harness) Render() (*image.RGBA, error) {
+	if h.renderer == nil || h.renderer.window.Size() != h.Size {
+		h.Close()
+
+		window, err := newWindow(h.Size)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNoRenderer, err)
+		}
+		h.renderer = &renderer{window: window}
+	}
+
+	if err := h.renderer.window.Frame(&h.ops); err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rectangle{Max: h.Size})
+	if err := h.renderer.window.Screenshot(img); err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// Close releases the resources of the renderer.
+func (h *Harness) Close() {
+	if h.renderer != nil {
+		h.renderer.window.Release()
+		h.renderer = nil
+	}
+}
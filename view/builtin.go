@@ -0,0 +1,86 @@
+package view
+
+import (
+	"gioui.org/io/key"
+	"gioui.org/layout"
+
+	"github.com/oligo/gioview/keybinding"
+)
+
+// The commands of the view manager, registered by NewViewManager. They act on
+// the view manager returned by NewViewManager, not on the ones created by its
+// ForWindow method.
+const (
+	CloseTabCommand   keybinding.CommandID = "view.closeTab"
+	GoBackCommand     keybinding.CommandID = "view.goBack"
+	GoForwardCommand  keybinding.CommandID = "view.goForward"
+	SplitRightCommand keybinding.CommandID = "view.splitRight"
+	// CloseModalCommand closes the top modal view, unless it is halted.
+	CloseModalCommand keybinding.CommandID = "view.closeModal"
+)
+
+// registerCommands registers the built-in commands, and the widget commands
+// of gioview.
+func (vm *defaultViewManager) registerCommands() {
+	commands := []*keybinding.Command{
+		{
+			ID:      CloseTabCommand,
+			Title:   "Close Tab",
+			Key:     keybinding.KeyChord{Name: "W", Modifiers: key.ModShortcut},
+			Handler: func(gtx layout.Context) { vm.CloseTab(vm.CurrentViewIndex()) },
+			Enabled: func() bool { return vm.CurrentView() != nil },
+		},
+		{
+			ID:      GoBackCommand,
+			Title:   "Go Back",
+			Key:     keybinding.KeyChord{Name: key.NameLeftArrow, Modifiers: key.ModAlt},
+			Handler: func(gtx layout.Context) { vm.NavBack() },
+			Enabled: vm.HasPrev,
+		},
+		{
+			ID:      GoForwardCommand,
+			Title:   "Go Forward",
+			Key:     keybinding.KeyChord{Name: key.NameRightArrow, Modifiers: key.ModAlt},
+			Handler: func(gtx layout.Context) { vm.NavForward() },
+			Enabled: vm.HasNext,
+		},
+		{
+			ID:      SplitRightCommand,
+			Title:   "Split Editor Right",
+			Key:     keybinding.KeyChord{Name: "\\", Modifiers: key.ModShortcut},
+			Handler: func(gtx layout.Context) { vm.SplitGroup(layout.Horizontal) },
+			Enabled: func() bool { return vm.CurrentView() != nil },
+		},
+		{
+			ID:    CloseModalCommand,
+			Title: "Close Modal",
+			Key:   keybinding.KeyChord{Name: key.NameEscape},
+			Handler: func(gtx layout.Context) {
+				if m := vm.topModal(); m != nil {
+					m.closed = true
+					vm.Invalidate()
+				}
+			},
+			Enabled: func() bool {
+				m := vm.topModal()
+				return m != nil && m.closable()
+			},
+		},
+	}
+	commands = append(commands, keybinding.WidgetCommands()...)
+
+	for _, cmd := range commands {
+		vm.registry.commands.Register(cmd)
+	}
+}
+
+// topModal returns the modal view on the top of the modal stack, if any.
+func (vm *defaultViewManager) topModal() *ModalView {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	if vm.modalStack == nil {
+		return nil
+	}
+	m, _ := vm.modalStack.Peek().(*ModalView)
+	return m
+}
@@ -0,0 +1,97 @@
+package view
+
+import (
+	"errors"
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+
+	"github.com/oligo/gioview/keybinding"
+	"github.com/oligo/gioview/uitest"
+)
+
+// openConfirm opens a confirmation modal, and returns it with its result.
+func openConfirm(t *testing.T, vm ViewManager) (*ModalView, *ModalResult[bool]) {
+	id := NewViewID("Confirm")
+	vm.Register(id, func() View { return &confirmView{testView: &testView{BaseView: &BaseView{}, id: id}} })
+	result, err := OpenModal[bool](vm, Intent{Target: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var modal *ModalView
+	for m := range vm.ModalViews() {
+		modal = m
+	}
+	return modal, result
+}
+
+func TestBuiltinCommands(t *testing.T) {
+	vm := newTestVM()
+	reg := vm.Commands()
+	gtx := layout.Context{Now: time.Now()}
+	if len(reg.Conflicts()) != 0 {
+		t.Fatalf("got conflicts %+v", reg.Conflicts())
+	}
+
+	if reg.Run(gtx, CloseTabCommand) {
+		t.Fatal("closing a tab without a view")
+	}
+	vm.RequestSwitch(Intent{Target: noteViewID})
+	if !reg.Run(gtx, CloseTabCommand) || vm.CurrentView() != nil {
+		t.Fatal("tab is not closed")
+	}
+
+	// The top modal is closed, unless it is halted.
+	modal, result := openConfirm(t, vm)
+	modal.ShowUp(gtx)
+	modal.Halted = true
+	if reg.Run(gtx, CloseModalCommand) {
+		t.Fatal("halted modal is closed")
+	}
+	modal.Halted = false
+	if !reg.Run(gtx, CloseModalCommand) || !modal.IsClosed(gtx) {
+		t.Fatal("modal is not closed")
+	}
+	if _, err := result.Wait(); !errors.Is(err, ErrModalCancelled) {
+		t.Fatalf("want ErrModalCancelled, got %v", err)
+	}
+}
+
+func TestModalEscape(t *testing.T) {
+	vm := newTestVM()
+	modal, result := openConfirm(t, vm)
+
+	// The commands of the view manager are not updated: the modal handles the
+	// key chord of CloseModalCommand itself.
+	var h *uitest.Harness
+	h = uitest.New(image.Pt(400, 300), func(gtx layout.Context) layout.Dimensions {
+		if h.FrameCount() == 0 {
+			modal.ShowUp(gtx)
+		}
+		return modal.Layout(gtx, h.Theme)
+	})
+	h.Frame()
+
+	modal.Halted = true
+	h.Key(key.NameEscape, 0)
+	if modal.IsClosed(layout.Context{}) {
+		t.Fatal("halted modal is closed")
+	}
+
+	modal.Halted = false
+	vm.Commands().SetBinding(CloseModalCommand, keybinding.KeyChord{Name: "Q", Modifiers: key.ModShortcut})
+	h.Key(key.NameEscape, 0)
+	if modal.IsClosed(layout.Context{}) {
+		t.Fatal("modal is closed by its unbound key chord")
+	}
+	h.Key("Q", key.ModShortcut)
+	if !modal.IsClosed(layout.Context{}) {
+		t.Fatal("modal is not closed by its key chord")
+	}
+	if _, err := result.Wait(); !errors.Is(err, ErrModalCancelled) {
+		t.Fatalf("want ErrModalCancelled, got %v", err)
+	}
+}
@@ -15,24 +15,38 @@ import (
 var _ ViewManager = (*defaultViewManager)(nil)
 
 type defaultViewManager struct {
-	window *app.Window
-	stacks []*ViewStack
+	window Window
+	// root of the workspace tree.
+	workspace *WorkspaceNode
+	// the focused tab group.
+	group *TabGroup
 	// views which are to be shown as modal.
-	modalStack    *ViewStack
-	currentTabIdx int
-	views         map[ViewID]ViewProvider
+	modalStack *ViewStack
+	// views and routes shared by the view managers of all windows.
+	registry *registry
+
+	// navigation guards and lifecycle event subscribers.
+	beforeLeave      []NavigationGuard
+	beforeEnter      []NavigationGuard
+	afterEnter       []func(vw View, intent Intent)
+	subscribers      []subscriber
+	lastSubscriberID int
+	pendingEvents    []LifecycleEvent
 
 	// title of the window
 	currentTitle string
-	mu           sync.Mutex
+	// mu guards the navigation state. All the exported methods changing the
+	// tabs, the history or the modals hold it, and dispatch the lifecycle
+	// events after it is released.
+	mu sync.Mutex
 }
 
 func (vm *defaultViewManager) CurrentView() View {
-	if len(vm.stacks) <= 0 {
+	if len(vm.group.stacks) <= 0 {
 		return nil
 	}
 
-	stack := vm.stacks[vm.currentTabIdx]
+	stack := vm.group.stacks[vm.group.currentTabIdx]
 	vw := stack.Peek()
 
 	if vm.currentTitle != vw.Title() {
@@ -75,11 +89,27 @@ func (vm *defaultViewManager) ModalViews() iter.Seq[*ModalView] {
 }
 
 func (vm *defaultViewManager) FinishModalView() {
-	vm.modalStack.Pop()
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if vm.modalStack == nil {
+		return
+	}
+
+	vw := vm.modalStack.Pop()
+	if vw == nil {
+		return
+	}
+
+	vw.(*ModalView).cancelResult()
+	if !vw.Finished() {
+		vm.finishView(vw)
+	}
 }
 
 func (vm *defaultViewManager) CurrentViewIndex() int {
-	return vm.currentTabIdx
+	return vm.group.currentTabIdx
 }
 
 func (vm *defaultViewManager) Register(ID ViewID, provider ViewProvider) error {
@@ -94,44 +124,105 @@ func (vm *defaultViewManager) Register(ID ViewID, provider ViewProvider) error {
 		return errors.New("view provider is nil")
 	}
 
-	if vm.views == nil {
-		vm.views = make(map[ViewID]ViewProvider)
-	}
-	vm.views[ID] = provider
+	vm.registry.register(ID, provider)
 	log.Println("registered view: ", ID)
 	return nil
 }
 
+func (vm *defaultViewManager) RegisteredViews() []ViewID {
+	return vm.registry.viewIDs()
+}
+
 func (vm *defaultViewManager) NavBack() View {
-	if len(vm.stacks) <= 0 {
+	return vm.NavHistory(-1)
+}
+
+func (vm *defaultViewManager) NavForward() View {
+	return vm.NavHistory(1)
+}
+
+func (vm *defaultViewManager) NavHistory(offset int) View {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if len(vm.group.stacks) <= 0 {
 		return nil
 	}
 
-	stack := vm.stacks[vm.currentTabIdx]
-	if stack.Depth() <= 1 {
-		// keep the last view
-		return stack.Peek()
+	stack := vm.group.stacks[vm.group.currentTabIdx]
+	current := stack.Peek()
+	if offset == 0 {
+		return current
 	}
 
-	vw := stack.Pop()
-	vw.OnFinish()
+	if offset < 0 && stack.Depth() <= 1 || offset > 0 && !stack.HasNext() {
+		// keep the current view
+		return current
+	}
 
+	kind := NavBackward
+	if offset > 0 {
+		kind = NavForward
+	}
+	if !vm.canLeave(kind, current) {
+		return current
+	}
+
+	for ; offset < 0 && stack.Depth() > 1; offset++ {
+		stack.Backward()
+	}
+	for ; offset > 0 && stack.HasNext(); offset-- {
+		stack.Forward()
+	}
+
+	vm.pauseView(current)
 	topVw := stack.Peek()
 	if topVw != nil {
-		topVw.OnResume()
+		vm.resumeView(topVw)
 	}
 	return topVw
 }
 
 func (vm *defaultViewManager) HasPrev() bool {
-	if len(vm.stacks) <= 0 {
+	if len(vm.group.stacks) <= 0 {
 		return false
 	}
-	stack := vm.stacks[vm.currentTabIdx]
+	stack := vm.group.stacks[vm.group.currentTabIdx]
 	return stack.Depth() > 1
 }
 
+func (vm *defaultViewManager) HasNext() bool {
+	if len(vm.group.stacks) <= 0 {
+		return false
+	}
+	return vm.group.stacks[vm.group.currentTabIdx].HasNext()
+}
+
+func (vm *defaultViewManager) History() []HistoryEntry {
+	if len(vm.group.stacks) <= 0 {
+		return nil
+	}
+
+	stack := vm.group.stacks[vm.group.currentTabIdx]
+	var entries []HistoryEntry
+	offset := 1 - stack.Depth()
+	for vw := range stack.All(true) {
+		entries = append(entries, HistoryEntry{View: vw, Offset: offset})
+		offset++
+	}
+
+	for vw := range stack.Forwards() {
+		entries = append(entries, HistoryEntry{View: vw, Offset: offset})
+		offset++
+	}
+
+	return entries
+}
+
 func (vm *defaultViewManager) RequestSwitch(intent Intent) error {
+	// events are dispatched after the mutex is released.
+	defer vm.flushEvents()
 	// use mutex to guard the dispatching
 	vm.mu.Lock()
 	defer vm.mu.Unlock()
@@ -142,102 +233,144 @@ func (vm *defaultViewManager) RequestSwitch(intent Intent) error {
 	if intent.Target == (ViewID{}) {
 		return nil
 	}
-	provider, ok := vm.views[intent.Target]
+
+	nav := &Navigation{Kind: NavSwitch, From: vm.currentView(), Intent: intent}
+	if err := vm.runGuards(vm.beforeEnter, nav); err != nil {
+		return err
+	}
+	// guards may have redirected the intent.
+	intent = nav.Intent
+
+	provider, ok := vm.registry.provider(intent.Target)
 	if !ok {
 		return fmt.Errorf("no target view found: %v", intent.Target)
 	}
 
-	// Pause the current view by calling its OnPause callback.
-	vm.pauseCurrentView()
+	if !intent.ShowAsModal && nav.From != nil {
+		if err := vm.runGuards(vm.beforeLeave, nav); err != nil {
+			return err
+		}
+	}
 
-	var targetView View
+	prevTab := vm.group.currentTabIdx
 	stack := vm.route(&intent)
 
-	// get target view
-	if topVw := stack.Peek(); topVw != nil && topVw.Location() == intent.Location() {
-		targetView = topVw
-	} else {
+	// get target view. Modals opened by OpenModal are never reused, as each
+	// of them settles its own result.
+	_, hasResult := intent.Params[modalResultParam]
+	targetView, pushed := stack.Peek(), false
+	if targetView == nil || targetView.Location() != intent.Location() || hasResult {
+		pushed = true
 		targetView = provider()
 		if intent.ShowAsModal {
-			targetView = &ModalView{View: targetView}
+			targetView = &ModalView{View: targetView, commands: vm.Commands()}
 		}
-		err := stack.Push(targetView)
-		if err != nil {
+	}
+
+	// Params are validated before the current view is paused, so a rejected
+	// intent leaves the current view untouched.
+	if err := validateParams(targetView, intent.Params); err != nil {
+		vm.revertRoute(stack, prevTab)
+		return err
+	}
+
+	if pushed {
+		if err := stack.Push(targetView); err != nil {
+			vm.revertRoute(stack, prevTab)
 			return fmt.Errorf("push to viewstack error: %w", err)
 		}
 	}
 
-	err := targetView.OnNavTo(intent)
-	if err != nil {
-		vm.revertPushToStack(stack)
+	// Pause the current view by calling its OnPause callback.
+	if nav.From != nil {
+		vm.pauseView(nav.From)
+	}
+
+	if err := vm.navTo(targetView, intent); err != nil {
+		if pushed {
+			stack.Pop()
+		}
+		vm.revertRoute(stack, prevTab)
+		if nav.From != nil {
+			vm.resumeView(nav.From)
+		}
 		return err
 	}
 
+	if pushed {
+		// Navigating to a new view discards the forward history.
+		for _, vw := range stack.TruncateForward() {
+			vm.finishView(vw)
+		}
+	}
+
+	for _, hook := range vm.afterEnter {
+		hook(targetView, intent)
+	}
+
 	location := intent.Location()
 	log.Printf("switching to %s", location.String())
 	return nil
 }
 
-func (vm *defaultViewManager) revertPushToStack(stack *ViewStack) {
-	stack.Pop()
-	// If stack is empty after pop, remove the entire tab
-	if stack.Depth() == 0 {
-		idx := slices.Index(vm.stacks, stack)
-		if idx >= 0 {
-			vm.stacks = slices.Delete(vm.stacks, idx, idx+1)
-			if vm.currentTabIdx >= idx && vm.currentTabIdx > 0 {
-				vm.currentTabIdx--
-			}
+// revertRoute undoes the routing of a failed navigation. The stack is removed
+// if it is left empty, and the previous tab becomes current again.
+func (vm *defaultViewManager) revertRoute(stack *ViewStack, prevTab int) {
+	if stack.IsEmpty() {
+		if idx := slices.Index(vm.group.stacks, stack); idx >= 0 {
+			vm.group.stacks = slices.Delete(vm.group.stacks, idx, idx+1)
 		}
 	}
+
+	vm.group.currentTabIdx = max(0, min(prevTab, len(vm.group.stacks)-1))
 }
 
 // route the intent to the proper viewstack/tab by intent.URL(). Note that this
 // method does not handle modal intent routing.
 func (vm *defaultViewManager) routeView(intent *Intent) *ViewStack {
-	if len(vm.stacks) <= vm.currentTabIdx {
+	if len(vm.group.stacks) <= vm.group.currentTabIdx {
 		// try to fix the illegal state
 		stack := NewViewStack()
-		vm.stacks = append(vm.stacks, stack)
-		vm.currentTabIdx = len(vm.stacks) - 1
+		vm.group.stacks = append(vm.group.stacks, stack)
+		vm.group.currentTabIdx = len(vm.group.stacks) - 1
 		return stack
 	}
 
 	// Iterate through all the viewstacks to find the top view with the same location.
 	// switch to and replace the existing view.
-	for idx, s := range vm.stacks {
+	for idx, s := range vm.group.stacks {
 		if s.Peek().Location() == intent.Location() {
 			// switch to the tab
-			vm.currentTabIdx = idx
+			vm.group.currentTabIdx = idx
 			return s
 		}
 	}
 
 	if intent.RequireNew {
 		stack := NewViewStack()
-		vm.stacks = append(vm.stacks, stack)
-		vm.currentTabIdx = len(vm.stacks) - 1
+		vm.group.stacks = append(vm.group.stacks, stack)
+		vm.group.currentTabIdx = len(vm.group.stacks) - 1
 		return stack
 	}
 
 	// Respect referer by checking its parent view.
 	if intent.Referer != (url.URL{}) && intent.Referer == vm.CurrentView().Location() {
 		// push to current view stack
-		return vm.stacks[vm.currentTabIdx]
+		return vm.group.stacks[vm.group.currentTabIdx]
 	}
 
 	// then try to match the viewID:
-	for idx, s := range vm.stacks {
+	for idx, s := range vm.group.stacks {
 		if intent.Target == s.Peek().ID() {
-			vm.currentTabIdx = idx
+			vm.group.currentTabIdx = idx
 			return s
 		}
 	}
 
 	// create new stack
 	stack := NewViewStack()
-	vm.stacks = append(vm.stacks, stack)
-	vm.currentTabIdx = len(vm.stacks) - 1
+	vm.group.stacks = append(vm.group.stacks, stack)
+	vm.group.currentTabIdx = len(vm.group.stacks) - 1
 
 	return stack
 }
@@ -255,8 +388,8 @@ func (vm *defaultViewManager) route(intent *Intent) *ViewStack {
 }
 
 func (vm *defaultViewManager) OpenedViews() []View {
-	views := make([]View, len(vm.stacks))
-	for idx, stack := range vm.stacks {
+	views := make([]View, len(vm.group.stacks))
+	for idx, stack := range vm.group.stacks {
 		view := stack.Peek()
 		if view != nil {
 			views[idx] = view
@@ -267,63 +400,107 @@ func (vm *defaultViewManager) OpenedViews() []View {
 }
 
 func (vm *defaultViewManager) CloseTab(idx int) {
-	if idx < 0 || idx >= len(vm.stacks) {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	vm.closeTab(idx)
+}
+
+func (vm *defaultViewManager) closeTab(idx int) {
+	if idx < 0 || idx >= len(vm.group.stacks) {
+		return
+	}
+
+	stack := vm.group.stacks[idx]
+	if !vm.canLeave(NavCloseTab, stack.Peek()) {
 		return
 	}
 
-	stack := vm.stacks[idx]
-	stack.Clear()
-	vm.stacks = slices.Delete(vm.stacks, idx, idx+1)
-	if vm.currentTabIdx >= idx && vm.currentTabIdx > 0 {
-		vm.currentTabIdx -= 1
+	vm.clearStack(stack)
+	vm.group.removeTab(idx)
+	vm.emit(LifecycleEvent{Kind: TabClosedEvent, Tab: idx})
+
+	// remove the group from the workspace when its last tab is closed.
+	if len(vm.group.stacks) <= 0 {
+		vm.removeGroup(vm.group)
 	}
 }
 
 func (vm *defaultViewManager) SwitchTab(idx int) {
-	if idx >= len(vm.stacks) || idx < 0 {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if idx >= len(vm.group.stacks) || idx < 0 {
+		return
+	}
+
+	if idx != vm.group.currentTabIdx && !vm.canLeave(NavSwitchTab, vm.currentView()) {
 		return
 	}
 
-	vm.currentTabIdx = idx
+	vm.group.currentTabIdx = idx
 
-	stack := vm.stacks[vm.currentTabIdx]
+	stack := vm.group.stacks[vm.group.currentTabIdx]
 	vw := stack.Peek()
 	if vw != nil {
-		vw.OnResume()
+		vm.resumeView(vw)
 	}
 }
 
 func (vm *defaultViewManager) pauseCurrentView() {
-	if vm.currentTabIdx >= len(vm.stacks) || vm.currentTabIdx < 0 {
+	if vm.group.currentTabIdx >= len(vm.group.stacks) || vm.group.currentTabIdx < 0 {
 		return
 	}
 
-	stack := vm.stacks[vm.currentTabIdx]
+	stack := vm.group.stacks[vm.group.currentTabIdx]
 	vw := stack.Peek()
 	if vw != nil {
-		vw.OnPause()
+		vm.pauseView(vw)
 	}
 }
 
+// currentView returns the top most view of the current tab without touching the
+// window title.
+func (vm *defaultViewManager) currentView() View {
+	if vm.group.currentTabIdx >= len(vm.group.stacks) || vm.group.currentTabIdx < 0 {
+		return nil
+	}
+
+	return vm.group.stacks[vm.group.currentTabIdx].Peek()
+}
+
 func (vm *defaultViewManager) Invalidate() {
 	vm.window.Invalidate()
 }
 
 func (vm *defaultViewManager) Reset() {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
 	if vm.modalStack != nil {
-		vm.modalStack.Clear()
-	}
-	for _, stack := range vm.stacks {
-		stack.Clear()
+		vm.clearStack(vm.modalStack)
 	}
 
-	vm.currentTabIdx = 0
-	vm.stacks = vm.stacks[:0]
+	vm.clearWorkspace()
 	vm.Invalidate()
 }
 
+// NewViewManager creates a ViewManager rendering to the window, with the
+// built-in commands registered. Use ForWindow of the returned ViewManager to
+// manage additional windows.
+func NewViewManager(window Window) ViewManager {
+	vm := &defaultViewManager{
+		window:   window,
+		registry: &registry{},
+	}
+	vm.resetWorkspace()
+	vm.registerCommands()
+	return vm
+}
+
 func DefaultViewManager(window *app.Window) ViewManager {
-	return &defaultViewManager{
-		window: window,
-	}
+	return NewViewManager(window)
 }
@@ -0,0 +1,78 @@
+package view
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func noteID(vw View) string {
+	loc := vw.Location()
+	return loc.Query().Get("id")
+}
+
+func TestForwardNavigation(t *testing.T) {
+	vm := newTestVM()
+	vm.RequestSwitch(Intent{Target: listViewID})
+	for i := 1; i <= 3; i++ {
+		vm.RequestSwitch(Intent{Target: noteViewID, Params: Params{"id": i}, Referer: vm.CurrentView().Location()})
+	}
+
+	vm.NavBack()
+	vm.NavBack()
+	if !vm.HasNext() || noteID(vm.CurrentView()) != "1" {
+		t.Fatalf("unexpected current view: %s", noteID(vm.CurrentView()))
+	}
+
+	history := vm.History()
+	if len(history) != 4 || history[0].Offset != -1 || history[3].Offset != 2 {
+		t.Fatalf("unexpected history: %v", history)
+	}
+
+	var buf bytes.Buffer
+	vm.SaveSession(&buf)
+	restored := newTestVM()
+	if err := restored.RestoreSession(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.History()) != 4 || !restored.HasNext() {
+		t.Fatal("forward history is not restored")
+	}
+
+	if vw := vm.NavHistory(2); noteID(vw) != "3" || vm.HasNext() {
+		t.Fatalf("unexpected view: %s", noteID(vw))
+	}
+
+	vm.NavBack()
+	forward := vm.History()[3].View
+	vm.RequestSwitch(Intent{Target: noteViewID, Params: Params{"id": 4}, Referer: vm.CurrentView().Location()})
+	if vm.HasNext() || !forward.Finished() {
+		t.Fatal("forward history should be discarded after navigating to a new view")
+	}
+}
+
+func TestConcurrentNavigation(t *testing.T) {
+	vm := newTestVM()
+	vm.RequestSwitch(Intent{Target: listViewID})
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				switch (i + j) % 4 {
+				case 0:
+					vm.RequestSwitch(Intent{Target: noteViewID, Params: Params{"id": j}, RequireNew: j%8 == 0})
+				case 1:
+					vm.NavBack()
+				case 2:
+					vm.SwitchTab(j % 3)
+				case 3:
+					vm.CloseTab(j % 3)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
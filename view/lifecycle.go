@@ -0,0 +1,198 @@
+package view
+
+import (
+	"errors"
+	"slices"
+)
+
+// ErrNavigationBlocked can be returned by navigation guards to block a navigation.
+var ErrNavigationBlocked = errors.New("navigation blocked")
+
+// NavigationKind tells how a navigation is triggered.
+type NavigationKind uint8
+
+const (
+	// Navigation triggered by RequestSwitch.
+	NavSwitch NavigationKind = iota
+	// Navigation triggered by NavBack or backward NavHistory.
+	NavBackward
+	// Navigation triggered by NavForward or forward NavHistory.
+	NavForward
+	// Navigation triggered by SwitchTab.
+	NavSwitchTab
+	// Navigation triggered by CloseTab.
+	NavCloseTab
+)
+
+// Navigation describes a navigation that is about to happen.
+type Navigation struct {
+	Kind NavigationKind
+	// From is the view that is to be left. It is nil if there is no opened view.
+	From View
+	// Intent is the target of the navigation. It is only set for NavSwitch.
+	// BeforeEnter guards can modify it to redirect the navigation.
+	Intent Intent
+}
+
+// NavigationGuard is called before a navigation happens. Returning a non-nil error
+// cancels the navigation. Guards are called with the view manager locked, so they
+// must not call methods of the view manager.
+type NavigationGuard func(nav *Navigation) error
+
+// LifecycleEventKind is the kind of view lifecycle transitions.
+type LifecycleEventKind uint8
+
+const (
+	// The view is initialized by OnNavTo.
+	NavToEvent LifecycleEventKind = iota
+	// The view is resumed and becomes visible.
+	ResumeEvent
+	// The view is paused and becomes invisible.
+	PauseEvent
+	// The view is finished.
+	FinishEvent
+	// A tab is closed.
+	TabClosedEvent
+)
+
+// LifecycleEvent is published by the view manager on view lifecycle transitions.
+type LifecycleEvent struct {
+	Kind LifecycleEventKind
+	View View
+	// Intent delivered to the view. Only set for NavToEvent.
+	Intent Intent
+	// Index of the closed tab. Only set for TabClosedEvent.
+	Tab int
+}
+
+type subscriber struct {
+	id int
+	fn func(evt LifecycleEvent)
+}
+
+func (k LifecycleEventKind) String() string {
+	switch k {
+	case NavToEvent:
+		return "NavTo"
+	case ResumeEvent:
+		return "Resume"
+	case PauseEvent:
+		return "Pause"
+	case FinishEvent:
+		return "Finish"
+	case TabClosedEvent:
+		return "TabClosed"
+	}
+
+	return "Unknown"
+}
+
+func (vm *defaultViewManager) BeforeLeave(guard NavigationGuard) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.beforeLeave = append(vm.beforeLeave, guard)
+}
+
+func (vm *defaultViewManager) BeforeEnter(guard NavigationGuard) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.beforeEnter = append(vm.beforeEnter, guard)
+}
+
+func (vm *defaultViewManager) AfterEnter(hook func(vw View, intent Intent)) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.afterEnter = append(vm.afterEnter, hook)
+}
+
+func (vm *defaultViewManager) Subscribe(fn func(evt LifecycleEvent)) func() {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	vm.lastSubscriberID++
+	id := vm.lastSubscriberID
+	vm.subscribers = append(vm.subscribers, subscriber{id: id, fn: fn})
+
+	return func() {
+		vm.mu.Lock()
+		defer vm.mu.Unlock()
+		vm.subscribers = slices.DeleteFunc(vm.subscribers, func(s subscriber) bool { return s.id == id })
+	}
+}
+
+func (vm *defaultViewManager) runGuards(guards []NavigationGuard, nav *Navigation) error {
+	for _, guard := range guards {
+		if err := guard(nav); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// canLeave runs the before-leave guards for leaving the view.
+func (vm *defaultViewManager) canLeave(kind NavigationKind, from View) bool {
+	if from == nil {
+		return true
+	}
+
+	return vm.runGuards(vm.beforeLeave, &Navigation{Kind: kind, From: from}) == nil
+}
+
+// emit queues an event. Events are dispatched by flushEvents after the view manager
+// is unlocked, so subscribers are free to call the view manager.
+func (vm *defaultViewManager) emit(evt LifecycleEvent) {
+	if len(vm.subscribers) <= 0 {
+		return
+	}
+	vm.pendingEvents = append(vm.pendingEvents, evt)
+}
+
+func (vm *defaultViewManager) flushEvents() {
+	vm.mu.Lock()
+	events := vm.pendingEvents
+	vm.pendingEvents = nil
+	subscribers := slices.Clone(vm.subscribers)
+	vm.mu.Unlock()
+
+	for _, evt := range events {
+		for _, s := range subscribers {
+			s.fn(evt)
+		}
+	}
+}
+
+func (vm *defaultViewManager) navTo(vw View, intent Intent) error {
+	if err := vw.OnNavTo(intent); err != nil {
+		return err
+	}
+
+	vm.emit(LifecycleEvent{Kind: NavToEvent, View: vw, Intent: intent})
+	return nil
+}
+
+func (vm *defaultViewManager) resumeView(vw View) {
+	vw.OnResume()
+	vm.emit(LifecycleEvent{Kind: ResumeEvent, View: vw})
+}
+
+func (vm *defaultViewManager) pauseView(vw View) {
+	vw.OnPause()
+	vm.emit(LifecycleEvent{Kind: PauseEvent, View: vw})
+}
+
+func (vm *defaultViewManager) finishView(vw View) {
+	vw.OnFinish()
+	vm.emit(LifecycleEvent{Kind: FinishEvent, View: vw})
+}
+
+// clearStack finishes all the views in the stack.
+func (vm *defaultViewManager) clearStack(stack *ViewStack) {
+	for vw := range stack.Forwards() {
+		vm.emit(LifecycleEvent{Kind: FinishEvent, View: vw})
+	}
+	for vw := range stack.All(false) {
+		vm.emit(LifecycleEvent{Kind: FinishEvent, View: vw})
+	}
+	stack.Clear()
+}
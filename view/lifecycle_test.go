@@ -0,0 +1,132 @@
+package view
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNavigationGuards(t *testing.T) {
+	vm := newTestVM()
+	loginID := NewViewID("Login")
+	vm.Register(loginID, func() View { return &testView{BaseView: &BaseView{}, id: loginID} })
+
+	loggedIn := false
+	vm.BeforeEnter(func(nav *Navigation) error {
+		if !loggedIn && nav.Intent.Target == noteViewID {
+			nav.Intent = Intent{Target: loginID}
+		}
+		return nil
+	})
+
+	vm.RequestSwitch(Intent{Target: noteViewID})
+	if vm.CurrentView().ID() != loginID {
+		t.Fatalf("want redirect to login view, got %v", vm.CurrentView().ID())
+	}
+
+	loggedIn = true
+	vm.RequestSwitch(Intent{Target: noteViewID, Referer: vm.CurrentView().Location()})
+	if vm.CurrentView().ID() != noteViewID {
+		t.Fatalf("want note view, got %v", vm.CurrentView().ID())
+	}
+
+	unsaved := true
+	vm.BeforeLeave(func(nav *Navigation) error {
+		if unsaved && nav.From.ID() == noteViewID {
+			return ErrNavigationBlocked
+		}
+		return nil
+	})
+
+	if vw := vm.NavBack(); vw.ID() != noteViewID {
+		t.Fatal("leaving view with unsaved changes should be blocked")
+	}
+
+	vm.CloseTab(0)
+	if len(vm.OpenedViews()) != 1 {
+		t.Fatal("closing tab with unsaved changes should be blocked")
+	}
+
+	err := vm.RequestSwitch(Intent{Target: listViewID, Referer: vm.CurrentView().Location()})
+	if !errors.Is(err, ErrNavigationBlocked) {
+		t.Fatalf("want ErrNavigationBlocked, got %v", err)
+	}
+
+	unsaved = false
+	if vw := vm.NavBack(); vw.ID() != loginID {
+		t.Fatalf("want login view, got %v", vw.ID())
+	}
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	vm := newTestVM()
+
+	var events []LifecycleEventKind
+	cancel := vm.Subscribe(func(evt LifecycleEvent) {
+		events = append(events, evt.Kind)
+	})
+
+	vm.RequestSwitch(Intent{Target: listViewID})
+	vm.RequestSwitch(Intent{Target: noteViewID, Referer: vm.CurrentView().Location()})
+	vm.NavBack()
+	vm.CloseTab(0)
+
+	want := []LifecycleEventKind{
+		NavToEvent,
+		PauseEvent, NavToEvent,
+		PauseEvent, ResumeEvent,
+		FinishEvent, FinishEvent, TabClosedEvent,
+	}
+	if !slices.Equal(events, want) {
+		t.Fatalf("want events %v, got %v", want, events)
+	}
+
+	cancel()
+	vm.RequestSwitch(Intent{Target: listViewID})
+	if len(events) != len(want) {
+		t.Fatal("events received after the subscription is cancelled")
+	}
+}
+
+type failingView struct {
+	*testView
+}
+
+func (vw *failingView) OnNavTo(intent Intent) error {
+	return errors.New("navigation failed")
+}
+
+func TestRejectedSwitchKeepsCurrentView(t *testing.T) {
+	vm := newTestVM()
+	pagedID := NewViewID("Paged")
+	vm.Register(pagedID, func() View { return &paramView{&testView{BaseView: &BaseView{}, id: pagedID}} })
+	failingID := NewViewID("Failing")
+	vm.Register(failingID, func() View { return &failingView{&testView{BaseView: &BaseView{}, id: failingID}} })
+
+	vm.RequestSwitch(Intent{Target: listViewID})
+
+	var events []LifecycleEventKind
+	vm.Subscribe(func(evt LifecycleEvent) {
+		events = append(events, evt.Kind)
+	})
+
+	// invalid params are rejected before the current view is paused.
+	if err := vm.RequestSwitch(Intent{Target: pagedID, Params: Params{"page": "abc"}}); err == nil {
+		t.Fatal("want error for invalid params")
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+
+	// the current view is resumed if the target view fails to initialize.
+	if err := vm.RequestSwitch(Intent{Target: failingID, RequireNew: true}); err == nil {
+		t.Fatal("want error from OnNavTo")
+	}
+	if want := []LifecycleEventKind{PauseEvent, ResumeEvent}; !slices.Equal(events, want) {
+		t.Fatalf("want events %v, got %v", want, events)
+	}
+
+	if len(vm.OpenedViews()) != 1 || vm.CurrentView().ID() != listViewID {
+		t.Fatalf("unexpected current view: %v", vm.CurrentView().ID())
+	}
+}
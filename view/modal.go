@@ -6,6 +6,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/oligo/gioview/keybinding"
 	"github.com/oligo/gioview/misc"
 	"github.com/oligo/gioview/theme"
 
@@ -51,13 +52,30 @@ type ModalView struct {
 	// The max value is restricted to 0.9 to prevent it overflow.
 	MaxHeight float32
 	Radius    unit.Dp
-	// Make the ModalView halted. Halted modal view will not receive key events.
+	// Make the ModalView halted. Halted modal view is not closed by the key
+	// chord of CloseModalCommand, which is Escape by default.
 	Halted bool
 	//position  f32.Point
 	dims     layout.Dimensions
 	closed   bool
 	closeBtn widget.Clickable
 	anim     *cmp.VisibilityAnimation
+	// commands looks up the key chord closing the modal.
+	commands *keybinding.Registry
+	// result opened by OpenModal, cancelled when the modal is closed.
+	result modalResult
+}
+
+func (m *ModalView) OnNavTo(intent Intent) error {
+	if r, ok := intent.Params[modalResultParam].(modalResult); ok {
+		// the replaced result would never be settled otherwise.
+		if m.result != nil && m.result != r {
+			m.result.cancel()
+		}
+		m.result = r
+	}
+
+	return m.View.OnNavTo(intent)
 }
 
 func (m *ModalView) IsClosed(gtx layout.Context) bool {
@@ -84,12 +102,12 @@ func (m *ModalView) update(gtx layout.Context) bool {
 		m.closed = true
 	}
 
-	if m.anim != nil && m.anim.Visible() && !m.Halted {
+	if k := m.closeKey(); k != (keybinding.KeyChord{}) && m.closable() {
 		for {
-			// Use a global event filter to catch quit events.
-			// Applications have to be very careful as they may also use global escape
-			// elsewhere, which causes conflicts.
-			event, ok := gtx.Event(key.Filter{Name: key.NameEscape})
+			// Use a global event filter to catch quit events. The registry
+			// of the commands gets them first if the application calls its
+			// Update method.
+			event, ok := gtx.Event(key.Filter{Name: k.Name, Required: k.Modifiers})
 			if !ok {
 				break
 			}
@@ -99,15 +117,41 @@ func (m *ModalView) update(gtx layout.Context) bool {
 				continue
 			}
 
-			if ev.Name == key.NameEscape && ev.State == key.Release {
+			if ev.State == key.Release {
 				m.closed = true
 			}
 		}
 	}
 
+	if m.closed {
+		m.cancelResult()
+	}
+
 	return m.closed
 }
 
+// closable reports whether the modal can be closed by CloseModalCommand.
+func (m *ModalView) closable() bool {
+	return m.anim != nil && m.anim.Visible() && !m.Halted
+}
+
+// closeKey returns the key chord of CloseModalCommand.
+func (m *ModalView) closeKey() keybinding.KeyChord {
+	if m.commands != nil {
+		if cmd := m.commands.Command(CloseModalCommand); cmd != nil {
+			return cmd.Binding()
+		}
+	}
+	return keybinding.KeyChord{Name: key.NameEscape}
+}
+
+// cancelResult cancels the result of the modal if it has not been settled.
+func (m *ModalView) cancelResult() {
+	if m.result != nil {
+		m.result.cancel()
+	}
+}
+
 func (m *ModalView) Layout(gtx layout.Context, th *theme.Theme) layout.Dimensions {
 	m.update(gtx)
 	if !m.anim.Visible() {
@@ -0,0 +1,146 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrModalCancelled is the error of a modal result when the modal is closed
+// without returning a value, e.g., by pressing Escape or the close button.
+var ErrModalCancelled = errors.New("modal cancelled")
+
+// reserved intent param to pass the modal result to the modal view.
+const modalResultParam = "_modalResult"
+
+// modalResult is the type independent part of ModalResult used by ModalView.
+type modalResult interface {
+	cancel()
+}
+
+// ModalResult carries the value returned from a modal view to the caller who
+// opens the modal. It is settled only once, either by Resolve, Reject, or
+// when the modal is closed.
+type ModalResult[T any] struct {
+	once      sync.Once
+	done      chan struct{}
+	mu        sync.Mutex
+	value     T
+	err       error
+	callbacks []func(val T, err error)
+}
+
+func newModalResult[T any]() *ModalResult[T] {
+	return &ModalResult[T]{done: make(chan struct{})}
+}
+
+// Resolve settles the result with a value.
+func (r *ModalResult[T]) Resolve(val T) {
+	r.settle(val, nil)
+}
+
+// Reject settles the result with an error.
+func (r *ModalResult[T]) Reject(err error) {
+	var zero T
+	r.settle(zero, err)
+}
+
+// Cancel settles the result with ErrModalCancelled.
+func (r *ModalResult[T]) Cancel() {
+	r.Reject(ErrModalCancelled)
+}
+
+func (r *ModalResult[T]) cancel() {
+	r.Cancel()
+}
+
+func (r *ModalResult[T]) settle(val T, err error) {
+	r.once.Do(func() {
+		r.mu.Lock()
+		r.value, r.err = val, err
+		callbacks := r.callbacks
+		r.callbacks = nil
+		close(r.done)
+		r.mu.Unlock()
+
+		for _, cb := range callbacks {
+			cb(val, err)
+		}
+	})
+}
+
+// Done returns a channel that is closed when the result is settled.
+func (r *ModalResult[T]) Done() <-chan struct{} {
+	return r.done
+}
+
+// Wait blocks until the result is settled. Do not call it in the UI
+// goroutine as it blocks the rendering of the modal.
+func (r *ModalResult[T]) Wait() (T, error) {
+	<-r.done
+	return r.value, r.err
+}
+
+// OnSettled registers a callback which is called when the result is settled.
+// The callback is called immediately if the result has been settled. Note
+// that callback may be called in the UI goroutine.
+func (r *ModalResult[T]) OnSettled(callback func(val T, err error)) {
+	r.mu.Lock()
+	select {
+	case <-r.done:
+		r.mu.Unlock()
+		callback(r.value, r.err)
+	default:
+		r.callbacks = append(r.callbacks, callback)
+		r.mu.Unlock()
+	}
+}
+
+// OpenModal opens the intent as a modal view and returns a result which
+// is settled by the modal view. The modal view gets the result using
+// ModalResultOf.
+func OpenModal[T any](vm ViewManager, intent Intent) (*ModalResult[T], error) {
+	result := newModalResult[T]()
+
+	params := make(Params, len(intent.Params)+1)
+	for k, v := range intent.Params {
+		params[k] = v
+	}
+	params[modalResultParam] = result
+
+	intent.Params = params
+	intent.ShowAsModal = true
+	if err := vm.RequestSwitch(intent); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// AwaitModal opens the intent as a modal view and blocks until the modal
+// returns a value or is closed. It must not be called in the UI goroutine.
+func AwaitModal[T any](vm ViewManager, intent Intent) (T, error) {
+	result, err := OpenModal[T](vm, intent)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return result.Wait()
+}
+
+// ModalResultOf returns the result of the intent opened by OpenModal. It is
+// used by modal views to return values to their callers.
+func ModalResultOf[T any](intent Intent) (*ModalResult[T], error) {
+	raw, ok := intent.Params[modalResultParam]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMissingParam, modalResultParam)
+	}
+
+	result, ok := raw.(*ModalResult[T])
+	if !ok {
+		return nil, fmt.Errorf("%w: %s, want %T, got %T", ErrInvalidParam, modalResultParam, result, raw)
+	}
+
+	return result, nil
+}
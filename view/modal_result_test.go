@@ -0,0 +1,94 @@
+package view
+
+import (
+	"errors"
+	"testing"
+)
+
+type confirmView struct {
+	*testView
+	result *ModalResult[bool]
+}
+
+func (vw *confirmView) OnNavTo(intent Intent) error {
+	vw.BaseView.OnNavTo(intent)
+	result, err := ModalResultOf[bool](intent)
+	if err != nil {
+		return err
+	}
+	vw.result = result
+	return nil
+}
+
+func TestModalResult(t *testing.T) {
+	vm := newTestVM()
+	id := NewViewID("Confirm")
+	var current *confirmView
+	vm.Register(id, func() View {
+		current = &confirmView{testView: &testView{BaseView: &BaseView{}, id: id}}
+		return current
+	})
+
+	result, err := OpenModal[bool](vm, Intent{Target: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current.result.Resolve(true)
+	if val, err := result.Wait(); err != nil || !val {
+		t.Fatalf("unexpected result: %v, %v", val, err)
+	}
+
+	// closing the modal without a result cancels it.
+	result, err = OpenModal[bool](vm, Intent{Target: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var settled error
+	result.OnSettled(func(val bool, err error) { settled = err })
+	vm.FinishModalView()
+	if !errors.Is(settled, ErrModalCancelled) {
+		t.Fatalf("want ErrModalCancelled, got %v", settled)
+	}
+
+	// the view requires a modal result.
+	if err := vm.RequestSwitch(Intent{Target: id, ShowAsModal: true}); !errors.Is(err, ErrMissingParam) {
+		t.Fatalf("want ErrMissingParam, got %v", err)
+	}
+}
+
+func TestOpenSameModalTwice(t *testing.T) {
+	vm := newTestVM()
+	id := NewViewID("Confirm")
+	var views []*confirmView
+	vm.Register(id, func() View {
+		vw := &confirmView{testView: &testView{BaseView: &BaseView{}, id: id}}
+		views = append(views, vw)
+		return vw
+	})
+
+	first, err := OpenModal[bool](vm, Intent{Target: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := OpenModal[bool](vm, Intent{Target: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(views) != 2 || views[0].result != first || views[1].result != second {
+		t.Fatal("each modal result should have its own modal view")
+	}
+
+	// closing the modals settles both of the results.
+	vm.FinishModalView()
+	vm.FinishModalView()
+	for _, result := range []*ModalResult[bool]{first, second} {
+		select {
+		case <-result.Done():
+		default:
+			t.Fatal("modal result is not settled")
+		}
+	}
+}
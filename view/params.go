@@ -0,0 +1,189 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	ErrMissingParam = errors.New("missing mandatory param")
+	ErrInvalidParam = errors.New("invalid param")
+)
+
+// Params holds the params of an intent. It has the same underlying type as
+// map[string]interface{}, so untyped maps can still be used. Use ParamKey to
+// read and write params in a type safe way.
+type Params map[string]interface{}
+
+// ParamSpec describes a param accepted by a view. It is implemented by ParamKey.
+type ParamSpec interface {
+	Name() string
+	// Validate checks if the param in params is present when it is required,
+	// and is of the expected type.
+	Validate(params Params) error
+}
+
+// ParamDeclarer can be implemented by views to declare the params they accept.
+// ViewManager validates the intent params against the declared params before
+// the intent is delivered to OnNavTo, and returns the validation error from
+// RequestSwitch.
+type ParamDeclarer interface {
+	DeclaredParams() []ParamSpec
+}
+
+// ParamKey is a typed key used to set and get intent params.
+//
+//	var noteIDParam = view.NewParamKey[int]("id").Required()
+//
+//	intent := view.Intent{Target: NoteViewID, Params: view.NewParams(noteIDParam.With(12))}
+//	id, err := noteIDParam.Get(intent.Params)
+type ParamKey[T any] struct {
+	name     string
+	required bool
+	check    func(val T) error
+}
+
+// ParamValue is a param value bound to its key, created by ParamKey.With.
+type ParamValue struct {
+	name  string
+	value interface{}
+}
+
+// NewParamKey creates an optional param key of name.
+func NewParamKey[T any](name string) ParamKey[T] {
+	return ParamKey[T]{name: name}
+}
+
+// Required returns a copy of the key that must be present in the params.
+func (k ParamKey[T]) Required() ParamKey[T] {
+	k.required = true
+	return k
+}
+
+// WithCheck returns a copy of the key that validates the param value using fn.
+func (k ParamKey[T]) WithCheck(fn func(val T) error) ParamKey[T] {
+	k.check = fn
+	return k
+}
+
+func (k ParamKey[T]) Name() string {
+	return k.name
+}
+
+// With binds a value to the key.
+func (k ParamKey[T]) With(val T) ParamValue {
+	return ParamValue{name: k.name, value: val}
+}
+
+// Set sets the param in params. params must not be nil.
+func (k ParamKey[T]) Set(params Params, val T) {
+	params[k.name] = val
+}
+
+// Get reads the param from params. Params parsed from a URL are strings, and
+// they are converted to T if T is a string, bool, int or float type. It returns
+// ErrMissingParam if the param is absent, or ErrInvalidParam if the param has
+// an unexpected type or is rejected by the check function.
+func (k ParamKey[T]) Get(params Params) (T, error) {
+	var zero T
+	raw, ok := params[k.name]
+	if !ok {
+		return zero, fmt.Errorf("%w: %s", ErrMissingParam, k.name)
+	}
+
+	val, ok := raw.(T)
+	if !ok {
+		str, isStr := raw.(string)
+		if !isStr {
+			return zero, fmt.Errorf("%w: %s, want %T, got %T", ErrInvalidParam, k.name, zero, raw)
+		}
+
+		var err error
+		val, err = parseParam[T](str)
+		if err != nil {
+			return zero, fmt.Errorf("%w: %s, %w", ErrInvalidParam, k.name, err)
+		}
+	}
+
+	if k.check != nil {
+		if err := k.check(val); err != nil {
+			return zero, fmt.Errorf("%w: %s, %w", ErrInvalidParam, k.name, err)
+		}
+	}
+
+	return val, nil
+}
+
+// Value is like Get, but returns the zero value of T if there is any error.
+func (k ParamKey[T]) Value(params Params) T {
+	val, _ := k.Get(params)
+	return val
+}
+
+func (k ParamKey[T]) Validate(params Params) error {
+	_, err := k.Get(params)
+	if errors.Is(err, ErrMissingParam) && !k.required {
+		return nil
+	}
+
+	return err
+}
+
+// NewParams builds intent params from the param values.
+func NewParams(values ...ParamValue) Params {
+	params := make(Params, len(values))
+	for _, v := range values {
+		params[v.name] = v.value
+	}
+
+	return params
+}
+
+func parseParam[T any](str string) (T, error) {
+	var val T
+	var parsed interface{}
+	var err error
+
+	switch any(val).(type) {
+	case string:
+		parsed = str
+	case bool:
+		parsed, err = strconv.ParseBool(str)
+	case int:
+		parsed, err = strconv.Atoi(str)
+	case int64:
+		parsed, err = strconv.ParseInt(str, 10, 64)
+	case float64:
+		parsed, err = strconv.ParseFloat(str, 64)
+	default:
+		return val, fmt.Errorf("can not convert string to %T", val)
+	}
+
+	if err != nil {
+		return val, err
+	}
+
+	return parsed.(T), nil
+}
+
+// validateParams validates the intent params if the view declares its params.
+func validateParams(vw View, params Params) error {
+	if modal, ok := vw.(*ModalView); ok {
+		vw = modal.View
+	}
+
+	declarer, ok := vw.(ParamDeclarer)
+	if !ok {
+		return nil
+	}
+
+	var errs []error
+	for _, spec := range declarer.DeclaredParams() {
+		if err := spec.Validate(params); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,74 @@
+package view
+
+import (
+	"errors"
+	"testing"
+)
+
+var (
+	pageParam  = NewParamKey[int]("page").Required()
+	titleParam = NewParamKey[string]("title")
+)
+
+type paramView struct {
+	*testView
+}
+
+func (vw *paramView) DeclaredParams() []ParamSpec {
+	return []ParamSpec{pageParam, titleParam}
+}
+
+func TestParamKey(t *testing.T) {
+	params := NewParams(pageParam.With(2))
+	if page, err := pageParam.Get(params); err != nil || page != 2 {
+		t.Fatalf("unexpected page: %d, %v", page, err)
+	}
+
+	// params parsed from URL are strings.
+	params = Params{"page": "3"}
+	if page, err := pageParam.Get(params); err != nil || page != 3 {
+		t.Fatalf("unexpected page: %d, %v", page, err)
+	}
+
+	params = Params{"page": 1.5}
+	if _, err := pageParam.Get(params); !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("want ErrInvalidParam, got %v", err)
+	}
+
+	if err := titleParam.Validate(Params{}); err != nil {
+		t.Fatalf("optional param should be valid when absent: %v", err)
+	}
+
+	if err := pageParam.Validate(Params{}); !errors.Is(err, ErrMissingParam) {
+		t.Fatalf("want ErrMissingParam, got %v", err)
+	}
+
+	positive := pageParam.WithCheck(func(val int) error {
+		if val <= 0 {
+			return errors.New("page must be positive")
+		}
+		return nil
+	})
+	if err := positive.Validate(Params{"page": 0}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("want ErrInvalidParam, got %v", err)
+	}
+}
+
+func TestRequestSwitchValidatesParams(t *testing.T) {
+	vm := newTestVM()
+	id := NewViewID("Paged")
+	vm.Register(id, func() View { return &paramView{&testView{BaseView: &BaseView{}, id: id}} })
+
+	err := vm.RequestSwitch(Intent{Target: id, Params: Params{"page": "abc"}})
+	if !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("want ErrInvalidParam, got %v", err)
+	}
+
+	if len(vm.OpenedViews()) != 0 {
+		t.Fatal("view with invalid params should not be opened")
+	}
+
+	if err := vm.RequestSwitch(Intent{Target: id, Params: NewParams(pageParam.With(1))}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,185 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// URLScheme is the scheme of URLs built by BuildURL and accepted by the router.
+const URLScheme = "gioview"
+
+var (
+	ErrInvalidURL    = errors.New("invalid gioview URL")
+	ErrRouteNotFound = errors.New("no route found")
+)
+
+// route maps a URL pattern to a registered view. The pattern has the form of
+// gioview://host/path/{param}, where each path segment is either a literal or
+// a named param. A param can be typed with a suffix, e.g. {id:int}. Supported
+// types are string(the default), int, float and bool.
+type route struct {
+	pattern  string
+	host     string
+	segments []routeSegment
+	target   ViewID
+}
+
+type routeSegment struct {
+	literal string
+	param   string
+	kind    string
+}
+
+func parseRoute(pattern string, target ViewID) (*route, error) {
+	u, err := url.Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+
+	if u.Scheme != URLScheme || u.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, pattern)
+	}
+
+	r := &route{pattern: pattern, host: u.Host, target: target}
+	for _, seg := range splitPath(u.Path) {
+		if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") {
+			r.segments = append(r.segments, routeSegment{literal: seg})
+			continue
+		}
+
+		name, kind, _ := strings.Cut(seg[1:len(seg)-1], ":")
+		if name == "" {
+			return nil, fmt.Errorf("%w: empty param name in %s", ErrInvalidURL, pattern)
+		}
+
+		switch kind {
+		case "":
+			kind = "string"
+		case "string", "int", "float", "bool":
+		default:
+			return nil, fmt.Errorf("%w: unsupported param type %q in %s", ErrInvalidURL, kind, pattern)
+		}
+
+		r.segments = append(r.segments, routeSegment{param: name, kind: kind})
+	}
+
+	return r, nil
+}
+
+// match tries to match the location and returns the path params if the location matches.
+func (r *route) match(location *url.URL) (map[string]interface{}, bool) {
+	if location.Host != r.host {
+		return nil, false
+	}
+
+	segs := splitPath(location.Path)
+	if len(segs) != len(r.segments) {
+		return nil, false
+	}
+
+	params := make(map[string]interface{})
+	for i, seg := range r.segments {
+		if seg.param == "" {
+			if seg.literal != segs[i] {
+				return nil, false
+			}
+			continue
+		}
+
+		val, err := convertParam(segs[i], seg.kind)
+		if err != nil {
+			return nil, false
+		}
+		params[seg.param] = val
+	}
+
+	return params, true
+}
+
+func convertParam(val string, kind string) (interface{}, error) {
+	switch kind {
+	case "int":
+		return strconv.Atoi(val)
+	case "float":
+		return strconv.ParseFloat(val, 64)
+	case "bool":
+		return strconv.ParseBool(val)
+	default:
+		return val, nil
+	}
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		if unescaped, err := url.PathUnescape(seg); err == nil {
+			segs[i] = unescaped
+		}
+	}
+	return segs
+}
+
+func (vm *defaultViewManager) RegisterRoute(pattern string, target ViewID) error {
+	r, err := parseRoute(pattern, target)
+	if err != nil {
+		return err
+	}
+
+	return vm.registry.addRoute(r)
+}
+
+func (vm *defaultViewManager) ParseURL(rawURL string) (Intent, error) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	return vm.parseURL(rawURL)
+}
+
+func (vm *defaultViewManager) parseURL(rawURL string) (Intent, error) {
+	location, err := url.Parse(rawURL)
+	if err != nil {
+		return Intent{}, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+
+	if location.Scheme != URLScheme {
+		return Intent{}, fmt.Errorf("%w: %s", ErrInvalidURL, rawURL)
+	}
+
+	params := paramsFromQuery(location.Query())
+
+	// Views can always be addressed by their ViewID path.
+	if target, ok := vm.registry.lookup(location); ok {
+		return Intent{Target: target, Params: params}, nil
+	}
+
+	if target, pathParams, ok := vm.registry.match(location); ok {
+		if params == nil {
+			params = make(map[string]interface{})
+		}
+		// path params take precedence over query params.
+		for k, v := range pathParams {
+			params[k] = v
+		}
+
+		return Intent{Target: target, Params: params}, nil
+	}
+
+	return Intent{}, fmt.Errorf("%w: %s", ErrRouteNotFound, rawURL)
+}
+
+func (vm *defaultViewManager) OpenURL(rawURL string) error {
+	intent, err := vm.ParseURL(rawURL)
+	if err != nil {
+		return err
+	}
+
+	return vm.RequestSwitch(intent)
+}
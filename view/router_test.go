@@ -0,0 +1,65 @@
+package view
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseURL(t *testing.T) {
+	vm := newTestVM()
+	if err := vm.RegisterRoute("gioview://notes/Note/{id:int}", noteViewID); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.RegisterRoute("gioview://notes/tags/{tag}", listViewID); err != nil {
+		t.Fatal(err)
+	}
+
+	noteURL := BuildURL(noteViewID, map[string]interface{}{"id": 3})
+
+	cases := []struct {
+		url    string
+		target ViewID
+		params map[string]interface{}
+		err    error
+	}{
+		{url: noteURL.String(), target: noteViewID, params: map[string]interface{}{"id": "3"}},
+		{url: "gioview://notes/Note/12?mode=edit", target: noteViewID, params: map[string]interface{}{"id": 12, "mode": "edit"}},
+		{url: "gioview://notes/tags/go%20lang", target: listViewID, params: map[string]interface{}{"tag": "go lang"}},
+		{url: "gioview://notes/Note/abc", err: ErrRouteNotFound},
+		{url: "https://notes/Note/12", err: ErrInvalidURL},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.url, func(t *testing.T) {
+			intent, err := vm.ParseURL(tc.url)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("want error %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if intent.Target != tc.target {
+				t.Fatalf("want target %v, got %v", tc.target, intent.Target)
+			}
+			for k, v := range tc.params {
+				if intent.Params[k] != v {
+					t.Fatalf("param %s: want %v(%T), got %v(%T)", k, v, v, intent.Params[k], intent.Params[k])
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterRoute(t *testing.T) {
+	vm := newTestVM()
+	if err := vm.RegisterRoute("gioview://notes/{id:uuid}", noteViewID); err == nil {
+		t.Fatal("expected error for unsupported param type")
+	}
+	if err := vm.RegisterRoute("gioview://notes/{id}", NewViewID("Unknown")); err == nil {
+		t.Fatal("expected error for unregistered view")
+	}
+}
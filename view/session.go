@@ -0,0 +1,340 @@
+package view
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"slices"
+
+	"gioui.org/layout"
+)
+
+// SessionVersion is the version of the session snapshot format written by
+// SaveSession. Snapshots with a newer version are rejected on restore.
+//
+// Version 2 adds the workspace tree of split tab groups.
+const SessionVersion = 2
+
+// Session is a serializable snapshot of the tabs managed by a ViewManager.
+type Session struct {
+	Version int `json:"version"`
+	// Index of the current tab.
+	CurrentTab int          `json:"currentTab"`
+	Tabs       []TabSession `json:"tabs"`
+	// Workspace is set instead of Tabs if the workspace is split into multiple
+	// tab groups.
+	Workspace *WorkspaceSession `json:"workspace,omitempty"`
+}
+
+// WorkspaceSession records a node of the workspace tree. Leaf nodes have
+// tabs, and inner nodes have two children.
+type WorkspaceSession struct {
+	Axis       layout.Axis       `json:"axis"`
+	Ratio      float32           `json:"ratio"`
+	First      *WorkspaceSession `json:"first,omitempty"`
+	Second     *WorkspaceSession `json:"second,omitempty"`
+	CurrentTab int               `json:"currentTab"`
+	Tabs       []TabSession      `json:"tabs,omitempty"`
+	Focused    bool              `json:"focused,omitempty"`
+}
+
+// TabSession holds the history stack of a tab, from the bottom of the
+// stack to the top most view, and the forward history from the next view
+// to the last one.
+type TabSession struct {
+	History []ViewSession `json:"history"`
+	Forward []ViewSession `json:"forward,omitempty"`
+	Pinned  bool          `json:"pinned,omitempty"`
+}
+
+// ViewSession records a single view in the history stack.
+type ViewSession struct {
+	// Location is the URL returned by View.Location, which is used to
+	// resolve the view ID and intent params on restore.
+	Location string `json:"location"`
+	// State is the view contributed state if it implements StatefulView.
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// StatefulView can be implemented by views that have states that can not
+// be recovered from their location URL, such as scroll positions or unsaved
+// input. SaveState is called when the session is saved, and RestoreState
+// is called after OnNavTo when the view is re-created from a session.
+type StatefulView interface {
+	SaveState() (json.RawMessage, error)
+	RestoreState(state json.RawMessage) error
+}
+
+func (vm *defaultViewManager) SaveSession(w io.Writer) error {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	session := Session{Version: SessionVersion}
+
+	if vm.workspace.IsLeaf() {
+		tabs, err := saveTabs(vm.group)
+		if err != nil {
+			return err
+		}
+		session.Tabs = tabs
+		session.CurrentTab = vm.group.currentTabIdx
+	} else {
+		ws, err := vm.saveWorkspace(vm.workspace)
+		if err != nil {
+			return err
+		}
+		session.Workspace = ws
+	}
+
+	return json.NewEncoder(w).Encode(&session)
+}
+
+func (vm *defaultViewManager) saveWorkspace(node *WorkspaceNode) (*WorkspaceSession, error) {
+	if node.IsLeaf() {
+		tabs, err := saveTabs(node.Group)
+		if err != nil {
+			return nil, err
+		}
+
+		return &WorkspaceSession{
+			Tabs:       tabs,
+			CurrentTab: node.Group.currentTabIdx,
+			Focused:    node.Group == vm.group,
+		}, nil
+	}
+
+	first, err := vm.saveWorkspace(node.First)
+	if err != nil {
+		return nil, err
+	}
+	second, err := vm.saveWorkspace(node.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WorkspaceSession{
+		Axis:   node.Axis,
+		Ratio:  node.Ratio(),
+		First:  first,
+		Second: second,
+	}, nil
+}
+
+func saveTabs(group *TabGroup) ([]TabSession, error) {
+	var tabs []TabSession
+	for idx, stack := range group.stacks {
+		tab := TabSession{Pinned: group.IsPinned(idx)}
+		for vw := range stack.All(true) {
+			state, err := saveViewState(vw)
+			if err != nil {
+				return nil, err
+			}
+			tab.History = append(tab.History, state)
+		}
+		for vw := range stack.Forwards() {
+			state, err := saveViewState(vw)
+			if err != nil {
+				return nil, err
+			}
+			tab.Forward = append(tab.Forward, state)
+		}
+		tabs = append(tabs, tab)
+	}
+
+	return tabs, nil
+}
+
+func (vm *defaultViewManager) RestoreSession(r io.Reader) error {
+	var session Session
+	if err := json.NewDecoder(r).Decode(&session); err != nil {
+		return fmt.Errorf("decode session error: %w", err)
+	}
+
+	if session.Version <= 0 || session.Version > SessionVersion {
+		return fmt.Errorf("unsupported session version: %d", session.Version)
+	}
+
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	defer vm.window.Invalidate()
+
+	vm.clearWorkspace()
+
+	ws := session.Workspace
+	if ws == nil {
+		ws = &WorkspaceSession{Tabs: session.Tabs, CurrentTab: session.CurrentTab}
+	}
+
+	var focused *TabGroup
+	if root := vm.restoreWorkspace(ws, &focused); root != nil {
+		vm.workspace = root
+		vm.group = root.Groups()[0]
+		if focused != nil {
+			vm.group = focused
+		}
+	}
+
+	for _, group := range vm.workspace.Groups() {
+		if vw := group.CurrentView(); vw != nil {
+			vm.resumeView(vw)
+		}
+	}
+
+	return nil
+}
+
+// restoreWorkspace re-creates the workspace tree. Nodes without any
+// restored views are dropped, and nil is returned if the whole tree is empty.
+func (vm *defaultViewManager) restoreWorkspace(ws *WorkspaceSession, focused **TabGroup) *WorkspaceNode {
+	if ws.First == nil || ws.Second == nil {
+		group := vm.restoreTabs(ws.Tabs, ws.CurrentTab)
+		if group == nil {
+			return nil
+		}
+		if ws.Focused {
+			*focused = group
+		}
+		return newLeafNode(group)
+	}
+
+	first := vm.restoreWorkspace(ws.First, focused)
+	second := vm.restoreWorkspace(ws.Second, focused)
+	if first == nil {
+		return second
+	}
+	if second == nil {
+		return first
+	}
+
+	node := &WorkspaceNode{Axis: ws.Axis, First: first, Second: second}
+	node.SetRatio(ws.Ratio)
+	first.parent, second.parent = node, node
+	return node
+}
+
+func (vm *defaultViewManager) restoreTabs(tabs []TabSession, currentTab int) *TabGroup {
+	group := &TabGroup{}
+	for _, tab := range tabs {
+		stack := NewViewStack()
+		var referer url.URL
+		forwards := 0
+		for idx, entry := range slices.Concat(tab.History, tab.Forward) {
+			vw, err := vm.restoreView(entry, referer)
+			if err != nil {
+				// Views may have been unregistered or failed to initialize, skip them.
+				log.Printf("skip restoring view %s: %v", entry.Location, err)
+				continue
+			}
+			stack.Push(vw)
+			referer = vw.Location()
+			if idx >= len(tab.History) {
+				forwards++
+			}
+		}
+
+		// move the forward entries back to the forward history.
+		for range forwards {
+			stack.Backward()
+		}
+
+		if !stack.IsEmpty() {
+			// pinned tabs are saved before the others.
+			if tab.Pinned && group.pinned == len(group.stacks) {
+				group.pinned++
+			}
+			group.stacks = append(group.stacks, stack)
+		}
+	}
+
+	if len(group.stacks) <= 0 {
+		return nil
+	}
+
+	if currentTab >= 0 && currentTab < len(group.stacks) {
+		group.currentTabIdx = currentTab
+	}
+
+	// Only the current view of the group is visible, and it is resumed after
+	// the whole workspace is restored.
+	current := group.CurrentView()
+	for _, stack := range group.stacks {
+		for vw := range stack.All(false) {
+			if vw != current {
+				vm.pauseView(vw)
+			}
+		}
+		for vw := range stack.Forwards() {
+			vm.pauseView(vw)
+		}
+	}
+
+	return group
+}
+
+func (vm *defaultViewManager) restoreView(entry ViewSession, referer url.URL) (View, error) {
+	intent, err := vm.parseURL(entry.Location)
+	if err != nil {
+		return nil, err
+	}
+	intent.Referer = referer
+
+	target := intent.Target
+	provider, ok := vm.registry.provider(target)
+	if !ok {
+		return nil, fmt.Errorf("no target view found: %v", target)
+	}
+	vw := provider()
+	// session files may be stale or edited by hand.
+	if err := validateParams(vw, intent.Params); err != nil {
+		return nil, err
+	}
+	if err := vm.navTo(vw, intent); err != nil {
+		vw.OnFinish()
+		return nil, err
+	}
+
+	if sv, ok := vw.(StatefulView); ok && len(entry.State) > 0 {
+		if err := sv.RestoreState(entry.State); err != nil {
+			log.Printf("restore state of view %s error: %v", target, err)
+		}
+	}
+
+	return vw, nil
+}
+
+func saveViewState(vw View) (ViewSession, error) {
+	location := vw.Location()
+	entry := ViewSession{Location: location.String()}
+
+	if sv, ok := vw.(StatefulView); ok {
+		state, err := sv.SaveState()
+		if err != nil {
+			return entry, fmt.Errorf("save state of view %s error: %w", vw.ID(), err)
+		}
+		entry.State = state
+	}
+
+	return entry, nil
+}
+
+// paramsFromQuery converts URL query values to intent params. Single values are
+// kept as string, and repeated keys are converted to []string.
+func paramsFromQuery(query url.Values) map[string]interface{} {
+	if len(query) <= 0 {
+		return nil
+	}
+
+	params := make(map[string]interface{}, len(query))
+	for k, v := range query {
+		if len(v) == 1 {
+			params[k] = v[0]
+		} else {
+			params[k] = v
+		}
+	}
+
+	return params
+}
@@ -0,0 +1,133 @@
+package view
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"gioui.org/layout"
+	"github.com/oligo/gioview/theme"
+)
+
+var (
+	noteViewID = NewViewID("Note")
+	listViewID = NewViewID("List")
+)
+
+type testView struct {
+	*BaseView
+	id     ViewID
+	scroll int
+}
+
+func (vw *testView) ID() ViewID    { return vw.id }
+func (vw *testView) Title() string { return vw.id.Name() }
+
+func (vw *testView) Layout(gtx layout.Context, th *theme.Theme) layout.Dimensions {
+	return layout.Dimensions{}
+}
+
+func (vw *testView) SaveState() (json.RawMessage, error) {
+	return json.Marshal(vw.scroll)
+}
+
+func (vw *testView) RestoreState(state json.RawMessage) error {
+	return json.Unmarshal(state, &vw.scroll)
+}
+
+func newTestVM() ViewManager {
+	vm := NewViewManager(&HeadlessWindow{})
+	vm.Register(noteViewID, func() View { return &testView{BaseView: &BaseView{}, id: noteViewID} })
+	vm.Register(listViewID, func() View { return &testView{BaseView: &BaseView{}, id: listViewID} })
+	return vm
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	vm := newTestVM()
+	vm.RequestSwitch(Intent{Target: listViewID})
+	vm.RequestSwitch(Intent{Target: noteViewID, Params: map[string]interface{}{"id": 1}, Referer: vm.CurrentView().Location()})
+	vm.CurrentView().(*testView).scroll = 42
+	vm.RequestSwitch(Intent{Target: noteViewID, Params: map[string]interface{}{"id": 2}, RequireNew: true})
+	vm.SwitchTab(0)
+
+	var buf bytes.Buffer
+	if err := vm.SaveSession(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newTestVM()
+	if err := restored.RestoreSession(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	views := restored.OpenedViews()
+	if len(views) != 2 {
+		t.Fatalf("want 2 tabs, got %d", len(views))
+	}
+
+	if restored.CurrentViewIndex() != 0 {
+		t.Fatalf("want current tab 0, got %d", restored.CurrentViewIndex())
+	}
+
+	current := restored.CurrentView().(*testView)
+	if current.ID() != noteViewID || current.scroll != 42 {
+		t.Fatalf("unexpected current view: %v, scroll: %d", current.Location(), current.scroll)
+	}
+
+	if !restored.HasPrev() {
+		t.Fatal("history of the first tab is not restored")
+	}
+
+	loc := views[1].Location()
+	if loc.Query().Get("id") != "2" {
+		t.Fatalf("unexpected location of the second tab: %s", loc.String())
+	}
+}
+
+func TestRestoreSessionVersion(t *testing.T) {
+	vm := newTestVM()
+	err := vm.RestoreSession(bytes.NewBufferString(`{"version": 99, "tabs": []}`))
+	if err == nil {
+		t.Fatal("expected error for unsupported session version")
+	}
+}
+
+func TestRestoreSessionLifecycle(t *testing.T) {
+	vm := newTestVM()
+	id := NewViewID("Paged")
+	vm.Register(id, func() View { return &paramView{&testView{BaseView: &BaseView{}, id: id}} })
+
+	note := BuildURL(noteViewID, nil)
+	valid := BuildURL(id, Params{"page": 1})
+	invalid := BuildURL(id, Params{"page": "abc"})
+	session := Session{Version: SessionVersion, Tabs: []TabSession{
+		{History: []ViewSession{{Location: note.String()}, {Location: valid.String()}}},
+		{History: []ViewSession{{Location: invalid.String()}}},
+	}}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(session); err != nil {
+		t.Fatal(err)
+	}
+
+	var paused []View
+	vm.Subscribe(func(evt LifecycleEvent) {
+		if evt.Kind == PauseEvent {
+			paused = append(paused, evt.View)
+		}
+	})
+
+	if err := vm.RestoreSession(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// the view with invalid params is skipped.
+	if len(vm.OpenedViews()) != 1 {
+		t.Fatalf("want 1 tab, got %d", len(vm.OpenedViews()))
+	}
+
+	// the view below the top of the stack is paused.
+	if len(paused) != 1 || paused[0].ID() != noteViewID {
+		t.Fatalf("unexpected paused views: %v", paused)
+	}
+}
@@ -0,0 +1,164 @@
+package view
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+)
+
+var errTabOutOfRange = errors.New("tab index out of range")
+
+// IsPinned reports whether the tab at idx is pinned. Pinned tabs are always
+// placed before the other tabs of the group.
+func (g *TabGroup) IsPinned(idx int) bool {
+	return idx >= 0 && idx < g.pinned
+}
+
+// PinnedCount returns the number of pinned tabs of the group.
+func (g *TabGroup) PinnedCount() int {
+	return g.pinned
+}
+
+// moveTab moves the tab at from to the index to, keeping the current tab
+// unchanged.
+func (g *TabGroup) moveTab(from, to int) {
+	if from == to {
+		return
+	}
+
+	current := g.stacks[g.currentTabIdx]
+	stack := g.stacks[from]
+	g.stacks = slices.Delete(g.stacks, from, from+1)
+	g.stacks = slices.Insert(g.stacks, to, stack)
+	g.currentTabIdx = slices.Index(g.stacks, current)
+}
+
+func (vm *defaultViewManager) ReorderTab(from, to int) error {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	g := vm.group
+	if from < 0 || from >= len(g.stacks) {
+		return errTabOutOfRange
+	}
+
+	// pinned and unpinned tabs can only be reordered in their own section.
+	if g.IsPinned(from) {
+		to = max(0, min(to, g.pinned-1))
+	} else {
+		to = max(g.pinned, min(to, len(g.stacks)-1))
+	}
+
+	g.moveTab(from, to)
+	vm.window.Invalidate()
+	return nil
+}
+
+func (vm *defaultViewManager) PinTab(idx int, pinned bool) error {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	g := vm.group
+	if idx < 0 || idx >= len(g.stacks) {
+		return errTabOutOfRange
+	}
+
+	if g.IsPinned(idx) == pinned {
+		return nil
+	}
+
+	// the tab is moved to the boundary of the pinned tabs.
+	if pinned {
+		g.moveTab(idx, g.pinned)
+		g.pinned++
+	} else {
+		g.moveTab(idx, g.pinned-1)
+		g.pinned--
+	}
+
+	vm.window.Invalidate()
+	return nil
+}
+
+func (vm *defaultViewManager) DuplicateTab(idx int) error {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	defer vm.window.Invalidate()
+
+	g := vm.group
+	if idx < 0 || idx >= len(g.stacks) {
+		return errTabOutOfRange
+	}
+
+	// The view is re-created from its location and state, just like
+	// restoring it from a session.
+	entry, err := saveViewState(g.stacks[idx].Peek())
+	if err != nil {
+		return err
+	}
+
+	vw, err := vm.restoreView(entry, url.URL{})
+	if err != nil {
+		return err
+	}
+
+	vm.pauseCurrentView()
+	stack := NewViewStack()
+	stack.Push(vw)
+
+	// the duplicated tab is never pinned.
+	pos := max(idx+1, g.pinned)
+	g.stacks = slices.Insert(g.stacks, pos, stack)
+	g.currentTabIdx = pos
+	vm.resumeView(vw)
+	return nil
+}
+
+func (vm *defaultViewManager) CloseOtherTabs(idx int) {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if idx < 0 || idx >= len(vm.group.stacks) {
+		return
+	}
+
+	keep := vm.group.stacks[idx]
+	vm.closeTabsWhere(func(i int, stack *ViewStack) bool { return stack != keep })
+}
+
+func (vm *defaultViewManager) CloseTabsToRight(idx int) {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if idx < 0 || idx >= len(vm.group.stacks) {
+		return
+	}
+
+	vm.closeTabsWhere(func(i int, stack *ViewStack) bool { return i > idx })
+}
+
+// closeTabsWhere closes the unpinned tabs of the focused group matching the
+// predicate. Tabs blocked by the navigation guards are kept.
+func (vm *defaultViewManager) closeTabsWhere(pred func(idx int, stack *ViewStack) bool) {
+	group := vm.group
+	var stacks []*ViewStack
+	for i, stack := range group.stacks {
+		if !group.IsPinned(i) && pred(i, stack) {
+			stacks = append(stacks, stack)
+		}
+	}
+
+	for _, stack := range stacks {
+		if idx := slices.Index(group.stacks, stack); idx >= 0 {
+			vm.closeTab(idx)
+		}
+
+		// the group is removed after its last tab is closed.
+		if vm.group != group {
+			return
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package view
+
+import (
+	"bytes"
+	"testing"
+)
+
+// openNotes opens n note tabs with ids from 0 to n-1.
+func openNotes(vm ViewManager, n int) {
+	for i := range n {
+		vm.RequestSwitch(Intent{Target: noteViewID, Params: Params{"id": i}, RequireNew: true})
+	}
+}
+
+func tabIDs(t *testing.T, vm ViewManager) []string {
+	t.Helper()
+	var ids []string
+	for _, vw := range vm.OpenedViews() {
+		location := vw.Location()
+		ids = append(ids, location.Query().Get("id"))
+	}
+	return ids
+}
+
+func assertTabs(t *testing.T, vm ViewManager, want ...string) {
+	t.Helper()
+	got := tabIDs(t, vm)
+	if len(got) != len(want) {
+		t.Fatalf("want tabs %v, got %v", want, got)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("want tabs %v, got %v", want, got)
+		}
+	}
+}
+
+func TestReorderAndPinTabs(t *testing.T) {
+	vm := newTestVM()
+	openNotes(vm, 4)
+	vm.SwitchTab(1)
+
+	if err := vm.ReorderTab(0, 2); err != nil {
+		t.Fatal(err)
+	}
+	assertTabs(t, vm, "1", "2", "0", "3")
+	if vm.CurrentViewIndex() != 0 {
+		t.Fatalf("current tab should follow the reordered tabs, got %d", vm.CurrentViewIndex())
+	}
+
+	vm.PinTab(3, true)
+	assertTabs(t, vm, "3", "1", "2", "0")
+	if !vm.FocusedGroup().IsPinned(0) || vm.FocusedGroup().IsPinned(1) {
+		t.Fatal("tab is not pinned")
+	}
+
+	// unpinned tabs can not be moved before the pinned ones.
+	vm.ReorderTab(3, 0)
+	assertTabs(t, vm, "3", "0", "1", "2")
+
+	vm.CloseOtherTabs(2)
+	assertTabs(t, vm, "3", "1")
+
+	vm.PinTab(0, false)
+	vm.CloseTabsToRight(0)
+	assertTabs(t, vm, "3")
+}
+
+func TestDuplicateTab(t *testing.T) {
+	vm := newTestVM()
+	openNotes(vm, 2)
+	vm.CurrentView().(*testView).scroll = 10
+	vm.PinTab(1, true)
+
+	if err := vm.DuplicateTab(0); err != nil {
+		t.Fatal(err)
+	}
+	assertTabs(t, vm, "1", "1", "0")
+
+	dup := vm.CurrentView().(*testView)
+	if vm.CurrentViewIndex() != 1 || dup.scroll != 10 {
+		t.Fatalf("duplicated tab is not current or state is not copied")
+	}
+	if vm.FocusedGroup().IsPinned(1) {
+		t.Fatal("duplicated tab should not be pinned")
+	}
+
+	var buf bytes.Buffer
+	vm.SaveSession(&buf)
+	restored := newTestVM()
+	if err := restored.RestoreSession(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if restored.FocusedGroup().PinnedCount() != 1 {
+		t.Fatal("pinned tabs are not restored")
+	}
+}
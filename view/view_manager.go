@@ -2,9 +2,11 @@ package view
 
 import (
 	"fmt"
+	"io"
 	"iter"
 	"net/url"
 
+	"github.com/oligo/gioview/keybinding"
 	"github.com/oligo/gioview/theme"
 
 	"gioui.org/layout"
@@ -22,7 +24,7 @@ type ViewID struct {
 
 type Intent struct {
 	Target      ViewID
-	Params      map[string]interface{}
+	Params      Params
 	Referer     url.URL
 	ShowAsModal bool
 	// indicates the provider to create a new view instance and show up
@@ -34,10 +36,52 @@ func (i Intent) Location() url.URL {
 	return BuildURL(i.Target, i.Params)
 }
 
+// HistoryEntry is an entry in the navigation history of a tab.
+type HistoryEntry struct {
+	View View
+	// Offset relative to the current view. Negative offsets are for backward
+	// entries, and positive offsets are for forward entries.
+	Offset int
+}
+
 type ViewAction struct {
 	Name      string
 	Icon      *widget.Icon
 	OnClicked func(gtx C)
+	// Command is run when the action is clicked if OnClicked is not set. The
+	// title and shortcut of the command are shown with the action.
+	Command *keybinding.Command
+}
+
+// Title returns the name of the action, or the title of its command if the
+// name is not set.
+func (a ViewAction) Title() string {
+	if a.Name == "" && a.Command != nil {
+		return a.Command.Title
+	}
+	return a.Name
+}
+
+// Shortcut returns the key chord of the command of the action for display.
+func (a ViewAction) Shortcut() string {
+	if a.Command == nil {
+		return ""
+	}
+	return a.Command.Shortcut()
+}
+
+// Enabled reports whether the action can be run.
+func (a ViewAction) Enabled() bool {
+	return a.OnClicked != nil || a.Command != nil && a.Command.IsEnabled()
+}
+
+// Run runs the action.
+func (a ViewAction) Run(gtx C) {
+	if a.OnClicked != nil {
+		a.OnClicked(gtx)
+	} else if a.Command != nil {
+		a.Command.Run(gtx)
+	}
 }
 
 type View interface {
@@ -75,6 +119,20 @@ type ViewManager interface {
 	// Register is used to register views before the view rendering happens.
 	// Use provider to enable us to use dynamically constructed views.
 	Register(ID ViewID, provider ViewProvider) error
+	// RegisteredViews returns the IDs of the registered views, sorted by their
+	// string forms.
+	RegisteredViews() []ViewID
+
+	// RegisterRoute maps a URL pattern like gioview://notes/Note/{id:int} to a
+	// registered view. Path params are passed to the view as intent params.
+	RegisterRoute(pattern string, target ViewID) error
+	// ParseURL resolves a gioview URL to an intent targeting a registered view.
+	// The URL is matched against view paths returned by [ViewID.Path] first, and
+	// then against the routes in their registration order.
+	ParseURL(rawURL string) (Intent, error)
+	// OpenURL parses the URL and switches to the resolved view. It can be used to
+	// handle links, command line arguments or URLs opened by the OS.
+	OpenURL(rawURL string) error
 
 	// Try to swith the current view to the requested view. If referer of the intent equals to
 	// the current viewID of the current tab, the requested view should be routed and pushed to
@@ -87,16 +145,41 @@ type ViewManager interface {
 	// Close the current tab and move backwards to the previous one if there's any.
 	CloseTab(idx int)
 	SwitchTab(idx int)
+	// ReorderTab moves the tab at from to the index to in the focused group. Pinned
+	// tabs are only reordered among the pinned tabs, and so are unpinned tabs.
+	ReorderTab(from, to int) error
+	// PinTab pins or unpins the tab at idx. Pinned tabs are moved before the unpinned
+	// ones.
+	PinTab(idx int, pinned bool) error
+	// DuplicateTab opens a copy of the top most view of the tab at idx in a new tab
+	// next to it. The view is re-created from its location, and its state is copied
+	// if it implements StatefulView.
+	DuplicateTab(idx int) error
+	// CloseOtherTabs closes all the unpinned tabs except the one at idx.
+	CloseOtherTabs(idx int)
+	// CloseTabsToRight closes the unpinned tabs after idx.
+	CloseTabsToRight(idx int)
 	// CurrentView returns the top most view of the current tab.
 	CurrentView() View
 	// current tab index
 	CurrentViewIndex() int
-	// Navigate back to the last view if there's any and pop out the current view.
-	// It returns the view that is to be rendered.
+	// Navigate back to the last view if there's any and move the current view to the
+	// forward history. It returns the view that is to be rendered.
 	NavBack() View
+	// Navigate forward to the next view in the forward history if there's any. It returns
+	// the view that is to be rendered.
+	NavForward() View
+	// NavHistory navigates to the history entry at offset relative to the current view.
+	// Offsets are from the entries returned by History.
+	NavHistory(offset int) View
 	// Check is there are any naviBack-able views in the current stack or not. This should not
 	// count for the current view.
 	HasPrev() bool
+	// HasNext reports whether there are views to navigate forward to in the current tab.
+	HasNext() bool
+	// History returns the navigation history of the current tab from the oldest entry to
+	// the newest one, including the current view.
+	History() []HistoryEntry
 	// return the next view that is intened to be shown in the modal layer. It returns nil if
 	// there's no shownAsModal intent request.
 	//
@@ -111,11 +194,71 @@ type ViewManager interface {
 
 	//Reset resets internal states of the VM
 	Reset()
+
+	// Workspace returns the root of the workspace tree. The workspace initially has
+	// a single tab group, and can be split into multiple tab groups laid out side by
+	// side. Tab related methods of ViewManager operate on the focused tab group.
+	Workspace() *WorkspaceNode
+	// TabGroups returns all the tab groups in the workspace in layout order.
+	TabGroups() []*TabGroup
+	// FocusedGroup returns the focused tab group.
+	FocusedGroup() *TabGroup
+	// FocusGroup sets the focused tab group.
+	FocusGroup(group *TabGroup) error
+	// SplitGroup splits the focused tab group along the axis, and focuses the newly
+	// created empty group. Use layout.Horizontal to place the new group on the right,
+	// or layout.Vertical to place it below.
+	SplitGroup(axis layout.Axis) *TabGroup
+	// MoveTab moves the tab at idx in the focused group to the target group, and
+	// focuses the target group. The source group is removed if it becomes empty.
+	MoveTab(idx int, target *TabGroup) error
+	// CloseGroup closes all the tabs in the group and removes the group from the workspace.
+	CloseGroup(group *TabGroup)
+
+	// Commands returns the command registry of the application, which is
+	// shared with the view managers created by ForWindow. Call its Update
+	// method at every frame to run the commands by their key chords.
+	Commands() *keybinding.Registry
+
+	// Window returns the window the ViewManager renders to.
+	Window() Window
+	// ForWindow creates a ViewManager for another window, e.g., a window holding
+	// a detached tab. The returned ViewManager shares the registered views and
+	// routes with this one, but has its own workspace, guards and subscribers.
+	ForWindow(window Window) ViewManager
+	// MoveTabTo moves the tab at idx in the focused group to the focused group of
+	// dst, which must be created by ForWindow. The view stack and history of the tab
+	// are kept. Views implementing WindowAware are notified of the new ViewManager.
+	MoveTabTo(idx int, dst ViewManager) error
+
+	// BeforeLeave registers a guard that is called before leaving the current view.
+	// It can be used to block leaving a view with unsaved changes.
+	BeforeLeave(guard NavigationGuard)
+	// BeforeEnter registers a guard that is called before an intent is routed by
+	// RequestSwitch. Guards can redirect the navigation by modifying Navigation.Intent.
+	BeforeEnter(guard NavigationGuard)
+	// AfterEnter registers a hook that is called after a view is navigated to. Like
+	// guards, hooks are called with the view manager locked.
+	AfterEnter(hook func(vw View, intent Intent))
+	// Subscribe registers a subscriber to view lifecycle events. It returns a
+	// function to cancel the subscription.
+	Subscribe(fn func(evt LifecycleEvent)) func()
+
+	// SaveSession writes a versioned snapshot of the workspace, the opened tabs, their
+	// history stacks and the current tab index to w. Modal views are not saved.
+	SaveSession(w io.Writer) error
+	// RestoreSession replaces the opened tabs with the ones in a snapshot written
+	// by SaveSession. Views are re-created using the registered view providers, so
+	// this should be called after all the views are registered.
+	RestoreSession(r io.Reader) error
 }
 
 func BuildURL(target ViewID, params map[string]interface{}) url.URL {
 	var urlParams = make(url.Values)
 	for k, v := range params {
+		if k == modalResultParam {
+			continue
+		}
 		urlParams.Add(k, fmt.Sprintf("%v", v))
 	}
 
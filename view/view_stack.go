@@ -8,6 +8,9 @@ import (
 // ViewStack is for view navigation history
 type ViewStack struct {
 	viewList *list.List
+	// views popped by navigating backward. The front is the next view
+	// to navigate forward to.
+	forwardList *list.List
 }
 
 func (vs *ViewStack) Pop() View {
@@ -90,6 +93,10 @@ func (vs *ViewStack) All(backward bool) iter.Seq[View] {
 }
 
 func (vs *ViewStack) Clear() {
+	for _, vw := range vs.TruncateForward() {
+		vw.OnFinish()
+	}
+
 	if vs.viewList == nil {
 		return
 	}
@@ -104,6 +111,69 @@ func (vs *ViewStack) Clear() {
 	vs.viewList.Init()
 }
 
+// Backward moves the top most view to the forward history, and returns it. The
+// last view in the stack is never moved.
+func (vs *ViewStack) Backward() View {
+	if vs.Depth() <= 1 {
+		return nil
+	}
+
+	if vs.forwardList == nil {
+		vs.forwardList = list.New()
+	}
+
+	vw := vs.Pop()
+	vs.forwardList.PushFront(vw)
+	return vw
+}
+
+// Forward moves the next view in the forward history back to the top of the
+// stack, and returns it.
+func (vs *ViewStack) Forward() View {
+	if !vs.HasNext() {
+		return nil
+	}
+
+	vw := vs.forwardList.Remove(vs.forwardList.Front()).(View)
+	vs.Push(vw)
+	return vw
+}
+
+// HasNext reports if there are views to navigate forward to.
+func (vs *ViewStack) HasNext() bool {
+	return vs.forwardList != nil && vs.forwardList.Len() > 0
+}
+
+// Forwards returns a iterator that iterates through the forward history from the
+// next view to the last one.
+func (vs *ViewStack) Forwards() iter.Seq[View] {
+	return func(yield func(View) bool) {
+		if vs.forwardList == nil {
+			return
+		}
+
+		for v := vs.forwardList.Front(); v != nil; v = v.Next() {
+			if !yield(v.Value.(View)) {
+				return
+			}
+		}
+	}
+}
+
+// TruncateForward removes all the views in the forward history and returns them,
+// so that the caller can finish them.
+func (vs *ViewStack) TruncateForward() []View {
+	var views []View
+	for vw := range vs.Forwards() {
+		views = append(views, vw)
+	}
+
+	if vs.forwardList != nil {
+		vs.forwardList.Init()
+	}
+	return views
+}
+
 func NewViewStack() *ViewStack {
 	return &ViewStack{}
 }
@@ -0,0 +1,233 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"gioui.org/app"
+	"gioui.org/unit"
+
+	"github.com/oligo/gioview/keybinding"
+)
+
+// Window is the window a ViewManager renders to. It is implemented by
+// *app.Window, and by HeadlessWindow for tests.
+type Window interface {
+	Invalidate()
+	Option(opts ...app.Option)
+}
+
+// HeadlessWindow is a Window without a platform window. It records the
+// invalidate requests and window options, which is useful for testing views
+// without a GPU.
+type HeadlessWindow struct {
+	mu          sync.Mutex
+	invalidated int
+	config      app.Config
+}
+
+func (w *HeadlessWindow) Invalidate() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.invalidated++
+}
+
+func (w *HeadlessWindow) Option(opts ...app.Option) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, opt := range opts {
+		opt(unit.Metric{}, &w.config)
+	}
+}
+
+// Invalidated returns how many times the window is invalidated.
+func (w *HeadlessWindow) Invalidated() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.invalidated
+}
+
+// Config returns the config set by the window options.
+func (w *HeadlessWindow) Config() app.Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.config
+}
+
+// WindowAware can be implemented by views holding a reference to their
+// ViewManager. OnWindowChanged is called when the view is moved to the
+// ViewManager of another window.
+type WindowAware interface {
+	OnWindowChanged(vm ViewManager)
+}
+
+// registry holds the registered views, routes and commands. It is shared by
+// the view managers of all the windows.
+type registry struct {
+	mu       sync.RWMutex
+	views    map[ViewID]ViewProvider
+	routes   []*route
+	commands keybinding.Registry
+}
+
+func (r *registry) register(ID ViewID, provider ViewProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.views == nil {
+		r.views = make(map[ViewID]ViewProvider)
+	}
+	r.views[ID] = provider
+}
+
+func (r *registry) provider(ID ViewID) (ViewProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, ok := r.views[ID]
+	return provider, ok
+}
+
+func (r *registry) viewIDs() []ViewID {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := slices.Collect(maps.Keys(r.views))
+	slices.SortFunc(ids, func(a, b ViewID) int { return strings.Compare(a.String(), b.String()) })
+	return ids
+}
+
+func (r *registry) addRoute(route *route) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.views[route.target]; !ok {
+		return fmt.Errorf("no target view found: %v", route.target)
+	}
+
+	r.routes = append(r.routes, route)
+	return nil
+}
+
+// lookup finds the registered view ID whose path matches the location.
+func (r *registry) lookup(location *url.URL) (ViewID, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for id := range r.views {
+		p := id.Path()
+		if p.Scheme == location.Scheme && p.Host == location.Host && p.Path == location.Path {
+			return id, true
+		}
+	}
+
+	return ViewID{}, false
+}
+
+// match finds the first route matching the location.
+func (r *registry) match(location *url.URL) (ViewID, map[string]interface{}, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, route := range r.routes {
+		if params, ok := route.match(location); ok {
+			return route.target, params, true
+		}
+	}
+
+	return ViewID{}, nil, false
+}
+
+func (vm *defaultViewManager) Window() Window {
+	return vm.window
+}
+
+func (vm *defaultViewManager) Commands() *keybinding.Registry {
+	return &vm.registry.commands
+}
+
+func (vm *defaultViewManager) ForWindow(window Window) ViewManager {
+	other := &defaultViewManager{
+		window:   window,
+		registry: vm.registry,
+	}
+	other.resetWorkspace()
+	return other
+}
+
+func (vm *defaultViewManager) MoveTabTo(idx int, dst ViewManager) error {
+	target, ok := dst.(*defaultViewManager)
+	if !ok {
+		return errors.New("unsupported view manager")
+	}
+
+	if target == vm {
+		return nil
+	}
+
+	if target.registry != vm.registry {
+		return errors.New("view managers do not share the same view registry")
+	}
+
+	stack, err := vm.detachTab(idx)
+	if err != nil {
+		return err
+	}
+
+	target.attachTab(stack)
+	return nil
+}
+
+// detachTab removes the tab at idx from the focused group.
+func (vm *defaultViewManager) detachTab(idx int) (*ViewStack, error) {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	defer vm.window.Invalidate()
+
+	if idx < 0 || idx >= len(vm.group.stacks) {
+		return nil, errors.New("tab index out of range")
+	}
+
+	if !vm.canLeave(NavCloseTab, vm.group.stacks[idx].Peek()) {
+		return nil, ErrNavigationBlocked
+	}
+
+	stack := vm.group.removeTab(idx)
+	if vw := vm.group.CurrentView(); vw != nil {
+		vm.resumeView(vw)
+	}
+	vm.emit(LifecycleEvent{Kind: TabClosedEvent, Tab: idx})
+
+	if vm.group.Len() <= 0 {
+		vm.removeGroup(vm.group)
+	}
+
+	return stack, nil
+}
+
+// attachTab adds the tab to the focused group and make it the current tab.
+func (vm *defaultViewManager) attachTab(stack *ViewStack) {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	defer vm.window.Invalidate()
+
+	vm.pauseCurrentView()
+	vm.group.stacks = append(vm.group.stacks, stack)
+	vm.group.currentTabIdx = len(vm.group.stacks) - 1
+
+	for vw := range stack.All(true) {
+		if aware, ok := vw.(WindowAware); ok {
+			aware.OnWindowChanged(vm)
+		}
+	}
+	for vw := range stack.Forwards() {
+		if aware, ok := vw.(WindowAware); ok {
+			aware.OnWindowChanged(vm)
+		}
+	}
+
+	if vw := stack.Peek(); vw != nil {
+		vm.resumeView(vw)
+	}
+}
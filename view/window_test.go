@@ -0,0 +1,94 @@
+package view
+
+import (
+	"testing"
+)
+
+type windowAwareView struct {
+	*testView
+	vm ViewManager
+}
+
+func (vw *windowAwareView) OnWindowChanged(vm ViewManager) {
+	vw.vm = vm
+}
+
+func TestHeadlessWindowTitle(t *testing.T) {
+	window := &HeadlessWindow{}
+	vm := newTestVM()
+	vm = vm.ForWindow(window)
+
+	if err := vm.RequestSwitch(Intent{Target: noteViewID}); err != nil {
+		t.Fatal(err)
+	}
+	vm.CurrentView()
+
+	if window.Invalidated() <= 0 {
+		t.Fatal("window is not invalidated")
+	}
+	if title := window.Config().Title; title != noteViewID.Name() {
+		t.Fatalf("want title %q, got %q", noteViewID.Name(), title)
+	}
+}
+
+func TestMoveTabToWindow(t *testing.T) {
+	vm := newTestVM()
+	awareID := NewViewID("Aware")
+	vm.Register(awareID, func() View {
+		return &windowAwareView{testView: &testView{BaseView: &BaseView{}, id: awareID}}
+	})
+
+	vm.RequestSwitch(Intent{Target: listViewID})
+	vm.RequestSwitch(Intent{Target: awareID, Referer: vm.CurrentView().Location()})
+	vm.RequestSwitch(Intent{Target: noteViewID, RequireNew: true})
+	vm.SwitchTab(0)
+
+	detached := vm.ForWindow(&HeadlessWindow{})
+	if err := detached.RequestSwitch(Intent{Target: noteViewID, Params: Params{"id": 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vm.MoveTabTo(0, detached); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(vm.OpenedViews()); n != 1 {
+		t.Fatalf("want 1 tab left, got %d", n)
+	}
+	if vm.CurrentView().ID() != noteViewID {
+		t.Fatalf("unexpected current view: %v", vm.CurrentView().ID())
+	}
+
+	if n := len(detached.OpenedViews()); n != 2 {
+		t.Fatalf("want 2 tabs in the detached window, got %d", n)
+	}
+	if detached.CurrentViewIndex() != 1 {
+		t.Fatalf("moved tab is not current: %d", detached.CurrentViewIndex())
+	}
+
+	moved := detached.CurrentView().(*windowAwareView)
+	if moved.vm != detached {
+		t.Fatal("view is not notified of the new view manager")
+	}
+	if !detached.HasPrev() {
+		t.Fatal("history of the moved tab is lost")
+	}
+
+	// views registered in either view manager are shared.
+	location := listViewID.Path()
+	if _, err := detached.ParseURL(location.String()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMoveTabToUnrelatedWindow(t *testing.T) {
+	vm := newTestVM()
+	vm.RequestSwitch(Intent{Target: noteViewID})
+
+	if err := vm.MoveTabTo(0, newTestVM()); err == nil {
+		t.Fatal("moving tab to an unrelated view manager should fail")
+	}
+	if err := vm.MoveTabTo(3, vm.ForWindow(&HeadlessWindow{})); err == nil {
+		t.Fatal("moving tab out of range should fail")
+	}
+}
@@ -0,0 +1,364 @@
+package view
+
+import (
+	"errors"
+	"image"
+	"slices"
+
+	"github.com/oligo/gioview/misc"
+	"github.com/oligo/gioview/theme"
+
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	cmp "gioui.org/x/component"
+)
+
+var ErrGroupNotFound = errors.New("tab group not found")
+
+// TabGroup is a group of tabs shown in a pane of the workspace. Each
+// tab has its own view stack.
+type TabGroup struct {
+	stacks        []*ViewStack
+	currentTabIdx int
+	// number of the pinned tabs at the start of stacks.
+	pinned int
+	node   *WorkspaceNode
+}
+
+// WorkspaceNode is a node of the workspace tree. A leaf node holds a tab
+// group, while an inner node splits its area between its two children
+// along the axis.
+type WorkspaceNode struct {
+	// Axis is layout.Horizontal for side by side children, or layout.Vertical
+	// for stacked children.
+	Axis   layout.Axis
+	First  *WorkspaceNode
+	Second *WorkspaceNode
+	// Group is set only for leaf nodes.
+	Group  *TabGroup
+	parent *WorkspaceNode
+	resize cmp.Resize
+}
+
+// Views returns the views on top of the stack of each tab in the group.
+func (g *TabGroup) Views() []View {
+	views := make([]View, len(g.stacks))
+	for idx, stack := range g.stacks {
+		views[idx] = stack.Peek()
+	}
+
+	return views
+}
+
+// CurrentIndex returns the index of the current tab in the group.
+func (g *TabGroup) CurrentIndex() int {
+	return g.currentTabIdx
+}
+
+// CurrentView returns the top most view of the current tab in the group.
+func (g *TabGroup) CurrentView() View {
+	if g.currentTabIdx < 0 || g.currentTabIdx >= len(g.stacks) {
+		return nil
+	}
+
+	return g.stacks[g.currentTabIdx].Peek()
+}
+
+// HasPrev reports whether the current tab of the group can navigate backward.
+func (g *TabGroup) HasPrev() bool {
+	if g.currentTabIdx < 0 || g.currentTabIdx >= len(g.stacks) {
+		return false
+	}
+	return g.stacks[g.currentTabIdx].Depth() > 1
+}
+
+// HasNext reports whether the current tab of the group can navigate forward.
+func (g *TabGroup) HasNext() bool {
+	if g.currentTabIdx < 0 || g.currentTabIdx >= len(g.stacks) {
+		return false
+	}
+	return g.stacks[g.currentTabIdx].HasNext()
+}
+
+// Len returns the number of tabs in the group.
+func (g *TabGroup) Len() int {
+	return len(g.stacks)
+}
+
+func (g *TabGroup) removeTab(idx int) *ViewStack {
+	stack := g.stacks[idx]
+	g.stacks = slices.Delete(g.stacks, idx, idx+1)
+	if g.currentTabIdx >= idx && g.currentTabIdx > 0 {
+		g.currentTabIdx -= 1
+	}
+	if idx < g.pinned {
+		g.pinned--
+	}
+
+	return stack
+}
+
+// IsLeaf reports whether the node holds a tab group.
+func (n *WorkspaceNode) IsLeaf() bool {
+	return n.Group != nil
+}
+
+// Ratio returns how much space is available to the first child.
+func (n *WorkspaceNode) Ratio() float32 {
+	return n.resize.Ratio
+}
+
+// SetRatio resizes the split. The ratio is clamped to [0.1, 0.9].
+func (n *WorkspaceNode) SetRatio(ratio float32) {
+	n.resize.Ratio = max(0.1, min(0.9, ratio))
+}
+
+// Groups returns the tab groups under the node in layout order.
+func (n *WorkspaceNode) Groups() []*TabGroup {
+	if n.IsLeaf() {
+		return []*TabGroup{n.Group}
+	}
+
+	return append(n.First.Groups(), n.Second.Groups()...)
+}
+
+func newLeafNode(group *TabGroup) *WorkspaceNode {
+	node := &WorkspaceNode{Group: group}
+	group.node = node
+	return node
+}
+
+func (vm *defaultViewManager) resetWorkspace() {
+	vm.group = &TabGroup{}
+	vm.workspace = newLeafNode(vm.group)
+}
+
+// clearWorkspace finishes all the views and collapses the workspace to a single group.
+func (vm *defaultViewManager) clearWorkspace() {
+	for _, group := range vm.workspace.Groups() {
+		for _, stack := range group.stacks {
+			vm.clearStack(stack)
+		}
+	}
+
+	vm.resetWorkspace()
+}
+
+func (vm *defaultViewManager) Workspace() *WorkspaceNode {
+	return vm.workspace
+}
+
+func (vm *defaultViewManager) TabGroups() []*TabGroup {
+	return vm.workspace.Groups()
+}
+
+func (vm *defaultViewManager) FocusedGroup() *TabGroup {
+	return vm.group
+}
+
+func (vm *defaultViewManager) FocusGroup(group *TabGroup) error {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if !slices.Contains(vm.workspace.Groups(), group) {
+		return ErrGroupNotFound
+	}
+
+	if group == vm.group {
+		return nil
+	}
+
+	vm.group = group
+	vm.window.Invalidate()
+	return nil
+}
+
+func (vm *defaultViewManager) SplitGroup(axis layout.Axis) *TabGroup {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	group := &TabGroup{}
+	leaf := vm.group.node
+	// The leaf node becomes an inner node, with the existing group as its
+	// first child and the new group as the second child.
+	first := newLeafNode(leaf.Group)
+	second := newLeafNode(group)
+	first.parent, second.parent = leaf, leaf
+
+	leaf.Group = nil
+	leaf.Axis = axis
+	leaf.First, leaf.Second = first, second
+	leaf.SetRatio(0.5)
+
+	vm.group = group
+	vm.window.Invalidate()
+	return group
+}
+
+func (vm *defaultViewManager) MoveTab(idx int, target *TabGroup) error {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if idx < 0 || idx >= len(vm.group.stacks) {
+		return errors.New("tab index out of range")
+	}
+
+	if !slices.Contains(vm.workspace.Groups(), target) {
+		return ErrGroupNotFound
+	}
+
+	if target == vm.group {
+		return nil
+	}
+
+	source := vm.group
+	// the view of the target group is hidden by the moved tab.
+	if vw := target.CurrentView(); vw != nil {
+		vm.pauseView(vw)
+	}
+	stack := source.removeTab(idx)
+	target.stacks = append(target.stacks, stack)
+	target.currentTabIdx = len(target.stacks) - 1
+
+	// the new current view of the source group becomes visible.
+	if vw := source.CurrentView(); vw != nil {
+		vm.resumeView(vw)
+	}
+
+	vm.group = target
+	if source.Len() <= 0 {
+		vm.removeGroup(source)
+	}
+
+	vm.window.Invalidate()
+	return nil
+}
+
+func (vm *defaultViewManager) CloseGroup(group *TabGroup) {
+	defer vm.flushEvents()
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if !slices.Contains(vm.workspace.Groups(), group) {
+		return
+	}
+
+	for _, stack := range group.stacks {
+		if !vm.canLeave(NavCloseTab, stack.Peek()) {
+			return
+		}
+	}
+
+	for _, stack := range group.stacks {
+		vm.clearStack(stack)
+	}
+	group.stacks = group.stacks[:0]
+	group.currentTabIdx = 0
+	group.pinned = 0
+	vm.removeGroup(group)
+	vm.window.Invalidate()
+}
+
+// removeGroup removes the group from the workspace tree and collapses its parent
+// node. The last group in the workspace is never removed.
+func (vm *defaultViewManager) removeGroup(group *TabGroup) {
+	leaf := group.node
+	parent := leaf.parent
+	if parent == nil {
+		return
+	}
+
+	sibling := parent.First
+	if sibling == leaf {
+		sibling = parent.Second
+	}
+
+	// replace the parent with the sibling.
+	parent.Axis = sibling.Axis
+	parent.First, parent.Second = sibling.First, sibling.Second
+	parent.Group = sibling.Group
+	parent.resize = sibling.resize
+	if parent.Group != nil {
+		parent.Group.node = parent
+	} else {
+		parent.First.parent, parent.Second.parent = parent, parent
+	}
+
+	if vm.group == group {
+		vm.group = parent.Groups()[0]
+	}
+}
+
+// SplitView lays out the workspace of a ViewManager. Each tab group is laid out
+// by LayoutGroup, separated by draggable divider bars. Pressing inside a group
+// focuses the group.
+type SplitView struct {
+	VM ViewManager
+	// LayoutGroup lays out a tab group, usually with a tab bar and the current
+	// view of the group.
+	LayoutGroup func(gtx C, th *theme.Theme, group *TabGroup) D
+	// BarWidth is the width of the divider bar between the groups.
+	BarWidth unit.Dp
+}
+
+func (sv SplitView) Layout(gtx C, th *theme.Theme) D {
+	if sv.BarWidth <= 0 {
+		sv.BarWidth = unit.Dp(4)
+	}
+
+	return sv.layoutNode(gtx, th, sv.VM.Workspace())
+}
+
+func (sv SplitView) layoutNode(gtx C, th *theme.Theme, node *WorkspaceNode) D {
+	if node.IsLeaf() {
+		return sv.layoutGroup(gtx, th, node.Group)
+	}
+
+	node.resize.Axis = node.Axis
+	return node.resize.Layout(gtx,
+		func(gtx C) D { return sv.layoutNode(gtx, th, node.First) },
+		func(gtx C) D { return sv.layoutNode(gtx, th, node.Second) },
+		func(gtx C) D { return sv.layoutBar(gtx, th, node.Axis) },
+	)
+}
+
+func (sv SplitView) layoutGroup(gtx C, th *theme.Theme, group *TabGroup) D {
+	for {
+		evt, ok := gtx.Event(pointer.Filter{Target: group, Kinds: pointer.Press})
+		if !ok {
+			break
+		}
+		if e, ok := evt.(pointer.Event); ok && e.Kind == pointer.Press && sv.VM.FocusedGroup() != group {
+			sv.VM.FocusGroup(group)
+		}
+	}
+
+	gtx.Constraints.Min = gtx.Constraints.Max
+	dims := sv.LayoutGroup(gtx, th, group)
+
+	defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
+	// let the widgets of the group receive pointer events.
+	defer pointer.PassOp{}.Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, group)
+	return dims
+}
+
+func (sv SplitView) layoutBar(gtx C, th *theme.Theme, axis layout.Axis) D {
+	size := image.Point{X: gtx.Dp(sv.BarWidth), Y: gtx.Constraints.Max.Y}
+	cursor := pointer.CursorColResize
+	if axis == layout.Vertical {
+		size = image.Point{X: gtx.Constraints.Max.X, Y: gtx.Dp(sv.BarWidth)}
+		cursor = pointer.CursorRowResize
+	}
+
+	rect := clip.Rect{Max: size}
+	defer rect.Push(gtx.Ops).Pop()
+	cursor.Add(gtx.Ops)
+	paint.FillShape(gtx.Ops, misc.WithAlpha(th.Fg, th.HoverAlpha), rect.Op())
+	return D{Size: size}
+}
@@ -0,0 +1,72 @@
+package view
+
+import (
+	"bytes"
+	"testing"
+
+	"gioui.org/layout"
+)
+
+func TestSplitWorkspace(t *testing.T) {
+	vm := newTestVM()
+	vm.RequestSwitch(Intent{Target: listViewID})
+	vm.RequestSwitch(Intent{Target: noteViewID, RequireNew: true})
+	left := vm.FocusedGroup()
+
+	right := vm.SplitGroup(layout.Horizontal)
+	if vm.FocusedGroup() != right || len(vm.TabGroups()) != 2 {
+		t.Fatal("the new group should be focused")
+	}
+
+	// opening a view in the focused group.
+	vm.RequestSwitch(Intent{Target: listViewID, Params: Params{"page": 2}})
+	if right.Len() != 1 || left.Len() != 2 {
+		t.Fatalf("unexpected tabs: left %d, right %d", left.Len(), right.Len())
+	}
+
+	vm.FocusGroup(left)
+	var events []LifecycleEvent
+	cancel := vm.Subscribe(func(evt LifecycleEvent) {
+		events = append(events, evt)
+	})
+	hidden := right.CurrentView()
+	if err := vm.MoveTab(1, right); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if right.Len() != 2 || right.CurrentView().ID() != noteViewID || vm.FocusedGroup() != right {
+		t.Fatal("tab is not moved to the target group")
+	}
+	// the hidden view of the target group is paused, and the new current view
+	// of the source group is resumed.
+	if len(events) != 2 || events[0].Kind != PauseEvent || events[0].View != hidden ||
+		events[1].Kind != ResumeEvent || events[1].View != left.CurrentView() {
+		t.Fatalf("unexpected lifecycle events: %+v", events)
+	}
+
+	vm.Workspace().SetRatio(0.3)
+
+	var buf bytes.Buffer
+	if err := vm.SaveSession(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newTestVM()
+	if err := restored.RestoreSession(&buf); err != nil {
+		t.Fatal(err)
+	}
+	root := restored.Workspace()
+	if root.IsLeaf() || root.Axis != layout.Horizontal || root.Ratio() != 0.3 {
+		t.Fatal("workspace tree is not restored")
+	}
+	if restored.FocusedGroup() != root.Second.Group || restored.CurrentView().ID() != noteViewID {
+		t.Fatal("focused group is not restored")
+	}
+
+	// closing the last tab of a group collapses the split.
+	vm.FocusGroup(left)
+	vm.CloseTab(0)
+	if len(vm.TabGroups()) != 1 || !vm.Workspace().IsLeaf() || vm.FocusedGroup() != right {
+		t.Fatal("empty group is not removed")
+	}
+}
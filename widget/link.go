@@ -1,6 +1,7 @@
 package widget
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"net/url"
@@ -23,7 +24,8 @@ import (
 )
 
 // LinkSrc defines a generic type constraint.
-// String type is for web url. And ViewID indicates a Gioview View.
+// String type is for web url or gioview URL which can be opened by
+// [view.ViewManager.OpenURL]. And ViewID indicates a Gioview View.
 type LinkSrc interface {
 	~string | view.ViewID
 }
@@ -32,7 +34,7 @@ type LinkSrc interface {
 type Link[T LinkSrc] struct {
 	Title  string
 	Src    T
-	Params map[string]interface{}
+	Params view.Params
 	// Open in new tab. Valid only if the link is a native gioview View.
 	OpenInNewTab bool
 	// Click handler for the link.
@@ -63,7 +65,7 @@ func (link *Link[T]) OnClick() error {
 
 	// Else parse it as a web url.
 	var loc = src.(string)
-	if link.Params == nil {
+	if link.Params != nil {
 		href, err := url.Parse(loc)
 		if err != nil {
 			return nil
@@ -71,8 +73,7 @@ func (link *Link[T]) OnClick() error {
 
 		query := href.Query()
 		for k, v := range link.Params {
-			// only string is allowed
-			query.Add(k, v.(string))
+			query.Add(k, fmt.Sprintf("%v", v))
 		}
 
 		href.RawQuery = query.Encode()
@@ -101,6 +102,16 @@ func (link *Link[T]) Layout(gtx C, lt *text.Shaper, font font.Font, size unit.Sp
 	return tl.Layout(gtx, lt, font, size, link.Title, textMaterial)
 }
 
+// LayoutArea registers the area as a clickable area of the link. It is used to
+// make a link laid out by other widgets clickable, e.g., a link wrapping over
+// several lines of a rich text. An area is registered per line then.
+func (link *Link[T]) LayoutArea(gtx C, area image.Rectangle) {
+	defer clip.Rect(area).Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, link)
+	link.click.Add(gtx.Ops)
+	pointer.CursorPointer.Add(gtx.Ops)
+}
+
 // Update handles link events and reports if the link was clicked.
 func (link *Link[T]) Update(gtx C) bool {
 	for {
@@ -208,6 +219,7 @@ func (ls *LinkStyle[T]) Layout(gtx C, th *theme.Theme) D {
 		}.Op())
 
 	event.Op(gtx.Ops, ls.state)
+	ls.state.click.Add(gtx.Ops)
 	linkCall.Add(gtx.Ops)
 
 	return dims
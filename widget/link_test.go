@@ -0,0 +1,40 @@
+package widget
+
+import (
+	"image"
+	"testing"
+
+	"github.com/oligo/gioview/uitest"
+)
+
+func TestLinkParams(t *testing.T) {
+	var opened any
+	link := &Link[string]{
+		Src:       "https://example.com/docs?lang=go",
+		OnClicked: func(intent any) error { opened = intent; return nil },
+	}
+
+	if link.OnClick(); opened != "https://example.com/docs?lang=go" {
+		t.Fatalf("got %v", opened)
+	}
+
+	// the params are added to the query of the url.
+	link.Params = map[string]interface{}{"page": 2}
+	if link.OnClick(); opened != "https://example.com/docs?lang=go&page=2" {
+		t.Fatalf("got %v", opened)
+	}
+}
+
+func TestLinkClick(t *testing.T) {
+	link := &Link[string]{Title: "gioview", Src: "https://github.com/oligo/gioview"}
+	var h *uitest.Harness
+	h = uitest.New(image.Pt(200, 50), func(gtx C) D {
+		return NewLink(link, "").Layout(gtx, h.Theme)
+	})
+	h.Frame()
+
+	h.Click(image.Pt(5, 5))
+	if !link.Clicked() {
+		t.Fatal("link is not clicked")
+	}
+}
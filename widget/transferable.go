@@ -17,6 +17,7 @@ import (
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
+	"github.com/oligo/gioview/keybinding"
 	"github.com/oligo/gioview/theme"
 )
 
@@ -34,7 +35,10 @@ type TransferTarget interface {
 
 type Transferable struct {
 	Target TransferTarget
-	isCut  bool
+	// Commands has the key chords of the copy, cut and paste commands. If it
+	// is nil, the commands have their default key chords.
+	Commands *keybinding.Registry
+	isCut    bool
 }
 
 type payload struct {
@@ -66,16 +70,16 @@ func toPayload(reader io.ReadCloser) (*payload, error) {
 }
 
 func (t *Transferable) Update(gtx C) error {
+	commands := []keybinding.CommandID{keybinding.CopyCommand, keybinding.CutCommand, keybinding.PasteCommand}
+	filters := []event.Filter{
+		key.FocusFilter{Target: t},
+		pointer.Filter{Target: t, Kinds: pointer.Press},
+		transfer.TargetFilter{Target: t, Type: mimeText},
+		transfer.TargetFilter{Target: t, Type: mimeOctStream}, // not work, why?
+	}
+	filters = append(filters, t.Commands.Filters(t, commands...)...)
 	for {
-		ke, ok := gtx.Event(
-			key.FocusFilter{Target: t},
-			pointer.Filter{Target: t, Kinds: pointer.Press},
-			key.Filter{Focus: t, Name: "C", Required: key.ModShortcut},
-			key.Filter{Focus: t, Name: "V", Required: key.ModShortcut},
-			key.Filter{Focus: t, Name: "X", Required: key.ModShortcut},
-			transfer.TargetFilter{Target: t, Type: mimeText},
-			transfer.TargetFilter{Target: t, Type: mimeOctStream}, // not work, why?
-		)
+		ke, ok := gtx.Event(filters...)
 
 		if !ok {
 			break
@@ -83,19 +87,20 @@ func (t *Transferable) Update(gtx C) error {
 
 		switch event := ke.(type) {
 		case key.Event:
-			if !event.Modifiers.Contain(key.ModShortcut) || event.State != key.Press {
+			id, ok := t.Commands.Match(event, commands...)
+			if !ok || event.State != key.Press {
 				break
 			}
 
-			switch event.Name {
+			switch id {
 			// Initiate a paste operation, by requesting the clipboard contents; other
 			// half is in DataEvent.
-			case "V":
+			case keybinding.PasteCommand:
 				gtx.Execute(clipboard.ReadCmd{Tag: t})
 
 			// Copy or Cut selection -- ignored if nothing selected.
-			case "C", "X":
-				gtx.Execute(clipboard.WriteCmd{Type: mimeOctStream, Data: io.NopCloser(asPayload(t.Target.Data(), event.Name == "X"))})
+			case keybinding.CopyCommand, keybinding.CutCommand:
+				gtx.Execute(clipboard.WriteCmd{Type: mimeOctStream, Data: io.NopCloser(asPayload(t.Target.Data(), id == keybinding.CutCommand))})
 				t.isCut = true
 			}
 